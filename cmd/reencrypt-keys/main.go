@@ -0,0 +1,76 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Command reencrypt-keys re-encrypts stored private keys from one passphrase to another.
+//
+// The keys are read as a JSON object mapping key names to {"cipher_version": ..., "encrypted_value": ...}, and
+// written in the same format. The passphrases are read from the OLD_KEY_PASSPHRASE and NEW_KEY_PASSPHRASE
+// environment variables, so that they do not appear in the process list or the shell history:
+//
+//	reencrypt-keys -in keys.json -out rotated-keys.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+)
+
+func main() {
+	inPath := flag.String("in", "", "file to read the encrypted keys from. Defaults to stdin")
+	outPath := flag.String("out", "", "file to write the re-encrypted keys to. Defaults to stdout")
+	iterations := flag.Int("iterations", crypto.DEFAULT_KEY_ENCRYPTION_ITERATIONS,
+		"number of PBKDF2 iterations used to encrypt")
+	flag.Parse()
+
+	if err := run(*inPath, *outPath, *iterations); err != nil {
+		fmt.Fprintln(os.Stderr, "reencrypt-keys:", err)
+		os.Exit(1)
+	}
+}
+
+func run(inPath string, outPath string, iterations int) error {
+	oldPassphrase, ok := os.LookupEnv("OLD_KEY_PASSPHRASE")
+	if !ok {
+		return fmt.Errorf("OLD_KEY_PASSPHRASE is not set")
+	}
+	newPassphrase, ok := os.LookupEnv("NEW_KEY_PASSPHRASE")
+	if !ok || newPassphrase == "" {
+		return fmt.Errorf("NEW_KEY_PASSPHRASE is not set")
+	}
+
+	var in io.Reader = os.Stdin
+	if inPath != "" {
+		file, err := os.Open(inPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	var keys map[string]crypto.EncryptedKey
+	if err := json.NewDecoder(in).Decode(&keys); err != nil {
+		return fmt.Errorf("error parsing keys: %w", err)
+	}
+
+	reEncryptedKeys, err := crypto.ReEncryptKeys(keys,
+		crypto.PassphraseKeyCipher{Passphrase: oldPassphrase},
+		crypto.PassphraseKeyCipher{Passphrase: newPassphrase, Iterations: iterations})
+	if err != nil {
+		return err
+	}
+	encodedKeys, err := json.MarshalIndent(reEncryptedKeys, "", "  ")
+	if err != nil {
+		return err
+	}
+	encodedKeys = append(encodedKeys, '\n')
+	if outPath == "" {
+		_, err = os.Stdout.Write(encodedKeys)
+		return err
+	}
+	// The keys stay encrypted, but the file should still only be readable by its owner.
+	return os.WriteFile(outPath, encodedKeys, 0o600)
+}
@@ -0,0 +1,57 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Command selfcheck verifies the configuration of a Lightspark integration with LightsparkClient.SelfCheck.
+//
+// The configuration is read from the environment:
+//
+//	LIGHTSPARK_API_TOKEN_CLIENT_ID, LIGHTSPARK_API_TOKEN_CLIENT_SECRET: the API token.
+//	LIGHTSPARK_BASE_URL: optional, the base URL of the API.
+//	LIGHTSPARK_NODE_ID, LIGHTSPARK_NODE_PASSWORD: optional, a node and the password of its signing key.
+//	LIGHTSPARK_WEBHOOK_SECRET: optional, the webhook signing secret.
+//	LIGHTSPARK_UMA_RECEIVER_ADDRESSES: optional, comma separated UMA addresses served by the integration.
+//
+// It exits with status 1 if a check fails.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/services"
+)
+
+func main() {
+	client, err := services.NewLightsparkClientFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "selfcheck:", err)
+		os.Exit(1)
+	}
+
+	config := services.SelfCheckConfig{WebhookSecret: os.Getenv("LIGHTSPARK_WEBHOOK_SECRET")}
+	if nodeId := os.Getenv("LIGHTSPARK_NODE_ID"); nodeId != "" {
+		config.NodeIds = []string{nodeId}
+		if password := os.Getenv("LIGHTSPARK_NODE_PASSWORD"); password != "" {
+			client.LoadNodeSigningKey(nodeId, *services.NewSigningKeyLoaderFromNodeIdAndPassword(nodeId, password))
+		}
+	}
+	if addresses := os.Getenv("LIGHTSPARK_UMA_RECEIVER_ADDRESSES"); addresses != "" {
+		config.UmaReceiverAddresses = strings.Split(addresses, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	report := client.SelfCheck(ctx, config)
+	for _, result := range report.Results {
+		if result.Err != nil {
+			fmt.Printf("FAIL %s: %s\n", result.Name, result.Err.Error())
+		} else {
+			fmt.Printf("ok   %s\n", result.Name)
+		}
+	}
+	if !report.Ok() {
+		os.Exit(1)
+	}
+}
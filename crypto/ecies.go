@@ -0,0 +1,105 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const eciesPublicKeyLen = 65
+const eciesNonceLen = 16
+const eciesTagLen = 16
+
+// EciesEncrypt encrypts a message for the holder of a secp256k1 public key. The output format is compatible with the
+// ECIES scheme used by the UMA SDKs: ephemeral public key (uncompressed) || nonce || tag || ciphertext.
+func EciesEncrypt(publicKeyBytes []byte, message []byte) ([]byte, error) {
+	publicKey, err := btcec.ParsePubKey(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublicKey := ephemeralKey.PubKey().SerializeUncompressed()
+	key, err := eciesSharedKey(ephemeralPublicKey, ephemeralKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newEciesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, eciesNonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nil, nonce, message, nil)
+	tag := sealed[len(sealed)-eciesTagLen:]
+	ciphertext := sealed[:len(sealed)-eciesTagLen]
+
+	result := make([]byte, 0, len(ephemeralPublicKey)+eciesNonceLen+eciesTagLen+len(ciphertext))
+	result = append(result, ephemeralPublicKey...)
+	result = append(result, nonce...)
+	result = append(result, tag...)
+	return append(result, ciphertext...), nil
+}
+
+// EciesDecrypt decrypts a message produced by EciesEncrypt with the matching secp256k1 private key.
+func EciesDecrypt(privateKeyBytes []byte, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < eciesPublicKeyLen+eciesNonceLen+eciesTagLen {
+		return nil, errors.New("encrypted message is too short")
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(privateKeyBytes)
+	ephemeralPublicKeyBytes := encrypted[:eciesPublicKeyLen]
+	ephemeralPublicKey, err := btcec.ParsePubKey(ephemeralPublicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := eciesSharedKey(ephemeralPublicKeyBytes, privateKey, ephemeralPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := encrypted[eciesPublicKeyLen:]
+	nonce := rest[:eciesNonceLen]
+	tag := rest[eciesNonceLen : eciesNonceLen+eciesTagLen]
+	ciphertext := rest[eciesNonceLen+eciesTagLen:]
+
+	gcm, err := newEciesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, append(append([]byte{}, ciphertext...), tag...), nil)
+}
+
+func eciesSharedKey(ephemeralPublicKey []byte, privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey) ([]byte, error) {
+	var point, sharedPoint btcec.JacobianPoint
+	publicKey.AsJacobian(&point)
+	btcec.ScalarMultNonConst(&privateKey.Key, &point, &sharedPoint)
+	sharedPoint.ToAffine()
+	sharedPublicKey := btcec.NewPublicKey(&sharedPoint.X, &sharedPoint.Y)
+
+	secret := append(append([]byte{}, ephemeralPublicKey...), sharedPublicKey.SerializeUncompressed()...)
+	key := make([]byte, KEY_LEN)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, nil), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newEciesGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, eciesNonceLen)
+}
@@ -0,0 +1,111 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// DEFAULT_KEY_ENCRYPTION_ITERATIONS is the number of PBKDF2 iterations used by EncryptPrivateKey.
+const DEFAULT_KEY_ENCRYPTION_ITERATIONS = 500000
+
+// EncryptedKey is a private key encrypted with a passphrase or a KMS key.
+type EncryptedKey struct {
+	// CipherVersion describes how the key was encrypted, as accepted by DecryptPrivateKey.
+	CipherVersion string `json:"cipher_version"`
+	// EncryptedValue is the base64 encoded encrypted key.
+	EncryptedValue string `json:"encrypted_value"`
+}
+
+// KeyCipher encrypts and decrypts stored private keys. Implement it to re-encrypt keys with a KMS.
+type KeyCipher interface {
+	Encrypt(privateKey []byte) (*EncryptedKey, error)
+	Decrypt(encryptedKey *EncryptedKey) ([]byte, error)
+}
+
+// PassphraseKeyCipher is a KeyCipher deriving its key from a passphrase with PBKDF2, in the format of
+// DecryptPrivateKey.
+type PassphraseKeyCipher struct {
+	Passphrase string
+	// Iterations is the number of PBKDF2 iterations used to encrypt. Defaults to DEFAULT_KEY_ENCRYPTION_ITERATIONS.
+	Iterations int
+}
+
+func (c PassphraseKeyCipher) Encrypt(privateKey []byte) (*EncryptedKey, error) {
+	iterations := c.Iterations
+	if iterations == 0 {
+		iterations = DEFAULT_KEY_ENCRYPTION_ITERATIONS
+	}
+	return EncryptPrivateKey(privateKey, c.Passphrase, iterations)
+}
+
+func (c PassphraseKeyCipher) Decrypt(encryptedKey *EncryptedKey) ([]byte, error) {
+	return DecryptPrivateKey(encryptedKey.CipherVersion, encryptedKey.EncryptedValue, c.Passphrase)
+}
+
+// EncryptPrivateKey encrypts a private key with a passphrase using AES-256-GCM and a PBKDF2-SHA256 derived key
+// (cipher version 4), so that it can be decrypted by DecryptPrivateKey.
+func EncryptPrivateKey(privateKey []byte, password string, iterations int) (*EncryptedKey, error) {
+	if iterations <= 0 {
+		return nil, errors.New("invalid number of iterations")
+	}
+	cipherVersion, err := json.Marshal(map[string]interface{}{"v": 4, "i": iterations})
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, nonce := deriveKeyIv([]byte(password), salt, iterations, KEY_LEN+12)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := gcm.Seal(salt, nonce, privateKey, nil)
+	return &EncryptedKey{
+		CipherVersion:  string(cipherVersion),
+		EncryptedValue: base64.StdEncoding.EncodeToString(encrypted),
+	}, nil
+}
+
+// ReEncryptKeys decrypts stored keys with one KeyCipher and encrypts them with another, e.g. to rotate the
+// passphrase or the KMS key protecting them. It fails without returning any key if one of them cannot be
+// re-encrypted, so that stores are never left partially rotated.
+//
+// Args:
+//
+//	keys: the encrypted keys by name.
+//	from: the cipher the keys are currently encrypted with.
+//	to: the cipher to encrypt the keys with.
+func ReEncryptKeys(keys map[string]EncryptedKey, from KeyCipher, to KeyCipher) (map[string]EncryptedKey, error) {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reEncryptedKeys := make(map[string]EncryptedKey, len(keys))
+	for _, name := range names {
+		encryptedKey := keys[name]
+		privateKey, err := from.Decrypt(&encryptedKey)
+		if err != nil {
+			return nil, errors.New("error decrypting key " + name + ": " + err.Error())
+		}
+		reEncryptedKey, err := to.Encrypt(privateKey)
+		if err != nil {
+			return nil, errors.New("error encrypting key " + name + ": " + err.Error())
+		}
+		reEncryptedKeys[name] = *reEncryptedKey
+	}
+	return reEncryptedKeys, nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/lightsparkdev/go-sdk/crypto"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
 	"github.com/stretchr/testify/require"
 )
@@ -17,3 +18,38 @@ func TestDerivePublicKey(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon", publicKey)
 }
+
+func TestEciesRoundTrip(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	message := []byte("invoice #1234")
+
+	encrypted, err := crypto.EciesEncrypt(privateKey.PubKey().SerializeCompressed(), message)
+	require.NoError(t, err)
+	decrypted, err := crypto.EciesDecrypt(privateKey.Serialize(), encrypted)
+	require.NoError(t, err)
+	require.Equal(t, message, decrypted)
+
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = crypto.EciesDecrypt(privateKey.Serialize(), encrypted)
+	require.Error(t, err)
+}
+
+func TestReEncryptKeys(t *testing.T) {
+	privateKey := []byte("node signing key")
+	oldCipher := crypto.PassphraseKeyCipher{Passphrase: "old passphrase", Iterations: 1000}
+	newCipher := crypto.PassphraseKeyCipher{Passphrase: "new passphrase", Iterations: 1000}
+	encryptedKey, err := oldCipher.Encrypt(privateKey)
+	require.NoError(t, err)
+
+	keys, err := crypto.ReEncryptKeys(map[string]crypto.EncryptedKey{"node": *encryptedKey}, oldCipher, newCipher)
+	require.NoError(t, err)
+	reEncryptedKey := keys["node"]
+	decrypted, err := crypto.DecryptPrivateKey(reEncryptedKey.CipherVersion, reEncryptedKey.EncryptedValue,
+		"new passphrase")
+	require.NoError(t, err)
+	require.Equal(t, privateKey, decrypted)
+
+	_, err = crypto.ReEncryptKeys(map[string]crypto.EncryptedKey{"node": *encryptedKey}, newCipher, oldCipher)
+	require.ErrorContains(t, err, "error decrypting key node")
+}
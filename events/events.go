@@ -0,0 +1,174 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package events defines the lifecycle events emitted by the SDK, e.g. to build custom monitoring without wrapping
+// every API. The fields of Event and the values of Type are stable: new ones may be added, but existing ones are not
+// renamed or removed.
+package events
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Type is the type of an Event.
+type Type string
+
+const (
+	// RequestStarted is emitted when a GraphQL request starts, before the request interceptors run.
+	RequestStarted Type = "request_started"
+	// RequestFinished is emitted when a GraphQL request finished, with its Duration and Error.
+	RequestFinished Type = "request_finished"
+	// RequestRetried is emitted before a failed attempt of a GraphQL request is retried, with the Attempt which
+	// failed, the Delay before the next one, and the Error.
+	RequestRetried Type = "request_retried"
+	// CacheHit is emitted when the result of a GraphQL request is served by the response cache.
+	CacheHit Type = "cache_hit"
+	// UmaStepCompleted is emitted when the SDK completed a step of an UMA payment, named by Step.
+	UmaStepCompleted Type = "uma_step_completed"
+)
+
+// Steps of UmaStepCompleted events.
+const (
+	UMA_STEP_INVOICE_CREATED  = "invoice_created"
+	UMA_STEP_PAYREQ_CANCELLED = "payreq_cancelled"
+)
+
+// Event is a lifecycle event of the SDK. Fields which do not apply to the Type are empty.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// OperationName is the name of the GraphQL operation, for request events.
+	OperationName string `json:"operation_name,omitempty"`
+	// RequestId is the X-Request-ID of the request, for request events.
+	RequestId string `json:"request_id,omitempty"`
+	// Attempt is the number of the attempt, starting at 1, for RequestRetried events.
+	Attempt int `json:"attempt,omitempty"`
+	// Delay is the delay before the next attempt, for RequestRetried events.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Duration is the duration of the request, including retries, for RequestFinished events.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Error is the redacted error of the request or attempt, if it failed.
+	Error string `json:"error,omitempty"`
+	// Step is the UMA step, for UmaStepCompleted events.
+	Step string `json:"step,omitempty"`
+	// Attributes hold additional details of the event, e.g. the counterparty domain of an UMA step.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Sink receives the events emitted by the SDK. Emit is called synchronously by the code emitting the event, so it
+// must not block for long; use a ChannelSink to process events asynchronously.
+type Sink interface {
+	Emit(event Event)
+}
+
+// SinkFunc is a Sink calling a function.
+type SinkFunc func(event Event)
+
+func (f SinkFunc) Emit(event Event) {
+	f(event)
+}
+
+// Emit sends an event to a sink, setting its Time if it is not set. It does nothing if sink is nil.
+func Emit(sink Sink, event Event) {
+	if sink == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	sink.Emit(event)
+}
+
+// Backpressure is what a ChannelSink does with an event when its buffer is full.
+type Backpressure int
+
+const (
+	// DropNewest drops the event, so that the emitting code is never slowed down. Dropped events are counted.
+	DropNewest Backpressure = iota
+	// DropOldest drops the oldest buffered event to make room for the event.
+	DropOldest
+	// Block waits until there is room in the buffer or the sink is closed, slowing down the emitting code.
+	Block
+)
+
+// ChannelSink is a Sink buffering events in a channel, to be consumed from Events. It is safe for concurrent use.
+type ChannelSink struct {
+	// dropped is first so that it is 64-bit aligned for atomic operations on 32-bit platforms.
+	dropped      uint64
+	events       chan Event
+	backpressure Backpressure
+	mutex        sync.RWMutex
+	closed       bool
+	// done is closed by Close, so that blocked Emit calls give up.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewChannelSink creates a ChannelSink buffering up to size events, handling a full buffer with backpressure. The
+// size must be positive unless backpressure is Block, in which case 0 makes every Emit wait for a consumer.
+func NewChannelSink(size int, backpressure Backpressure) (*ChannelSink, error) {
+	if size < 0 || (size == 0 && backpressure != Block) {
+		return nil, errors.New("the buffer size of a ChannelSink must be positive unless its backpressure is Block")
+	}
+	return &ChannelSink{events: make(chan Event, size), backpressure: backpressure, done: make(chan struct{})}, nil
+}
+
+// Events returns the channel of the events. It is closed by Close.
+func (s *ChannelSink) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer was full or the sink was closed.
+func (s *ChannelSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *ChannelSink) Emit(event Event) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	switch s.backpressure {
+	case Block:
+		select {
+		case s.events <- event:
+		case <-s.done:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		return
+	case DropOldest:
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.events:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	}
+	select {
+	case s.events <- event:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Close closes the channel of the events once the pending Emit calls returned. Emit calls blocked on a full buffer and
+// the events emitted afterwards are dropped.
+func (s *ChannelSink) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
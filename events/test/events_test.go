@@ -0,0 +1,76 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelSink_Backpressure(t *testing.T) {
+	sink, err := events.NewChannelSink(1, events.DropNewest)
+	require.NoError(t, err)
+	events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "first"})
+	events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "second"})
+	require.Equal(t, uint64(1), sink.Dropped())
+	event := <-sink.Events()
+	require.Equal(t, "first", event.OperationName)
+	require.False(t, event.Time.IsZero())
+
+	sink, err = events.NewChannelSink(1, events.DropOldest)
+	require.NoError(t, err)
+	events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "first"})
+	events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "second"})
+	require.Equal(t, uint64(1), sink.Dropped())
+	require.Equal(t, "second", (<-sink.Events()).OperationName)
+
+	sink.Close()
+	events.Emit(sink, events.Event{Type: events.RequestStarted})
+	_, ok := <-sink.Events()
+	require.False(t, ok)
+}
+
+func TestChannelSink_RejectsEmptyDropBuffers(t *testing.T) {
+	_, err := events.NewChannelSink(0, events.DropNewest)
+	require.Error(t, err)
+	_, err = events.NewChannelSink(0, events.DropOldest)
+	require.Error(t, err)
+	_, err = events.NewChannelSink(-1, events.Block)
+	require.Error(t, err)
+	_, err = events.NewChannelSink(0, events.Block)
+	require.NoError(t, err)
+}
+
+func TestChannelSink_CloseReleasesBlockedEmits(t *testing.T) {
+	sink, err := events.NewChannelSink(1, events.Block)
+	require.NoError(t, err)
+	events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "first"})
+
+	emitted := make(chan struct{})
+	go func() {
+		events.Emit(sink, events.Event{Type: events.RequestStarted, OperationName: "second"})
+		close(emitted)
+	}()
+	select {
+	case <-emitted:
+		t.Fatal("Emit did not block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		sink.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on a pending Emit")
+	}
+	<-emitted
+	require.Equal(t, uint64(1), sink.Dropped())
+	require.Equal(t, "first", (<-sink.Events()).OperationName)
+	_, ok := <-sink.Events()
+	require.False(t, ok)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/gin-gonic/gin"
+	lsuma "github.com/lightsparkdev/go-sdk/uma"
 	"github.com/uma-universal-money-address/uma-go-sdk/uma"
 	"log"
 	"os"
@@ -30,10 +31,16 @@ func main() {
 	pubKeyCache := uma.NewInMemoryPublicKeyCache()
 	oneDayAgo := time.Now().AddDate(0, 0, -1)
 	vasp1 := NewVasp1(&config, pubKeyCache)
+	encryptionPrivKey, err := config.UmaEncryptionPrivKeyBytes()
+	if err != nil {
+		log.Fatalf("Invalid encryption private key: %v", err)
+	}
 	vasp2 := Vasp2{
 		config:      &config,
 		pubKeyCache: pubKeyCache,
 		nonceCache:  uma.NewInMemoryNonceCache(oneDayAgo),
+		// Senders which do not name themselves in their pubkey requests get the master key.
+		encryptionKeys: lsuma.CounterpartyEncryptionKeys{MasterPrivateKey: encryptionPrivKey, AcceptMasterKey: true},
 	}
 
 	// VASP1 Routes:
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/services"
+	lsuma "github.com/lightsparkdev/go-sdk/uma"
 	"github.com/lightsparkdev/go-sdk/utils"
 	"github.com/uma-universal-money-address/uma-go-sdk/uma"
 	"io"
@@ -28,17 +29,53 @@ type Vasp1 struct {
 	requestCache *Vasp1RequestCache
 	nonceCache   uma.NonceCache
 	client       *services.LightsparkClient
+	// identities holds the signing keys of the VASP domains payments are sent from.
+	identities *lsuma.IdentityRegistry
 }
 
 func NewVasp1(config *UmaConfig, pubKeyCache uma.PublicKeyCache) *Vasp1 {
 	oneDayAgo := time.Now().AddDate(0, 0, -1)
+	identities := lsuma.NewIdentityRegistry()
+	if config.SenderVaspDomain != "" {
+		signingPrivateKey, err := config.UmaSigningPrivKeyBytes()
+		if err != nil {
+			log.Fatalf("Invalid UMA signing private key: %v", err)
+		}
+		signingPubKey, err := config.UmaSigningPubKeyBytes()
+		if err != nil {
+			log.Fatalf("Invalid UMA signing public key: %v", err)
+		}
+		err = identities.Register(lsuma.SendingIdentity{
+			Name:              "default",
+			VaspDomain:        config.SenderVaspDomain,
+			SigningPrivateKey: signingPrivateKey,
+			SigningPubKey:     signingPubKey,
+		})
+		if err != nil {
+			log.Fatalf("Invalid UMA sending identity: %v", err)
+		}
+	}
 	return &Vasp1{
 		config:       config,
 		pubKeyCache:  pubKeyCache,
 		requestCache: NewVasp1RequestCache(),
 		nonceCache:   uma.NewInMemoryNonceCache(oneDayAgo),
 		client:       services.NewLightsparkClient(config.ApiClientID, config.ApiClientSecret, config.ClientBaseURL),
+		identities:   identities,
+	}
+}
+
+// signingPrivateKey returns the signing key of the sending identity of the VASP domain a request is sent from.
+func (v *Vasp1) signingPrivateKey(context *gin.Context) ([]byte, error) {
+	identity, err := v.identities.ForVaspDomain(v.getVaspDomain(context))
+	if errors.Is(err, lsuma.ErrUnknownIdentity) && v.config.SenderVaspDomain == "" {
+		// Without a configured domain, the domain is the host of the request, e.g. localhost:8080 in development.
+		return v.config.UmaSigningPrivKeyBytes()
+	}
+	if err != nil {
+		return nil, err
 	}
+	return identity.SigningPrivateKey, nil
 }
 
 func (v *Vasp1) handleClientUmaLookup(context *gin.Context) {
@@ -54,7 +91,14 @@ func (v *Vasp1) handleClientUmaLookup(context *gin.Context) {
 	addressParts := strings.Split(receiverAddress, "@")
 	receiverId := addressParts[0]
 	receiverVasp := addressParts[1]
-	signingKey, err := v.config.UmaSigningPrivKeyBytes()
+	signingKey, err := v.signingPrivateKey(context)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"status": "ERROR",
+			"reason": err.Error(),
+		})
+		return
+	}
 
 	lnurlpRequest, err := uma.GetSignedLnurlpRequestUrl(
 		signingKey, receiverAddress, v.getVaspDomain(context), true, nil)
@@ -255,7 +299,7 @@ func (v *Vasp1) handleClientPayReq(context *gin.Context) {
 		return
 	}
 
-	umaSigningPrivateKey, err := v.config.UmaSigningPrivKeyBytes()
+	umaSigningPrivateKey, err := v.signingPrivateKey(context)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
 			"status": "ERROR",
@@ -19,6 +19,8 @@ type Vasp2 struct {
 	config      *UmaConfig
 	pubKeyCache uma.PublicKeyCache
 	nonceCache  uma.NonceCache
+	// encryptionKeys derives the encryption key published to each sending VASP from the master encryption key.
+	encryptionKeys lsuma.CounterpartyEncryptionKeys
 }
 
 // Note: In a real application, this exchange rate would come from some real oracle.
@@ -349,6 +351,19 @@ func (v *Vasp2) handleUmaPayreq(context *gin.Context) {
 		return
 	}
 
+	if request.PayerData.Compliance != nil && request.PayerData.Compliance.EncryptedTravelRuleInfo != nil {
+		// In practice, you'd screen the travel rule info and keep it for your records.
+		_, err := v.encryptionKeys.DecryptTravelRuleInfo(sendingVaspDomain,
+			*request.PayerData.Compliance.EncryptedTravelRuleInfo)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"status": "ERROR",
+				"reason": fmt.Sprintf("Invalid travel rule info: %v", err),
+			})
+			return
+		}
+	}
+
 	metadata, err := v.getMetadata()
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
@@ -425,7 +440,7 @@ func (v *Vasp2) handlePubKeyRequest(context *gin.Context) {
 		})
 		return
 	}
-	encryptionPubKeyBytes, err := v.config.UmaEncryptionPubKeyBytes()
+	encryptionPubKeyBytes, err := v.encryptionKeys.PubKeyForRequest(context.Request)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{
 			"status": "ERROR",
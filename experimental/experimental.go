@@ -0,0 +1,76 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package experimental holds the feature flags gating experimental SDK APIs. Experimental features are disabled by
+// default and may change or be removed in any release, so the default behavior of the SDK stays stable.
+package experimental
+
+import (
+	"os"
+	"strings"
+)
+
+// Feature is the name of an experimental feature.
+type Feature string
+
+const (
+	// FeatureSubscriptions Enables GraphQL subscriptions.
+	FeatureSubscriptions Feature = "subscriptions"
+)
+
+// ENV_VAR is the environment variable read by FromEnv, holding a comma-separated list of features.
+const ENV_VAR = "LIGHTSPARK_EXPERIMENTAL_FEATURES"
+
+// Features is a set of enabled experimental features. The zero value has no feature enabled.
+type Features struct {
+	enabled map[Feature]bool
+}
+
+// NewFeatures returns a set with the given features enabled.
+func NewFeatures(features ...Feature) Features {
+	return Features{}.With(features...)
+}
+
+// FromEnv returns the set of features listed in the LIGHTSPARK_EXPERIMENTAL_FEATURES environment variable.
+func FromEnv() Features {
+	var features []Feature
+	for _, name := range strings.Split(os.Getenv(ENV_VAR), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			features = append(features, Feature(name))
+		}
+	}
+	return NewFeatures(features...)
+}
+
+// With returns a copy of the set with the given features enabled in addition.
+func (f Features) With(features ...Feature) Features {
+	enabled := make(map[Feature]bool, len(f.enabled)+len(features))
+	for feature := range f.enabled {
+		enabled[feature] = true
+	}
+	for _, feature := range features {
+		enabled[feature] = true
+	}
+	return Features{enabled: enabled}
+}
+
+// IsEnabled returns whether the feature is enabled.
+func (f Features) IsEnabled(feature Feature) bool {
+	return f.enabled[feature]
+}
+
+// Require returns a *NotEnabledError if the feature is not enabled.
+func (f Features) Require(feature Feature) error {
+	if !f.IsEnabled(feature) {
+		return &NotEnabledError{Feature: feature}
+	}
+	return nil
+}
+
+// NotEnabledError is returned when calling an experimental API without enabling its feature.
+type NotEnabledError struct {
+	Feature Feature
+}
+
+func (e *NotEnabledError) Error() string {
+	return "experimental feature " + string(e.Feature) + " is not enabled"
+}
@@ -0,0 +1,29 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package defaultruntime holds the override of the default runtime of the sdkruntime package. It is internal so that
+// only the tests of the SDK can replace the clock and the entropy used where no runtime is injected, see
+// sdkruntimetest.SetDefault.
+package defaultruntime
+
+import "sync"
+
+var (
+	mutex    sync.RWMutex
+	override interface{}
+)
+
+// Load returns the override, or nil if there is none.
+func Load() interface{} {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return override
+}
+
+// Swap replaces the override and returns the previous one.
+func Swap(runtime interface{}) interface{} {
+	mutex.Lock()
+	defer mutex.Unlock()
+	previous := override
+	override = runtime
+	return previous
+}
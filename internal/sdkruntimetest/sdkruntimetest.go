@@ -0,0 +1,19 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package sdkruntimetest lets the tests of the SDK replace the default runtime of the sdkruntime package. It is
+// internal so that no other package can make the nonces and keys of the SDK deterministic process-wide.
+package sdkruntimetest
+
+import (
+	"github.com/lightsparkdev/go-sdk/internal/defaultruntime"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// SetDefault replaces the runtime used where none is injected, e.g. by the package-level functions of the uma
+// package, and returns a function restoring the previous one.
+func SetDefault(runtime *sdkruntime.Runtime) (restore func()) {
+	previous := defaultruntime.Swap(runtime)
+	return func() {
+		defaultruntime.Swap(previous)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusCollector is a requester.MetricsCollector exporting Prometheus metrics:
+//
+//   - lightspark_requests_total: the number of requests, by operation name.
+//   - lightspark_request_errors_total: the number of failed requests, by operation name and error type.
+//   - lightspark_request_duration_seconds: a histogram of request latencies, by operation name.
+//   - lightspark_uma_store_entries: the number of entries of UMA protocol state stores, by store name.
+//   - lightspark_connections_total: the number of connections used by HTTP attempts, by whether they were reused.
+//   - lightspark_connection_idle_seconds: a histogram of the idle time of reused connections.
+//
+// It also implements requester.ConnectionMetricsCollector and uma.StoreMetrics.
+type PrometheusCollector struct {
+	requests  *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	storeSize *prometheus.GaugeVec
+	conns     *prometheus.CounterVec
+	idleTime  prometheus.Histogram
+}
+
+// NewPrometheusCollector creates a PrometheusCollector and registers its metrics with the given registerer, usually
+// prometheus.DefaultRegisterer.
+func NewPrometheusCollector(registerer prometheus.Registerer) (*PrometheusCollector, error) {
+	collector := &PrometheusCollector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lightspark_requests_total",
+			Help: "Number of Lightspark API requests.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lightspark_request_errors_total",
+			Help: "Number of failed Lightspark API requests.",
+		}, []string{"operation", "error_type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lightspark_request_duration_seconds",
+			Help:    "Latency of Lightspark API requests, including retries.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		storeSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lightspark_uma_store_entries",
+			Help: "Number of entries of UMA protocol state stores.",
+		}, []string{"store"}),
+		conns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lightspark_connections_total",
+			Help: "Number of connections used by Lightspark API requests.",
+		}, []string{"reused"}),
+		idleTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "lightspark_connection_idle_seconds",
+			Help:    "Idle time of the pooled connections reused by Lightspark API requests.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+	for _, metric := range []prometheus.Collector{
+		collector.requests, collector.errors, collector.duration, collector.storeSize, collector.conns, collector.idleTime,
+	} {
+		if err := registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+	return collector, nil
+}
+
+func (c *PrometheusCollector) ObserveRequest(operationName string, duration time.Duration, err error) {
+	c.requests.WithLabelValues(operationName).Inc()
+	c.duration.WithLabelValues(operationName).Observe(duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(operationName, errorType(err)).Inc()
+	}
+}
+
+func (c *PrometheusCollector) ObserveConnection(reused bool, idleTime time.Duration) {
+	if !reused {
+		c.conns.WithLabelValues("false").Inc()
+		return
+	}
+	c.conns.WithLabelValues("true").Inc()
+	c.idleTime.Observe(idleTime.Seconds())
+}
+
+func (c *PrometheusCollector) ObserveStoreSize(store string, size int) {
+	c.storeSize.WithLabelValues(store).Set(float64(size))
+}
+
+// errorType classifies an error into a low-cardinality label value.
+func errorType(err error) string {
+	var rateLimitedErr *requester.RateLimitedError
+	var graphqlErr *requester.GraphQLError
+	var quotaErr *requester.QuotaExceededError
+	switch {
+	case errors.As(err, &rateLimitedErr):
+		return "rate_limited"
+	case errors.As(err, &quotaErr):
+		return "quota_exceeded"
+	case errors.As(err, &graphqlErr):
+		if graphqlErr.StatusCode < 200 || graphqlErr.StatusCode > 299 {
+			return "http"
+		}
+		return "graphql"
+	}
+	return "network"
+}
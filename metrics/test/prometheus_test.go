@@ -0,0 +1,30 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/metrics"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewPrometheusCollector(registry)
+	require.NoError(t, err)
+
+	collector.ObserveRequest("CurrentAccount", 10*time.Millisecond, nil)
+	collector.ObserveRequest("CurrentAccount", 20*time.Millisecond, &requester.GraphQLError{Message: "boom", StatusCode: 200})
+	collector.ObserveRequest("PayInvoice", 30*time.Millisecond, errors.New("connection reset"))
+
+	count, err := testutil.GatherAndCount(registry, "lightspark_requests_total")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = testutil.GatherAndCount(registry, "lightspark_request_errors_total")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
@@ -0,0 +1,348 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_TOKEN_REFRESH_MARGIN is the time before their expiry at which OAuth2 and JWT credentials are renewed.
+const DEFAULT_TOKEN_REFRESH_MARGIN = 30 * time.Second
+
+// DEFAULT_JWT_LIFETIME is the default lifetime of the tokens signed by JWTAuthenticator.
+const DEFAULT_JWT_LIFETIME = 5 * time.Minute
+
+// maxTokenResponseBytes caps the size of the OAuth2 token responses read by OAuth2ClientCredentialsAuthenticator.
+const maxTokenResponseBytes = 64 * 1024
+
+// Authenticator sets the credentials of the requests and subscriptions of a Requester, e.g. an Authorization header,
+// so that the same Requester can be used with other authentication schemes than the basic auth of API tokens, e.g.
+// against partner gateways. It is called for every request and must be safe for concurrent use.
+//
+// An Authenticator can also implement `Identity() string`, returning an identifier of its credentials which does not
+// change when they are renewed, e.g. an OAuth2 client id. Results of a Requester.ResponseCache are only cached for
+// authenticators with an identity.
+type Authenticator interface {
+	Authenticate(ctx context.Context, header http.Header) error
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface.
+type AuthenticatorFunc func(ctx context.Context, header http.Header) error
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, header http.Header) error {
+	return f(ctx, header)
+}
+
+// WithAuthenticator authenticates requests with the authenticator instead of the API token of the Requester.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(r *Requester) {
+		r.Authenticator = authenticator
+	}
+}
+
+// BasicAuthenticator authenticates requests with HTTP basic auth. It is the default authenticator, using the API
+// token of the Requester.
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuthenticator) Authenticate(ctx context.Context, header http.Header) error {
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password)))
+	return nil
+}
+
+// Identity identifies the credentials by the username and the password, which the scope of Requester.ResponseCache
+// only keeps a hash of.
+func (a BasicAuthenticator) Identity() string {
+	return "basic " + a.Username + ":" + a.Password
+}
+
+// BearerTokenAuthenticator authenticates requests with a static bearer token.
+type BearerTokenAuthenticator struct {
+	Token string
+}
+
+func (a BearerTokenAuthenticator) Authenticate(ctx context.Context, header http.Header) error {
+	if a.Token == "" {
+		return errors.New("missing bearer token")
+	}
+	header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+func (a BearerTokenAuthenticator) Identity() string {
+	if a.Token == "" {
+		return ""
+	}
+	return "bearer " + a.Token
+}
+
+// OAuth2ClientCredentialsAuthenticator authenticates requests with a bearer token obtained from an OAuth2 token
+// endpoint with the client credentials grant (RFC 6749 section 4.4). The token is cached, and refreshed
+// DEFAULT_TOKEN_REFRESH_MARGIN before it expires or after Invalidate was called.
+type OAuth2ClientCredentialsAuthenticator struct {
+	// TokenUrl is the URL of the token endpoint.
+	TokenUrl string
+	// ClientId and ClientSecret are the credentials of the client, sent with basic auth.
+	ClientId     string
+	ClientSecret string
+	// Scopes are the scopes requested, if any.
+	Scopes []string
+	// HTTPClient is used to request tokens. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Runtime is the clock the expiry of tokens is measured with. Defaults to sdkruntime.Default().
+	Runtime *sdkruntime.Runtime
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (a *OAuth2ClientCredentialsAuthenticator) Authenticate(ctx context.Context, header http.Header) error {
+	token, err := a.currentToken(ctx)
+	if err != nil {
+		return err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate drops the cached token, e.g. after it was rejected, so that the next request gets a new one.
+func (a *OAuth2ClientCredentialsAuthenticator) Invalidate() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = ""
+}
+
+// Identity identifies the credentials by the token endpoint, the client id and the scopes, which do not change when
+// the token is refreshed.
+func (a *OAuth2ClientCredentialsAuthenticator) Identity() string {
+	return strings.Join(append([]string{"oauth2", a.TokenUrl, a.ClientId}, a.Scopes...), " ")
+}
+
+func (a *OAuth2ClientCredentialsAuthenticator) currentToken(ctx context.Context) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && (a.expiresAt.IsZero() || a.Runtime.Until(a.expiresAt) > DEFAULT_TOKEN_REFRESH_MARGIN) {
+		return a.token, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", a.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(a.ClientId), url.QueryEscape(a.ClientSecret))
+	httpClient := a.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxTokenResponseBytes))
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", errors.New("oauth2 token request failed with status " + strconv.Itoa(response.StatusCode))
+	}
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", errors.New("invalid oauth2 token response")
+	}
+	if tokenResponse.AccessToken == "" || !strings.EqualFold(tokenResponse.TokenType, "bearer") {
+		return "", errors.New("invalid oauth2 token response: missing bearer access token")
+	}
+	a.token = tokenResponse.AccessToken
+	a.expiresAt = time.Time{}
+	if tokenResponse.ExpiresIn > 0 {
+		a.expiresAt = a.Runtime.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	}
+	return a.token, nil
+}
+
+// JWTAuthenticator authenticates requests with a bearer JSON Web Token signed with a private key, e.g. for gateways
+// authenticating clients by their public key. RSA keys sign with RS256 and P-256 ECDSA keys with ES256. A token is
+// reused until DEFAULT_TOKEN_REFRESH_MARGIN before it expires.
+type JWTAuthenticator struct {
+	// PrivateKey is the key signing the tokens, an *rsa.PrivateKey or a P-256 *ecdsa.PrivateKey.
+	PrivateKey crypto.Signer
+	// KeyId, if set, is sent in the `kid` header of the tokens.
+	KeyId string
+	// Issuer, Subject and Audience are the `iss`, `sub` and `aud` claims of the tokens.
+	Issuer   string
+	Subject  string
+	Audience string
+	// Lifetime is the lifetime of the tokens. Defaults to DEFAULT_JWT_LIFETIME.
+	Lifetime time.Duration
+	// Runtime is the clock and the source of the `jti` claims of the tokens. Defaults to sdkruntime.Default().
+	Runtime *sdkruntime.Runtime
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, header http.Header) error {
+	token, err := a.currentToken()
+	if err != nil {
+		return err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate drops the cached token, e.g. after it was rejected, so that the next request signs a new one.
+func (a *JWTAuthenticator) Invalidate() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = ""
+}
+
+// Identity identifies the credentials by the public key and the claims of the tokens, which do not change when a new
+// token is signed.
+func (a *JWTAuthenticator) Identity() string {
+	if a.PrivateKey == nil {
+		return ""
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(a.PrivateKey.Public())
+	if err != nil {
+		return ""
+	}
+	return strings.Join([]string{"jwt", hex.EncodeToString(publicKey), a.KeyId, a.Issuer, a.Subject, a.Audience}, " ")
+}
+
+func (a *JWTAuthenticator) currentToken() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	now := a.Runtime.Now()
+	if a.token != "" && a.expiresAt.Sub(now) > DEFAULT_TOKEN_REFRESH_MARGIN {
+		return a.token, nil
+	}
+	lifetime := a.Lifetime
+	if lifetime <= 0 {
+		lifetime = DEFAULT_JWT_LIFETIME
+	}
+
+	var algorithm string
+	switch key := a.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		algorithm = "RS256"
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", errors.New("unsupported ECDSA curve: only P-256 keys are supported")
+		}
+		algorithm = "ES256"
+	default:
+		return "", errors.New("unsupported JWT signing key: must be an RSA or P-256 ECDSA private key")
+	}
+	tokenHeader := map[string]interface{}{"alg": algorithm, "typ": "JWT"}
+	if a.KeyId != "" {
+		tokenHeader["kid"] = a.KeyId
+	}
+	jti := make([]byte, 16)
+	if _, err := a.Runtime.Read(jti); err != nil {
+		return "", err
+	}
+	expiresAt := now.Add(lifetime)
+	claims := map[string]interface{}{
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+		"jti": hex.EncodeToString(jti),
+	}
+	for name, value := range map[string]string{"iss": a.Issuer, "sub": a.Subject, "aud": a.Audience} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+	encodedHeader, err := json.Marshal(tokenHeader)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." +
+		base64.RawURLEncoding.EncodeToString(encodedClaims)
+	signature, err := a.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	a.token = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	a.expiresAt = expiresAt
+	return a.token, nil
+}
+
+func (a *JWTAuthenticator) sign(signingInput []byte) ([]byte, error) {
+	hashed := sha256.Sum256(signingInput)
+	if key, ok := a.PrivateKey.(*ecdsa.PrivateKey); ok {
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s, not in ASN.1.
+		r, s, err := ecdsa.Sign(a.Runtime.Reader(), key, hashed[:])
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	}
+	return a.PrivateKey.Sign(a.Runtime.Reader(), hashed[:], crypto.SHA256)
+}
+
+// authenticate sets the credentials of a request with the Authenticator, or the API token if there is none.
+func (r *Requester) authenticate(ctx context.Context, header http.Header) error {
+	if r.Authenticator != nil {
+		return r.Authenticator.Authenticate(ctx, header)
+	}
+	return BasicAuthenticator{Username: r.ApiTokenClientId, Password: r.ApiTokenClientSecret}.Authenticate(ctx, header)
+}
+
+// credentialsIdentity returns the identity of the credentials of the Authenticator, or of the API token if there is
+// none, without authenticating, so that it neither requests nor signs a token. It is empty if the Authenticator has
+// no identity.
+func (r *Requester) credentialsIdentity() string {
+	if r.Authenticator == nil {
+		return BasicAuthenticator{Username: r.ApiTokenClientId, Password: r.ApiTokenClientSecret}.Identity()
+	}
+	if identified, ok := r.Authenticator.(interface{ Identity() string }); ok {
+		return identified.Identity()
+	}
+	return ""
+}
+
+// invalidateCredentials drops the credentials cached by the Authenticator after the server rejected them.
+func (r *Requester) invalidateCredentials() {
+	if invalidator, ok := r.Authenticator.(interface{ Invalidate() }); ok {
+		invalidator.Invalidate()
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// BaseUrlPolicy decides which base URLs are accepted. The zero value is the default policy, which requires HTTPS
+// except for localhost, 127.0.0.1 and the hosts of the .local and .internal top-level domains.
+type BaseUrlPolicy struct {
+	// AllowedHosts, if set, only accepts base URLs with one of these host names. A name starting with "*." matches
+	// any subdomain of the rest of the name.
+	AllowedHosts []string
+	// InsecureHosts are hosts accepted with plain HTTP in addition to the local hosts of the default policy, e.g. the
+	// host of a mock server in CI. Names starting with "*." match subdomains like in AllowedHosts.
+	InsecureHosts []string
+	// AllowInsecure accepts plain HTTP for any host. It should only be set in CI environments.
+	AllowInsecure bool
+}
+
+// Validate returns an error if a base URL is not accepted by the policy.
+func (p BaseUrlPolicy) Validate(baseUrl string) error {
+	parsedUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return errors.New("invalid base url. Not a valid URL")
+	}
+	hostName := strings.ToLower(parsedUrl.Hostname())
+	if len(p.AllowedHosts) > 0 && !matchHost(hostName, p.AllowedHosts) {
+		return errors.New("invalid base url. Host " + hostName + " is not allowed")
+	}
+	if parsedUrl.Scheme == "https" || p.AllowInsecure || isLocalHost(hostName) ||
+		matchHost(hostName, p.InsecureHosts) {
+		return nil
+	}
+	return errors.New("invalid base url. Must be https:// if not targeting localhost")
+}
+
+// ValidateBaseUrl validates a base URL with the BaseUrlPolicy of the requester, or the default policy.
+func (r *Requester) ValidateBaseUrl(baseUrl string) error {
+	if r.BaseUrlPolicy != nil {
+		return r.BaseUrlPolicy.Validate(baseUrl)
+	}
+	return ValidateBaseUrl(baseUrl)
+}
+
+// WithBaseUrlPolicy sets the policy validating the base URL. See BaseUrlPolicy.
+func WithBaseUrlPolicy(policy BaseUrlPolicy) Option {
+	return func(r *Requester) {
+		r.BaseUrlPolicy = &policy
+	}
+}
+
+func isLocalHost(hostName string) bool {
+	hostNameParts := strings.Split(hostName, ".")
+	hostNameTld := hostNameParts[len(hostNameParts)-1]
+	return hostName == "localhost" ||
+		hostNameTld == "local" ||
+		hostNameTld == "internal" ||
+		hostName == "127.0.0.1"
+}
+
+func matchHost(hostName string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(hostName, "."+suffix) {
+				return true
+			}
+		} else if hostName == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// BatchResult is the result of one operation of a batch: either Result or Err is set.
+type BatchResult struct {
+	Result *GraphqlResult
+	Err    error
+}
+
+// NewGraphqlRequest creates a GraphqlRequest for ExecuteGraphqlBatch, extracting the operation name of the query.
+func NewGraphqlRequest(query string, variables map[string]interface{}) (*GraphqlRequest, error) {
+	re := operationRegexp()
+	matches := re.FindStringSubmatch(query)
+	index := re.SubexpIndex("OperationName")
+	if len(matches) <= index {
+		return nil, errors.New("invalid query payload")
+	}
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	return &GraphqlRequest{
+		OperationName: matches[index],
+		Query:         query,
+		Variables:     variables,
+		IsMutation:    strings.EqualFold(matches[1], "mutation"),
+		Header:        http.Header{},
+	}, nil
+}
+
+// ExecuteGraphqlBatch sends several unsigned operations in one HTTP request, as a JSON array of payloads, and returns
+// their results in the same order. The returned error is set when the batch as a whole failed; errors of individual
+// operations are returned in their BatchResult. Batches are retried according to the RetryPolicy, as mutations if
+// they contain any mutation.
+//
+// Each operation goes through the request interceptors, the query checks and the response interceptors like with
+// ExecuteGraphql, and takes one request from the quota budget, which is given back if the batch is not sent. The
+// batch is traced, measured and reported to the event sink as one request, named by the operations joined with
+// commas.
+func (r *Requester) ExecuteGraphqlBatch(ctx context.Context, requests []GraphqlRequest) ([]BatchResult, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	requests = append([]GraphqlRequest(nil), requests...)
+	operationNames := make([]string, 0, len(requests))
+	batchRequest := &GraphqlRequest{Header: http.Header{}}
+	for i := range requests {
+		request := &requests[i]
+		if err := r.checkOperation(request); err != nil {
+			return nil, err
+		}
+		request.Header = request.Header.Clone()
+		if request.Header == nil {
+			request.Header = http.Header{}
+		}
+		operationNames = append(operationNames, request.OperationName)
+		batchRequest.IsMutation = batchRequest.IsMutation || request.IsMutation
+	}
+	batchRequest.OperationName = strings.Join(operationNames, ",")
+	requestId, err := r.setRequestId(ctx, batchRequest)
+	if err != nil {
+		return nil, err
+	}
+	ctx = ContextWithRequestId(ctx, requestId)
+	ctx, finish := r.startRequest(ctx, batchRequest, requestId)
+
+	dryRun := r.DryRun && batchRequest.IsMutation
+	payloads := make([]map[string]interface{}, 0, len(requests))
+	for i := range requests {
+		request := &requests[i]
+		if request.Priority == PriorityNormal {
+			request.Priority = r.priority(ctx, request.OperationName)
+		}
+		for _, interceptor := range r.RequestInterceptors {
+			if err := interceptor(ctx, request); err != nil {
+				return nil, finish(withRequestId(err, requestId))
+			}
+		}
+		if err := r.checkQuery(request, r.ValidateVariables || dryRun); err != nil {
+			return nil, finish(withRequestId(err, requestId))
+		}
+		payloads = append(payloads, map[string]interface{}{
+			"operationName": request.OperationName,
+			"query":         request.Query,
+			"variables":     request.Variables,
+		})
+		if i == 0 || request.Priority > batchRequest.Priority {
+			batchRequest.Priority = request.Priority
+		}
+		for name, values := range request.Header {
+			for _, value := range values {
+				batchRequest.Header.Add(name, value)
+			}
+		}
+	}
+	encodedPayload, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, finish(withRequestId(errors.New("error when encoding payload"), requestId))
+	}
+	if dryRun {
+		return nil, finish(&DryRunError{
+			OperationName: batchRequest.OperationName,
+			Header:        batchRequest.Header.Clone(),
+			Payload:       encodedPayload,
+		})
+	}
+
+	serverUrl, err := r.serverUrl()
+	if err != nil {
+		return nil, finish(withRequestId(err, requestId))
+	}
+	if r.QuotaBudgeter != nil {
+		if err := r.QuotaBudgeter.reserve(r.Feature, len(requests), r.Runtime.Now()); err != nil {
+			return nil, finish(withRequestId(err, requestId))
+		}
+	}
+	data, statusCode, err := r.postWithRetry(ctx, serverUrl, batchRequest, encodedPayload, "", r.RetryPolicy)
+	if err != nil {
+		if statusCode == 0 && !isTransportError(err) && ctx.Err() == nil && r.QuotaBudgeter != nil {
+			// The batch failed before it was sent, e.g. in the Authenticator.
+			r.QuotaBudgeter.refund(r.Feature, len(requests), r.Runtime.Now())
+		}
+		return nil, finish(withRequestId(err, requestId))
+	}
+	results, err := parseBatchResponse(data, statusCode, len(requests))
+	for i := range results {
+		results[i].Result, results[i].Err = r.interceptResponse(ctx, &requests[i], results[i].Result, results[i].Err)
+		results[i].Err = withRequestId(results[i].Err, requestId)
+	}
+	return results, finish(withRequestId(err, requestId))
+}
+
+func parseBatchResponse(data []byte, statusCode int, count int) ([]BatchResult, error) {
+	var responses []json.RawMessage
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return nil, errors.New("error parsing batch response: the server may not support batching")
+	}
+	if len(responses) != count {
+		return nil, errors.New("batch response does not match the number of operations")
+	}
+	results := make([]BatchResult, len(responses))
+	for i, response := range responses {
+		results[i].Result, results[i].Err = parseGraphqlResponse(response, statusCode)
+	}
+	return results, nil
+}
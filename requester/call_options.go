@@ -0,0 +1,63 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"time"
+)
+
+// CallOption overrides the Requester configuration for a single call to ExecuteGraphqlWithOptions.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout        time.Duration
+	retryPolicy    *RetryPolicy
+	idempotencyKey string
+	signingExpiry  time.Duration
+	priority       *Priority
+	bypassCache    bool
+	decodeTarget   interface{}
+	// rawData leaves the `data` of the result undecoded, in GraphqlResult.RawData.
+	rawData bool
+}
+
+// WithCallTimeout sets the deadline of the call, including retries, overriding Requester.Timeout. A zero timeout
+// removes the default timeout.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(options *callOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithCallRetryPolicy sets the retry policy of the call, overriding Requester.RetryPolicy. A nil policy disables
+// retries.
+func WithCallRetryPolicy(policy *RetryPolicy) CallOption {
+	return func(options *callOptions) {
+		options.retryPolicy = policy
+	}
+}
+
+// WithCallSigningExpiry sets the time after which the signed request expires, overriding Requester.SigningExpiry.
+func WithCallSigningExpiry(signingExpiry time.Duration) CallOption {
+	return func(options *callOptions) {
+		if signingExpiry > 0 {
+			options.signingExpiry = signingExpiry
+		}
+	}
+}
+
+// ExecuteGraphqlWithOptions executes a GraphQL request like ExecuteGraphqlForResultWithContext, with per-call
+// overrides of the timeout, retry policy, signing expiry, priority and response cache, or an idempotency key.
+func (r *Requester) ExecuteGraphqlWithOptions(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey, options ...CallOption,
+) (*GraphqlResult, error) {
+	resolvedOptions := r.defaultCallOptions()
+	for _, option := range options {
+		option(&resolvedOptions)
+	}
+	return r.executeGraphql(ctx, query, variables, signingKey, resolvedOptions)
+}
+
+func (r *Requester) defaultCallOptions() callOptions {
+	return callOptions{timeout: r.Timeout, retryPolicy: r.RetryPolicy, signingExpiry: r.signingExpiry()}
+}
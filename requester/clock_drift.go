@@ -0,0 +1,97 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DEFAULT_CLOCK_DRIFT_THRESHOLD is the default drift between the local clock and the API server clock above which a
+// ClockDriftError is reported. Drift breaks the signed `expires_at` of requests and UMA timestamps.
+const DEFAULT_CLOCK_DRIFT_THRESHOLD = 30 * time.Second
+
+// CLOCK_DRIFT_WARNING_INTERVAL is the minimum time between two warnings logged about a clock drift, so that a drifting
+// host does not log a warning for every response.
+const CLOCK_DRIFT_WARNING_INTERVAL = 10 * time.Minute
+
+// clockDriftState is the clock drift observed by a Requester. It is held by pointer, so that the copies of a
+// Requester, e.g. for a feature, share it.
+type clockDriftState struct {
+	lastDrift atomic.Int64
+	// lastWarning is the time the last warning was logged, in Unix nanoseconds.
+	lastWarning atomic.Int64
+}
+
+// sharedClockDrift is the state of the Requesters which were not created with NewRequester.
+var sharedClockDrift clockDriftState
+
+func (r *Requester) clockDriftState() *clockDriftState {
+	if r.clockDrift == nil {
+		return &sharedClockDrift
+	}
+	return r.clockDrift
+}
+
+// ClockDriftError describes a drift between the local clock and the API server clock.
+type ClockDriftError struct {
+	// Drift is the local time minus the server time. It is positive when the local clock is ahead.
+	Drift time.Duration
+	// Threshold is the threshold which was exceeded.
+	Threshold time.Duration
+}
+
+func (e *ClockDriftError) Error() string {
+	return "local clock drifts from the Lightspark API server clock by " + e.Drift.String() +
+		" (threshold " + e.Threshold.String() + "). Check that your host clock is synchronized"
+}
+
+// LastClockDrift returns the drift between the local clock and the server clock observed on the last response, or 0
+// if no response with a `Date` header was received yet.
+func (r *Requester) LastClockDrift() time.Duration {
+	return time.Duration(r.clockDriftState().lastDrift.Load())
+}
+
+// checkClockDrift compares the `Date` header of a response with the local time at which it was received.
+func (r *Requester) checkClockDrift(response *http.Response, receivedAt time.Time) {
+	dateHeader := response.Header.Get("Date")
+	if dateHeader == "" {
+		return
+	}
+	serverTime, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return
+	}
+	// The Date header has a one second resolution, so round the local time down the same way.
+	drift := receivedAt.Truncate(time.Second).Sub(serverTime)
+	state := r.clockDriftState()
+	state.lastDrift.Store(int64(drift))
+
+	threshold := r.ClockDriftThreshold
+	if threshold == 0 {
+		threshold = DEFAULT_CLOCK_DRIFT_THRESHOLD
+	}
+	if drift < threshold && drift > -threshold {
+		return
+	}
+	driftErr := &ClockDriftError{Drift: drift, Threshold: threshold}
+	if r.OnClockDrift != nil {
+		r.OnClockDrift(driftErr)
+		return
+	}
+	lastWarning := state.lastWarning.Load()
+	now := r.Runtime.Now().UnixNano()
+	if lastWarning != 0 && time.Duration(now-lastWarning) < CLOCK_DRIFT_WARNING_INTERVAL {
+		return
+	}
+	if !state.lastWarning.CompareAndSwap(lastWarning, now) {
+		// Another response is logging the warning.
+		return
+	}
+	if r.Logger != nil {
+		r.Logger.Warn("clock drift detected", "drift", drift, "threshold", threshold)
+	} else {
+		log.Printf("WARNING: %s", driftErr.Error())
+	}
+}
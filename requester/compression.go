@@ -0,0 +1,49 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MIN_COMPRESSED_REQUEST_SIZE is the size in bytes above which request bodies are compressed when
+// Requester.CompressRequests is set. Smaller bodies do not benefit from compression.
+const MIN_COMPRESSED_REQUEST_SIZE = 1024
+
+// compressRequestBody gzips a request body if it is large enough, returning the body to send and its
+// Content-Encoding.
+func compressRequestBody(body []byte) ([]byte, string, error) {
+	if len(body) < MIN_COMPRESSED_REQUEST_SIZE {
+		return body, "", nil
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(body); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return compressed.Bytes(), "gzip", nil
+}
+
+// readResponseBody reads a response body, decompressing it if the server gzipped it.
+func readResponseBody(response *http.Response) ([]byte, error) {
+	reader, err := responseBodyReader(response)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// responseBodyReader returns a reader of a response body, decompressing it if the server gzipped it.
+func responseBodyReader(response *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(response.Body), nil
+	}
+	return gzip.NewReader(response.Body)
+}
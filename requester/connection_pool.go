@@ -0,0 +1,64 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// ConnectionPoolOptions tunes the connection pool of the HTTP transport. Zero fields keep the value of the transport.
+type ConnectionPoolOptions struct {
+	// MaxIdleConns is the maximum number of idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept per host. The default of http.Transport is
+	// 2, which makes callers sending more concurrent requests than that open and close connections constantly.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost, if set, limits the number of connections per host, including those in use.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept before being closed.
+	IdleConnTimeout time.Duration
+}
+
+// WithConnectionPool tunes the connection pool of the HTTP transport. See WithHTTPClient for custom clients.
+func WithConnectionPool(options ConnectionPoolOptions) Option {
+	return func(r *Requester) {
+		r.configureTransport(func(transport *http.Transport) {
+			if options.MaxIdleConns > 0 {
+				transport.MaxIdleConns = options.MaxIdleConns
+			}
+			if options.MaxIdleConnsPerHost > 0 {
+				transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+			}
+			if options.MaxConnsPerHost > 0 {
+				transport.MaxConnsPerHost = options.MaxConnsPerHost
+			}
+			if options.IdleConnTimeout > 0 {
+				transport.IdleConnTimeout = options.IdleConnTimeout
+			}
+		})
+	}
+}
+
+// ConnectionMetricsCollector is implemented by MetricsCollectors which also observe the connections used by
+// requests, e.g. to monitor the reuse of pooled keep-alive connections.
+type ConnectionMetricsCollector interface {
+	// ObserveConnection is called for each HTTP attempt once it got a connection. reused is true if the connection
+	// was taken from the pool, in which case idleTime is how long it was idle.
+	ObserveConnection(reused bool, idleTime time.Duration)
+}
+
+// withConnectionTrace reports the connections of the requests sent with the returned context to the
+// MetricsCollector, if it is a ConnectionMetricsCollector.
+func (r *Requester) withConnectionTrace(ctx context.Context) context.Context {
+	collector, ok := r.MetricsCollector.(ConnectionMetricsCollector)
+	if !ok {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			collector.ObserveConnection(info.Reused, info.IdleTime)
+		},
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"log"
+	"sync"
+)
+
+// reportedDeprecations holds the operation and field of the deprecation warnings already reported, so that each is
+// only reported once per process.
+var reportedDeprecations sync.Map
+
+// reportDeprecations reports the deprecation warnings of a result which were not reported yet, to OnDeprecation if
+// set, or else to the Logger or the standard logger.
+func (r *Requester) reportDeprecations(operationName string, result *GraphqlResult) {
+	if result == nil || result.Extensions == nil {
+		return
+	}
+	for _, deprecation := range result.Deprecations() {
+		if _, reported := reportedDeprecations.LoadOrStore(operationName+"\x00"+deprecation.Field, true); reported {
+			continue
+		}
+		if r.OnDeprecation != nil {
+			r.OnDeprecation(operationName, deprecation)
+		} else if r.Logger != nil {
+			r.Logger.Warn("lightspark request uses a deprecated field", "operation", operationName,
+				"field", deprecation.Field, "reason", deprecation.Reason)
+		} else {
+			log.Printf("WARNING: %s uses the deprecated field %s: %s", operationName, deprecation.Field,
+				deprecation.Reason)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"net/http"
+	"time"
+)
+
+// DryRunError is returned instead of executing a mutation when the requester is in DryRun mode. It holds the request
+// which would have been sent, after the request interceptors and the local validation of its variables.
+type DryRunError struct {
+	OperationName string
+	Query         string
+	Variables     map[string]interface{}
+	// Header holds the additional HTTP headers which would have been sent.
+	Header http.Header
+	// Payload is the encoded request body, without compression. Payloads of signed mutations get a nonce and an
+	// expiry but are not signed.
+	Payload []byte
+	// Signed is true if the mutation would have been signed.
+	Signed bool
+}
+
+func (e *DryRunError) Error() string {
+	return "dry run: mutation " + e.OperationName + " was not sent"
+}
+
+// WithDryRun makes the requester validate mutations locally and return them in a DryRunError instead of sending
+// them. Queries are still sent.
+func WithDryRun() Option {
+	return func(r *Requester) {
+		r.DryRun = true
+	}
+}
+
+// dryRun returns the DryRunError of a mutation.
+func (r *Requester) dryRun(graphqlRequest *GraphqlRequest, signed bool, signingExpiry time.Duration) error {
+	payload, err := r.encodePayload(graphqlRequest, signed, signingExpiry, nil)
+	if err != nil {
+		return err
+	}
+	return &DryRunError{
+		OperationName: graphqlRequest.OperationName,
+		Query:         graphqlRequest.Query,
+		Variables:     graphqlRequest.Variables,
+		Header:        graphqlRequest.Header.Clone(),
+		Payload:       payload,
+		Signed:        signed,
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Environment variables read by NewRequesterFromEnv.
+const (
+	ENV_API_TOKEN_CLIENT_ID     = "LIGHTSPARK_API_TOKEN_CLIENT_ID"
+	ENV_API_TOKEN_CLIENT_SECRET = "LIGHTSPARK_API_TOKEN_CLIENT_SECRET"
+	ENV_BASE_URL                = "LIGHTSPARK_BASE_URL"
+)
+
+// NewRequesterFromEnv creates a Requester with the API token read from the LIGHTSPARK_API_TOKEN_CLIENT_ID and
+// LIGHTSPARK_API_TOKEN_CLIENT_SECRET environment variables, and the base URL read from LIGHTSPARK_BASE_URL if it is
+// set. It returns an error if the token is missing or the base URL is invalid.
+//
+// Args:
+//
+//	options: the options to apply, in order, after the configuration read from the environment
+func NewRequesterFromEnv(options ...Option) (*Requester, error) {
+	apiTokenClientId, apiTokenClientSecret, err := ApiTokenFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl := strings.TrimSpace(os.Getenv(ENV_BASE_URL)); baseUrl != "" {
+		options = append([]Option{WithBaseUrl(baseUrl)}, options...)
+	}
+	return NewRequesterWithOptions(apiTokenClientId, apiTokenClientSecret, options...)
+}
+
+// ApiTokenFromEnv returns the API token read from the LIGHTSPARK_API_TOKEN_CLIENT_ID and
+// LIGHTSPARK_API_TOKEN_CLIENT_SECRET environment variables, or an error if one of them is missing.
+func ApiTokenFromEnv() (string, string, error) {
+	apiTokenClientId := strings.TrimSpace(os.Getenv(ENV_API_TOKEN_CLIENT_ID))
+	if apiTokenClientId == "" {
+		return "", "", errors.New("missing environment variable " + ENV_API_TOKEN_CLIENT_ID)
+	}
+	apiTokenClientSecret := strings.TrimSpace(os.Getenv(ENV_API_TOKEN_CLIENT_SECRET))
+	if apiTokenClientSecret == "" {
+		return "", "", errors.New("missing environment variable " + ENV_API_TOKEN_CLIENT_SECRET)
+	}
+	return apiTokenClientId, apiTokenClientSecret, nil
+}
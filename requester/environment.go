@@ -0,0 +1,88 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Environment is a Lightspark API environment with preset settings.
+type Environment int
+
+const (
+	// EnvironmentProduction The production Lightspark API.
+	EnvironmentProduction Environment = iota
+	// EnvironmentDev The Lightspark development API, running the release candidate schema.
+	EnvironmentDev
+	// EnvironmentCustom A custom base URL, e.g. a proxy or a local server.
+	EnvironmentCustom
+)
+
+// EnvironmentConfig holds the preset settings of an Environment.
+type EnvironmentConfig struct {
+	// BaseUrl is the GraphQL endpoint of the environment.
+	BaseUrl string
+	// SchemaVersion is the version of the GraphQL schema served at BaseUrl.
+	SchemaVersion string
+	// Timeout is the default HTTP timeout of requests.
+	Timeout time.Duration
+}
+
+var environmentConfigs = map[Environment]EnvironmentConfig{
+	EnvironmentProduction: {
+		BaseUrl:       DEFAULT_BASE_URL,
+		SchemaVersion: "2023-09-13",
+		Timeout:       60 * time.Second,
+	},
+	EnvironmentDev: {
+		BaseUrl:       "https://api.dev.dev.sparkinfra.net/graphql/server/rc",
+		SchemaVersion: "rc",
+		Timeout:       120 * time.Second,
+	},
+}
+
+// Config returns the preset settings of the environment. EnvironmentCustom has no presets.
+func (e Environment) Config() EnvironmentConfig {
+	return environmentConfigs[e]
+}
+
+func (e Environment) String() string {
+	switch e {
+	case EnvironmentProduction:
+		return "PRODUCTION"
+	case EnvironmentDev:
+		return "DEV"
+	case EnvironmentCustom:
+		return "CUSTOM"
+	}
+	return "UNDEFINED"
+}
+
+// ParseEnvironment parses an environment name as returned by Environment.String, ignoring case.
+func ParseEnvironment(name string) (Environment, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "PRODUCTION", "PROD":
+		return EnvironmentProduction, nil
+	case "DEV":
+		return EnvironmentDev, nil
+	case "CUSTOM":
+		return EnvironmentCustom, nil
+	}
+	return EnvironmentCustom, errors.New("unknown environment: " + name)
+}
+
+// NewRequesterForEnvironment creates a Requester targeting one of the preset environments, with its base URL and
+// default timeout. Use NewRequesterWithBaseUrl for EnvironmentCustom.
+func NewRequesterForEnvironment(apiTokenClientId string, apiTokenClientSecret string,
+	environment Environment) (*Requester, error) {
+	config, ok := environmentConfigs[environment]
+	if !ok {
+		return nil, errors.New("environment " + environment.String() + " has no preset base url")
+	}
+	baseUrl := config.BaseUrl
+	requester := NewRequesterWithBaseUrl(apiTokenClientId, apiTokenClientSecret, &baseUrl)
+	requester.HTTPClient = &http.Client{Timeout: config.Timeout}
+	return requester, nil
+}
@@ -0,0 +1,181 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GraphQLError is returned when the Lightspark API rejects a request, either with a non-2xx HTTP status or with a
+// GraphQL error. Use errors.As to branch on the error name or status code.
+type GraphQLError struct {
+	// Name is the `error_name` extension of the GraphQL error, if any.
+	Name string
+	// Message is the error message.
+	Message string
+	// Extensions are the extensions of the GraphQL error, if any.
+	Extensions map[string]interface{}
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// RequestId is the ID sent in the X-Request-ID header of the failed call, to reference it in support tickets.
+	RequestId string
+}
+
+func (e *GraphQLError) Error() string {
+	if e.Name == "" {
+		return e.Message
+	}
+	return e.Name + " - " + e.Message
+}
+
+// MAX_HTTP_ERROR_BODY_SIZE is the maximum number of bytes of a response body kept in an HTTPError.
+const MAX_HTTP_ERROR_BODY_SIZE = 4096
+
+// HTTPError is returned when the Lightspark API, or a proxy or gateway in front of it, responds with a non-2xx HTTP
+// status. It holds the response headers and the beginning of its body, which usually explain why the request was
+// rejected, e.g. by a WAF. It wraps a GraphQLError with the status code, so errors.As can still be used to branch on
+// the status code of a GraphQLError.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Header holds the headers of the response.
+	Header http.Header
+	// Body holds up to MAX_HTTP_ERROR_BODY_SIZE bytes of the response body.
+	Body []byte
+	// BodyTruncated is true if the response body was longer than Body.
+	BodyTruncated bool
+	Err           *GraphQLError
+}
+
+func (e *HTTPError) Error() string {
+	body := strings.TrimSpace(string(e.Body))
+	if body == "" {
+		return e.Err.Error()
+	}
+	if e.BodyTruncated {
+		body += "..."
+	}
+	return e.Err.Error() + ": " + body
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// newHTTPError reads the beginning of the body of a non-2xx response into an HTTPError.
+func newHTTPError(response *http.Response) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: response.StatusCode,
+		Header:     response.Header.Clone(),
+		Err: &GraphQLError{
+			Message:    "lightspark request failed: " + response.Status,
+			StatusCode: response.StatusCode,
+		},
+	}
+	var reader io.Reader = response.Body
+	if strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return httpErr
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	body, _ := io.ReadAll(io.LimitReader(reader, MAX_HTTP_ERROR_BODY_SIZE+1))
+	if len(body) > MAX_HTTP_ERROR_BODY_SIZE {
+		body = body[:MAX_HTTP_ERROR_BODY_SIZE]
+		httpErr.BodyTruncated = true
+	}
+	httpErr.Body = body
+	return httpErr
+}
+
+// RateLimitedError is returned when the API rejects a request with 429 Too Many Requests, or with 503 Service
+// Unavailable and a `Retry-After` header. It wraps the HTTPError of the response, which wraps the underlying
+// GraphQLError.
+type RateLimitedError struct {
+	// RetryAfter is the delay requested by the `Retry-After` header, or 0 if the server did not send one.
+	RetryAfter time.Duration
+	// RateLimit is the rate limit state parsed from the `X-RateLimit-*` headers, or nil if they are missing.
+	RateLimit *RateLimitInfo
+	Err       *GraphQLError
+	// HTTPError is the error of the response, if any.
+	HTTPError *HTTPError
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter == 0 {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + " (retry after " + e.RetryAfter.String() + ")"
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	if e.HTTPError != nil {
+		return e.HTTPError
+	}
+	return e.Err
+}
+
+// newRateLimitedError returns a RateLimitedError if a failed response is rate limited, or nil otherwise.
+func newRateLimitedError(response *http.Response, graphqlErr *GraphQLError, now time.Time) *RateLimitedError {
+	retryAfter := parseRetryAfter(response.Header.Get("Retry-After"), now)
+	if response.StatusCode != http.StatusTooManyRequests &&
+		!(response.StatusCode == http.StatusServiceUnavailable && retryAfter > 0) {
+		return nil
+	}
+	return &RateLimitedError{
+		RetryAfter: retryAfter,
+		RateLimit:  parseRateLimitHeaders(response.Header),
+		Err:        graphqlErr,
+	}
+}
+
+func parseRateLimitHeaders(header http.Header) *RateLimitInfo {
+	var rateLimit RateLimitInfo
+	found := false
+	if limit, err := strconv.ParseInt(header.Get("X-RateLimit-Limit"), 10, 64); err == nil {
+		rateLimit.Limit = &limit
+		found = true
+	}
+	if remaining, err := strconv.ParseInt(header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+		rateLimit.Remaining = &remaining
+		found = true
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt := time.Unix(reset, 0)
+		rateLimit.ResetAt = &resetAt
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &rateLimit
+}
+
+// ResponseDecodeError is returned by Execute and ExecuteField when a response was received but its data could not be
+// decoded. If Executed is true the request was a mutation which the API executed: it must not be re-sent without
+// first looking up its outcome, e.g. the payment it created.
+type ResponseDecodeError struct {
+	// Message describes the decoding failure.
+	Message string
+	// RawData is the data of the response.
+	RawData json.RawMessage
+	// Executed is true if the request was a mutation, and was therefore executed despite the error.
+	Executed bool
+	// Err is the underlying decoding error, if any.
+	Err error
+}
+
+func (e *ResponseDecodeError) Error() string {
+	return e.Message
+}
+
+func (e *ResponseDecodeError) Unwrap() error {
+	return e.Err
+}
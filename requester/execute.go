@@ -0,0 +1,76 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Execute executes a GraphQL request and decodes its data into a T, which is usually a struct whose fields are
+// tagged with the top-level fields of the query:
+//
+//	type currentAccountResult struct {
+//		CurrentAccount objects.Account `json:"current_account"`
+//	}
+//	result, err := requester.Execute[currentAccountResult](ctx, r, scripts.CURRENT_ACCOUNT_QUERY, nil, nil)
+//
+// Errors are returned as by ExecuteGraphqlWithContext, and decoding errors as a ResponseDecodeError, which tells
+// whether the request was an executed mutation. The options override the settings of the Requester for this call,
+// like in ExecuteGraphqlWithOptions.
+func Execute[T any](ctx context.Context, r *Requester, query string, variables map[string]interface{},
+	signingKey SigningKey, options ...CallOption,
+) (T, error) {
+	var result T
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	data, err := r.executeGraphqlRaw(ctx, query, variables, signingKey, options...)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, newResponseDecodeError("error parsing response data: "+err.Error(), query, data, err)
+	}
+	return result, nil
+}
+
+// ExecuteField executes a GraphQL request like Execute, but decodes only one field of its data into a T. The field
+// is a top-level field of the query, or the path of a nested field with its parent fields separated by dots, e.g.
+// `create_invoice.invoice` for the invoice of the create_invoice mutation. It returns an error if the field or one of
+// its parents is missing or null, as a ResponseDecodeError.
+func ExecuteField[T any](ctx context.Context, r *Requester, query string, variables map[string]interface{},
+	signingKey SigningKey, field string, options ...CallOption,
+) (T, error) {
+	var result T
+	data, err := Execute[json.RawMessage](ctx, r, query, variables, signingKey, options...)
+	if err != nil {
+		return result, err
+	}
+	fieldData := data
+	for _, name := range strings.Split(field, ".") {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(fieldData, &fields); err != nil {
+			return result, newResponseDecodeError("error parsing "+field+": "+err.Error(), query, data, err)
+		}
+		var ok bool
+		fieldData, ok = fields[name]
+		if !ok || string(fieldData) == "null" {
+			return result, newResponseDecodeError("missing field in response: "+field, query, data, nil)
+		}
+	}
+	if err := json.Unmarshal(fieldData, &result); err != nil {
+		return result, newResponseDecodeError("error parsing "+field+": "+err.Error(), query, data, err)
+	}
+	return result, nil
+}
+
+func newResponseDecodeError(message string, query string, data json.RawMessage, err error) *ResponseDecodeError {
+	matches := operationRegexp().FindStringSubmatch(query)
+	return &ResponseDecodeError{
+		Message:  message,
+		RawData:  data,
+		Executed: len(matches) > 1 && strings.EqualFold(matches[1], "mutation"),
+		Err:      err,
+	}
+}
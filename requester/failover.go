@@ -0,0 +1,237 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_BASE_URL_COOLDOWN is the default duration for which a failing base URL is only used as a last resort.
+const DEFAULT_BASE_URL_COOLDOWN = 30 * time.Second
+
+// BaseUrlPool is an ordered list of base URLs of the Lightspark API, e.g. regional endpoints or a proxy tier. Requests
+// go to the first healthy URL, and fail over to the next ones when it does not respond or responds with a server
+// error. Mutations only fail over when they certainly did not reach the backend. A failing URL is considered unhealthy,
+// and only tried after the healthy ones, for the Cooldown duration. It is safe for concurrent use and can be shared by
+// several requesters.
+type BaseUrlPool struct {
+	// Cooldown is the duration for which a failing URL is unhealthy. Defaults to DEFAULT_BASE_URL_COOLDOWN.
+	Cooldown time.Duration
+	// OnFailover, if set, is called when a failure, or a successful request or health check of a preferred URL,
+	// changes the base URL requests are sent to first.
+	OnFailover func(previous string, current string)
+
+	baseUrls       []string
+	mutex          sync.Mutex
+	unhealthyUntil []time.Time
+}
+
+// NewBaseUrlPool creates a BaseUrlPool from base URLs in order of preference. It returns an error if one of them is
+// invalid.
+func NewBaseUrlPool(baseUrls ...string) (*BaseUrlPool, error) {
+	return NewBaseUrlPoolWithPolicy(BaseUrlPolicy{}, baseUrls...)
+}
+
+// NewBaseUrlPoolWithPolicy creates a BaseUrlPool like NewBaseUrlPool, validating the base URLs with the given policy.
+func NewBaseUrlPoolWithPolicy(policy BaseUrlPolicy, baseUrls ...string) (*BaseUrlPool, error) {
+	if len(baseUrls) == 0 {
+		return nil, errors.New("at least one base url is required")
+	}
+	for _, baseUrl := range baseUrls {
+		if err := policy.Validate(baseUrl); err != nil {
+			return nil, err
+		}
+	}
+	return &BaseUrlPool{
+		baseUrls:       append([]string(nil), baseUrls...),
+		unhealthyUntil: make([]time.Time, len(baseUrls)),
+	}, nil
+}
+
+// BaseUrls returns the base URLs in the order in which they are tried: healthy ones first.
+func (p *BaseUrlPool) BaseUrls() []string {
+	return p.baseUrlsAt(sdkruntime.Now())
+}
+
+// baseUrlsAt returns the base URLs like BaseUrls, at the given time of the clock of the caller.
+func (p *BaseUrlPool) baseUrlsAt(now time.Time) []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.orderedBaseUrls(now)
+}
+
+func (p *BaseUrlPool) orderedBaseUrls(now time.Time) []string {
+	healthy := make([]string, 0, len(p.baseUrls))
+	var unhealthy []string
+	for i, baseUrl := range p.baseUrls {
+		if now.Before(p.unhealthyUntil[i]) {
+			unhealthy = append(unhealthy, baseUrl)
+		} else {
+			healthy = append(healthy, baseUrl)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// report marks a base URL as healthy or unhealthy at the given time of the clock of the caller.
+func (p *BaseUrlPool) report(baseUrl string, healthy bool, now time.Time) {
+	cooldown := p.Cooldown
+	if cooldown <= 0 {
+		cooldown = DEFAULT_BASE_URL_COOLDOWN
+	}
+	p.mutex.Lock()
+	previous := p.orderedBaseUrls(now)[0]
+	for i := range p.baseUrls {
+		if p.baseUrls[i] != baseUrl {
+			continue
+		}
+		if healthy {
+			p.unhealthyUntil[i] = time.Time{}
+		} else {
+			p.unhealthyUntil[i] = now.Add(cooldown)
+		}
+	}
+	current := p.orderedBaseUrls(now)[0]
+	p.mutex.Unlock()
+	if current != previous && p.OnFailover != nil {
+		p.OnFailover(previous, current)
+	}
+}
+
+// WithBaseUrlPool sends requests to the base URLs of the pool, failing over between them. It overrides the base URL.
+func WithBaseUrlPool(pool *BaseUrlPool) Option {
+	return func(r *Requester) {
+		r.BaseUrlPool = pool
+	}
+}
+
+// WithHedgeDelay sends queries which got no response after delay to the next base URL of the pool as well, and uses
+// the first response. Without a BaseUrlPool, a second copy of the query is sent to the base URL. Mutations and signed
+// queries, whose nonce the server rejects when it is replayed, are never hedged.
+func WithHedgeDelay(delay time.Duration) Option {
+	return func(r *Requester) {
+		r.HedgeDelay = delay
+	}
+}
+
+// isFailoverError returns whether an attempt failed because of the endpoint rather than the request itself.
+func isFailoverError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var graphqlErr *GraphQLError
+	if !errors.As(err, &graphqlErr) {
+		return true
+	}
+	return graphqlErr.StatusCode >= 500
+}
+
+// isUnsentError returns whether an attempt certainly never reached the backend: the connection to the endpoint
+// could not be established, or the endpoint answered 503 Service Unavailable without a body, as load balancers do
+// when they have no healthy backend. Other errors, e.g. a 502 or 504 from a proxy tier, do not tell whether the
+// request was executed upstream.
+func isUnsentError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusServiceUnavailable && len(httpErr.Body) == 0
+}
+
+// postToPool sends one attempt of a request to the base URLs of the pool. Mutations only fail over when the attempt
+// certainly never reached the backend, see isUnsentError, so that they are not executed twice.
+func (r *Requester) postToPool(ctx context.Context, graphqlRequest *GraphqlRequest, body []byte,
+	contentEncoding string, signingHeader string,
+) ([]byte, int, error) {
+	baseUrls := r.BaseUrlPool.baseUrlsAt(r.Runtime.Now())
+	if r.hedges(graphqlRequest, signingHeader) && len(baseUrls) > 1 {
+		return r.postHedged(ctx, baseUrls, graphqlRequest, body, contentEncoding, signingHeader)
+	}
+
+	var data []byte
+	var statusCode int
+	var err error
+	for _, baseUrl := range baseUrls {
+		data, statusCode, err = r.post(ctx, baseUrl, graphqlRequest, body, contentEncoding, signingHeader)
+		if err == nil || !isFailoverError(ctx, err) {
+			r.reportBaseUrl(baseUrl, true)
+			return data, statusCode, err
+		}
+		r.reportBaseUrl(baseUrl, false)
+		if graphqlRequest.IsMutation && !isUnsentError(err) {
+			break
+		}
+	}
+	return data, statusCode, err
+}
+
+type hedgedResponse struct {
+	baseUrl    string
+	data       []byte
+	statusCode int
+	err        error
+}
+
+// hedges returns whether a request is hedged after HedgeDelay.
+func (r *Requester) hedges(graphqlRequest *GraphqlRequest, signingHeader string) bool {
+	return r.HedgeDelay > 0 && !graphqlRequest.IsMutation && signingHeader == ""
+}
+
+// postHedged sends a query to the first base URL, and to each next one when no response was received after
+// HedgeDelay or the previous ones failed. It returns the first successful response. The base URLs are reported to
+// the BaseUrlPool, if any.
+func (r *Requester) postHedged(ctx context.Context, baseUrls []string, graphqlRequest *GraphqlRequest, body []byte,
+	contentEncoding string, signingHeader string,
+) ([]byte, int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	responses := make(chan hedgedResponse, len(baseUrls))
+	send := func(baseUrl string) {
+		data, statusCode, err := r.post(ctx, baseUrl, graphqlRequest, body, contentEncoding, signingHeader)
+		responses <- hedgedResponse{baseUrl: baseUrl, data: data, statusCode: statusCode, err: err}
+	}
+
+	go send(baseUrls[0])
+	sent, pending := 1, 1
+	hedgeTimer := time.NewTimer(r.HedgeDelay)
+	defer hedgeTimer.Stop()
+	var lastResponse hedgedResponse
+	for pending > 0 {
+		select {
+		case <-hedgeTimer.C:
+			if sent < len(baseUrls) {
+				go send(baseUrls[sent])
+				sent++
+				pending++
+				hedgeTimer.Reset(r.HedgeDelay)
+			}
+		case response := <-responses:
+			pending--
+			lastResponse = response
+			if response.err == nil || !isFailoverError(ctx, response.err) {
+				r.reportBaseUrl(response.baseUrl, true)
+				return response.data, response.statusCode, response.err
+			}
+			r.reportBaseUrl(response.baseUrl, false)
+			if sent < len(baseUrls) {
+				go send(baseUrls[sent])
+				sent++
+				pending++
+			}
+		}
+	}
+	return lastResponse.data, lastResponse.statusCode, lastResponse.err
+}
+
+func (r *Requester) reportBaseUrl(baseUrl string, healthy bool) {
+	if r.BaseUrlPool != nil {
+		r.BaseUrlPool.report(baseUrl, healthy, r.Runtime.Now())
+	}
+}
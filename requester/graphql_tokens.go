@@ -0,0 +1,160 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type graphqlTokenKind int
+
+const (
+	// graphqlPunctuator is one of ! $ & ( ) ... : = @ [ ] { | }.
+	graphqlPunctuator graphqlTokenKind = iota
+	graphqlName
+	graphqlNumber
+	// graphqlString is a string or block string literal. Its value is the raw literal, including the quotes.
+	graphqlString
+)
+
+type graphqlToken struct {
+	kind   graphqlTokenKind
+	value  string
+	offset int
+}
+
+func (t graphqlToken) is(punctuator string) bool {
+	return t.kind == graphqlPunctuator && t.value == punctuator
+}
+
+// tokenizeGraphql splits a GraphQL document into tokens, following the lexical grammar of the GraphQL specification:
+// whitespace, commas and comments are skipped, and string literals, including block strings and escaped quotes, are
+// single tokens, so that their content is never mistaken for syntax.
+func tokenizeGraphql(document string) ([]graphqlToken, error) {
+	var tokens []graphqlToken
+	for i := 0; i < len(document); {
+		char := document[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == ',':
+			i++
+		case strings.HasPrefix(document[i:], "\uFEFF"):
+			// Byte order mark.
+			i += len("\uFEFF")
+		case char == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(document[i:], `"""`):
+			end := blockStringEnd(document, i+3)
+			if end < 0 {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlString, value: document[i:end], offset: i})
+			i = end
+		case char == '"':
+			end := stringEnd(document, i+1)
+			if end < 0 {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlString, value: document[i:end], offset: i})
+			i = end
+		case strings.HasPrefix(document[i:], "..."):
+			tokens = append(tokens, graphqlToken{kind: graphqlPunctuator, value: "...", offset: i})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{|}", char) >= 0:
+			tokens = append(tokens, graphqlToken{kind: graphqlPunctuator, value: string(char), offset: i})
+			i++
+		case isNameStart(char):
+			start := i
+			for i < len(document) && (isNameStart(document[i]) || isDigit(document[i])) {
+				i++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlName, value: document[start:i], offset: start})
+		case char == '-' || isDigit(char):
+			start := i
+			i++
+			for i < len(document) && (isDigit(document[i]) || strings.IndexByte(".eE+-", document[i]) >= 0) {
+				i++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlNumber, value: document[start:i], offset: start})
+		default:
+			unexpected, _ := utf8.DecodeRuneInString(document[i:])
+			return nil, errors.New("unexpected character " + strconv.QuoteRune(unexpected) + " at offset " +
+				strconv.Itoa(i))
+		}
+	}
+	return tokens, nil
+}
+
+// stringEnd returns the offset after the closing quote of a string literal whose content starts at start, or -1 if
+// it is not terminated on the same line.
+func stringEnd(document string, start int) int {
+	for i := start; i < len(document); i++ {
+		switch document[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '\n', '\r':
+			return -1
+		}
+	}
+	return -1
+}
+
+// blockStringEnd returns the offset after the closing triple quote of a block string whose content starts at start,
+// or -1 if it is not terminated. Only the escaped triple quote \""" is an escape sequence in block strings.
+func blockStringEnd(document string, start int) int {
+	for i := start; i < len(document); i++ {
+		if strings.HasPrefix(document[i:], `\"""`) {
+			i += 3
+		} else if strings.HasPrefix(document[i:], `"""`) {
+			return i + 3
+		}
+	}
+	return -1
+}
+
+func isNameStart(char byte) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+// variableDefinitionTokens returns the index of the operation name token of the first operation of a document, and
+// the range of tokens between the parentheses of its variable definitions, which is empty if it has none. The
+// operation index is -1 if the document has no named query, mutation or subscription.
+func variableDefinitionTokens(tokens []graphqlToken) (int, int, int) {
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.is("{"):
+			depth++
+		case token.is("}"):
+			depth--
+		case depth == 0 && token.kind == graphqlName && i+1 < len(tokens) && tokens[i+1].kind == graphqlName &&
+			(token.value == "query" || token.value == "mutation" || token.value == "subscription"):
+			start := i + 2
+			if start >= len(tokens) || !tokens[start].is("(") {
+				return i + 1, start, start
+			}
+			nesting := 0
+			for end := start; end < len(tokens); end++ {
+				if tokens[end].is("(") || tokens[end].is("[") || tokens[end].is("{") {
+					nesting++
+				} else if tokens[end].is(")") || tokens[end].is("]") || tokens[end].is("}") {
+					nesting--
+					if nesting == 0 {
+						return i + 1, start + 1, end
+					}
+				}
+			}
+			return i + 1, start + 1, len(tokens)
+		}
+	}
+	return -1, 0, 0
+}
@@ -0,0 +1,81 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_HEALTH_CHECK_INTERVAL is the default interval between the health checks of RunHealthChecks.
+const DEFAULT_HEALTH_CHECK_INTERVAL = 10 * time.Second
+
+// HealthCheckFunc checks whether a base URL of a BaseUrlPool can serve requests. It returns nil if it can.
+type HealthCheckFunc func(ctx context.Context, baseUrl string) error
+
+// HTTPHealthCheck returns a HealthCheckFunc sending a GET request to the base URL with the given client, or
+// http.DefaultClient if it is nil. The base URL is healthy if it responds with a status other than a server error.
+func HTTPHealthCheck(httpClient *http.Client) HealthCheckFunc {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return func(ctx context.Context, baseUrl string) error {
+		request, err := http.NewRequestWithContext(ctx, "GET", baseUrl, nil)
+		if err != nil {
+			return err
+		}
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode >= 500 {
+			return errors.New("health check failed: " + response.Status)
+		}
+		return nil
+	}
+}
+
+// ActiveBaseUrl returns the base URL requests are sent to first: the first healthy one.
+func (p *BaseUrlPool) ActiveBaseUrl() string {
+	return p.BaseUrls()[0]
+}
+
+// CheckHealth checks every base URL of the pool once. Failing URLs are unhealthy for the Cooldown duration, so that
+// requests fail over before hitting them, and URLs passing the check are healthy again right away, so that requests
+// fail back to a preferred URL as soon as it recovers. It returns the errors of the failing URLs, by base URL.
+func (p *BaseUrlPool) CheckHealth(ctx context.Context, check HealthCheckFunc) map[string]error {
+	failures := map[string]error{}
+	for _, baseUrl := range p.baseUrls {
+		err := check(ctx, baseUrl)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			failures[baseUrl] = err
+		}
+		p.report(baseUrl, err == nil, sdkruntime.Now())
+	}
+	return failures
+}
+
+// RunHealthChecks runs CheckHealth every interval, or DEFAULT_HEALTH_CHECK_INTERVAL if it is 0, until ctx is done.
+// The interval should be shorter than the Cooldown, so that failing URLs stay unhealthy until they recover.
+func (p *BaseUrlPool) RunHealthChecks(ctx context.Context, interval time.Duration, check HealthCheckFunc) {
+	if interval <= 0 {
+		interval = DEFAULT_HEALTH_CHECK_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx, check)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"encoding/hex"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// IDEMPOTENCY_KEY_HEADER is the header carrying the idempotency key of a mutation. The API does not deduplicate
+// mutations by this header yet, so mutations carrying one are still neither retried nor failed over after an
+// ambiguous network error. The key lets the caller correlate the attempts of a payment with its own records.
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+// WithIdempotencyKey attaches an idempotency key to a mutation. It has no effect on queries. See
+// IDEMPOTENCY_KEY_HEADER: before re-sending a mutation whose outcome is unknown, e.g. after a network failure,
+// check whether it was executed.
+func WithIdempotencyKey(key string) CallOption {
+	return func(options *callOptions) {
+		options.idempotencyKey = key
+	}
+}
+
+// NewIdempotencyKey returns a random idempotency key.
+func NewIdempotencyKey() (string, error) {
+	return newRandomKey(nil)
+}
+
+// newRandomKey returns a random key read from the entropy of runtime, for idempotency keys and request IDs.
+func newRandomKey(runtime *sdkruntime.Runtime) (string, error) {
+	keyBytes := make([]byte, 16)
+	if _, err := runtime.Read(keyBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(keyBytes), nil
+}
+
+// setIdempotencyKey attaches the idempotency key of a mutation, generating one if the requester is configured to.
+func (r *Requester) setIdempotencyKey(graphqlRequest *GraphqlRequest, key string) error {
+	if !graphqlRequest.IsMutation {
+		return nil
+	}
+	if key == "" && r.IdempotencyKeys {
+		var err error
+		key, err = newRandomKey(r.Runtime)
+		if err != nil {
+			return err
+		}
+	}
+	if key != "" {
+		graphqlRequest.Header.Set(IDEMPOTENCY_KEY_HEADER, key)
+	}
+	return nil
+}
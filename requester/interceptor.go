@@ -0,0 +1,36 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"net/http"
+)
+
+// GraphqlRequest is a GraphQL request as seen by interceptors.
+type GraphqlRequest struct {
+	OperationName string
+	Query         string
+	// Variables are the request variables. Request interceptors can modify them before the request is encoded and
+	// signed.
+	Variables map[string]interface{}
+	// IsMutation is true if the request is a mutation.
+	IsMutation bool
+	// Header holds additional HTTP headers sent with the request.
+	Header http.Header
+	// Priority is the priority of the request when waiting for the RateLimiter. Request interceptors can change it.
+	Priority Priority
+
+	// decodeTarget, if set, receives the `data` of the response, decoded by post as the body is read.
+	decodeTarget interface{}
+	// decodedResult is the result of the response decoded into decodeTarget.
+	decodedResult *GraphqlResult
+}
+
+// RequestInterceptor is called before a request is encoded and sent. Returning an error aborts the request with that
+// error.
+type RequestInterceptor func(ctx context.Context, request *GraphqlRequest) error
+
+// ResponseInterceptor is called once a request completed, after any retry, with its result or error. The returned
+// result and error replace the ones passed in.
+type ResponseInterceptor func(ctx context.Context, request *GraphqlRequest, result *GraphqlResult,
+	err error) (*GraphqlResult, error)
@@ -0,0 +1,38 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Logger is a structured logger receiving the network activity of the SDK. Arguments are alternating keys and
+// values. *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// RedactError describes an error for logging without its message when the message may echo request data: GraphQL
+// errors are reduced to their name and HTTP status code, and URL errors lose their URL.
+func RedactError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Op + ": " + RedactError(urlErr.Err)
+	}
+	var graphqlErr *GraphQLError
+	if errors.As(err, &graphqlErr) {
+		description := "GraphQLError status=" + strconv.Itoa(graphqlErr.StatusCode)
+		if graphqlErr.Name != "" {
+			description += " name=" + graphqlErr.Name
+		}
+		return description
+	}
+	return err.Error()
+}
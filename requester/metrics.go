@@ -0,0 +1,11 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import "time"
+
+// MetricsCollector receives the outcome of every GraphQL request executed by a Requester, e.g. to export request
+// counts, latencies and errors by operation name. See the metrics package for a Prometheus implementation.
+type MetricsCollector interface {
+	// ObserveRequest is called once per request, after any retry. err is nil if the request succeeded.
+	ObserveRequest(operationName string, duration time.Duration, err error)
+}
@@ -0,0 +1,139 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// PreparedRequest is a signed operation whose payload was encoded but not signed yet. It is produced by
+// PrepareGraphql and sent by ExecutePreparedGraphql once the payload has been signed, e.g. by an isolated signer
+// service holding the signing key.
+type PreparedRequest struct {
+	OperationName string
+	IsMutation    bool
+	// Payload is the canonical payload to sign. It must be sent and signed byte for byte as is.
+	Payload []byte
+	// Header holds the headers set by the request interceptors.
+	Header http.Header
+	// Query and Variables are the operation, as passed to the response interceptors. Changing them does not change
+	// the Payload.
+	Query     string
+	Variables map[string]interface{}
+	// KeyId, if set, is the id of the key the payload is signed with, as returned by a SigningKeyProvider. It is sent
+	// with the signature, so that the server checks it against that key while keys are rotated.
+	KeyId string
+}
+
+// PrepareGraphql runs the request interceptors and encodes the payload of a signed operation, without sending it.
+// The payload carries a nonce and expires SigningExpiry after it was prepared.
+func (r *Requester) PrepareGraphql(ctx context.Context, query string, variables map[string]interface{},
+) (*PreparedRequest, error) {
+	graphqlRequest, err := NewGraphqlRequest(query, variables)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkOperation(graphqlRequest); err != nil {
+		return nil, err
+	}
+	for _, interceptor := range r.RequestInterceptors {
+		if err := interceptor(ctx, graphqlRequest); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.checkQuery(graphqlRequest, r.ValidateVariables); err != nil {
+		return nil, err
+	}
+	encodedPayload, err := r.encodePayload(graphqlRequest, true, r.signingExpiry(), nil)
+	if err != nil {
+		return nil, err
+	}
+	ObserveSignablePayload(SIGNABLE_GRAPHQL_REQUEST, graphqlRequest.OperationName, encodedPayload, false)
+	return &PreparedRequest{
+		OperationName: graphqlRequest.OperationName,
+		IsMutation:    graphqlRequest.IsMutation,
+		Payload:       encodedPayload,
+		Header:        graphqlRequest.Header,
+		Query:         graphqlRequest.Query,
+		Variables:     graphqlRequest.Variables,
+	}, nil
+}
+
+// ExecutePreparedGraphql sends a payload returned by PrepareGraphql with its signature, computed elsewhere with the
+// signing key of the node. Set prepared.KeyId first if the signer uses rotating keys. The request interceptors ran
+// when it was prepared; the response interceptors, tracing, metrics and events apply as with ExecuteGraphql.
+//
+// Args:
+//
+//	prepared: the request returned by PrepareGraphql.
+//	signature: the signature of prepared.Payload.
+func (r *Requester) ExecutePreparedGraphql(ctx context.Context, prepared *PreparedRequest, signature []byte,
+) (*GraphqlResult, error) {
+	if prepared == nil || len(prepared.Payload) == 0 {
+		return nil, errors.New("missing prepared payload")
+	}
+	if len(signature) == 0 {
+		return nil, errors.New("missing signature")
+	}
+	header := prepared.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	graphqlRequest := &GraphqlRequest{
+		OperationName: prepared.OperationName,
+		Query:         prepared.Query,
+		Variables:     prepared.Variables,
+		IsMutation:    prepared.IsMutation,
+		Header:        header,
+		Priority:      r.priority(ctx, prepared.OperationName),
+	}
+	if err := r.checkOperation(graphqlRequest); err != nil {
+		return nil, err
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	requestId, err := r.setRequestId(ctx, graphqlRequest)
+	if err != nil {
+		return nil, err
+	}
+	ctx = ContextWithRequestId(ctx, requestId)
+	ctx, finish := r.startRequest(ctx, graphqlRequest, requestId)
+
+	var result *GraphqlResult
+	if r.DryRun && prepared.IsMutation {
+		err = &DryRunError{
+			OperationName: prepared.OperationName,
+			Header:        graphqlRequest.Header.Clone(),
+			Payload:       prepared.Payload,
+			Signed:        true,
+		}
+	} else {
+		result, err = r.executePrepared(ctx, graphqlRequest, prepared, signature)
+	}
+	result, err = r.interceptResponse(ctx, graphqlRequest, result, err)
+	return result, finish(withRequestId(err, requestId))
+}
+
+func (r *Requester) executePrepared(ctx context.Context, graphqlRequest *GraphqlRequest, prepared *PreparedRequest,
+	signature []byte,
+) (*GraphqlResult, error) {
+	serverUrl, err := r.serverUrl()
+	if err != nil {
+		return nil, err
+	}
+	if r.QuotaBudgeter != nil {
+		if err := r.QuotaBudgeter.reserve(r.Feature, 1, r.Runtime.Now()); err != nil {
+			return nil, err
+		}
+	}
+	data, statusCode, err := r.postWithRetry(ctx, serverUrl, graphqlRequest, prepared.Payload,
+		encodeSigningHeader(signature, prepared.KeyId), r.RetryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return parseGraphqlResponse(data, statusCode)
+}
@@ -0,0 +1,49 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+// OperationNotAllowedError is returned, without sending the request, when an operation is rejected by the ReadOnly
+// mode or the AllowedOperations of a Requester.
+type OperationNotAllowedError struct {
+	// OperationName is the name of the rejected operation.
+	OperationName string
+	// IsMutation is true if the operation is a mutation.
+	IsMutation bool
+}
+
+func (e *OperationNotAllowedError) Error() string {
+	if e.IsMutation {
+		return "mutation " + e.OperationName + " is not allowed by this requester"
+	}
+	return "operation " + e.OperationName + " is not allowed by this requester"
+}
+
+// WithReadOnly restricts the requester to queries, so that a service holding a token, e.g. for analytics, cannot
+// execute mutations.
+func WithReadOnly() Option {
+	return func(r *Requester) {
+		r.ReadOnly = true
+	}
+}
+
+// WithAllowedOperations restricts the requester to the operations with the given names.
+func WithAllowedOperations(operationNames ...string) Option {
+	return func(r *Requester) {
+		r.AllowedOperations = make(map[string]bool, len(operationNames))
+		for _, operationName := range operationNames {
+			r.AllowedOperations[operationName] = true
+		}
+	}
+}
+
+// checkOperation returns an OperationNotAllowedError if a request is rejected by the ReadOnly mode or the
+// AllowedOperations.
+func (r *Requester) checkOperation(graphqlRequest *GraphqlRequest) error {
+	if (r.ReadOnly && graphqlRequest.IsMutation) ||
+		(r.AllowedOperations != nil && !r.AllowedOperations[graphqlRequest.OperationName]) {
+		return &OperationNotAllowedError{
+			OperationName: graphqlRequest.OperationName,
+			IsMutation:    graphqlRequest.IsMutation,
+		}
+	}
+	return nil
+}
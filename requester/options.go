@@ -0,0 +1,110 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// Option configures a Requester created with NewRequester or NewRequesterWithOptions.
+type Option func(*Requester)
+
+// WithBaseUrl sets the base URL of the Lightspark API. It is validated by NewRequesterWithOptions.
+func WithBaseUrl(baseUrl string) Option {
+	return func(r *Requester) {
+		r.BaseUrl = &baseUrl
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests. The options configuring the transport applied after it,
+// e.g. WithConnectionPool, WithProxyURL or WithClientCertificate, modify a copy of the client and of its transport,
+// so that a client shared with other code is left unchanged.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(r *Requester) {
+		r.HTTPClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of the HTTP client. If a client was set with a previous WithHTTPClient option, a copy
+// of it with the timeout is used.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Requester) {
+		httpClient := &http.Client{}
+		if r.HTTPClient != nil {
+			clientCopy := *r.HTTPClient
+			httpClient = &clientCopy
+		}
+		httpClient.Timeout = timeout
+		r.HTTPClient = httpClient
+	}
+}
+
+// WithRetryPolicy sets the retry policy of requests.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(r *Requester) {
+		r.RetryPolicy = policy
+	}
+}
+
+// WithSigningExpiry sets the time after which signed requests expire. See Requester.SigningExpiry.
+func WithSigningExpiry(signingExpiry time.Duration) Option {
+	return func(r *Requester) {
+		r.SigningExpiry = signingExpiry
+	}
+}
+
+// WithDefaultHeader adds a static header sent with every request. See Requester.DefaultHeaders.
+func WithDefaultHeader(name string, value string) Option {
+	return func(r *Requester) {
+		if r.DefaultHeaders == nil {
+			r.DefaultHeaders = http.Header{}
+		}
+		r.DefaultHeaders.Add(name, value)
+	}
+}
+
+// WithUserAgentSuffix appends an application identifier, e.g. "billing-service/1.2", to the User-Agent header.
+func WithUserAgentSuffix(suffix string) Option {
+	return func(r *Requester) {
+		r.UserAgentSuffix = suffix
+	}
+}
+
+// WithEventSink sends the lifecycle events of requests to the sink. See Requester.EventSink.
+func WithEventSink(sink events.Sink) Option {
+	return func(r *Requester) {
+		r.EventSink = sink
+	}
+}
+
+// WithRuntime sets the clock and the source of randomness of the requests. See Requester.Runtime.
+func WithRuntime(runtime *sdkruntime.Runtime) Option {
+	return func(r *Requester) {
+		r.Runtime = runtime
+	}
+}
+
+// NewRequesterWithOptions creates a Requester configured with the given options. Unlike NewRequesterWithBaseUrl, it
+// returns an error instead of panicking if the base URL is invalid.
+//
+// Args:
+//
+//	apiTokenClientId: the client id of the API token
+//	apiTokenClientSecret: the client secret of the API token
+//	options: the options to apply, in order
+func NewRequesterWithOptions(apiTokenClientId string, apiTokenClientSecret string,
+	options ...Option) (*Requester, error) {
+	r := NewRequester(apiTokenClientId, apiTokenClientSecret, options...)
+	if r.optionErr != nil {
+		return nil, r.optionErr
+	}
+	if r.BaseUrl != nil {
+		if err := r.ValidateBaseUrl(*r.BaseUrl); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
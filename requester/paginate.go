@@ -0,0 +1,128 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// PageIterator iterates over the pages of a cursor-paginated query. It is returned by Paginate:
+//
+//	pages := r.Paginate(ctx, query, map[string]interface{}{"entity_id": accountId, "first": 100},
+//		"entity.account_to_transactions_connection_page_info")
+//	for pages.Next() {
+//		result := pages.Page()
+//		...
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+type PageIterator struct {
+	requester    *Requester
+	ctx          context.Context
+	query        string
+	variables    map[string]interface{}
+	pageInfoPath []string
+
+	page *GraphqlResult
+	err  error
+	done bool
+}
+
+// Paginate returns an iterator over the pages of a query taking an `$after: String` cursor variable. Each page is
+// requested with the end cursor of the previous page until a page has no next page.
+//
+// Args:
+//
+//	ctx: the context of the page requests.
+//	query: the GraphQL query.
+//	variables: the variables of the first page. An `after` variable, if set, is the cursor of the first page.
+//	pageInfoPath: the dot-separated path of the page info object in the response data, e.g.
+//	  "entity.account_to_transactions_connection_page_info". Both the page info field names and the
+//	  `page_info_`-prefixed aliases of the objects package fragments are supported.
+func (r *Requester) Paginate(ctx context.Context, query string, variables map[string]interface{},
+	pageInfoPath string,
+) *PageIterator {
+	pageVariables := make(map[string]interface{}, len(variables)+1)
+	for name, value := range variables {
+		pageVariables[name] = value
+	}
+	return &PageIterator{
+		requester:    r,
+		ctx:          ctx,
+		query:        query,
+		variables:    pageVariables,
+		pageInfoPath: strings.Split(pageInfoPath, "."),
+	}
+}
+
+// Next requests the next page. It returns false when there are no more pages or a request failed, in which case Err
+// returns the error.
+func (p *PageIterator) Next() bool {
+	if p.done {
+		return false
+	}
+	page, err := p.requester.ExecuteGraphqlForResultWithContext(p.ctx, p.query, p.variables, nil)
+	if err != nil {
+		p.fail(err)
+		return false
+	}
+	hasNextPage, endCursor, err := p.pageInfo(page.Data)
+	if err != nil {
+		p.fail(err)
+		return false
+	}
+	p.page = page
+	if !hasNextPage || endCursor == "" {
+		p.done = true
+		return true
+	}
+	if previousCursor, ok := p.variables["after"].(string); ok && previousCursor == endCursor {
+		p.fail(errors.New("pagination cursor did not advance"))
+		return false
+	}
+	p.variables["after"] = endCursor
+	return true
+}
+
+// Page returns the current page.
+func (p *PageIterator) Page() *GraphqlResult {
+	return p.page
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (p *PageIterator) Err() error {
+	return p.err
+}
+
+func (p *PageIterator) fail(err error) {
+	p.err = err
+	p.page = nil
+	p.done = true
+}
+
+func (p *PageIterator) pageInfo(data map[string]interface{}) (bool, string, error) {
+	var value interface{} = data
+	for _, field := range p.pageInfoPath {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false, "", errors.New("page info not found in response")
+		}
+		value = object[field]
+	}
+	pageInfo, ok := value.(map[string]interface{})
+	if !ok {
+		return false, "", errors.New("page info not found in response")
+	}
+	hasNextPage, _ := pageInfoField(pageInfo, "has_next_page").(bool)
+	endCursor, _ := pageInfoField(pageInfo, "end_cursor").(string)
+	return hasNextPage, endCursor, nil
+}
+
+func pageInfoField(pageInfo map[string]interface{}, name string) interface{} {
+	if value, ok := pageInfo[name]; ok {
+		return value
+	}
+	return pageInfo["page_info_"+name]
+}
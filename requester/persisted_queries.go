@@ -0,0 +1,54 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// persistedQuery refers to a query by its hash, following the automatic persisted queries protocol.
+type persistedQuery struct {
+	hash         string
+	includeQuery bool
+}
+
+func (p *persistedQuery) extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"persistedQuery": map[string]interface{}{
+			"version":    1,
+			"sha256Hash": p.hash,
+		},
+	}
+}
+
+func persistedQueryHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// isPersistedQueryMiss returns whether the server asked for the full query, because it does not know its hash or does
+// not support persisted queries.
+func isPersistedQueryMiss(err error) bool {
+	var graphqlErr *GraphQLError
+	if !errors.As(err, &graphqlErr) {
+		return false
+	}
+	switch graphqlErr.Message {
+	case "PersistedQueryNotFound", "PersistedQueryNotSupported":
+		return true
+	}
+	switch graphqlErr.Extensions["code"] {
+	case "PERSISTED_QUERY_NOT_FOUND", "PERSISTED_QUERY_NOT_SUPPORTED":
+		return true
+	}
+	return false
+}
+
+// WithPersistedQueries sends the hash of queries instead of their text when the server already knows them. See
+// Requester.PersistedQueries.
+func WithPersistedQueries() Option {
+	return func(r *Requester) {
+		r.PersistedQueries = true
+	}
+}
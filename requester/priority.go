@@ -0,0 +1,54 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import "context"
+
+// Priority is the priority class of an operation. When the RateLimiter of a requester is saturated, operations of a
+// higher priority get the available capacity first.
+type Priority int
+
+const (
+	// PriorityBackground is for background work which can wait, e.g. reconciliation jobs.
+	PriorityBackground Priority = -1
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 0
+	// PriorityCritical is for operations on the critical payment path.
+	PriorityCritical Priority = 1
+)
+
+type priorityContextKey struct{}
+
+// ContextWithPriority returns a context whose GraphQL calls have the given priority, overriding the
+// OperationPriorities of the requester.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// WithCallPriority sets the priority of the call, overriding the priority of its context and the
+// OperationPriorities of the requester.
+func WithCallPriority(priority Priority) CallOption {
+	return func(options *callOptions) {
+		options.priority = &priority
+	}
+}
+
+// WithOperationPriority sets the priority of the operations with the given names. See Requester.OperationPriorities.
+func WithOperationPriority(priority Priority, operationNames ...string) Option {
+	return func(r *Requester) {
+		if r.OperationPriorities == nil {
+			r.OperationPriorities = map[string]Priority{}
+		}
+		for _, operationName := range operationNames {
+			r.OperationPriorities[operationName] = priority
+		}
+	}
+}
+
+// priority returns the priority of an operation: the priority of its context if any, or the one configured for its
+// name in OperationPriorities, or PriorityNormal.
+func (r *Requester) priority(ctx context.Context, operationName string) Priority {
+	if priority, ok := ctx.Value(priorityContextKey{}).(Priority); ok {
+		return priority
+	}
+	return r.OperationPriorities[operationName]
+}
@@ -0,0 +1,145 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MAX_SAFE_FLOAT_INTEGER is the largest integer up to which every integer is exactly representable as a float64.
+// Integers above it, e.g. large msat amounts, lose precision once converted to a float64.
+const MAX_SAFE_FLOAT_INTEGER = 1 << 53
+
+// Query builds the variables of a GraphQL request with typed values, checking that each value is sent to the server
+// exactly as given. Errors are accumulated and returned by Build and ExecuteQuery.
+type Query struct {
+	query     string
+	variables map[string]interface{}
+	err       error
+}
+
+// NewQuery creates a Query for a GraphQL document, e.g. one of the queries of the scripts package.
+func NewQuery(query string) *Query {
+	return &Query{query: query, variables: map[string]interface{}{}}
+}
+
+// WithVariable sets a variable of the query. Integers are sent exactly. Floats of MAX_SAFE_FLOAT_INTEGER or more,
+// which may have been rounded from a larger integer, NaN and infinite floats, and unsigned integers overflowing an
+// int64 are rejected. Maps, slices and the exported fields of structs are checked recursively.
+func (q *Query) WithVariable(name string, value interface{}) *Query {
+	if q.err != nil {
+		return q
+	}
+	if name == "" {
+		q.err = errors.New("missing variable name")
+		return q
+	}
+	if err := checkVariableValue(reflect.ValueOf(value)); err != nil {
+		q.err = errors.New("invalid value for variable $" + name + ": " + err.Error())
+		return q
+	}
+	if _, err := json.Marshal(value); err != nil {
+		q.err = errors.New("invalid value for variable $" + name + ": " + err.Error())
+		return q
+	}
+	q.variables[name] = value
+	return q
+}
+
+// Build validates the variables against the variable definitions of the query, like ValidateVariables, and returns
+// the request, e.g. to send it in a batch with ExecuteGraphqlBatch.
+func (q *Query) Build() (*GraphqlRequest, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if err := ValidateVariables(q.query, q.variables); err != nil {
+		return nil, err
+	}
+	return NewGraphqlRequest(q.query, q.variables)
+}
+
+// ExecuteQuery builds a Query and executes it like ExecuteGraphqlWithOptions.
+//
+// Args:
+//
+//	query: the query to execute.
+//	signingKey: the key to sign the request with, or nil for unsigned requests.
+//	options: the per-call overrides.
+func (r *Requester) ExecuteQuery(ctx context.Context, query *Query, signingKey SigningKey, options ...CallOption,
+) (*GraphqlResult, error) {
+	request, err := query.Build()
+	if err != nil {
+		return nil, err
+	}
+	return r.ExecuteGraphqlWithOptions(ctx, request.Query, request.Variables, signingKey, options...)
+}
+
+func checkVariableValue(value reflect.Value) error {
+	if !value.IsValid() {
+		return nil
+	}
+	if value.Type().Implements(jsonMarshalerType) {
+		// The value encodes itself.
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		number := value.Float()
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return errors.New("float is not a finite number")
+		}
+		if math.Abs(number) >= MAX_SAFE_FLOAT_INTEGER {
+			return errors.New(strconv.FormatFloat(number, 'f', -1, 64) +
+				" cannot be represented exactly as a float, use an int64 instead")
+		}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return errors.New(strconv.FormatUint(value.Uint(), 10) + " overflows an int64")
+		}
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return checkVariableValue(value.Elem())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < value.Len(); i++ {
+			if err := checkVariableValue(value.Index(i)); err != nil {
+				return errors.New("item " + strconv.Itoa(i) + ": " + err.Error())
+			}
+		}
+	case reflect.Map:
+		iterator := value.MapRange()
+		for iterator.Next() {
+			if err := checkVariableValue(iterator.Value()); err != nil {
+				return errors.New("field " + fmt.Sprint(iterator.Key().Interface()) + ": " + err.Error())
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			tag := field.Tag.Get("json")
+			if !field.IsExported() || tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = field.Name
+			}
+			if err := checkVariableValue(value.Field(i)); err != nil {
+				return errors.New("field " + name + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
@@ -0,0 +1,191 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DEFAULT_MAX_QUERY_BYTES is the default size above which QueryLinter rejects a query.
+	DEFAULT_MAX_QUERY_BYTES = 256 * 1024
+	// DEFAULT_MAX_VARIABLES_BYTES is the default size above which QueryLinter rejects the encoded variables.
+	DEFAULT_MAX_VARIABLES_BYTES = 1024 * 1024
+	// DEFAULT_MAX_QUERY_DEPTH is the default selection set depth above which QueryLinter rejects a query.
+	DEFAULT_MAX_QUERY_DEPTH = 20
+)
+
+// QueryLintError is returned when a request fails the checks of a QueryLinter. It is returned before any network
+// I/O.
+type QueryLintError struct {
+	// OperationName is the name of the operation, if it could be parsed.
+	OperationName string
+	// Problems lists every problem found, e.g. `variable $node_id is used but not defined`.
+	Problems []string
+}
+
+func (e *QueryLintError) Error() string {
+	operationName := e.OperationName
+	if operationName == "" {
+		operationName = "query"
+	}
+	return "invalid " + operationName + ": " + strings.Join(e.Problems, "; ")
+}
+
+// QueryLinter checks requests locally before they are sent, e.g. when building queries dynamically: the operation
+// must have a name, brackets must be balanced, the variables used by the query must be defined and the defined ones
+// used, the variables must match their definitions like with ValidateVariables, and the query and its variables must
+// be within approximate size limits. Zero fields use the defaults.
+type QueryLinter struct {
+	// MaxQueryBytes is the maximum size of the query. Defaults to DEFAULT_MAX_QUERY_BYTES.
+	MaxQueryBytes int
+	// MaxVariablesBytes is the maximum size of the JSON-encoded variables. Defaults to DEFAULT_MAX_VARIABLES_BYTES.
+	MaxVariablesBytes int
+	// MaxDepth is the maximum nesting depth of selection sets. Defaults to DEFAULT_MAX_QUERY_DEPTH.
+	MaxDepth int
+}
+
+// WithQueryLinter checks every request with the linter before sending it. See Requester.QueryLinter.
+func WithQueryLinter(linter QueryLinter) Option {
+	return func(r *Requester) {
+		r.QueryLinter = &linter
+	}
+}
+
+// checkQuery checks a request with the QueryLinter if it is set, or else with ValidateVariables if validate is true.
+func (r *Requester) checkQuery(graphqlRequest *GraphqlRequest, validate bool) error {
+	if r.QueryLinter != nil {
+		return r.QueryLinter.Lint(graphqlRequest.Query, graphqlRequest.Variables)
+	}
+	if validate {
+		return ValidateVariables(graphqlRequest.Query, graphqlRequest.Variables)
+	}
+	return nil
+}
+
+// Lint checks a query and its variables, returning a QueryLintError listing every problem found.
+func (l QueryLinter) Lint(query string, variables map[string]interface{}) error {
+	var problems []string
+	maxQueryBytes := l.MaxQueryBytes
+	if maxQueryBytes <= 0 {
+		maxQueryBytes = DEFAULT_MAX_QUERY_BYTES
+	}
+	if len(query) > maxQueryBytes {
+		problems = append(problems, "query is "+strconv.Itoa(len(query))+" bytes, above the limit of "+
+			strconv.Itoa(maxQueryBytes))
+	}
+	maxVariablesBytes := l.MaxVariablesBytes
+	if maxVariablesBytes <= 0 {
+		maxVariablesBytes = DEFAULT_MAX_VARIABLES_BYTES
+	}
+	encodedVariables, err := json.Marshal(variables)
+	if err != nil {
+		problems = append(problems, "variables cannot be encoded: "+err.Error())
+	} else if len(encodedVariables) > maxVariablesBytes {
+		problems = append(problems, "variables are "+strconv.Itoa(len(encodedVariables))+
+			" bytes, above the limit of "+strconv.Itoa(maxVariablesBytes))
+	}
+
+	maxDepth := l.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DEFAULT_MAX_QUERY_DEPTH
+	}
+	tokens, err := tokenizeGraphql(query)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if depth, bracketProblem := selectionDepth(tokens); bracketProblem != "" {
+		problems = append(problems, bracketProblem)
+	} else if depth > maxDepth {
+		problems = append(problems, "query depth is "+strconv.Itoa(depth)+", above the limit of "+
+			strconv.Itoa(maxDepth))
+	}
+
+	operationName, definitions, err := parseVariableDefinitions(query)
+	if err != nil {
+		problems = append(problems, "cannot parse the operation: "+err.Error())
+		return &QueryLintError{OperationName: operationName, Problems: problems}
+	}
+	if tokens != nil {
+		problems = append(problems, variableUsageProblems(tokens, definitions)...)
+	}
+	if err := ValidateVariables(query, variables); err != nil {
+		var validationErr *VariableValidationError
+		if !errors.As(err, &validationErr) {
+			return err
+		}
+		problems = append(problems, validationErr.Problems...)
+	}
+	if len(problems) > 0 {
+		return &QueryLintError{OperationName: operationName, Problems: problems}
+	}
+	return nil
+}
+
+// selectionDepth returns the maximum nesting of braces of a query, or a problem if its brackets are unbalanced.
+func selectionDepth(tokens []graphqlToken) (int, string) {
+	var stack []string
+	depth, maxDepth := 0, 0
+	closing := map[string]string{"}": "{", ")": "(", "]": "["}
+	for _, token := range tokens {
+		if token.kind != graphqlPunctuator {
+			continue
+		}
+		switch token.value {
+		case "{", "(", "[":
+			stack = append(stack, token.value)
+			if token.value == "{" {
+				depth++
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+			}
+		case "}", ")", "]":
+			if len(stack) == 0 || stack[len(stack)-1] != closing[token.value] {
+				return 0, "unbalanced " + token.value + " at offset " + strconv.Itoa(token.offset)
+			}
+			stack = stack[:len(stack)-1]
+			if token.value == "}" {
+				depth--
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return 0, "unclosed " + stack[len(stack)-1]
+	}
+	return maxDepth, ""
+}
+
+// variableUsageProblems reports the variables used by a query which are not defined, and the defined variables
+// which are never used.
+func variableUsageProblems(tokens []graphqlToken, definitions []variableDefinition) []string {
+	_, definitionsStart, definitionsEnd := variableDefinitionTokens(tokens)
+	used := map[string]bool{}
+	for i := 0; i+1 < len(tokens); i++ {
+		if i >= definitionsStart && i < definitionsEnd {
+			// Skip the variable definitions, whose default values cannot use variables.
+			continue
+		}
+		if tokens[i].is("$") && tokens[i+1].kind == graphqlName {
+			used[tokens[i+1].value] = true
+		}
+	}
+	defined := map[string]bool{}
+	var problems []string
+	for _, definition := range definitions {
+		defined[definition.name] = true
+		if !used[definition.name] {
+			problems = append(problems, "variable $"+definition.name+" is defined but not used")
+		}
+	}
+	var undefined []string
+	for name := range used {
+		if !defined[name] {
+			undefined = append(undefined, "variable $"+name+" is used but not defined")
+		}
+	}
+	sort.Strings(undefined)
+	return append(problems, undefined...)
+}
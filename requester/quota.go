@@ -0,0 +1,126 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_QUOTA_FEATURE is the feature name of requests which are not tagged with a feature.
+const DEFAULT_QUOTA_FEATURE = "default"
+
+// FeatureBudget is the request rate budget of a logical feature, enforced as a token bucket.
+type FeatureBudget struct {
+	// RequestsPerSecond is the sustained request rate allowed for the feature.
+	RequestsPerSecond float64
+	// Burst is the number of requests which can be made at once above the sustained rate. Defaults to 1.
+	Burst int
+}
+
+// QuotaExceededError is returned when a request is rejected because its feature exhausted its budget.
+type QuotaExceededError struct {
+	// Feature is the feature whose budget was exhausted.
+	Feature string
+	// RetryAfter is the time after which the feature will have budget again.
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return "API quota budget exhausted for feature " + e.Feature + ", retry after " + e.RetryAfter.String()
+}
+
+// QuotaBudgeter enforces per-feature request budgets, so that a runaway background job (e.g. reconciliation) cannot
+// starve other features (e.g. payments) of API quota. Features without a budget are not limited. It is safe for
+// concurrent use and can be shared by several requesters.
+type QuotaBudgeter struct {
+	mutex   sync.Mutex
+	budgets map[string]FeatureBudget
+	buckets map[string]*quotaBucket
+}
+
+type quotaBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewQuotaBudgeter(budgets map[string]FeatureBudget) *QuotaBudgeter {
+	copiedBudgets := make(map[string]FeatureBudget, len(budgets))
+	for feature, budget := range budgets {
+		copiedBudgets[feature] = budget
+	}
+	return &QuotaBudgeter{budgets: copiedBudgets, buckets: map[string]*quotaBucket{}}
+}
+
+// SetBudget sets or replaces the budget of a feature.
+func (q *QuotaBudgeter) SetBudget(feature string, budget FeatureBudget) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.budgets[feature] = budget
+	delete(q.buckets, feature)
+}
+
+// Reserve takes one request from the budget of a feature, or returns a QuotaExceededError if the budget is exhausted.
+func (q *QuotaBudgeter) Reserve(feature string) error {
+	return q.reserve(feature, 1, sdkruntime.Now())
+}
+
+// reserve takes count requests from the budget of a feature, or none if the budget does not have them all. The
+// budget is refilled up to now, the time of the clock of the caller, e.g. of the requester sending the requests.
+func (q *QuotaBudgeter) reserve(feature string, count int, now time.Time) error {
+	if feature == "" {
+		feature = DEFAULT_QUOTA_FEATURE
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	budget, ok := q.budgets[feature]
+	if !ok {
+		return nil
+	}
+	bucket, burst := q.refill(feature, budget, now)
+	if bucket.tokens >= float64(count) {
+		bucket.tokens -= float64(count)
+		return nil
+	}
+
+	retryAfter := time.Duration(math.MaxInt64)
+	if budget.RequestsPerSecond > 0 && float64(count) <= burst {
+		retryAfter = time.Duration((float64(count) - bucket.tokens) / budget.RequestsPerSecond * float64(time.Second))
+	}
+	return &QuotaExceededError{Feature: feature, RetryAfter: retryAfter}
+}
+
+// refund gives back count requests reserved from the budget of a feature which were not sent.
+func (q *QuotaBudgeter) refund(feature string, count int, now time.Time) {
+	if feature == "" {
+		feature = DEFAULT_QUOTA_FEATURE
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	budget, ok := q.budgets[feature]
+	if !ok {
+		return
+	}
+	bucket, burst := q.refill(feature, budget, now)
+	bucket.tokens = math.Min(burst, bucket.tokens+float64(count))
+}
+
+// refill returns the bucket of a feature, with the tokens accrued since it was last updated, and its capacity.
+func (q *QuotaBudgeter) refill(feature string, budget FeatureBudget, now time.Time) (*quotaBucket, float64) {
+	burst := float64(budget.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	bucket, ok := q.buckets[feature]
+	if !ok {
+		bucket = &quotaBucket{tokens: burst, updatedAt: now}
+		q.buckets[feature] = bucket
+	}
+	if elapsed := now.Sub(bucket.updatedAt); elapsed > 0 {
+		bucket.tokens = math.Min(burst, bucket.tokens+elapsed.Seconds()*budget.RequestsPerSecond)
+		bucket.updatedAt = now
+	}
+	return bucket, burst
+}
@@ -0,0 +1,128 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// RateLimiter is a client-side token bucket limiting the rate of requests sent to the API, so that high-throughput
+// integrations stay below the server-side rate limit instead of being throttled. It is safe for concurrent use and
+// can be shared by several requesters. While requests of a higher priority are waiting, requests of a lower priority
+// are held back, so critical operations get the limited capacity first.
+type RateLimiter struct {
+	requestsPerSecond float64
+	burst             float64
+
+	mutex     sync.Mutex
+	tokens    float64
+	updatedAt time.Time
+	waiting   map[Priority]int
+}
+
+// NewRateLimiter creates a RateLimiter allowing requestsPerSecond requests per second on average, and bursts of up to
+// burst requests.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		requestsPerSecond: requestsPerSecond,
+		burst:             float64(burst),
+		tokens:            float64(burst),
+		waiting:           map[Priority]int{},
+	}
+}
+
+// Wait blocks until a request of PriorityNormal can be sent or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return l.WaitPriority(ctx, PriorityNormal)
+}
+
+// WaitPriority blocks until a request of the given priority can be sent or the context is done.
+func (l *RateLimiter) WaitPriority(ctx context.Context, priority Priority) error {
+	return l.waitPriority(ctx, priority, nil)
+}
+
+// waitPriority waits like WaitPriority, refilling the bucket with the clock of the given runtime, e.g. the runtime of
+// the requester sending the request.
+func (l *RateLimiter) waitPriority(ctx context.Context, priority Priority, runtime *sdkruntime.Runtime) error {
+	l.addWaiting(priority, 1)
+	defer l.addWaiting(priority, -1)
+	for {
+		delay := l.reserve(priority, runtime.Now())
+		if delay == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available and no request of a higher priority is waiting, and returns 0, or
+// returns the time after which to try again.
+func (l *RateLimiter) reserve(priority Priority, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.updatedAt.IsZero() {
+		l.updatedAt = now
+	}
+	if elapsed := now.Sub(l.updatedAt); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.requestsPerSecond)
+		l.updatedAt = now
+	}
+	if l.tokens >= 1 && !l.higherPriorityWaiting(priority) {
+		l.tokens--
+		return 0
+	}
+	if l.requestsPerSecond <= 0 {
+		return time.Second
+	}
+	if l.tokens >= 1 {
+		// The token is left to the waiting requests of a higher priority.
+		return time.Duration(float64(time.Second) / l.requestsPerSecond)
+	}
+	return time.Duration((1 - l.tokens) / l.requestsPerSecond * float64(time.Second))
+}
+
+func (l *RateLimiter) addWaiting(priority Priority, delta int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.waiting[priority] += delta
+}
+
+func (l *RateLimiter) higherPriorityWaiting(priority Priority) bool {
+	for waitingPriority, count := range l.waiting {
+		if waitingPriority > priority && count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses a `Retry-After` header, given either in seconds or as an HTTP date. It returns 0 if the
+// header is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if retryAt, err := http.ParseTime(header); err == nil && retryAt.After(now) {
+		return retryAt.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+)
+
+// REQUEST_ID_HEADER is the header carrying the ID correlating a GraphQL call with the server logs. The same ID is
+// sent with every attempt of a call.
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+type requestIdContextKey struct{}
+
+// ContextWithRequestId returns a context whose GraphQL calls are sent with the given request ID, e.g. the ID of the
+// incoming request being served, instead of a random one.
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey{}, requestId)
+}
+
+// RequestIdFromContext returns the request ID set with ContextWithRequestId, or "" if there is none. Interceptors
+// receive a context carrying the ID of the call they intercept.
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+	return requestId
+}
+
+// RequestIdFromError returns the request ID of the failed call which returned err, or "" if it is unknown.
+func RequestIdFromError(err error) string {
+	var graphqlErr *GraphQLError
+	if errors.As(err, &graphqlErr) {
+		return graphqlErr.RequestId
+	}
+	return ""
+}
+
+// setRequestId sets the request ID of a request, taken from the context or generated, unless it already has one.
+func (r *Requester) setRequestId(ctx context.Context, graphqlRequest *GraphqlRequest) (string, error) {
+	if requestId := graphqlRequest.Header.Get(REQUEST_ID_HEADER); requestId != "" {
+		return requestId, nil
+	}
+	requestId := RequestIdFromContext(ctx)
+	if requestId == "" {
+		var err error
+		requestId, err = newRandomKey(r.Runtime)
+		if err != nil {
+			return "", err
+		}
+	}
+	graphqlRequest.Header.Set(REQUEST_ID_HEADER, requestId)
+	return requestId, nil
+}
+
+// withRequestId records the request ID of a call in the GraphQL error it returned, if any.
+func withRequestId(err error, requestId string) error {
+	var graphqlErr *GraphQLError
+	if errors.As(err, &graphqlErr) && graphqlErr.RequestId == "" {
+		graphqlErr.RequestId = requestId
+	}
+	return err
+}
@@ -3,20 +3,22 @@ package requester
 
 import (
 	"bytes"
-	"crypto/rand"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"math/big"
 	"net/http"
-	"net/url"
 	"regexp"
 	"runtime"
 	"strings"
 	"time"
 
 	lightspark "github.com/lightsparkdev/go-sdk"
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/experimental"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Requester struct {
@@ -27,62 +29,492 @@ type Requester struct {
 	BaseUrl *string
 
 	HTTPClient *http.Client
+
+	// ExperimentalFeatures are the experimental features enabled for this requester.
+	ExperimentalFeatures experimental.Features
+
+	// ClockDriftThreshold is the drift between the local clock and the server `Date` header above which
+	// OnClockDrift is called. Defaults to DEFAULT_CLOCK_DRIFT_THRESHOLD.
+	ClockDriftThreshold time.Duration
+
+	// OnClockDrift is called when a clock drift beyond ClockDriftThreshold is detected. Defaults to logging a
+	// warning with Logger, at most once per CLOCK_DRIFT_WARNING_INTERVAL.
+	OnClockDrift func(err *ClockDriftError)
+
+	// QuotaBudgeter, if set, enforces the request budget of Feature before each request.
+	QuotaBudgeter *QuotaBudgeter
+
+	// Feature is the logical feature requests are accounted to by the QuotaBudgeter. Defaults to
+	// DEFAULT_QUOTA_FEATURE.
+	Feature string
+
+	// RetryPolicy, if set, retries requests failing with a transient error. By default requests are not retried.
+	RetryPolicy *RetryPolicy
+
+	// Timeout is the default deadline of each call, including retries. It can be overridden per call with
+	// ExecuteGraphqlWithOptions. By default calls only have the deadline of their context and HTTP client.
+	Timeout time.Duration
+
+	// RateLimiter, if set, limits the rate of requests sent by this requester. Each attempt waits for the limiter,
+	// which lets requests of a higher priority through first.
+	RateLimiter *RateLimiter
+
+	// OperationPriorities are the priorities of operations, by operation name. Other operations have PriorityNormal.
+	// The priority of a call can be overridden with ContextWithPriority or WithCallPriority.
+	OperationPriorities map[string]Priority
+
+	// RequestInterceptors are called in order before each request is encoded and sent.
+	RequestInterceptors []RequestInterceptor
+
+	// ResponseInterceptors are called in order after each request completed.
+	ResponseInterceptors []ResponseInterceptor
+
+	// TracerProvider, if set, is used to create an OpenTelemetry span for each request.
+	TracerProvider trace.TracerProvider
+
+	// MetricsCollector, if set, observes the outcome of each request.
+	MetricsCollector MetricsCollector
+
+	// ValidateVariables checks the variables of each request against the variable definitions of its operation
+	// before sending it. See ValidateVariables.
+	ValidateVariables bool
+
+	// QueryLinter, if set, checks each request locally before sending it, including its variables like
+	// ValidateVariables, and fails it with a QueryLintError. See QueryLinter.
+	QueryLinter *QueryLinter
+
+	// CompressRequests gzips request bodies larger than MIN_COMPRESSED_REQUEST_SIZE. Responses are always requested
+	// and transparently decompressed with gzip.
+	CompressRequests bool
+
+	// SigningExpiry is the time after which signed requests expire, bounding the window in which a captured request
+	// can be replayed. Defaults to DEFAULT_SIGNING_EXPIRY.
+	SigningExpiry time.Duration
+
+	// PersistedQueries sends the SHA-256 hash of queries instead of their text, and only sends the text when the
+	// server does not know the hash yet (automatic persisted queries).
+	PersistedQueries bool
+
+	// BaseUrlPolicy, if set, replaces the default BaseUrlPolicy validating BaseUrl.
+	BaseUrlPolicy *BaseUrlPolicy
+
+	// BaseUrlPool, if set, overrides BaseUrl with several base URLs between which requests fail over.
+	BaseUrlPool *BaseUrlPool
+	// HedgeDelay, if set, sends unsigned queries which got no response after this delay again, to the next base URL
+	// of the BaseUrlPool if there is one. See WithHedgeDelay.
+	HedgeDelay time.Duration
+
+	// IdempotencyKeys attaches a random idempotency key to every mutation which has none. See IDEMPOTENCY_KEY_HEADER.
+	IdempotencyKeys bool
+
+	// ResponseCache, if set, caches the results of unsigned queries, so that polling the same query does not send a
+	// request each time. Mutations and signed queries are never cached. Results are keyed by the server and the
+	// identity of the credentials of the Requester, so a cache can be shared by several Requesters. Authenticators
+	// without an identity, see Authenticator, are not cached.
+	ResponseCache ResponseCache
+
+	// DryRun validates mutations locally and returns them in a DryRunError instead of sending them.
+	DryRun bool
+
+	// ReadOnly rejects mutations with an OperationNotAllowedError without sending them.
+	ReadOnly bool
+
+	// AllowedOperations, if set, rejects the operations whose name it does not contain with an
+	// OperationNotAllowedError without sending them.
+	AllowedOperations map[string]bool
+
+	// DefaultHeaders are static headers sent with every request, e.g. internal routing headers. Headers set by
+	// request interceptors or the SDK take precedence.
+	DefaultHeaders http.Header
+
+	// UserAgentSuffix, if set, identifies the application and is appended to the User-Agent header, so that API
+	// usage can be attributed to internal services.
+	UserAgentSuffix string
+
+	// OnDeprecation is called the first time a response reports that an operation uses a deprecated field, so that
+	// upcoming schema changes are noticed before they break the integration. Defaults to logging a warning.
+	OnDeprecation func(operationName string, warning DeprecationWarning)
+
+	// ResponseVerifier, if set, rejects the responses whose RESPONSE_SIGNATURE_HEADER is missing or does not match
+	// the pinned key of the verifier with a ResponseSignatureError, before their data is returned.
+	ResponseVerifier ResponseVerifier
+
+	// Authenticator, if set, authenticates the requests and subscriptions instead of the basic auth of
+	// ApiTokenClientId and ApiTokenClientSecret, e.g. with OAuth2 or signed JWTs. See WithAuthenticator.
+	Authenticator Authenticator
+
+	// Runtime, if set, is the clock and the source of randomness of the nonces, expiries, idempotency keys and request
+	// IDs of requests, e.g. a deterministic runtime in tests. Defaults to sdkruntime.Default(). See WithRuntime.
+	Runtime *sdkruntime.Runtime
+
+	// EventSink, if set, receives the lifecycle events of requests: started, retried, served from the cache and
+	// finished. See the events package.
+	EventSink events.Sink
+
+	// Logger, if set, receives the operation name, request ID, duration and retries of each request, and the warnings
+	// of the requester (clock drift, subscription reconnections) instead of the standard logger.
+	Logger Logger
+
+	clockDrift *clockDriftState
+	// optionErr is the error of an option which could not be applied, returned by the requests.
+	optionErr error
 }
 
-func NewRequester(apiTokenClientId string, apiTokenClientSecret string) *Requester {
-	return &Requester{
+// NewRequester creates a Requester configured with the given options, e.g. WithBaseUrl, WithHTTPClient, WithTimeout
+// and WithRetryPolicy. An invalid base URL fails the requests; use NewRequesterWithOptions to reject it when the
+// Requester is created.
+//
+// Args:
+//
+//	apiTokenClientId: the client id of the API token
+//	apiTokenClientSecret: the client secret of the API token
+//	options: the options to apply, in order
+func NewRequester(apiTokenClientId string, apiTokenClientSecret string, options ...Option) *Requester {
+	r := &Requester{
 		ApiTokenClientId:     apiTokenClientId,
 		ApiTokenClientSecret: apiTokenClientSecret,
+		clockDrift:           &clockDriftState{},
+	}
+	for _, option := range options {
+		option(r)
 	}
+	return r
 }
 
 func NewRequesterWithBaseUrl(apiTokenClientId string, apiTokenClientSecret string, baseUrl *string) *Requester {
 	if baseUrl == nil {
 		return NewRequester(apiTokenClientId, apiTokenClientSecret)
 	}
-	if err := ValidateBaseUrl(*baseUrl); err != nil {
+	r, err := NewRequesterWithOptions(apiTokenClientId, apiTokenClientSecret, WithBaseUrl(*baseUrl))
+	if err != nil {
 		panic(err)
 	}
-	return &Requester{
-		ApiTokenClientId:     apiTokenClientId,
-		ApiTokenClientSecret: apiTokenClientSecret,
-		BaseUrl:              baseUrl,
-	}
+	return r
 }
 
-func ValidateBaseUrl(baseUrl string) error {
-	parsedUrl, err := url.Parse(baseUrl)
-	if err != nil {
-		return errors.New("invalid base url. Not a valid URL")
+// Clone returns a copy of the Requester whose configuration can be changed without affecting it, e.g. its Feature. The
+// maps and slices of the configuration are copied, while the HTTP client, caches, pools, limiters and the observed
+// clock drift are shared with the Requester.
+func (r *Requester) Clone() *Requester {
+	clone := &Requester{}
+	*clone = *r
+	if r.BaseUrl != nil {
+		baseUrl := *r.BaseUrl
+		clone.BaseUrl = &baseUrl
+	}
+	if r.OperationPriorities != nil {
+		clone.OperationPriorities = make(map[string]Priority, len(r.OperationPriorities))
+		for operationName, priority := range r.OperationPriorities {
+			clone.OperationPriorities[operationName] = priority
+		}
 	}
-	hostNameParts := strings.Split(parsedUrl.Hostname(), ".")
-	hostNameTld := hostNameParts[len(hostNameParts)-1]
-	isWhitelistedLocalHost := parsedUrl.Hostname() == "localhost" ||
-		hostNameTld == "local" ||
-		hostNameTld == "internal" ||
-		parsedUrl.Hostname() == "127.0.0.1"
-	if parsedUrl.Scheme != "https" && !isWhitelistedLocalHost {
-		return errors.New("invalid base url. Must be https:// if not targeting localhost")
+	if r.AllowedOperations != nil {
+		clone.AllowedOperations = make(map[string]bool, len(r.AllowedOperations))
+		for operationName, allowed := range r.AllowedOperations {
+			clone.AllowedOperations[operationName] = allowed
+		}
 	}
-	return nil
+	clone.RequestInterceptors = append([]RequestInterceptor(nil), r.RequestInterceptors...)
+	clone.ResponseInterceptors = append([]ResponseInterceptor(nil), r.ResponseInterceptors...)
+	clone.DefaultHeaders = r.DefaultHeaders.Clone()
+	return clone
+}
+
+// ValidateBaseUrl validates a base URL with the default BaseUrlPolicy.
+func ValidateBaseUrl(baseUrl string) error {
+	return BaseUrlPolicy{}.Validate(baseUrl)
 }
 
+// DEFAULT_SIGNING_EXPIRY is the default time after which signed requests expire.
+const DEFAULT_SIGNING_EXPIRY = time.Hour
+
 const DEFAULT_BASE_URL = "https://api.lightspark.com/graphql/server/2023-09-13"
 
 func (r *Requester) ExecuteGraphql(query string, variables map[string]interface{},
 	signingKey SigningKey,
 ) (map[string]interface{}, error) {
-	re := regexp.MustCompile(`(?i)\s*(?:query|mutation)\s+(?P<OperationName>\w+)`)
+	return r.ExecuteGraphqlWithContext(context.Background(), query, variables, signingKey)
+}
+
+// ExecuteGraphqlWithContext executes a GraphQL request like ExecuteGraphql, cancelling it and any pending retry when
+// the context is done.
+func (r *Requester) ExecuteGraphqlWithContext(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey,
+) (map[string]interface{}, error) {
+	result, err := r.ExecuteGraphqlForResultWithContext(ctx, query, variables, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// ExecuteGraphqlRaw executes a GraphQL request like ExecuteGraphql, but returns the undecoded `data` field of the
+// response, so that it can be unmarshalled directly into typed structs with full number precision.
+func (r *Requester) ExecuteGraphqlRaw(query string, variables map[string]interface{},
+	signingKey SigningKey,
+) (json.RawMessage, error) {
+	return r.ExecuteGraphqlRawWithContext(context.Background(), query, variables, signingKey)
+}
+
+// ExecuteGraphqlRawWithContext executes a GraphQL request like ExecuteGraphqlRaw, cancelling it and any pending retry
+// when the context is done.
+func (r *Requester) ExecuteGraphqlRawWithContext(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey,
+) (json.RawMessage, error) {
+	return r.executeGraphqlRaw(ctx, query, variables, signingKey)
+}
+
+func (r *Requester) executeGraphqlRaw(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey, options ...CallOption,
+) (json.RawMessage, error) {
+	resolvedOptions := r.defaultCallOptions()
+	for _, option := range options {
+		option(&resolvedOptions)
+	}
+	resolvedOptions.rawData = true
+	result, err := r.executeGraphql(ctx, query, variables, signingKey, resolvedOptions)
+	if err != nil {
+		return nil, err
+	}
+	return result.RawData, nil
+}
+
+// ExecuteGraphqlForResult executes a GraphQL request like ExecuteGraphql, but returns the full result envelope,
+// including the response extensions (request cost, rate-limit info, deprecation warnings).
+func (r *Requester) ExecuteGraphqlForResult(query string, variables map[string]interface{},
+	signingKey SigningKey,
+) (*GraphqlResult, error) {
+	return r.ExecuteGraphqlForResultWithContext(context.Background(), query, variables, signingKey)
+}
+
+// ExecuteGraphqlForResultWithContext executes a GraphQL request like ExecuteGraphqlForResult, cancelling it and any
+// pending retry when the context is done.
+func (r *Requester) ExecuteGraphqlForResultWithContext(ctx context.Context, query string,
+	variables map[string]interface{}, signingKey SigningKey,
+) (*GraphqlResult, error) {
+	return r.executeGraphql(ctx, query, variables, signingKey, r.defaultCallOptions())
+}
+
+func (r *Requester) executeGraphql(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey, options callOptions,
+) (*GraphqlResult, error) {
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+	re := operationRegexp()
 	matches := re.FindStringSubmatch(query)
 	index := re.SubexpIndex("OperationName")
 	if len(matches) <= index {
 		return nil, errors.New("invalid query payload")
 	}
-	operationName := matches[index]
+	graphqlRequest := &GraphqlRequest{
+		OperationName: matches[index],
+		Query:         query,
+		Variables:     variables,
+		IsMutation:    strings.EqualFold(matches[1], "mutation"),
+		Header:        http.Header{},
+		Priority:      r.priority(ctx, matches[index]),
+	}
+	if options.priority != nil {
+		graphqlRequest.Priority = *options.priority
+	}
+	if err := r.checkOperation(graphqlRequest); err != nil {
+		return nil, err
+	}
+	if err := r.setIdempotencyKey(graphqlRequest, options.idempotencyKey); err != nil {
+		return nil, err
+	}
+	requestId, err := r.setRequestId(ctx, graphqlRequest)
+	if err != nil {
+		return nil, err
+	}
+	ctx = ContextWithRequestId(ctx, requestId)
+	ctx, finish := r.startRequest(ctx, graphqlRequest, requestId)
+	for _, interceptor := range r.RequestInterceptors {
+		if err := interceptor(ctx, graphqlRequest); err != nil {
+			return nil, finish(err)
+		}
+	}
+	dryRun := r.DryRun && graphqlRequest.IsMutation
+	if err := r.checkQuery(graphqlRequest, r.ValidateVariables || dryRun); err != nil {
+		return nil, finish(err)
+	}
 
+	var result *GraphqlResult
+	if dryRun {
+		err = r.dryRun(graphqlRequest, signingKey != nil, options.signingExpiry)
+	} else {
+		result, err = r.execute(ctx, graphqlRequest, signingKey, options)
+	}
+	result, err = r.interceptResponse(ctx, graphqlRequest, result, err)
+	return result, finish(withRequestId(err, requestId))
+}
+
+// startRequest emits the RequestStarted event of a request and starts its span. The returned function is called with
+// the final error of the request, and returns it after ending the span and reporting the request to the metrics
+// collector, the event sink and the logger.
+func (r *Requester) startRequest(ctx context.Context, graphqlRequest *GraphqlRequest, requestId string,
+) (context.Context, func(err error) error) {
+	startedAt := time.Now()
+	events.Emit(r.EventSink, events.Event{
+		Type:          events.RequestStarted,
+		OperationName: graphqlRequest.OperationName,
+		RequestId:     requestId,
+	})
+	ctx, span := r.startGraphqlSpan(ctx, graphqlRequest)
+	return ctx, func(err error) error {
+		endGraphqlSpan(span, err)
+		duration := time.Since(startedAt)
+		if r.MetricsCollector != nil {
+			r.MetricsCollector.ObserveRequest(graphqlRequest.OperationName, duration, err)
+		}
+		if r.EventSink != nil {
+			finished := events.Event{
+				Type:          events.RequestFinished,
+				OperationName: graphqlRequest.OperationName,
+				RequestId:     requestId,
+				Duration:      duration,
+			}
+			if err != nil {
+				finished.Error = RedactError(err)
+			}
+			events.Emit(r.EventSink, finished)
+		}
+		if r.Logger != nil {
+			if err != nil {
+				r.Logger.Warn("lightspark request failed", "operation", graphqlRequest.OperationName,
+					"request_id", requestId, "duration", duration, "error", RedactError(err))
+			} else {
+				r.Logger.Debug("lightspark request", "operation", graphqlRequest.OperationName,
+					"request_id", requestId, "duration", duration)
+			}
+		}
+		return err
+	}
+}
+
+// interceptResponse runs the response interceptors on the outcome of a request, and reports the deprecations of its
+// result.
+func (r *Requester) interceptResponse(ctx context.Context, graphqlRequest *GraphqlRequest, result *GraphqlResult,
+	err error,
+) (*GraphqlResult, error) {
+	for _, interceptor := range r.ResponseInterceptors {
+		result, err = interceptor(ctx, graphqlRequest, result, err)
+	}
+	if err == nil {
+		r.reportDeprecations(graphqlRequest.OperationName, result)
+	}
+	return result, err
+}
+
+func (r *Requester) execute(ctx context.Context, graphqlRequest *GraphqlRequest, signingKey SigningKey,
+	options callOptions,
+) (*GraphqlResult, error) {
+	serverUrl, err := r.serverUrl()
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheKey string
+	if r.ResponseCache != nil && !graphqlRequest.IsMutation && signingKey == nil {
+		if scope, ok := r.responseCacheScope(serverUrl); ok {
+			cacheKey, err = ResponseCacheKey(scope, graphqlRequest.Query, graphqlRequest.Variables)
+			if err != nil {
+				return nil, errors.New("error when encoding payload")
+			}
+		}
+		if cacheKey != "" && !options.bypassCache {
+			if result := cachedResult(r.ResponseCache, cacheKey, !options.rawData, r.Runtime.Now()); result != nil {
+				events.Emit(r.EventSink, events.Event{
+					Type:          events.CacheHit,
+					OperationName: graphqlRequest.OperationName,
+					RequestId:     graphqlRequest.Header.Get(REQUEST_ID_HEADER),
+				})
+				if options.decodeTarget != nil {
+					return decodeCachedResult(result, options.decodeTarget)
+				}
+				return result, nil
+			}
+		}
+	}
+
+	if r.QuotaBudgeter != nil {
+		if err := r.QuotaBudgeter.reserve(r.Feature, 1, r.Runtime.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	send := func(persisted *persistedQuery) (*GraphqlResult, error) {
+		encodedPayload, err := r.encodePayload(graphqlRequest, signingKey != nil, options.signingExpiry, persisted)
+		if err != nil {
+			return nil, err
+		}
+
+		var signingHeader string
+		if signingKey != nil {
+			ObserveSignablePayload(SIGNABLE_GRAPHQL_REQUEST, graphqlRequest.OperationName, encodedPayload, false)
+			key, keyId, err := currentSigningKey(signingKey)
+			if err != nil {
+				return nil, err
+			}
+			signature, err := key.Sign(encodedPayload)
+			if err != nil {
+				return nil, err
+			}
+			signingHeader = encodeSigningHeader(signature, keyId)
+		}
+
+		// Hedged copies of the request would decode into the same target concurrently, so their responses are
+		// buffered like the responses whose signature is checked.
+		streamed := options.decodeTarget != nil && r.ResponseVerifier == nil && !r.hedges(graphqlRequest, signingHeader)
+		graphqlRequest.decodeTarget, graphqlRequest.decodedResult = nil, nil
+		if streamed {
+			graphqlRequest.decodeTarget = options.decodeTarget
+		}
+		data, statusCode, err := r.postWithRetry(ctx, serverUrl, graphqlRequest, encodedPayload, signingHeader,
+			options.retryPolicy)
+		if err != nil {
+			return nil, err
+		}
+		if streamed {
+			return graphqlRequest.decodedResult, nil
+		}
+		if options.decodeTarget != nil {
+			return decodeGraphqlResponse(bytes.NewReader(data), statusCode, options.decodeTarget)
+		}
+		if options.rawData {
+			return parseGraphqlEnvelope(data, statusCode)
+		}
+		return parseGraphqlResponse(data, statusCode)
+	}
+
+	var result *GraphqlResult
+	if !r.PersistedQueries {
+		result, err = send(nil)
+	} else {
+		// Signed payloads get a new nonce when the query is sent, so the server does not see a replayed nonce.
+		hash := persistedQueryHash(graphqlRequest.Query)
+		result, err = send(&persistedQuery{hash: hash})
+		if isPersistedQueryMiss(err) {
+			result, err = send(&persistedQuery{hash: hash, includeQuery: true})
+		}
+	}
+	if err == nil && cacheKey != "" && options.decodeTarget == nil {
+		setCachedResult(r.ResponseCache, cacheKey, result, r.Runtime.Now())
+	}
+	return result, err
+}
+
+// encodePayload encodes the payload of a request. Signed payloads get a random nonce, and expire after signingExpiry.
+// If persisted is set, the payload refers to the query by its hash, and only includes it if requested.
+func (r *Requester) encodePayload(graphqlRequest *GraphqlRequest, signed bool, signingExpiry time.Duration,
+	persisted *persistedQuery,
+) ([]byte, error) {
 	var nonce uint64
-	if signingKey != nil {
-		randomBigInt, err := rand.Int(rand.Reader, big.NewInt(0x7FFFFFFFFFFFFFFF))
+	if signed {
+		randomBigInt, err := r.Runtime.Int(big.NewInt(0x7FFFFFFFFFFFFFFF))
 		if err != nil {
 			return nil, err
 		}
@@ -90,50 +522,169 @@ func (r *Requester) ExecuteGraphql(query string, variables map[string]interface{
 	}
 
 	var expiresAt string
-	if signingKey != nil {
-		expiresAt = time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	if signed {
+		expiresAt = r.Runtime.Now().UTC().Add(signingExpiry).Format(time.RFC3339)
 	}
 
 	payload := map[string]interface{}{
-		"operationName": operationName,
-		"query":         query,
-		"variables":     variables,
+		"operationName": graphqlRequest.OperationName,
+		"query":         graphqlRequest.Query,
+		"variables":     graphqlRequest.Variables,
 		"nonce":         nonce,
 		"expires_at":    expiresAt,
 	}
+	if persisted != nil {
+		payload["extensions"] = persisted.extensions()
+		if !persisted.includeQuery {
+			delete(payload, "query")
+		}
+	}
 
 	encodedPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, errors.New("error when encoding payload")
 	}
+	return encodedPayload, nil
+}
 
-	var serverUrl string
-	if r.BaseUrl == nil {
-		serverUrl = DEFAULT_BASE_URL
-	} else {
-		serverUrl = *r.BaseUrl
+func (r *Requester) signingExpiry() time.Duration {
+	if r.SigningExpiry > 0 {
+		return r.SigningExpiry
 	}
-	if err := ValidateBaseUrl(serverUrl); err != nil {
-		return nil, err
+	return DEFAULT_SIGNING_EXPIRY
+}
+
+// encodeSigningHeader encodes the `X-Lightspark-Signing` header carrying the signature of a payload, and the id of the
+// signing key if it is known.
+func encodeSigningHeader(signature []byte, keyId string) string {
+	signaturePayload := map[string]interface{}{
+		"v":         1,
+		"signature": base64.StdEncoding.EncodeToString(signature),
+	}
+	if keyId != "" {
+		signaturePayload["key_id"] = keyId
 	}
+	signaturePayloadBytes, _ := json.Marshal(signaturePayload)
+	return bytes.NewBuffer(signaturePayloadBytes).String()
+}
 
-	request, err := http.NewRequest("POST", serverUrl, bytes.NewBuffer(encodedPayload))
-	request.SetBasicAuth(r.ApiTokenClientId, r.ApiTokenClientSecret)
-	request.Header.Add("Content-Type", "application/json")
-	request.Header.Add("X-GraphQL-Operation", operationName)
-	request.Header.Add("User-Agent", r.getUserAgent())
-	request.Header.Add("X-Lightspark-SDK", r.getUserAgent())
+func (r *Requester) serverUrl() (string, error) {
+	if r.optionErr != nil {
+		return "", r.optionErr
+	}
+	if r.BaseUrlPool != nil {
+		return r.BaseUrlPool.baseUrlsAt(r.Runtime.Now())[0], nil
+	}
+	serverUrl := DEFAULT_BASE_URL
+	if r.BaseUrl != nil {
+		serverUrl = *r.BaseUrl
+	}
+	if err := r.ValidateBaseUrl(serverUrl); err != nil {
+		return "", err
+	}
+	return serverUrl, nil
+}
 
-	if signingKey != nil {
-		signature, err := signingKey.Sign(encodedPayload)
+// postWithRetry sends a GraphQL request with post, retrying it according to the retry policy.
+func (r *Requester) postWithRetry(ctx context.Context, serverUrl string, graphqlRequest *GraphqlRequest,
+	encodedPayload []byte, signingHeader string, retryPolicy *RetryPolicy,
+) ([]byte, int, error) {
+	body, contentEncoding := encodedPayload, ""
+	if r.CompressRequests {
+		var err error
+		body, contentEncoding, err = compressRequestBody(encodedPayload)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		signaturePayloadBytes, err := json.Marshal(map[string]interface{}{
-			"v":         1,
-			"signature": base64.StdEncoding.EncodeToString(signature),
-		})
-		request.Header.Add("X-Lightspark-Signing", bytes.NewBuffer(signaturePayloadBytes).String())
+	}
+
+	maxAttempts := retryPolicy.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		if r.RateLimiter != nil {
+			if err := r.RateLimiter.waitPriority(ctx, graphqlRequest.Priority, r.Runtime); err != nil {
+				return nil, 0, err
+			}
+		}
+		var data []byte
+		var statusCode int
+		var err error
+		if r.BaseUrlPool != nil {
+			data, statusCode, err = r.postToPool(ctx, graphqlRequest, body, contentEncoding, signingHeader)
+		} else if r.hedges(graphqlRequest, signingHeader) {
+			data, statusCode, err = r.postHedged(ctx, []string{serverUrl, serverUrl}, graphqlRequest, body,
+				contentEncoding, signingHeader)
+		} else {
+			data, statusCode, err = r.post(ctx, serverUrl, graphqlRequest, body, contentEncoding, signingHeader)
+		}
+		recordGraphqlAttempt(ctx, attempt, statusCode)
+		if err == nil {
+			return data, statusCode, nil
+		}
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return nil, statusCode, err
+		}
+		retry, retryAfter := retryPolicy.shouldRetry(err, graphqlRequest.IsMutation)
+		if !retry {
+			return nil, statusCode, err
+		}
+		delay := retryPolicy.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+			if maxBackoff := retryPolicy.maxBackoff(); delay > maxBackoff {
+				delay = maxBackoff
+			}
+		}
+		if r.Logger != nil {
+			r.Logger.Info("retrying lightspark request", "operation", graphqlRequest.OperationName,
+				"request_id", graphqlRequest.Header.Get(REQUEST_ID_HEADER), "attempt", attempt, "delay", delay,
+				"error", RedactError(err))
+		}
+		if r.EventSink != nil {
+			events.Emit(r.EventSink, events.Event{
+				Type:          events.RequestRetried,
+				OperationName: graphqlRequest.OperationName,
+				RequestId:     graphqlRequest.Header.Get(REQUEST_ID_HEADER),
+				Attempt:       attempt,
+				Delay:         delay,
+				Error:         RedactError(err),
+			})
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// post sends one GraphQL request and returns the response body. The returned status code is 0 if no response was
+// received. If the request has a decodeTarget, the response is decoded into it and no body is returned.
+func (r *Requester) post(ctx context.Context, serverUrl string, graphqlRequest *GraphqlRequest,
+	body []byte, contentEncoding string, signingHeader string,
+) ([]byte, int, error) {
+	request, err := http.NewRequestWithContext(r.withConnectionTrace(ctx), "POST", serverUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	r.addDefaultHeaders(request.Header, graphqlRequest.Header)
+	for name, values := range graphqlRequest.Header {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	if err := r.authenticate(ctx, request.Header); err != nil {
+		return nil, 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if contentEncoding != "" {
+		request.Header.Set("Content-Encoding", contentEncoding)
+	}
+	// Setting Accept-Encoding disables the transparent decompression of http.Transport, so that responses are
+	// decompressed the same way with any transport.
+	request.Header.Set("Accept-Encoding", "gzip")
+	request.Header.Set("X-GraphQL-Operation", graphqlRequest.OperationName)
+	request.Header.Set("User-Agent", r.userAgentWithSuffix())
+	request.Header.Set("X-Lightspark-SDK", r.getUserAgent())
+	if signingHeader != "" {
+		request.Header.Set("X-Lightspark-Signing", signingHeader)
 	}
 
 	httpClient := r.HTTPClient
@@ -142,38 +693,114 @@ func (r *Requester) ExecuteGraphql(query string, variables map[string]interface{
 	}
 	response, err := httpClient.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer response.Body.Close()
+	r.checkClockDrift(response, r.Runtime.Now())
 	if response.StatusCode < 200 || response.StatusCode > 299 {
-		return nil, errors.New("lightspark request failed: " + response.Status)
+		if response.StatusCode == http.StatusUnauthorized {
+			r.invalidateCredentials()
+		}
+		httpErr := newHTTPError(response)
+		if rateLimitedErr := newRateLimitedError(response, httpErr.Err, r.Runtime.Now()); rateLimitedErr != nil {
+			rateLimitedErr.HTTPError = httpErr
+			return nil, response.StatusCode, rateLimitedErr
+		}
+		return nil, response.StatusCode, httpErr
+	}
+
+	if graphqlRequest.decodeTarget != nil {
+		// Without a signature to check, the response is decoded as it is read instead of being buffered.
+		reader, err := responseBodyReader(response)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer reader.Close()
+		graphqlRequest.decodedResult, err = decodeGraphqlResponse(reader, response.StatusCode,
+			graphqlRequest.decodeTarget)
+		var graphqlErr *GraphQLError
+		if err != nil && !errors.As(err, &graphqlErr) {
+			return nil, 0, err
+		}
+		return nil, response.StatusCode, err
+	}
+	data, err := readResponseBody(response)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.verifyResponse(response, data); err != nil {
+		return nil, response.StatusCode, err
 	}
+	return data, response.StatusCode, nil
+}
 
-	data, err := ioutil.ReadAll(response.Body)
-	var result map[string]interface{}
-	err = json.Unmarshal(data, &result)
+func parseGraphqlResponse(data []byte, statusCode int) (*GraphqlResult, error) {
+	graphqlResult, err := parseGraphqlEnvelope(data, statusCode)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(graphqlResult.RawData, &graphqlResult.Data); err != nil {
+		return nil, err
+	}
+	return graphqlResult, nil
+}
 
-	if errs, ok := result["errors"]; ok {
-		err := errs.([]interface{})[0]
-		errMap := err.(map[string]interface{})
-		errorMessage := errMap["message"].(string)
-		if errMap["extensions"] == nil {
-			return nil, errors.New(errorMessage)
-		}
-		extensions := errMap["extensions"].(map[string]interface{})
-		if extensions["error_name"] == nil {
-			return nil, errors.New(errorMessage)
+// parseGraphqlEnvelope parses a GraphQL response like parseGraphqlResponse, leaving its `data` undecoded in RawData.
+func parseGraphqlEnvelope(data []byte, statusCode int) (*GraphqlResult, error) {
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	err := json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Errors) > 0 {
+		graphqlErr := &GraphQLError{Message: result.Errors[0].Message, StatusCode: statusCode}
+		if extensions := result.Errors[0].Extensions; extensions != nil {
+			graphqlErr.Extensions = extensions
+			if errorName, ok := extensions["error_name"].(string); ok {
+				graphqlErr.Name = errorName
+			}
 		}
-		errorName := extensions["error_name"].(string)
-		return nil, errors.New(errorName + " - " + errorMessage)
+		return nil, graphqlErr
+	}
+
+	graphqlResult := &GraphqlResult{RawData: result.Data, Extensions: result.Extensions}
+	if len(result.Data) == 0 {
+		return nil, errors.New("missing data in response")
 	}
+	return graphqlResult, nil
+}
 
-	return result["data"].(map[string]interface{}), nil
+func operationRegexp() *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\s*(query|mutation)\s+(?P<OperationName>\w+)`)
 }
 
 func (r *Requester) getUserAgent() string {
 	return "lightspark-go-sdk/" + lightspark.VERSION + " go/" + runtime.Version()
 }
+
+func (r *Requester) userAgentWithSuffix() string {
+	if r.UserAgentSuffix == "" {
+		return r.getUserAgent()
+	}
+	return r.getUserAgent() + " " + r.UserAgentSuffix
+}
+
+// addDefaultHeaders adds the DefaultHeaders which are not set by the request to a header.
+func (r *Requester) addDefaultHeaders(header http.Header, requestHeader http.Header) {
+	for name, values := range r.DefaultHeaders {
+		if requestHeader.Get(name) != "" {
+			continue
+		}
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+}
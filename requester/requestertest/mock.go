@@ -0,0 +1,231 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package requestertest provides an in-memory mock of the Lightspark GraphQL API and a recorder of API fixtures, to
+// unit test code using the SDK without calling the live API.
+package requestertest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+)
+
+// MOCK_BASE_URL is the base URL of the requesters returned by Mock.Requester. No request is sent to it.
+const MOCK_BASE_URL = "https://api.lightspark.test/graphql/server/mock"
+
+// Call is a GraphQL operation received by a Mock.
+type Call struct {
+	OperationName string
+	Query         string
+	Variables     map[string]interface{}
+	// Header holds the HTTP headers of the request.
+	Header http.Header
+}
+
+// Response is the response of a Mock to an operation. Either Data is returned as the `data` of the response, or
+// Error as its first GraphQL error. A non-2xx StatusCode fails the request at the HTTP level.
+type Response struct {
+	Data       interface{}
+	Error      *requester.GraphQLError
+	StatusCode int
+
+	// raw is a recorded response body, returned as is.
+	raw json.RawMessage
+}
+
+// Handler computes the response of a Mock to a call.
+type Handler func(call Call) Response
+
+// Mock is an http.RoundTripper serving GraphQL operations with canned responses, matched by operation name. Batches
+// are served operation by operation. It is safe for concurrent use.
+type Mock struct {
+	mutex    sync.Mutex
+	handlers map[string][]Handler
+	calls    []Call
+}
+
+// NewMock creates a Mock without responses.
+func NewMock() *Mock {
+	return &Mock{handlers: map[string][]Handler{}}
+}
+
+// Handle responds to the calls of an operation with a handler. Handlers of an operation are used in the order they
+// were registered, one call each, and the last one is used for all the remaining calls.
+func (m *Mock) Handle(operationName string, handler Handler) *Mock {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers[operationName] = append(m.handlers[operationName], handler)
+	return m
+}
+
+// RespondData responds to the calls of an operation with the given data, which is encoded to JSON.
+func (m *Mock) RespondData(operationName string, data interface{}) *Mock {
+	return m.Handle(operationName, func(Call) Response { return Response{Data: data} })
+}
+
+// RespondError responds to the calls of an operation with a GraphQL error.
+func (m *Mock) RespondError(operationName string, err *requester.GraphQLError) *Mock {
+	return m.Handle(operationName, func(Call) Response { return Response{Error: err} })
+}
+
+// Calls returns the operations received so far, in order.
+func (m *Mock) Calls() []Call {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Requester returns a requester sending its requests to the mock.
+func (m *Mock) Requester() *requester.Requester {
+	r, _ := requester.NewRequesterWithOptions("mock_client_id", "mock_client_secret",
+		requester.WithBaseUrl(MOCK_BASE_URL), requester.WithHTTPClient(&http.Client{Transport: m}))
+	return r
+}
+
+func (m *Mock) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := readBody(request.Body, request.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	payloads, batch, err := decodePayloads(body)
+	if err != nil {
+		return textResponse(request, http.StatusBadRequest, err.Error()), nil
+	}
+
+	responses := make([]json.RawMessage, 0, len(payloads))
+	for _, payload := range payloads {
+		call := Call{
+			OperationName: payload.OperationName,
+			Query:         payload.Query,
+			Variables:     payload.Variables,
+			Header:        request.Header.Clone(),
+		}
+		response := m.respond(call)
+		if response.StatusCode != 0 && (response.StatusCode < 200 || response.StatusCode > 299) {
+			return textResponse(request, response.StatusCode, http.StatusText(response.StatusCode)), nil
+		}
+		encoded, err := encodeResponse(response)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, encoded)
+	}
+	if !batch {
+		return jsonResponse(request, responses[0]), nil
+	}
+	encoded, err := json.Marshal(responses)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(request, encoded), nil
+}
+
+func (m *Mock) respond(call Call) Response {
+	m.mutex.Lock()
+	m.calls = append(m.calls, call)
+	handlers := m.handlers[call.OperationName]
+	var handler Handler
+	if len(handlers) > 0 {
+		handler = handlers[0]
+		if len(handlers) > 1 {
+			m.handlers[call.OperationName] = handlers[1:]
+		}
+	}
+	m.mutex.Unlock()
+	if handler == nil {
+		return Response{Error: &requester.GraphQLError{Message: "no mock response for operation " + call.OperationName}}
+	}
+	return handler(call)
+}
+
+type graphqlPayload struct {
+	OperationName string                 `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// decodePayloads decodes a request body holding one payload, or an array of payloads for batches.
+func decodePayloads(body []byte) ([]graphqlPayload, bool, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var payloads []graphqlPayload
+		if err := json.Unmarshal(trimmed, &payloads); err != nil {
+			return nil, true, err
+		}
+		return payloads, true, nil
+	}
+	var payload graphqlPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false, err
+	}
+	return []graphqlPayload{payload}, false, nil
+}
+
+func encodeResponse(response Response) (json.RawMessage, error) {
+	if response.raw != nil {
+		return response.raw, nil
+	}
+	if response.Error != nil {
+		graphqlErr := map[string]interface{}{"message": response.Error.Message}
+		extensions := map[string]interface{}{}
+		for name, value := range response.Error.Extensions {
+			extensions[name] = value
+		}
+		if response.Error.Name != "" {
+			extensions["error_name"] = response.Error.Name
+		}
+		if len(extensions) > 0 {
+			graphqlErr["extensions"] = extensions
+		}
+		return json.Marshal(map[string]interface{}{"errors": []interface{}{graphqlErr}})
+	}
+	data := response.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return json.Marshal(map[string]interface{}{"data": data})
+}
+
+func readBody(body io.ReadCloser, contentEncoding string) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+	if !strings.EqualFold(contentEncoding, "gzip") {
+		return io.ReadAll(body)
+	}
+	reader, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func jsonResponse(request *http.Request, body []byte) *http.Response {
+	response := textResponse(request, http.StatusOK, "")
+	response.Header.Set("Content-Type", "application/json")
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	response.ContentLength = int64(len(body))
+	return response
+}
+
+func textResponse(request *http.Request, statusCode int, body string) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
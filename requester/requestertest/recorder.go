@@ -0,0 +1,129 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requestertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Fixture is a recorded GraphQL operation and its response.
+type Fixture struct {
+	OperationName string                 `json:"operation_name"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	StatusCode    int                    `json:"status_code"`
+	// Response is the decompressed response body.
+	Response json.RawMessage `json:"response"`
+}
+
+// Recorder is an http.RoundTripper recording the operations sent through it and their responses as fixtures, which
+// can then be replayed with Mock.LoadFixtures. Only operations, variables and responses are recorded: credentials and
+// signatures sent in headers are not. Batches are not recorded. It is safe for concurrent use.
+type Recorder struct {
+	// Next is the transport used to send the requests. Defaults to http.DefaultTransport.
+	Next http.RoundTripper
+
+	mutex    sync.Mutex
+	fixtures []Fixture
+}
+
+// NewRecorder creates a Recorder sending requests with the given transport, or http.DefaultTransport if it is nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	return &Recorder{Next: next}
+}
+
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if request.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	next := r.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	response, err := next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	decodedRequest, err := readBody(io.NopCloser(bytes.NewReader(requestBody)), request.Header.Get("Content-Encoding"))
+	if err != nil {
+		return response, nil
+	}
+	payloads, batch, err := decodePayloads(decodedRequest)
+	if err != nil || batch {
+		return response, nil
+	}
+	decodedResponse, err := readBody(io.NopCloser(bytes.NewReader(responseBody)), response.Header.Get("Content-Encoding"))
+	if err != nil {
+		return response, nil
+	}
+	fixture := Fixture{
+		OperationName: payloads[0].OperationName,
+		Variables:     payloads[0].Variables,
+		StatusCode:    response.StatusCode,
+	}
+	if json.Valid(decodedResponse) {
+		fixture.Response = decodedResponse
+	}
+	r.mutex.Lock()
+	r.fixtures = append(r.fixtures, fixture)
+	r.mutex.Unlock()
+	return response, nil
+}
+
+// Fixtures returns the fixtures recorded so far, in order.
+func (r *Recorder) Fixtures() []Fixture {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Fixture(nil), r.fixtures...)
+}
+
+// Save writes the recorded fixtures to a JSON file.
+func (r *Recorder) Save(path string) error {
+	encoded, err := json.MarshalIndent(r.Fixtures(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0o644)
+}
+
+// LoadFixtures responds to operations with the fixtures of a JSON file written by Recorder.Save. The fixtures of an
+// operation are replayed in the order they were recorded.
+func (m *Mock) LoadFixtures(path string) error {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(encoded, &fixtures); err != nil {
+		return errors.New("invalid fixtures file: " + err.Error())
+	}
+	for _, fixture := range fixtures {
+		m.AddFixture(fixture)
+	}
+	return nil
+}
+
+// AddFixture responds to the next call of the operation of a fixture with its recorded response.
+func (m *Mock) AddFixture(fixture Fixture) *Mock {
+	return m.Handle(fixture.OperationName, func(Call) Response {
+		return Response{raw: fixture.Response, StatusCode: fixture.StatusCode}
+	})
+}
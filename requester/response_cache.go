@@ -0,0 +1,196 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// ResponseCache caches the results of queries, keyed by ResponseCacheKey.
+type ResponseCache interface {
+	// Get returns the cached result with the given key, or nil if it is not cached.
+	Get(key string) *GraphqlResult
+	Set(key string, result *GraphqlResult)
+	Invalidate(key string)
+}
+
+// timedResponseCache is implemented by the caches measuring the age of their results, so that a Requester gets and
+// sets results at the time of its own clock, see Requester.Runtime.
+type timedResponseCache interface {
+	getAt(key string, now time.Time) *GraphqlResult
+	setAt(key string, result *GraphqlResult, now time.Time)
+}
+
+// getCachedResult returns the result with the given key from a cache, at the given time if the cache is timed.
+func getCachedResult(cache ResponseCache, key string, now time.Time) *GraphqlResult {
+	if timedCache, ok := cache.(timedResponseCache); ok {
+		return timedCache.getAt(key, now)
+	}
+	return cache.Get(key)
+}
+
+// setCachedResult caches a result with the given key, at the given time if the cache is timed.
+func setCachedResult(cache ResponseCache, key string, result *GraphqlResult, now time.Time) {
+	if timedCache, ok := cache.(timedResponseCache); ok {
+		timedCache.setAt(key, result, now)
+		return
+	}
+	cache.Set(key, result)
+}
+
+// ResponseCacheKey returns the key of the result of a query with the given variables. The scope identifies the server
+// and the credentials the query is sent with, so that a cache shared by several Requesters never serves the data of
+// another account or environment.
+func ResponseCacheKey(scope string, query string, variables map[string]interface{}) (string, error) {
+	// Map keys are sorted when encoded, so equal variables have the same encoding.
+	encodedVariables, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(scope))
+	hash.Write([]byte{0})
+	hash.Write([]byte(query))
+	hash.Write([]byte{0})
+	hash.Write(encodedVariables)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// responseCacheScope returns the scope of the cache keys of a Requester: its server URLs and a hash of the identity
+// of its credentials, see credentialsIdentity. It returns false if the credentials have no stable identity, in which
+// case results are not cached.
+func (r *Requester) responseCacheScope(serverUrl string) (string, bool) {
+	identity := r.credentialsIdentity()
+	if identity == "" {
+		return "", false
+	}
+	serverUrls := []string{serverUrl}
+	if r.BaseUrlPool != nil {
+		// The order of the pool changes on failover, while all its servers serve the same data.
+		serverUrls = r.BaseUrlPool.baseUrlsAt(r.Runtime.Now())
+		sort.Strings(serverUrls)
+	}
+	hash := sha256.Sum256([]byte(identity))
+	return strings.Join(serverUrls, " ") + "\x00" + hex.EncodeToString(hash[:]), true
+}
+
+// LRUResponseCache is a ResponseCache keeping up to a maximum number of results in memory for a fixed time, evicting
+// the least recently used results first. It is safe for concurrent use.
+type LRUResponseCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type responseCacheEntry struct {
+	key      string
+	result   *GraphqlResult
+	cachedAt time.Time
+}
+
+// NewLRUResponseCache creates an LRUResponseCache keeping up to capacity results for the given time.
+func NewLRUResponseCache(capacity int, ttl time.Duration) *LRUResponseCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUResponseCache{capacity: capacity, ttl: ttl, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *LRUResponseCache) Get(key string) *GraphqlResult {
+	return c.getAt(key, sdkruntime.Now())
+}
+
+func (c *LRUResponseCache) getAt(key string, now time.Time) *GraphqlResult {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*responseCacheEntry)
+	if now.Sub(entry.cachedAt) > c.ttl {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil
+	}
+	c.order.MoveToFront(element)
+	return entry.result
+}
+
+func (c *LRUResponseCache) Set(key string, result *GraphqlResult) {
+	c.setAt(key, result, sdkruntime.Now())
+}
+
+func (c *LRUResponseCache) setAt(key string, result *GraphqlResult, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = &responseCacheEntry{key: key, result: result, cachedAt: now}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&responseCacheEntry{key: key, result: result, cachedAt: now})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+func (c *LRUResponseCache) Invalidate(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// WithResponseCache caches the results of unsigned queries in the given cache. See Requester.ResponseCache.
+func WithResponseCache(cache ResponseCache) Option {
+	return func(r *Requester) {
+		r.ResponseCache = cache
+	}
+}
+
+// WithCallCacheBypass sends the call to the API even if its result is cached, and caches the new result.
+func WithCallCacheBypass() CallOption {
+	return func(options *callOptions) {
+		options.bypassCache = true
+	}
+}
+
+// cachedResult returns a copy of the cached result of a request, so that callers cannot modify the cached data.
+func cachedResult(cache ResponseCache, key string, decodeData bool, now time.Time) *GraphqlResult {
+	result := getCachedResult(cache, key, now)
+	if result == nil {
+		return nil
+	}
+	resultCopy := &GraphqlResult{RawData: result.RawData}
+	if decodeData {
+		if err := json.Unmarshal(result.RawData, &resultCopy.Data); err != nil {
+			return nil
+		}
+	}
+	if result.Extensions != nil {
+		encodedExtensions, err := json.Marshal(result.Extensions)
+		if err != nil {
+			return nil
+		}
+		if err := json.Unmarshal(encodedExtensions, &resultCopy.Extensions); err != nil {
+			return nil
+		}
+	}
+	return resultCopy
+}
@@ -0,0 +1,113 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+)
+
+// RESPONSE_SIGNATURE_HEADER is the header holding the base64-encoded signature of the response body.
+const RESPONSE_SIGNATURE_HEADER = "X-Lightspark-Response-Signature"
+
+// ResponseVerifier verifies the signature of a response body against a pinned public key of the Lightspark API.
+type ResponseVerifier interface {
+	Verify(body []byte, signature []byte) error
+}
+
+// Secp256k1ResponseVerifier verifies ECDSA signatures over the SHA-256 hash of response bodies.
+type Secp256k1ResponseVerifier struct {
+	// PublicKey is the compressed or uncompressed secp256k1 public key.
+	PublicKey []byte
+}
+
+func (v *Secp256k1ResponseVerifier) Verify(body []byte, signature []byte) error {
+	valid, err := lightspark_crypto.VerifyEcdsa(body, signature, v.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// RsaResponseVerifier verifies RSA-PSS signatures over the SHA-256 hash of response bodies.
+type RsaResponseVerifier struct {
+	// PublicKey is the DER-encoded PKIX public key.
+	PublicKey []byte
+}
+
+func (v *RsaResponseVerifier) Verify(body []byte, signature []byte) error {
+	publicKey, err := x509.ParsePKIXPublicKey(v.PublicKey)
+	if err != nil {
+		return err
+	}
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("public key is not an RSA key")
+	}
+	hashed := sha256.Sum256(body)
+	if err := rsa.VerifyPSS(rsaKey, crypto.SHA256, hashed[:], signature, nil); err != nil {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// ResponseSignatureError is returned when a response has no valid signature while a ResponseVerifier is set. The
+// data of the response is not returned. It wraps a GraphQLError with the status code of the response, so it is
+// neither retried nor failed over.
+type ResponseSignatureError struct {
+	// Reason explains why the signature was rejected.
+	Reason string
+	Err    *GraphQLError
+}
+
+func (e *ResponseSignatureError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResponseSignatureError) Unwrap() error {
+	return e.Err
+}
+
+// WithResponseVerifier rejects the responses which are not signed with the pinned key of the verifier.
+func WithResponseVerifier(verifier ResponseVerifier) Option {
+	return func(r *Requester) {
+		r.ResponseVerifier = verifier
+	}
+}
+
+// verifyResponse checks the signature of a response body if a ResponseVerifier is set.
+func (r *Requester) verifyResponse(response *http.Response, body []byte) error {
+	if r.ResponseVerifier == nil {
+		return nil
+	}
+	reject := func(reason string) error {
+		return &ResponseSignatureError{
+			Reason: reason,
+			Err: &GraphQLError{
+				Message:    "invalid response signature: " + reason,
+				StatusCode: response.StatusCode,
+			},
+		}
+	}
+	encodedSignature := response.Header.Get(RESPONSE_SIGNATURE_HEADER)
+	if encodedSignature == "" {
+		return reject("missing " + RESPONSE_SIGNATURE_HEADER + " header")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return reject("signature is not valid base64")
+	}
+	if err := r.ResponseVerifier.Verify(body, signature); err != nil {
+		return reject(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GraphqlResult is the full result of a GraphQL request.
+type GraphqlResult struct {
+	// Data is the `data` field of the GraphQL response. Numbers are decoded as float64, so integers above 2^53 lose
+	// precision: decode RawData, or use Execute, to read large msat amounts exactly. It is nil for the requests of
+	// ExecuteGraphqlRaw and Execute, which only decode RawData.
+	Data map[string]interface{}
+	// RawData is the undecoded `data` field of the GraphQL response.
+	RawData json.RawMessage
+	// Extensions is the `extensions` field of the GraphQL response, or nil if the server did not send any.
+	Extensions map[string]interface{}
+}
+
+// RateLimitInfo describes the API rate limit state reported by the server for a request.
+type RateLimitInfo struct {
+	// Limit is the maximum number of requests allowed in the current window.
+	Limit *int64 `json:"limit"`
+	// Remaining is the number of requests left in the current window.
+	Remaining *int64 `json:"remaining"`
+	// ResetAt is the time at which the current window resets.
+	ResetAt *time.Time `json:"reset_at"`
+}
+
+// DeprecationWarning describes a deprecated field or argument used by a request.
+type DeprecationWarning struct {
+	// Field is the path of the deprecated field, e.g. `LightsparkNode.balances`.
+	Field string `json:"field"`
+	// Reason is the deprecation reason, usually naming the replacement.
+	Reason string `json:"reason"`
+}
+
+// Cost returns the cost of the request as reported in the `cost` extension, if any.
+func (r *GraphqlResult) Cost() *float64 {
+	cost, ok := r.Extensions["cost"].(float64)
+	if !ok {
+		return nil
+	}
+	return &cost
+}
+
+// RateLimit returns the rate limit info reported in the `rate_limit` extension, if any.
+func (r *GraphqlResult) RateLimit() *RateLimitInfo {
+	var rateLimit *RateLimitInfo
+	if !r.decodeExtension("rate_limit", &rateLimit) {
+		return nil
+	}
+	return rateLimit
+}
+
+// Deprecations returns the deprecation warnings reported in the `deprecations` extension, if any.
+func (r *GraphqlResult) Deprecations() []DeprecationWarning {
+	var deprecations []DeprecationWarning
+	if !r.decodeExtension("deprecations", &deprecations) {
+		return nil
+	}
+	return deprecations
+}
+
+func (r *GraphqlResult) decodeExtension(name string, target interface{}) bool {
+	extension, ok := r.Extensions[name]
+	if !ok || extension == nil {
+		return false
+	}
+	extensionJson, err := json.Marshal(extension)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(extensionJson, target) == nil
+}
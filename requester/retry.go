@@ -0,0 +1,138 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy configures the automatic retry of GraphQL requests failing with a transient network error or a
+// retryable HTTP status code. Other errors, e.g. of the Authenticator or when decoding responses, are not retried.
+// Retries resend the exact same payload, including its nonce and signature.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Defaults to 200ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor applied to the delay after each retry. Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomized, between 0 and 1. A jitter of 0.2 picks each delay
+	// in [0.8 * delay, 1.2 * delay].
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes which are retried. Defaults to 502, 503 and 504.
+	RetryableStatusCodes []int
+	// RetryMutations also retries mutations. By default only queries are retried, since a mutation failing with a
+	// network error may have been executed.
+	RetryMutations bool
+	// RetryRateLimited retries requests rejected with 429 Too Many Requests. For 429 and 503 responses, retries wait
+	// for the delay given by the `Retry-After` header (capped at MaxBackoff) when it is longer than the backoff.
+	// Rate-limited requests are not executed, so mutations are retried as well.
+	RetryRateLimited bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with 3 attempts and the default backoff.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 3, Jitter: 0.2}
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 2 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry returns whether a failed attempt should be retried, and the minimum delay requested by the server.
+func (p *RetryPolicy) shouldRetry(err error, isMutation bool) (bool, time.Duration) {
+	var graphqlErr *GraphQLError
+	if !errors.As(err, &graphqlErr) {
+		if !isTransportError(err) {
+			// Authentication, encoding and decoding errors fail the same way when retried.
+			return false, 0
+		}
+		// Network error: the request may have reached the server.
+		return !isMutation || p.RetryMutations, 0
+	}
+	var retryAfter time.Duration
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		retryAfter = rateLimitedErr.RetryAfter
+	}
+	if graphqlErr.StatusCode == http.StatusTooManyRequests && p.RetryRateLimited {
+		return true, retryAfter
+	}
+	if graphqlErr.StatusCode < 200 || graphqlErr.StatusCode > 299 {
+		return (!isMutation || p.RetryMutations) && p.isRetryableStatus(graphqlErr.StatusCode), retryAfter
+	}
+	return false, 0
+}
+
+// isTransportError returns whether an error is a failure of the connection to the server, such as a refused connection
+// or a reset stream, excluding cancellations and deadlines.
+func isTransportError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+func (p *RetryPolicy) isRetryableStatus(statusCode int) bool {
+	retryableStatusCodes := p.RetryableStatusCodes
+	if retryableStatusCodes == nil {
+		retryableStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, retryableStatusCode := range retryableStatusCodes {
+		if statusCode == retryableStatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	initialBackoff := p.InitialBackoff
+	if initialBackoff == 0 {
+		initialBackoff = 200 * time.Millisecond
+	}
+	maxBackoff := p.maxBackoff()
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := math.Min(float64(initialBackoff)*math.Pow(multiplier, float64(retry-1)), float64(maxBackoff))
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// maxBackoff returns the cap of the delay between attempts.
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff == 0 {
+		return 10 * time.Second
+	}
+	return p.MaxBackoff
+}
+
+// sleepContext waits for the given delay, returning early with the context error if it is done.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import "sync"
+
+// Kinds of SignablePayload.
+const (
+	SIGNABLE_GRAPHQL_REQUEST     = "graphql_request"
+	SIGNABLE_PROOF_OF_PAYMENT    = "proof_of_payment"
+	SIGNABLE_AUDIT_RECORD        = "audit_record"
+	SIGNABLE_WEBHOOK             = "webhook"
+	SIGNABLE_PAYREQ_CANCELLATION = "payreq_cancellation"
+)
+
+// SignablePayload is the exact sequence of bytes signed or verified by the SDK for one message.
+type SignablePayload struct {
+	// Kind is the kind of message, e.g. SIGNABLE_GRAPHQL_REQUEST.
+	Kind string
+	// Name further identifies the message when relevant, e.g. the operation name of a GraphQL request.
+	Name string
+	// Payload is a copy of the signed or verified bytes.
+	Payload []byte
+	// Verifying is true when the signature of the payload is verified rather than created.
+	Verifying bool
+}
+
+// SignablePayloadObserver receives every payload signed or verified by the SDK. It is called synchronously, so it
+// should be fast and must be safe for concurrent use.
+type SignablePayloadObserver func(payload SignablePayload)
+
+var (
+	signablePayloadObserverMutex sync.RWMutex
+	signablePayloadObserver      SignablePayloadObserver
+)
+
+// SetSignablePayloadObserver installs an observer of the payloads signed and verified by all the SDK packages, and
+// returns a function restoring the previous observer. It is meant for tests and migration tooling, e.g. to confirm
+// that two versions of the SDK produce identical payloads before an upgrade, and should not be installed in
+// production since payloads can contain sensitive data.
+func SetSignablePayloadObserver(observer SignablePayloadObserver) (restore func()) {
+	signablePayloadObserverMutex.Lock()
+	defer signablePayloadObserverMutex.Unlock()
+	previous := signablePayloadObserver
+	signablePayloadObserver = observer
+	return func() {
+		signablePayloadObserverMutex.Lock()
+		defer signablePayloadObserverMutex.Unlock()
+		signablePayloadObserver = previous
+	}
+}
+
+// ObserveSignablePayload reports a signed or verified payload to the observer, if any. It is called by the SDK
+// packages when they sign or verify a message.
+func ObserveSignablePayload(kind string, name string, payload []byte, verifying bool) {
+	signablePayloadObserverMutex.RLock()
+	observer := signablePayloadObserver
+	signablePayloadObserverMutex.RUnlock()
+	if observer == nil {
+		return
+	}
+	observer(SignablePayload{
+		Kind:      kind,
+		Name:      name,
+		Payload:   append([]byte(nil), payload...),
+		Verifying: verifying,
+	})
+}
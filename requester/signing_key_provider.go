@@ -0,0 +1,73 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"errors"
+	"sync"
+)
+
+// SigningKeyProvider provides the current signing key of a node and the id of the key, for keys which are rotated
+// while requests are in flight. When the signing key passed to ExecuteGraphql implements SigningKeyProvider, the
+// key and its id are read once per payload, so a payload is always signed by the key named by the `key_id` field of
+// its X-Lightspark-Signing header.
+type SigningKeyProvider interface {
+	// CurrentSigningKey returns the current key and its id.
+	CurrentSigningKey() (SigningKey, string, error)
+}
+
+// RotatingSigningKey is a SigningKey and SigningKeyProvider whose key can be rotated safely while it is used by
+// concurrent requests.
+type RotatingSigningKey struct {
+	mutex sync.RWMutex
+	key   SigningKey
+	keyId string
+}
+
+// NewRotatingSigningKey creates a RotatingSigningKey with an initial key.
+//
+// Args:
+//
+//	key: the initial signing key.
+//	keyId: the id of key, sent with the signatures.
+func NewRotatingSigningKey(key SigningKey, keyId string) *RotatingSigningKey {
+	return &RotatingSigningKey{key: key, keyId: keyId}
+}
+
+// Rotate replaces the signing key. The requests already signed keep the previous key and its id.
+//
+// Args:
+//
+//	key: the new signing key.
+//	keyId: the id of key, sent with the signatures.
+func (k *RotatingSigningKey) Rotate(key SigningKey, keyId string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.key = key
+	k.keyId = keyId
+}
+
+func (k *RotatingSigningKey) CurrentSigningKey() (SigningKey, string, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	if k.key == nil {
+		return nil, "", errors.New("missing signing key")
+	}
+	return k.key, k.keyId, nil
+}
+
+// Sign signs the payload with the current key. Callers which need the id of the key should use CurrentSigningKey.
+func (k *RotatingSigningKey) Sign(payload []byte) ([]byte, error) {
+	key, _, err := k.CurrentSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.Sign(payload)
+}
+
+// currentSigningKey returns the key to sign a payload with and its id, which is empty for keys without a provider.
+func currentSigningKey(signingKey SigningKey) (SigningKey, string, error) {
+	if provider, ok := signingKey.(SigningKeyProvider); ok {
+		return provider.CurrentSigningKey()
+	}
+	return signingKey, "", nil
+}
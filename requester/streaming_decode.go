@@ -0,0 +1,118 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ExecuteGraphqlInto executes a GraphQL request like ExecuteGraphqlWithOptions, decoding the `data` field of the
+// response directly into target, e.g. a pointer to a struct mirroring the query. The response is decoded in a single
+// pass with a json.Decoder as it is read, without the intermediate copies and maps of GraphqlResult.Data, which cuts
+// allocations for multi-megabyte responses. Responses are only buffered when a ResponseVerifier must check their
+// signature first, or when the query is hedged. The returned result only holds the extensions of the response. Its
+// results are not stored in the ResponseCache, but are served from it.
+//
+// Args:
+//
+//	query: the GraphQL query.
+//	variables: the variables of the query.
+//	signingKey: the key to sign the request with, or nil for unsigned requests.
+//	target: a pointer to the value the `data` field is decoded into.
+//	options: the per-call overrides.
+func (r *Requester) ExecuteGraphqlInto(ctx context.Context, query string, variables map[string]interface{},
+	signingKey SigningKey, target interface{}, options ...CallOption,
+) (*GraphqlResult, error) {
+	if target == nil {
+		return nil, errors.New("missing decoding target")
+	}
+	resolvedOptions := r.defaultCallOptions()
+	for _, option := range options {
+		option(&resolvedOptions)
+	}
+	resolvedOptions.decodeTarget = target
+	return r.executeGraphql(ctx, query, variables, signingKey, resolvedOptions)
+}
+
+// decodeGraphqlResponse decodes a GraphQL response from reader, decoding its `data` field into target as it is read.
+func decodeGraphqlResponse(reader io.Reader, statusCode int, target interface{}) (*GraphqlResult, error) {
+	decoder := json.NewDecoder(reader)
+	if err := expectDelimiter(decoder, '{'); err != nil {
+		return nil, err
+	}
+	var errorsField []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	result := &GraphqlResult{}
+	hasData := false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		switch key {
+		case "data":
+			// A null data field leaves the target untouched.
+			if err := decoder.Decode(target); err != nil {
+				return nil, err
+			}
+			hasData = true
+		case "errors":
+			if err := decoder.Decode(&errorsField); err != nil {
+				return nil, err
+			}
+		case "extensions":
+			if err := decoder.Decode(&result.Extensions); err != nil {
+				return nil, err
+			}
+		default:
+			var ignored json.RawMessage
+			if err := decoder.Decode(&ignored); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := expectDelimiter(decoder, '}'); err != nil {
+		return nil, err
+	}
+
+	if len(errorsField) > 0 {
+		graphqlErr := &GraphQLError{Message: errorsField[0].Message, StatusCode: statusCode}
+		if extensions := errorsField[0].Extensions; extensions != nil {
+			graphqlErr.Extensions = extensions
+			if errorName, ok := extensions["error_name"].(string); ok {
+				graphqlErr.Name = errorName
+			}
+		}
+		return nil, graphqlErr
+	}
+	if !hasData {
+		return nil, errors.New("missing data in response")
+	}
+	return result, nil
+}
+
+func expectDelimiter(decoder *json.Decoder, delimiter json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delimiter {
+		return errors.New("invalid GraphQL response")
+	}
+	return nil
+}
+
+// decodeCachedResult decodes a cached result into target, returning a result which only holds the extensions.
+func decodeCachedResult(result *GraphqlResult, target interface{}) (*GraphqlResult, error) {
+	decoder := json.NewDecoder(bytes.NewReader(result.RawData))
+	if err := decoder.Decode(target); err != nil {
+		return nil, err
+	}
+	return &GraphqlResult{Extensions: result.Extensions}, nil
+}
@@ -0,0 +1,269 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lightsparkdev/go-sdk/experimental"
+)
+
+// graphqlWsProtocol is the websocket subprotocol of https://github.com/enisdenjo/graphql-ws.
+const graphqlWsProtocol = "graphql-transport-ws"
+
+const (
+	subscriptionKeepAliveInterval = 30 * time.Second
+	subscriptionAckTimeout        = 10 * time.Second
+	subscriptionMinReconnectDelay = time.Second
+	subscriptionMaxReconnectDelay = 30 * time.Second
+)
+
+type graphqlWsMessage struct {
+	Id      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// errSubscriptionCompleted is returned by runSubscription when the server ends the subscription.
+var errSubscriptionCompleted = errors.New("subscription completed")
+
+// ExecuteGraphqlSubscription starts a GraphQL subscription over a websocket using the graphql-ws protocol, and returns
+// a channel receiving the `data` of every event. The connection is kept alive with pings and re-established with a
+// backoff when it drops. The channel is closed when the context is done, when the server completes the subscription
+// or when it reports a GraphQL error.
+//
+// This is an experimental feature, which must be enabled with experimental.FeatureSubscriptions.
+func (r *Requester) ExecuteGraphqlSubscription(ctx context.Context, query string,
+	variables map[string]interface{},
+) (<-chan map[string]interface{}, error) {
+	if err := r.ExperimentalFeatures.Require(experimental.FeatureSubscriptions); err != nil {
+		return nil, err
+	}
+	re := regexp.MustCompile(`(?i)\s*subscription\s+(?P<OperationName>\w+)`)
+	matches := re.FindStringSubmatch(query)
+	index := re.SubexpIndex("OperationName")
+	if len(matches) <= index {
+		return nil, errors.New("invalid subscription payload")
+	}
+	if err := r.checkOperation(&GraphqlRequest{OperationName: matches[index]}); err != nil {
+		return nil, err
+	}
+	serverUrl, err := r.subscriptionUrl()
+	if err != nil {
+		return nil, err
+	}
+	subscribePayload, err := json.Marshal(map[string]interface{}{
+		"operationName": matches[index],
+		"query":         query,
+		"variables":     variables,
+	})
+	if err != nil {
+		return nil, errors.New("error when encoding payload")
+	}
+
+	events := make(chan map[string]interface{})
+	go func() {
+		defer close(events)
+		reconnectDelay := subscriptionMinReconnectDelay
+		for {
+			connected, err := r.runSubscription(ctx, serverUrl, subscribePayload, events)
+			if ctx.Err() != nil || errors.Is(err, errSubscriptionCompleted) {
+				return
+			}
+			var graphqlErr *GraphQLError
+			if errors.As(err, &graphqlErr) {
+				if r.Logger != nil {
+					r.Logger.Error("lightspark subscription failed", "operation", matches[index],
+						"error", RedactError(err))
+				}
+				return
+			}
+			if connected {
+				reconnectDelay = subscriptionMinReconnectDelay
+			}
+			if r.Logger != nil {
+				r.Logger.Warn("lightspark subscription disconnected", "operation", matches[index],
+					"reconnect_delay", reconnectDelay, "error", RedactError(err))
+			}
+			if sleepContext(ctx, reconnectDelay) != nil {
+				return
+			}
+			reconnectDelay *= 2
+			if reconnectDelay > subscriptionMaxReconnectDelay {
+				reconnectDelay = subscriptionMaxReconnectDelay
+			}
+		}
+	}()
+	return events, nil
+}
+
+// runSubscription runs one websocket connection of a subscription until it fails. It returns whether the connection
+// was acknowledged by the server.
+func (r *Requester) runSubscription(ctx context.Context, serverUrl string, subscribePayload []byte,
+	events chan<- map[string]interface{},
+) (bool, error) {
+	header := http.Header{}
+	r.addDefaultHeaders(header, http.Header{})
+	if err := r.authenticate(ctx, header); err != nil {
+		return false, err
+	}
+	header.Set("User-Agent", r.userAgentWithSuffix())
+	header.Set("X-Lightspark-SDK", r.getUserAgent())
+	dialer := r.subscriptionDialer()
+	conn, _, err := dialer.DialContext(ctx, serverUrl, header)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	// Unblock the reads below when the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := conn.WriteJSON(graphqlWsMessage{Type: "connection_init"}); err != nil {
+		return false, err
+	}
+	conn.SetReadDeadline(time.Now().Add(subscriptionAckTimeout))
+	var ack graphqlWsMessage
+	if err := conn.ReadJSON(&ack); err != nil {
+		return false, err
+	}
+	if ack.Type != "connection_ack" {
+		return false, errors.New("unexpected subscription message: " + ack.Type)
+	}
+	conn.SetReadDeadline(time.Time{})
+	if err := conn.WriteJSON(graphqlWsMessage{Id: "1", Type: "subscribe", Payload: subscribePayload}); err != nil {
+		return true, err
+	}
+
+	// gorilla/websocket supports one concurrent writer, so all writes, including keep-alive pings, happen in this loop.
+	keepAlive := time.NewTicker(subscriptionKeepAliveInterval)
+	defer keepAlive.Stop()
+	messages := make(chan graphqlWsMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var message graphqlWsMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- message:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.WriteJSON(graphqlWsMessage{Id: "1", Type: "complete"})
+			return true, ctx.Err()
+		case err := <-readErr:
+			return true, err
+		case <-keepAlive.C:
+			if err := conn.WriteJSON(graphqlWsMessage{Type: "ping"}); err != nil {
+				return true, err
+			}
+		case message := <-messages:
+			switch message.Type {
+			case "ping":
+				if err := conn.WriteJSON(graphqlWsMessage{Type: "pong"}); err != nil {
+					return true, err
+				}
+			case "next":
+				data, err := parseGraphqlResponse(message.Payload, http.StatusOK)
+				if err != nil {
+					return true, err
+				}
+				select {
+				case events <- data.Data:
+				case <-ctx.Done():
+					return true, ctx.Err()
+				}
+			case "error":
+				return true, parseSubscriptionError(message.Payload)
+			case "complete":
+				return true, errSubscriptionCompleted
+			}
+		}
+	}
+}
+
+// subscriptionDialer returns the websocket dialer of the subscriptions, which connects with the TLS configuration,
+// proxy, dialer and handshake timeout of the transport of the HTTP client, if it is an *http.Transport.
+func (r *Requester) subscriptionDialer() *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		Subprotocols:     []string{graphqlWsProtocol},
+		HandshakeTimeout: subscriptionAckTimeout,
+		Proxy:            http.ProxyFromEnvironment,
+	}
+	var transport *http.Transport
+	if r.HTTPClient != nil && r.HTTPClient.Transport != nil {
+		transport, _ = r.HTTPClient.Transport.(*http.Transport)
+	} else {
+		transport, _ = http.DefaultTransport.(*http.Transport)
+	}
+	if transport == nil {
+		return dialer
+	}
+	dialer.Proxy = transport.Proxy
+	dialer.NetDialContext = transport.DialContext
+	if transport.TLSClientConfig != nil {
+		dialer.TLSClientConfig = transport.TLSClientConfig.Clone()
+		// Websockets are upgraded from HTTP/1.1 connections.
+		dialer.TLSClientConfig.NextProtos = nil
+	}
+	if transport.TLSHandshakeTimeout > 0 {
+		dialer.HandshakeTimeout = transport.TLSHandshakeTimeout
+	}
+	return dialer
+}
+
+func (r *Requester) subscriptionUrl() (string, error) {
+	serverUrl, err := r.serverUrl()
+	if err != nil {
+		return "", err
+	}
+	parsedUrl, err := url.Parse(serverUrl)
+	if err != nil {
+		return "", errors.New("invalid base url. Not a valid URL")
+	}
+	if strings.EqualFold(parsedUrl.Scheme, "https") {
+		parsedUrl.Scheme = "wss"
+	} else {
+		parsedUrl.Scheme = "ws"
+	}
+	return parsedUrl.String(), nil
+}
+
+// parseSubscriptionError parses the payload of an `error` message, which is a list of GraphQL errors.
+func parseSubscriptionError(payload []byte) error {
+	var errs []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	if err := json.Unmarshal(payload, &errs); err != nil || len(errs) == 0 {
+		return &GraphQLError{Message: "subscription failed", StatusCode: http.StatusOK}
+	}
+	graphqlErr := &GraphQLError{Message: errs[0].Message, Extensions: errs[0].Extensions, StatusCode: http.StatusOK}
+	if errorName, ok := errs[0].Extensions["error_name"].(string); ok {
+		graphqlErr.Name = errorName
+	}
+	return graphqlErr
+}
@@ -0,0 +1,82 @@
+package requester_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level   string
+	message string
+	fields  map[string]interface{}
+}
+
+type recordingLogger struct {
+	entries []logEntry
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("debug", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("info", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("warn", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("error", msg, args) }
+
+func (l *recordingLogger) record(level string, msg string, args []interface{}) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[args[i].(string)] = args[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, message: msg, fields: fields})
+}
+
+func TestLogger_Requests(t *testing.T) {
+	attempts := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+		default:
+			w.Write([]byte(`{"errors": [{"message": "invalid invoice lnbc1secret", ` +
+				`"extensions": {"error_name": "InvalidInputException"}}]}`))
+		}
+	})
+	logger := &recordingLogger{}
+	r.Logger = logger
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 2, InitialBackoff: 1}
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+
+	require.Len(t, logger.entries, 3)
+	retry := logger.entries[0]
+	require.Equal(t, "info", retry.level)
+	require.Equal(t, "retrying lightspark request", retry.message)
+	require.Equal(t, "CurrentAccount", retry.fields["operation"])
+	require.Equal(t, 1, retry.fields["attempt"])
+	require.Equal(t, "GraphQLError status=503", retry.fields["error"])
+
+	success := logger.entries[1]
+	require.Equal(t, "debug", success.level)
+	require.Equal(t, "CurrentAccount", success.fields["operation"])
+	require.NotEmpty(t, success.fields["request_id"])
+	require.Contains(t, success.fields, "duration")
+	require.Equal(t, retry.fields["request_id"], success.fields["request_id"])
+
+	failure := logger.entries[2]
+	require.Equal(t, "warn", failure.level)
+	require.Equal(t, "lightspark request failed", failure.message)
+	require.Equal(t, "GraphQLError status=200 name=InvalidInputException", failure.fields["error"])
+	for _, entry := range logger.entries {
+		for _, value := range entry.fields {
+			require.NotContains(t, fmt.Sprint(value), "lnbc1secret")
+		}
+	}
+}
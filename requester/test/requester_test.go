@@ -0,0 +1,1746 @@
+package requester_test
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/stretchr/testify/require"
+)
+
+const testQuery = "query CurrentAccount { current_account { id } }"
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *requester.Requester {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+}
+
+func TestExecuteGraphqlForResult_Extensions(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}, "extensions": {"cost": 3.5, "rate_limit": {"limit": 100, "remaining": 42}, "deprecations": [{"field": "Account.name", "reason": "Use display_name"}]}}`))
+	})
+
+	result, err := r.ExecuteGraphqlForResult(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", result.Data["current_account"].(map[string]interface{})["id"])
+	require.Equal(t, 3.5, *result.Cost())
+	require.Equal(t, int64(42), *result.RateLimit().Remaining)
+	require.Equal(t, []requester.DeprecationWarning{{Field: "Account.name", Reason: "Use display_name"}}, result.Deprecations())
+}
+
+func TestExecuteGraphqlForResult_NoExtensions(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+
+	result, err := r.ExecuteGraphqlForResult(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Nil(t, result.Cost())
+	require.Nil(t, result.RateLimit())
+	require.Nil(t, result.Deprecations())
+}
+
+func TestExecuteGraphql_ClockDrift(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+		w.Write([]byte(`{"data": {}}`))
+	})
+	var driftErr *requester.ClockDriftError
+	r.OnClockDrift = func(err *requester.ClockDriftError) { driftErr = err }
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, driftErr)
+	require.InDelta(t, (2 * time.Minute).Seconds(), driftErr.Drift.Seconds(), 2)
+	require.Equal(t, driftErr.Drift, r.LastClockDrift())
+}
+
+type countingLogger struct {
+	warnings int
+}
+
+func (l *countingLogger) Debug(msg string, args ...interface{}) {}
+func (l *countingLogger) Info(msg string, args ...interface{})  {}
+func (l *countingLogger) Warn(msg string, args ...interface{})  { l.warnings++ }
+func (l *countingLogger) Error(msg string, args ...interface{}) {}
+
+func TestExecuteGraphql_ClockDriftWarningIsRateLimited(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+		w.Write([]byte(`{"data": {}}`))
+	})
+	logger := &countingLogger{}
+	r.Logger = logger
+
+	for i := 0; i < 3; i++ {
+		_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, logger.warnings)
+	require.InDelta(t, (2 * time.Minute).Seconds(), r.LastClockDrift().Seconds(), 2)
+}
+
+func TestRequester_Clone(t *testing.T) {
+	r := requester.NewRequester("client_id", "client_secret")
+	r.Feature = "payments"
+	r.DefaultHeaders = http.Header{"X-Route": {"a"}}
+	r.AllowedOperations = map[string]bool{"CurrentAccount": true}
+
+	clone := r.Clone()
+	clone.Feature = "reconciliation"
+	clone.DefaultHeaders.Set("X-Route", "b")
+	clone.AllowedOperations["CreateInvoice"] = true
+	require.Equal(t, "payments", r.Feature)
+	require.Equal(t, "a", r.DefaultHeaders.Get("X-Route"))
+	require.Len(t, r.AllowedOperations, 1)
+	require.Equal(t, "client_id", clone.ApiTokenClientId)
+}
+
+func TestExecuteGraphql_QuotaBudget(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.QuotaBudgeter = requester.NewQuotaBudgeter(map[string]requester.FeatureBudget{
+		"reconciliation": {RequestsPerSecond: 0.001, Burst: 2},
+	})
+	r.Feature = "reconciliation"
+
+	for i := 0; i < 2; i++ {
+		_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var quotaErr *requester.QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	require.Equal(t, "reconciliation", quotaErr.Feature)
+	require.Equal(t, 2, requests)
+
+	r.Feature = "payments"
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+}
+
+func TestExecuteGraphql_Retry(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, requests)
+
+	requests = 0
+	_, err = r.ExecuteGraphql("mutation CancelInvoice { cancel_invoice { invoice { id } } }", map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+}
+
+func TestExecuteGraphql_RetryOnlyTransportErrors(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	authentications := 0
+	r.Authenticator = requester.AuthenticatorFunc(func(ctx context.Context, header http.Header) error {
+		authentications++
+		return errors.New("token endpoint unavailable")
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.ErrorContains(t, err, "token endpoint unavailable")
+	require.Equal(t, 1, authentications)
+	require.Equal(t, 0, requests)
+
+	closedUrl := "http://127.0.0.1:1/graphql"
+	attempts := 0
+	r = requester.NewRequesterWithBaseUrl("client_id", "client_secret", &closedUrl)
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	r.Authenticator = requester.AuthenticatorFunc(func(ctx context.Context, header http.Header) error {
+		attempts++
+		return nil
+	})
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Equal(t, 3, attempts)
+}
+
+func TestExecuteGraphql_GraphQLError(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"errors": [{"message": "Invalid node", "extensions": {"error_name": "InvalidInputException"}}]}`))
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var graphqlErr *requester.GraphQLError
+	require.ErrorAs(t, err, &graphqlErr)
+	require.Equal(t, "InvalidInputException", graphqlErr.Name)
+	require.Equal(t, "Invalid node", graphqlErr.Message)
+	require.Equal(t, http.StatusOK, graphqlErr.StatusCode)
+	require.Equal(t, "InvalidInputException - Invalid node", err.Error())
+}
+
+func TestExecuteGraphql_RetryAfter(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"data": {}}`))
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var graphqlErr *requester.GraphQLError
+	require.ErrorAs(t, err, &graphqlErr)
+	require.Equal(t, http.StatusTooManyRequests, graphqlErr.StatusCode)
+	var rateLimitedErr *requester.RateLimitedError
+	require.ErrorAs(t, err, &rateLimitedErr)
+	require.Equal(t, time.Second, rateLimitedErr.RetryAfter)
+	require.Equal(t, int64(0), *rateLimitedErr.RateLimit.Remaining)
+
+	requests = 0
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 2, MaxBackoff: 10 * time.Millisecond, RetryRateLimited: true}
+	_, err = r.ExecuteGraphql("mutation CancelInvoice { cancel_invoice { invoice { id } } }", map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestExecuteField(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	type account struct {
+		Id string `json:"id"`
+	}
+
+	result, err := requester.ExecuteField[account](context.Background(), r, testQuery, nil, nil, "current_account")
+	require.NoError(t, err)
+	require.Equal(t, "account:1", result.Id)
+
+	_, err = requester.ExecuteField[account](context.Background(), r, testQuery, nil, nil, "entity")
+	require.Error(t, err)
+}
+
+func TestExecuteField_NestedFieldWithOptions(t *testing.T) {
+	var idempotencyKey string
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		idempotencyKey = req.Header.Get("Idempotency-Key")
+		w.Write([]byte(`{"data": {"create_invoice": {"invoice": {"id": "invoice:1"}}}}`))
+	})
+	type invoice struct {
+		Id string `json:"id"`
+	}
+
+	mutation := "mutation CreateInvoice { create_invoice { invoice { id } } }"
+	result, err := requester.ExecuteField[invoice](context.Background(), r, mutation, nil, nil,
+		"create_invoice.invoice", requester.WithIdempotencyKey("key-1"))
+	require.NoError(t, err)
+	require.Equal(t, "invoice:1", result.Id)
+	require.Equal(t, "key-1", idempotencyKey)
+
+	_, err = requester.ExecuteField[invoice](context.Background(), r, mutation, nil, nil, "create_invoice.payment")
+	require.EqualError(t, err, "missing field in response: create_invoice.payment")
+}
+
+func TestExecuteField_DecodeErrors(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": 1}, "pay_invoice": {"payment": {"id": 1}}}}`))
+	})
+	type entity struct {
+		Id string `json:"id"`
+	}
+
+	_, err := requester.ExecuteField[entity](context.Background(), r, testQuery, nil, nil, "current_account")
+	var decodeErr *requester.ResponseDecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	require.False(t, decodeErr.Executed)
+
+	// The payment was sent even though its response could not be decoded.
+	mutation := "mutation PayInvoice { pay_invoice { payment { id } } }"
+	_, err = requester.ExecuteField[entity](context.Background(), r, mutation, nil, nil, "pay_invoice.payment")
+	require.True(t, errors.As(err, &decodeErr))
+	require.True(t, decodeErr.Executed)
+	require.JSONEq(t, `{"current_account": {"id": 1}, "pay_invoice": {"payment": {"id": 1}}}`, string(decodeErr.RawData))
+	var typeErr *json.UnmarshalTypeError
+	require.True(t, errors.As(err, &typeErr))
+
+	_, err = requester.ExecuteField[entity](context.Background(), r, mutation, nil, nil, "pay_invoice.invoice")
+	require.True(t, errors.As(err, &decodeErr))
+	require.True(t, decodeErr.Executed)
+	require.EqualError(t, err, "missing field in response: pay_invoice.invoice")
+}
+
+func TestExecuteGraphql_Interceptors(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "audit-1", req.Header.Get("X-Audit-Id"))
+		w.Write([]byte(`{"data": {}}`))
+	})
+	var operationName string
+	r.RequestInterceptors = append(r.RequestInterceptors, func(ctx context.Context, request *requester.GraphqlRequest) error {
+		request.Header.Set("X-Audit-Id", "audit-1")
+		return nil
+	})
+	r.ResponseInterceptors = append(r.ResponseInterceptors, func(ctx context.Context, request *requester.GraphqlRequest,
+		result *requester.GraphqlResult, err error) (*requester.GraphqlResult, error) {
+		operationName = request.OperationName
+		return result, err
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "CurrentAccount", operationName)
+}
+
+func TestNewRequesterWithOptions(t *testing.T) {
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret",
+		requester.WithHTTPClient(&http.Client{}), requester.WithTimeout(5*time.Second),
+		requester.WithRetryPolicy(requester.DefaultRetryPolicy()))
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, r.HTTPClient.Timeout)
+	require.Equal(t, 3, r.RetryPolicy.MaxAttempts)
+
+	_, err = requester.NewRequesterWithOptions("client_id", "client_secret",
+		requester.WithBaseUrl("http://api.lightspark.com/graphql"))
+	require.Error(t, err)
+}
+
+func TestNewRequester_Options(t *testing.T) {
+	r := requester.NewRequester("client_id", "client_secret", requester.WithTimeout(5*time.Second),
+		requester.WithRetryPolicy(requester.DefaultRetryPolicy()))
+	require.Equal(t, "client_id", r.ApiTokenClientId)
+	require.Equal(t, 5*time.Second, r.HTTPClient.Timeout)
+	require.Equal(t, 3, r.RetryPolicy.MaxAttempts)
+
+	r = requester.NewRequester("client_id", "client_secret", requester.WithBaseUrl("http://api.lightspark.com/graphql"))
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+}
+
+func TestValidateVariables(t *testing.T) {
+	query := "mutation CreateInvoice($node_id: ID!, $amount_msats: Long!, $memo: String, $expiry_secs: Int = 86400) { create_invoice { invoice { id } } }"
+	memo := "memo"
+	require.NoError(t, requester.ValidateVariables(query, map[string]interface{}{
+		"node_id": "node:1", "amount_msats": int64(1000), "memo": &memo,
+	}))
+
+	err := requester.ValidateVariables(query, map[string]interface{}{
+		"amount_msats": 1000, "memo": 3, "expiry_secs": "soon", "extra": true,
+	})
+	var validationErr *requester.VariableValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "CreateInvoice", validationErr.OperationName)
+	require.Equal(t, []string{
+		"missing required variable $node_id",
+		"variable $memo does not match type String",
+		"variable $expiry_secs does not match type Int",
+		"unexpected variable $extra",
+	}, validationErr.Problems)
+}
+
+func TestValidateVariables_Defaults(t *testing.T) {
+	query := `query Search($filter: SearchFilter = {text: "a) $b"}, $tags: [String!] = ["(", "$"] @deprecated,
+		$first: Int!) { search { id } }`
+	require.NoError(t, requester.ValidateVariables(query, map[string]interface{}{"first": 10}))
+
+	err := requester.ValidateVariables(query, map[string]interface{}{"first": int64(1) << 31, "b": true})
+	var validationErr *requester.VariableValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, []string{
+		"variable $first does not match type Int!",
+		"unexpected variable $b",
+	}, validationErr.Problems)
+	require.NoError(t, requester.ValidateVariables(query, map[string]interface{}{"first": -(int64(1) << 31)}))
+}
+
+func TestExecuteGraphqlWithOptions_Timeout(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.Timeout = 10 * time.Millisecond
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = r.ExecuteGraphqlWithOptions(context.Background(), testQuery, map[string]interface{}{}, nil,
+		requester.WithCallTimeout(5*time.Second))
+	require.NoError(t, err)
+}
+
+func TestExecuteGraphql_Gzip(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(req.Body)
+		require.NoError(t, err)
+		_, err = io.ReadAll(reader)
+		require.NoError(t, err)
+
+		require.Equal(t, "gzip", req.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+		writer.Close()
+	})
+	r.CompressRequests = true
+
+	data, err := r.ExecuteGraphql(testQuery, map[string]interface{}{"padding": strings.Repeat("a", 2048)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", data["current_account"].(map[string]interface{})["id"])
+}
+
+func TestExecuteGraphqlBatch(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var payloads []map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payloads))
+		require.Len(t, payloads, 2)
+		w.Write([]byte(`[{"data": {"entity": {"id": "invoice:1"}}}, {"errors": [{"message": "Entity not found"}]}]`))
+	})
+	first, err := requester.NewGraphqlRequest("query GetEntity($id: ID!) { entity(id: $id) { id } }",
+		map[string]interface{}{"id": "invoice:1"})
+	require.NoError(t, err)
+	second, err := requester.NewGraphqlRequest("query GetEntity($id: ID!) { entity(id: $id) { id } }",
+		map[string]interface{}{"id": "invoice:2"})
+	require.NoError(t, err)
+
+	results, err := r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{*first, *second})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "invoice:1", results[0].Result.Data["entity"].(map[string]interface{})["id"])
+	require.EqualError(t, results[1].Err, "Entity not found")
+}
+
+func TestExecuteGraphqlBatch_Pipeline(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		var payloads []map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payloads))
+		var ids []string
+		var responses []string
+		for _, payload := range payloads {
+			ids = append(ids, payload["variables"].(map[string]interface{})["id"].(string))
+			responses = append(responses, `{"data": {"entity": {"id": "invoice:1"}}}`)
+		}
+		require.Equal(t, ids, req.Header.Values("X-Interceptor"))
+		w.Write([]byte("[" + strings.Join(responses, ",") + "]"))
+	})
+	r.RequestInterceptors = []requester.RequestInterceptor{
+		func(ctx context.Context, request *requester.GraphqlRequest) error {
+			request.Header.Add("X-Interceptor", request.Variables["id"].(string))
+			return nil
+		},
+	}
+	var intercepted []string
+	r.ResponseInterceptors = []requester.ResponseInterceptor{func(ctx context.Context, request *requester.GraphqlRequest,
+		result *requester.GraphqlResult, err error,
+	) (*requester.GraphqlResult, error) {
+		intercepted = append(intercepted, request.Variables["id"].(string))
+		return result, err
+	}}
+	var finished []events.Event
+	r.EventSink = events.SinkFunc(func(event events.Event) {
+		if event.Type == events.RequestFinished {
+			finished = append(finished, event)
+		}
+	})
+	r.ValidateVariables = true
+	r.QuotaBudgeter = requester.NewQuotaBudgeter(map[string]requester.FeatureBudget{
+		requester.DEFAULT_QUOTA_FEATURE: {RequestsPerSecond: 0.001, Burst: 3},
+	})
+	newRequest := func(variables map[string]interface{}) requester.GraphqlRequest {
+		request, err := requester.NewGraphqlRequest("query GetEntity($id: ID!) { entity(id: $id) { id } }", variables)
+		require.NoError(t, err)
+		return *request
+	}
+
+	results, err := r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{
+		newRequest(map[string]interface{}{"id": "a"}), newRequest(map[string]interface{}{"id": "b"}),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, []string{"a", "b"}, intercepted)
+	require.Len(t, finished, 1)
+	require.Equal(t, "GetEntity,GetEntity", finished[0].OperationName)
+
+	// Invalid variables fail the batch before it takes any quota.
+	_, err = r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{
+		newRequest(map[string]interface{}{"id": "a"}), newRequest(map[string]interface{}{"id": "b", "extra": 1}),
+	})
+	var validationErr *requester.VariableValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, finished, 2)
+
+	// The quota has one request left: a batch of two is rejected without consuming it.
+	_, err = r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{
+		newRequest(map[string]interface{}{"id": "a"}), newRequest(map[string]interface{}{"id": "b"}),
+	})
+	var quotaErr *requester.QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	r.Authenticator = requester.AuthenticatorFunc(func(ctx context.Context, header http.Header) error {
+		return errors.New("no credentials")
+	})
+	_, err = r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{
+		newRequest(map[string]interface{}{"id": "a"}),
+	})
+	require.EqualError(t, err, "no credentials")
+	r.Authenticator = nil
+	_, err = r.ExecuteGraphqlBatch(context.Background(), []requester.GraphqlRequest{
+		newRequest(map[string]interface{}{"id": "a"}),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestExecutePreparedGraphql(t *testing.T) {
+	var prepared *requester.PreparedRequest
+	expectedSigningHeader := `{"v": 1, "signature": "c2lnbmF0dXJl"}`
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Equal(t, prepared.Payload, body)
+		require.JSONEq(t, expectedSigningHeader, req.Header.Get("X-Lightspark-Signing"))
+		w.Write([]byte(`{"data": {"pay_invoice": {"payment": {"id": "payment:1"}}}}`))
+	})
+
+	var err error
+	prepared, err = r.PrepareGraphql(context.Background(),
+		"mutation PayInvoice($invoice: String!) { pay_invoice(input: {encoded_invoice: $invoice}) { payment { id } } }",
+		map[string]interface{}{"invoice": "lnbc1"})
+	require.NoError(t, err)
+	require.Equal(t, "PayInvoice", prepared.OperationName)
+	require.True(t, prepared.IsMutation)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(prepared.Payload, &payload))
+	require.NotZero(t, payload["nonce"])
+	require.NotEmpty(t, payload["expires_at"])
+
+	result, err := r.ExecutePreparedGraphql(context.Background(), prepared, []byte("signature"))
+	require.NoError(t, err)
+	require.NotNil(t, result.Data["pay_invoice"])
+
+	prepared.KeyId = "key-2"
+	expectedSigningHeader = `{"v": 1, "signature": "c2lnbmF0dXJl", "key_id": "key-2"}`
+	var intercepted *requester.GraphqlRequest
+	r.ResponseInterceptors = []requester.ResponseInterceptor{func(ctx context.Context, request *requester.GraphqlRequest,
+		result *requester.GraphqlResult, err error,
+	) (*requester.GraphqlResult, error) {
+		intercepted = request
+		return result, err
+	}}
+	var eventTypes []events.Type
+	r.EventSink = events.SinkFunc(func(event events.Event) { eventTypes = append(eventTypes, event.Type) })
+	_, err = r.ExecutePreparedGraphql(context.Background(), prepared, []byte("signature"))
+	require.NoError(t, err)
+	require.NotNil(t, intercepted)
+	require.Equal(t, "lnbc1", intercepted.Variables["invoice"])
+	require.Equal(t, []events.Type{events.RequestStarted, events.RequestFinished}, eventTypes)
+}
+
+func TestExecuteGraphqlRaw(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"entity": {"id": "invoice:1", "amount_msats": 9007199254740993}}}`))
+	})
+	data, err := r.ExecuteGraphqlRaw("query GetEntity($id: ID!) { entity(id: $id) { id } }",
+		map[string]interface{}{"id": "invoice:1"}, nil)
+	require.NoError(t, err)
+
+	var result struct {
+		Entity struct {
+			Id          string `json:"id"`
+			AmountMsats int64  `json:"amount_msats"`
+		} `json:"entity"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Equal(t, "invoice:1", result.Entity.Id)
+	require.Equal(t, int64(9007199254740993), result.Entity.AmountMsats)
+}
+
+func TestExecuteGraphqlRaw_DoesNotDecodeData(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"entity": {"id": "invoice:1"}}}`))
+	})
+	var intercepted *requester.GraphqlResult
+	r.ResponseInterceptors = append(r.ResponseInterceptors, func(ctx context.Context, request *requester.GraphqlRequest,
+		result *requester.GraphqlResult, err error) (*requester.GraphqlResult, error) {
+		intercepted = result
+		return result, err
+	})
+
+	data, err := r.ExecuteGraphqlRaw(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"entity": {"id": "invoice:1"}}`, string(data))
+	require.NotNil(t, intercepted)
+	require.Nil(t, intercepted.Data)
+
+	result, err := r.ExecuteGraphqlForResult(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": "invoice:1"}, result.Data["entity"])
+}
+
+func TestExecuteGraphql_IdempotencyKey(t *testing.T) {
+	var keys []string
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		keys = append(keys, req.Header.Get(requester.IDEMPOTENCY_KEY_HEADER))
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	// The API does not deduplicate mutations by idempotency key, so they are not retried.
+	_, err := r.ExecuteGraphqlWithOptions(context.Background(),
+		"mutation CancelInvoice { cancel_invoice { invoice { id } } }", map[string]interface{}{}, nil,
+		requester.WithIdempotencyKey("key-1"))
+	require.Error(t, err)
+	require.Equal(t, []string{"key-1"}, keys)
+
+	keys = nil
+	r.IdempotencyKeys = true
+	_, err = r.ExecuteGraphql("mutation CancelInvoice { cancel_invoice { invoice { id } } }",
+		map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotEmpty(t, keys[0])
+}
+
+func TestBaseUrlPool_Failover(t *testing.T) {
+	primaryRequests := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		primaryRequests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(primary.Close)
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	}))
+	t.Cleanup(secondary.Close)
+	pool, err := requester.NewBaseUrlPool(primary.URL, secondary.URL)
+	require.NoError(t, err)
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrlPool(pool))
+	require.NoError(t, err)
+
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{secondary.URL, primary.URL}, pool.BaseUrls())
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, primaryRequests)
+}
+
+func TestBaseUrlPool_MutationFailover(t *testing.T) {
+	const mutation = "mutation CancelInvoice { cancel_invoice { invoice { id } } }"
+	secondaryRequests := 0
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		secondaryRequests++
+		w.Write([]byte(`{"data": {"cancel_invoice": {"invoice": {"id": "invoice:1"}}}}`))
+	}))
+	t.Cleanup(secondary.Close)
+	newRequester := func(primaryUrl string) *requester.Requester {
+		pool, err := requester.NewBaseUrlPool(primaryUrl, secondary.URL)
+		require.NoError(t, err)
+		r, err := requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrlPool(pool))
+		require.NoError(t, err)
+		return r
+	}
+
+	// A gateway timeout does not tell whether the mutation was executed upstream.
+	gatewayTimeout := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	t.Cleanup(gatewayTimeout.Close)
+	_, err := newRequester(gatewayTimeout.URL).ExecuteGraphql(mutation, map[string]interface{}{}, nil)
+	var graphqlErr *requester.GraphQLError
+	require.True(t, errors.As(err, &graphqlErr))
+	require.Equal(t, http.StatusGatewayTimeout, graphqlErr.StatusCode)
+	require.Equal(t, 0, secondaryRequests)
+
+	// A 503 with a body may come from the backend itself.
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"errors": [{"message": "payment in progress"}]}`))
+	}))
+	t.Cleanup(unavailable.Close)
+	_, err = newRequester(unavailable.URL).ExecuteGraphql(mutation, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Equal(t, 0, secondaryRequests)
+
+	// A 503 without a body, e.g. from a load balancer without healthy backends, and a refused connection never
+	// reached the backend.
+	noBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(noBackend.Close)
+	_, err = newRequester(noBackend.URL).ExecuteGraphql(mutation, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, secondaryRequests)
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	closed.Close()
+	_, err = newRequester(closed.URL).ExecuteGraphql(mutation, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, secondaryRequests)
+}
+
+func TestBaseUrlPool_Hedging(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.ReadAll(req.Body)
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:slow"}}}`))
+	}))
+	t.Cleanup(slow.Close)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:fast"}}}`))
+	}))
+	t.Cleanup(fast.Close)
+	pool, err := requester.NewBaseUrlPool(slow.URL, fast.URL)
+	require.NoError(t, err)
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrlPool(pool),
+		requester.WithHedgeDelay(20*time.Millisecond))
+	require.NoError(t, err)
+
+	data, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:fast", data["current_account"].(map[string]interface{})["id"])
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrl(server.URL),
+		requester.WithRootCAs(rootCAs))
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+
+	r, err = requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrl(server.URL),
+		requester.WithRootCAs(rootCAs), requester.WithClientCertificate(server.TLS.Certificates[0]))
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+}
+
+type testSigningKey struct{}
+
+func (testSigningKey) Sign(payload []byte) ([]byte, error) {
+	return []byte("signature"), nil
+}
+
+func TestExecuteGraphql_SigningExpiry(t *testing.T) {
+	var expiresAt time.Time
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		expiresAt = payload.ExpiresAt
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.SigningExpiry = 5 * time.Minute
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, testSigningKey{})
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, 5*time.Second)
+
+	_, err = r.ExecuteGraphqlWithOptions(context.Background(), testQuery, map[string]interface{}{}, testSigningKey{},
+		requester.WithCallSigningExpiry(30*time.Second))
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(30*time.Second), expiresAt, 5*time.Second)
+}
+
+func TestExecuteGraphql_PersistedQueries(t *testing.T) {
+	knownHashes := map[string]bool{}
+	var sentQueries []bool
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			Query      *string `json:"query"`
+			Extensions struct {
+				PersistedQuery struct {
+					Sha256Hash string `json:"sha256Hash"`
+				} `json:"persistedQuery"`
+			} `json:"extensions"`
+		}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		hash := payload.Extensions.PersistedQuery.Sha256Hash
+		require.NotEmpty(t, hash)
+		sentQueries = append(sentQueries, payload.Query != nil)
+		if payload.Query == nil && !knownHashes[hash] {
+			w.Write([]byte(`{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+		knownHashes[hash] = true
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	r.PersistedQueries = true
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, false}, sentQueries)
+}
+
+func TestSignablePayloadObserver(t *testing.T) {
+	var body []byte
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"data": {}}`))
+	})
+	var observed []requester.SignablePayload
+	restore := requester.SetSignablePayloadObserver(func(payload requester.SignablePayload) {
+		observed = append(observed, payload)
+	})
+	defer restore()
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, testSigningKey{})
+	require.NoError(t, err)
+	require.Len(t, observed, 1)
+	require.Equal(t, requester.SIGNABLE_GRAPHQL_REQUEST, observed[0].Kind)
+	require.False(t, observed[0].Verifying)
+	require.Equal(t, body, observed[0].Payload)
+
+	restore()
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, testSigningKey{})
+	require.NoError(t, err)
+	require.Len(t, observed, 1)
+}
+
+func TestExecuteGraphql_RequestId(t *testing.T) {
+	var requestIds []string
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requestIds = append(requestIds, req.Header.Get(requester.REQUEST_ID_HEADER))
+		w.Write([]byte(`{"errors": [{"message": "Something went wrong"}]}`))
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Len(t, requestIds, 1)
+	require.NotEmpty(t, requestIds[0])
+	require.Equal(t, requestIds[0], requester.RequestIdFromError(err))
+
+	ctx := requester.ContextWithRequestId(context.Background(), "incoming-request")
+	_, err = r.ExecuteGraphqlWithContext(ctx, testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	require.Equal(t, "incoming-request", requestIds[1])
+	require.Equal(t, "incoming-request", requester.RequestIdFromError(err))
+}
+
+func TestExecuteGraphql_ReadOnly(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	})
+	r.ReadOnly = true
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql("mutation CreateInvoice { create_invoice { invoice { id } } }",
+		map[string]interface{}{}, nil)
+	var notAllowedErr *requester.OperationNotAllowedError
+	require.ErrorAs(t, err, &notAllowedErr)
+	require.True(t, notAllowedErr.IsMutation)
+	require.Equal(t, 1, requests)
+
+	requester.WithAllowedOperations("AccountDashboard")(r)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.ErrorAs(t, err, &notAllowedErr)
+	require.Equal(t, "CurrentAccount", notAllowedErr.OperationName)
+	require.Equal(t, 1, requests)
+}
+
+func TestRateLimiter_Priority(t *testing.T) {
+	limiter := requester.NewRateLimiter(20, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	order := make(chan requester.Priority, 2)
+	backgroundStarted := make(chan struct{})
+	go func() {
+		close(backgroundStarted)
+		require.NoError(t, limiter.WaitPriority(context.Background(), requester.PriorityBackground))
+		order <- requester.PriorityBackground
+	}()
+	<-backgroundStarted
+	go func() {
+		require.NoError(t, limiter.WaitPriority(context.Background(), requester.PriorityCritical))
+		order <- requester.PriorityCritical
+	}()
+	// Both requests wait for the next token, which goes to the critical one even if it started waiting last.
+	require.Equal(t, requester.PriorityCritical, <-order)
+	require.Equal(t, requester.PriorityBackground, <-order)
+}
+
+func TestNewRequesterFromEnv(t *testing.T) {
+	t.Setenv(requester.ENV_API_TOKEN_CLIENT_ID, "client_id")
+	t.Setenv(requester.ENV_API_TOKEN_CLIENT_SECRET, "")
+	_, err := requester.NewRequesterFromEnv()
+	require.ErrorContains(t, err, requester.ENV_API_TOKEN_CLIENT_SECRET)
+
+	t.Setenv(requester.ENV_API_TOKEN_CLIENT_SECRET, "client_secret")
+	t.Setenv(requester.ENV_BASE_URL, "http://api.example.com/graphql")
+	_, err = requester.NewRequesterFromEnv()
+	require.Error(t, err)
+
+	t.Setenv(requester.ENV_BASE_URL, "https://api.example.com/graphql")
+	r, err := requester.NewRequesterFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "client_id", r.ApiTokenClientId)
+	require.Equal(t, "client_secret", r.ApiTokenClientSecret)
+	require.Equal(t, "https://api.example.com/graphql", *r.BaseUrl)
+}
+
+func TestExecuteGraphql_ResponseCache(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	r.ResponseCache = requester.NewLRUResponseCache(10, time.Minute)
+
+	data, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	data["current_account"] = nil
+	data, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", data["current_account"].(map[string]interface{})["id"])
+	require.Equal(t, 1, requests)
+
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{"first": 1}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphqlWithOptions(context.Background(), testQuery, map[string]interface{}{}, nil,
+		requester.WithCallCacheBypass())
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, testSigningKey{})
+	require.NoError(t, err)
+	require.Equal(t, 4, requests)
+}
+
+func TestExecuteGraphql_SharedResponseCache(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		clientId, _, _ := req.BasicAuth()
+		w.Write([]byte(`{"data": {"current_account": {"id": "` + clientId + `"}}, "extensions": {"cost": 1}}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(server.Close)
+	otherServer := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(otherServer.Close)
+	cache := requester.NewLRUResponseCache(10, time.Minute)
+	accountId := func(r *requester.Requester) string {
+		r.ResponseCache = cache
+		result, err := r.ExecuteGraphqlForResult(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+		return result.Data["current_account"].(map[string]interface{})["id"].(string)
+	}
+
+	require.Equal(t, "alice", accountId(requester.NewRequesterWithBaseUrl("alice", "secret", &server.URL)))
+	require.Equal(t, "bob", accountId(requester.NewRequesterWithBaseUrl("bob", "secret", &server.URL)))
+	require.Equal(t, "carol", accountId(requester.NewRequesterWithBaseUrl("carol", "secret", &otherServer.URL)))
+
+	r := requester.NewRequesterWithBaseUrl("alice", "secret", &server.URL)
+	r.ResponseCache = cache
+	result, err := r.ExecuteGraphqlForResult(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	result.Extensions["cost"] = 100.0
+	result, err = r.ExecuteGraphqlForResult(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, *result.Cost())
+}
+
+func TestExecuteGraphql_ResponseCacheScope(t *testing.T) {
+	tokens := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tokens++
+		w.Write([]byte(`{"access_token": "token-` + strconv.Itoa(tokens) + `", "token_type": "Bearer"}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	authenticator := &requester.OAuth2ClientCredentialsAuthenticator{
+		TokenUrl:     tokenServer.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+	}
+	requester.WithAuthenticator(authenticator)(r)
+	r.ResponseCache = requester.NewLRUResponseCache(10, time.Minute)
+
+	// Cache hits do not request a token, and the cached results survive token rotations.
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	authenticator.Invalidate()
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+	require.Equal(t, 1, tokens)
+
+	// Results are not cached for authenticators without an identity.
+	requester.WithAuthenticator(requester.AuthenticatorFunc(func(ctx context.Context, header http.Header) error {
+		header.Set("Authorization", "Bearer token")
+		return nil
+	}))(r)
+	for i := 0; i < 2; i++ {
+		_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, requests)
+}
+
+func TestLRUResponseCache(t *testing.T) {
+	cache := requester.NewLRUResponseCache(2, time.Minute)
+	cache.Set("a", &requester.GraphqlResult{})
+	cache.Set("b", &requester.GraphqlResult{})
+	require.NotNil(t, cache.Get("a"))
+	cache.Set("c", &requester.GraphqlResult{})
+	require.NotNil(t, cache.Get("a"))
+	require.Nil(t, cache.Get("b"))
+	cache.Invalidate("a")
+	require.Nil(t, cache.Get("a"))
+	require.NotNil(t, cache.Get("c"))
+}
+
+func TestLRUResponseCache_ExpiresWithRuntimeClock(t *testing.T) {
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Now(), "seed")
+	defer sdkruntimetest.SetDefault(runtime)()
+
+	cache := requester.NewLRUResponseCache(2, time.Minute)
+	cache.Set("a", &requester.GraphqlResult{})
+	clock.Advance(30 * time.Second)
+	require.NotNil(t, cache.Get("a"))
+	clock.Advance(time.Minute)
+	require.Nil(t, cache.Get("a"))
+}
+
+func TestPaginate(t *testing.T) {
+	var cursors []interface{}
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		cursors = append(cursors, payload.Variables["after"])
+		if len(cursors) < 3 {
+			w.Write([]byte(`{"data": {"entity": {"page_info": {"page_info_has_next_page": true,
+				"page_info_end_cursor": "cursor` + strconv.Itoa(len(cursors)) + `"}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"entity": {"page_info": {"page_info_has_next_page": false}}}}`))
+	})
+
+	pages := r.Paginate(context.Background(), testQuery, map[string]interface{}{"first": 10}, "entity.page_info")
+	count := 0
+	for pages.Next() {
+		require.NotNil(t, pages.Page())
+		count++
+	}
+	require.NoError(t, pages.Err())
+	require.Equal(t, 3, count)
+	require.Equal(t, []interface{}{nil, "cursor1", "cursor2"}, cursors)
+}
+
+func TestExecuteGraphql_DryRun(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	})
+	requester.WithDryRun()(r)
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql("mutation CreateInvoice($amount_msats: Long!) { create_invoice(amount_msats: $amount_msats) { invoice { id } } }",
+		map[string]interface{}{"amount_msats": 1000}, testSigningKey{})
+	var dryRunErr *requester.DryRunError
+	require.ErrorAs(t, err, &dryRunErr)
+	require.Equal(t, "CreateInvoice", dryRunErr.OperationName)
+	require.True(t, dryRunErr.Signed)
+	require.Contains(t, string(dryRunErr.Payload), `"amount_msats":1000`)
+	require.Equal(t, 1, requests)
+
+	_, err = r.ExecuteGraphql("mutation CreateInvoice($amount_msats: Long!) { create_invoice(amount_msats: $amount_msats) { invoice { id } } }",
+		map[string]interface{}{}, nil)
+	var validationErr *requester.VariableValidationError
+	require.ErrorAs(t, err, &validationErr)
+}
+
+func TestExecuteGraphql_HTTPError(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Blocked-By", "waf")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"reason": "blocked"}` + strings.Repeat(" ", requester.MAX_HTTP_ERROR_BODY_SIZE)))
+	})
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var httpErr *requester.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+	require.Equal(t, "waf", httpErr.Header.Get("X-Blocked-By"))
+	require.Len(t, httpErr.Body, requester.MAX_HTTP_ERROR_BODY_SIZE)
+	require.True(t, httpErr.BodyTruncated)
+	require.Contains(t, err.Error(), `{"reason": "blocked"}`)
+	var graphqlErr *requester.GraphQLError
+	require.ErrorAs(t, err, &graphqlErr)
+	require.Equal(t, http.StatusForbidden, graphqlErr.StatusCode)
+	require.Equal(t, "GraphQLError status=403", requester.RedactError(err))
+}
+
+func TestRedactError(t *testing.T) {
+	err := &url.Error{Op: "Get", URL: "https://vasp.com/.well-known/lnurlp/alice", Err: errors.New("connection refused")}
+	require.Equal(t, "Get: connection refused", requester.RedactError(err))
+	require.Equal(t, "", requester.RedactError(nil))
+}
+
+func TestExecuteGraphql_OnDeprecation(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {}, "extensions": {"deprecations": [{"field": "Account.legacy_name", "reason": "Use name"}]}}`))
+	})
+	var warnings []requester.DeprecationWarning
+	r.OnDeprecation = func(operationName string, warning requester.DeprecationWarning) {
+		require.Equal(t, "DeprecatedFieldQuery", operationName)
+		warnings = append(warnings, warning)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := r.ExecuteGraphql("query DeprecatedFieldQuery { current_account { legacy_name } }",
+			map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []requester.DeprecationWarning{{Field: "Account.legacy_name", Reason: "Use name"}}, warnings)
+}
+
+func TestExecuteGraphql_DefaultHeaders(t *testing.T) {
+	var header http.Header
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header
+		w.Write([]byte(`{"data": {}}`))
+	})
+	requester.WithDefaultHeader("X-Internal-Route", "payments")(r)
+	requester.WithDefaultHeader("Content-Type", "text/plain")(r)
+	requester.WithDefaultHeader("X-Lightspark-SDK", "other-sdk")(r)
+	requester.WithUserAgentSuffix("billing-service/1.2")(r)
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "payments", header.Get("X-Internal-Route"))
+	require.True(t, strings.HasPrefix(header.Get("User-Agent"), "lightspark-go-sdk/"))
+	require.True(t, strings.HasSuffix(header.Get("User-Agent"), " billing-service/1.2"))
+	require.NotContains(t, header.Get("X-Lightspark-SDK"), "billing-service")
+	require.Equal(t, []string{"application/json"}, header.Values("Content-Type"))
+	require.Len(t, header.Values("X-Lightspark-SDK"), 1)
+	require.True(t, strings.HasPrefix(header.Get("X-Lightspark-SDK"), "lightspark-go-sdk/"))
+}
+
+func TestBaseUrlPolicy(t *testing.T) {
+	require.NoError(t, requester.ValidateBaseUrl("http://localhost:5000/graphql"))
+	require.Error(t, requester.ValidateBaseUrl("http://mock-api:8080/graphql"))
+
+	policy := requester.BaseUrlPolicy{InsecureHosts: []string{"mock-api"}}
+	require.NoError(t, policy.Validate("http://mock-api:8080/graphql"))
+	require.Error(t, policy.Validate("http://api.lightspark.com/graphql"))
+
+	policy = requester.BaseUrlPolicy{AllowedHosts: []string{"*.lightspark.com"}}
+	require.NoError(t, policy.Validate("https://api.lightspark.com/graphql"))
+	require.Error(t, policy.Validate("https://api.example.com/graphql"))
+
+	_, err := requester.NewRequesterWithOptions("client_id", "client_secret",
+		requester.WithBaseUrl("http://mock-api:8080/graphql"),
+		requester.WithBaseUrlPolicy(requester.BaseUrlPolicy{AllowInsecure: true}))
+	require.NoError(t, err)
+}
+
+func TestQueryBuilder(t *testing.T) {
+	query := "query GetNode($node_id: ID!, $amount_msats: Long!) { entity(id: $node_id) { id } }"
+	request, err := requester.NewQuery(query).
+		WithVariable("node_id", "node1").
+		WithVariable("amount_msats", int64(9007199254740993)).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "GetNode", request.OperationName)
+	require.Equal(t, int64(9007199254740993), request.Variables["amount_msats"])
+
+	_, err = requester.NewQuery(query).
+		WithVariable("node_id", "node1").
+		WithVariable("amount_msats", float64(9007199254740993)).
+		Build()
+	require.ErrorContains(t, err, "$amount_msats")
+
+	_, err = requester.NewQuery(query).WithVariable("node_id", "node1").Build()
+	var validationErr *requester.VariableValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	type amount struct {
+		Value float64 `json:"value"`
+		Unit  string  `json:"unit"`
+	}
+	_, err = requester.NewQuery(query).
+		WithVariable("input", struct{ Amounts []amount }{[]amount{{Value: 9007199254740993, Unit: "MSATOSHI"}}}).
+		Build()
+	require.ErrorContains(t, err, "$input: field Amounts: item 0: field value")
+}
+
+func TestExecute_DecodesNumbersExactly(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"entity": {"amount_msats": 9007199254740993}}}`))
+	})
+	type entity struct {
+		AmountMsats int64 `json:"amount_msats"`
+	}
+	result, err := requester.ExecuteField[entity](context.Background(), r, testQuery, nil, nil, "entity")
+	require.NoError(t, err)
+	require.Equal(t, int64(9007199254740993), result.AmountMsats)
+
+	// ExecuteGraphql keeps decoding numbers as float64 for compatibility.
+	data, err := r.ExecuteGraphql(testQuery, nil, nil)
+	require.NoError(t, err)
+	require.IsType(t, float64(0), data["entity"].(map[string]interface{})["amount_msats"])
+}
+
+type connectionCollector struct {
+	reused []bool
+}
+
+func (c *connectionCollector) ObserveRequest(operationName string, duration time.Duration, err error) {
+}
+
+func (c *connectionCollector) ObserveConnection(reused bool, idleTime time.Duration) {
+	c.reused = append(c.reused, reused)
+}
+
+func TestConnectionPool(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	requester.WithConnectionPool(requester.ConnectionPoolOptions{
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     time.Minute,
+	})(r)
+	transport := r.HTTPClient.Transport.(*http.Transport)
+	require.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	require.Equal(t, time.Minute, transport.IdleConnTimeout)
+
+	collector := &connectionCollector{}
+	r.MetricsCollector = collector
+	for i := 0; i < 2; i++ {
+		_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []bool{false, true}, collector.reused)
+}
+
+func TestBaseUrlPool_HealthChecks(t *testing.T) {
+	primaryDown := true
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if primaryDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(primary.Close)
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	t.Cleanup(secondary.Close)
+	pool, err := requester.NewBaseUrlPool(primary.URL, secondary.URL)
+	require.NoError(t, err)
+	var failovers []string
+	pool.OnFailover = func(previous string, current string) {
+		failovers = append(failovers, current)
+	}
+	check := requester.HTTPHealthCheck(nil)
+
+	failures := pool.CheckHealth(context.Background(), check)
+	require.Contains(t, failures, primary.URL)
+	require.Equal(t, secondary.URL, pool.ActiveBaseUrl())
+
+	primaryDown = false
+	require.Empty(t, pool.CheckHealth(context.Background(), check))
+	require.Equal(t, primary.URL, pool.ActiveBaseUrl())
+	require.Equal(t, []string{secondary.URL, primary.URL}, failovers)
+}
+
+func TestResponseVerifier(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	body := []byte(`{"data": {"current_account": {"id": "account:1"}}}`)
+	hashed := sha256.Sum256(body)
+	signature, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, hashed[:], nil)
+	require.NoError(t, err)
+
+	signed := true
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		if signed {
+			w.Header().Set(requester.RESPONSE_SIGNATURE_HEADER, base64.StdEncoding.EncodeToString(signature))
+		}
+		w.Write(body)
+	})
+	requester.WithResponseVerifier(&requester.RsaResponseVerifier{PublicKey: publicKey})(r)
+
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+
+	signed = false
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var signatureErr *requester.ResponseSignatureError
+	require.ErrorAs(t, err, &signatureErr)
+	require.Contains(t, signatureErr.Reason, "missing")
+}
+
+func TestExecuteGraphql_EventSink(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	var received []events.Event
+	requester.WithEventSink(events.SinkFunc(func(event events.Event) {
+		received = append(received, event)
+	}))(r)
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+	require.Equal(t, events.RequestStarted, received[0].Type)
+	require.Equal(t, events.RequestFinished, received[1].Type)
+	require.Equal(t, "CurrentAccount", received[1].OperationName)
+	require.NotEmpty(t, received[1].RequestId)
+	require.Empty(t, received[1].Error)
+}
+
+func TestExecuteGraphqlInto(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1", "balance_msats": 9007199254740993}}, "extensions": {"cost": 2}}`))
+	})
+	var data struct {
+		CurrentAccount struct {
+			Id           string `json:"id"`
+			BalanceMsats int64  `json:"balance_msats"`
+		} `json:"current_account"`
+	}
+	result, err := r.ExecuteGraphqlInto(context.Background(), testQuery, nil, nil, &data)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", data.CurrentAccount.Id)
+	require.Equal(t, int64(9007199254740993), data.CurrentAccount.BalanceMsats)
+	require.Equal(t, 2.0, *result.Cost())
+	require.Nil(t, result.Data)
+
+	r = newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": null, "errors": [{"message": "boom", "extensions": {"error_name": "NotFound"}}]}`))
+	})
+	_, err = r.ExecuteGraphqlInto(context.Background(), testQuery, nil, nil, &data)
+	var graphqlErr *requester.GraphQLError
+	require.ErrorAs(t, err, &graphqlErr)
+	require.Equal(t, "NotFound", graphqlErr.Name)
+}
+
+type signalingTarget struct {
+	decoded chan struct{}
+}
+
+func (s *signalingTarget) UnmarshalJSON(data []byte) error {
+	close(s.decoded)
+	return nil
+}
+
+func TestExecuteGraphqlInto_DecodesWhileReading(t *testing.T) {
+	target := &signalingTarget{decoded: make(chan struct{})}
+	streamed := false
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}},`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-target.decoded:
+			streamed = true
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte(` "extensions": {"cost": 2}}`))
+	})
+
+	result, err := r.ExecuteGraphqlInto(context.Background(), testQuery, nil, nil, target)
+	require.NoError(t, err)
+	require.True(t, streamed)
+	require.Equal(t, 2.0, *result.Cost())
+}
+
+func TestWithProxyURL(t *testing.T) {
+	var proxiedUrl string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		proxiedUrl = req.URL.String()
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	}))
+	t.Cleanup(proxy.Close)
+	proxyUrl, err := requester.ParseProxyURL(proxy.URL)
+	require.NoError(t, err)
+	_, err = requester.ParseProxyURL("ftp://proxy.internal")
+	require.Error(t, err)
+
+	baseUrl := "http://api.internal/graphql"
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret",
+		requester.WithBaseUrlPolicy(requester.BaseUrlPolicy{InsecureHosts: []string{"api.internal"}}),
+		requester.WithBaseUrl(baseUrl), requester.WithProxyURL(proxyUrl))
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, baseUrl, proxiedUrl)
+}
+
+func TestQueryLinter(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {"entity": {"id": "node1"}}}`))
+	})
+	requester.WithQueryLinter(requester.QueryLinter{MaxDepth: 2})(r)
+
+	_, err := r.ExecuteGraphql("query GetEntity($id: ID!) { entity(id: $id) { id } }",
+		map[string]interface{}{"id": "node1"}, nil)
+	require.NoError(t, err)
+
+	_, err = r.ExecuteGraphql("query GetEntity($id: ID!, $first: Int) { entity(id: $other) { owner { id } }",
+		map[string]interface{}{}, nil)
+	var lintErr *requester.QueryLintError
+	require.ErrorAs(t, err, &lintErr)
+	require.Equal(t, "GetEntity", lintErr.OperationName)
+	require.Equal(t, []string{
+		"unclosed {",
+		"variable $id is defined but not used",
+		"variable $first is defined but not used",
+		"variable $other is used but not defined",
+		"missing required variable $id",
+	}, lintErr.Problems)
+
+	_, err = r.ExecuteGraphql("query GetEntity { entity(id: \"node1\") { owner { node { id } } } }", nil, nil)
+	require.ErrorContains(t, err, "query depth is 4")
+	require.Equal(t, 1, requests)
+}
+
+func TestQueryLinter_Lexing(t *testing.T) {
+	linter := requester.QueryLinter{}
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"comment with a bracket", "query GetEntity($id: ID!) { # comment with {\n entity(id: $id) { id } }"},
+		{"dollar in a string", `query GetEntity($id: ID!) { entity(id: $id, memo: "pay $5") { id } }`},
+		{"escaped backslash", `query GetEntity($id: ID!) { entity(id: $id, s: "\\") { id } }`},
+		{"escaped quote", `query GetEntity($id: ID!) { entity(id: $id, s: "say \"{\"") { id } }`},
+		{"block string", "query GetEntity($id: ID!) { entity(id: $id, s: \"\"\"a } \\\"\"\" $x\"\"\") { id } }"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, linter.Lint(test.query, map[string]interface{}{"id": "node1"}))
+		})
+	}
+
+	err := linter.Lint(`query GetEntity($id: ID!) { entity(id: $id, s: "unterminated) { id } }`,
+		map[string]interface{}{"id": "node1"})
+	require.ErrorContains(t, err, "unterminated string literal")
+}
+
+// keyIdSigningKey signs payloads with its id, so the server can check the key named by the signing header.
+type keyIdSigningKey string
+
+func (k keyIdSigningKey) Sign(payload []byte) ([]byte, error) {
+	return []byte(k), nil
+}
+
+func TestRotatingSigningKey(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		var header struct {
+			Signature string `json:"signature"`
+			KeyId     string `json:"key_id"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(req.Header.Get("X-Lightspark-Signing")), &header))
+		signature, err := base64.StdEncoding.DecodeString(header.Signature)
+		require.NoError(t, err)
+		if string(signature) != header.KeyId {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": {"pay_invoice": {"id": "payment:1"}}}`))
+	})
+	signingKey := requester.NewRotatingSigningKey(keyIdSigningKey("key:0"), "key:0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := r.ExecuteGraphql("mutation PayInvoice { pay_invoice { id } }", nil, signingKey)
+			require.NoError(t, err)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			keyId := "key:" + strconv.Itoa(i+1)
+			signingKey.Rotate(keyIdSigningKey(keyId), keyId)
+		}(i)
+	}
+	wg.Wait()
+
+	_, keyId, err := signingKey.CurrentSigningKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(keyId, "key:"))
+}
+
+func TestWithForceHTTP2(t *testing.T) {
+	var protocol string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		protocol = req.Proto
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	r, err := requester.NewRequesterWithOptions("client_id", "client_secret", requester.WithBaseUrl(server.URL),
+		requester.WithRootCAs(rootCAs), requester.WithForceHTTP2(),
+		requester.WithHandshakeTimeouts(time.Second, time.Second))
+	require.NoError(t, err)
+	require.Equal(t, time.Second, r.HTTPClient.Transport.(*http.Transport).TLSHandshakeTimeout)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/2.0", protocol)
+}
+
+func TestHedgeDelay_WithoutPool(t *testing.T) {
+	var mutex sync.Mutex
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		requests++
+		first := requests == 1
+		mutex.Unlock()
+		if first {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+		}
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	requester.WithHedgeDelay(20 * time.Millisecond)(r)
+
+	start := time.Now()
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), time.Second)
+	mutex.Lock()
+	require.Equal(t, 2, requests)
+	mutex.Unlock()
+}
+
+func TestHedgeDelay_SignedQueriesAreNotHedged(t *testing.T) {
+	var mutex sync.Mutex
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		requests++
+		mutex.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	requester.WithHedgeDelay(10 * time.Millisecond)(r)
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, testSigningKey{})
+	require.NoError(t, err)
+	mutex.Lock()
+	require.Equal(t, 1, requests)
+	mutex.Unlock()
+}
+
+func TestWithRuntime(t *testing.T) {
+	var payloads []string
+	var requestIds []string
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		payloads = append(payloads, string(body))
+		requestIds = append(requestIds, req.Header.Get(requester.REQUEST_ID_HEADER))
+		w.Write([]byte(`{"data": {"pay_invoice": {"id": "payment:1"}}}`))
+	})
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		runtime, _ := sdkruntime.NewDeterministicRuntime(now, "seed")
+		requester.WithRuntime(runtime)(r)
+		_, err := r.ExecuteGraphql("mutation PayInvoice { pay_invoice { id } }", nil, testSigningKey{})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, payloads[0], payloads[1])
+	require.Equal(t, requestIds[0], requestIds[1])
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(payloads[0]), &payload))
+	require.Equal(t, now.Add(requester.DEFAULT_SIGNING_EXPIRY).Format(time.RFC3339), payload["expires_at"])
+}
+
+func TestWithRuntime_Clock(t *testing.T) {
+	requests := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	})
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "seed")
+	requester.WithRuntime(runtime)(r)
+
+	// The response cache expires entries on the requester's clock, not the default one.
+	r.ResponseCache = requester.NewLRUResponseCache(10, time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+		clock.Advance(30 * time.Second)
+	}
+	require.Equal(t, 1, requests)
+	clock.Advance(time.Minute)
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	// So do the quota budgets and the rate limiter.
+	r.ResponseCache = nil
+	r.RateLimiter = requester.NewRateLimiter(0.01, 1)
+	r.QuotaBudgeter = requester.NewQuotaBudgeter(map[string]requester.FeatureBudget{
+		"reconciliation": {RequestsPerSecond: 0.01, Burst: 1},
+	})
+	r.Feature = "reconciliation"
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var quotaErr *requester.QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	clock.Advance(100 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = r.ExecuteGraphqlWithContext(ctx, testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, requests)
+}
+
+func TestOAuth2ClientCredentialsAuthenticator(t *testing.T) {
+	tokens := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		clientId, clientSecret, ok := req.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "client", clientId)
+		require.Equal(t, "secret", clientSecret)
+		require.NoError(t, req.ParseForm())
+		require.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+		tokens++
+		w.Write([]byte(`{"access_token": "token-` + strconv.Itoa(tokens) + `", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+	rejectNext := false
+	var authorizations []string
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		authorizations = append(authorizations, req.Header.Get("Authorization"))
+		if rejectNext {
+			rejectNext = false
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	requester.WithAuthenticator(&requester.OAuth2ClientCredentialsAuthenticator{
+		TokenUrl:     tokenServer.URL,
+		ClientId:     "client",
+		ClientSecret: "secret",
+	})(r)
+
+	for i := 0; i < 2; i++ {
+		_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+		require.NoError(t, err)
+	}
+	rejectNext = true
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-1", "Bearer token-2"}, authorizations)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(token, ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		require.True(t, ecdsa.Verify(&privateKey.PublicKey, hashed[:], new(big.Int).SetBytes(signature[:32]),
+			new(big.Int).SetBytes(signature[32:])))
+		encodedClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(encodedClaims, &claims))
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	requester.WithAuthenticator(&requester.JWTAuthenticator{
+		PrivateKey: privateKey,
+		Issuer:     "partner",
+		Audience:   "gateway.internal",
+	})(r)
+
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "partner", claims["iss"])
+	require.Equal(t, "gateway.internal", claims["aud"])
+	require.Equal(t, float64(300), claims["exp"].(float64)-claims["iat"].(float64))
+}
@@ -0,0 +1,63 @@
+package requester_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("CurrentAccount", map[string]interface{}{"current_account": map[string]interface{}{"id": "account:1"}}).
+		RespondError("CurrentAccount", &requester.GraphQLError{Name: "Unauthorized", Message: "invalid token"})
+	r := mock.Requester()
+
+	data, err := r.ExecuteGraphql(testQuery, map[string]interface{}{"first": 1}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", data["current_account"].(map[string]interface{})["id"])
+
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	var graphqlErr *requester.GraphQLError
+	require.ErrorAs(t, err, &graphqlErr)
+	require.Equal(t, "Unauthorized", graphqlErr.Name)
+
+	calls := mock.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, "CurrentAccount", calls[0].OperationName)
+	require.Equal(t, float64(1), calls[0].Variables["first"])
+}
+
+func TestRecorder(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	recorder := requestertest.NewRecorder(nil)
+	r.HTTPClient = &http.Client{Transport: recorder}
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	require.NoError(t, recorder.Save(path))
+
+	mock := requestertest.NewMock()
+	require.NoError(t, mock.LoadFixtures(path))
+	data, err := mock.Requester().ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "account:1", data["current_account"].(map[string]interface{})["id"])
+}
+
+func TestMock_TransportOptionsDoNotBypassTheMock(t *testing.T) {
+	mock := requestertest.NewMock()
+	_, err := requester.NewRequesterWithOptions("client_id", "client_secret",
+		requester.WithHTTPClient(&http.Client{Transport: mock}), requester.WithProxyURL(nil))
+	require.ErrorIs(t, err, requester.ErrUnsupportedTransport)
+
+	r := mock.Requester()
+	requester.WithProxyURL(nil)(r)
+	_, err = r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.ErrorIs(t, err, requester.ErrUnsupportedTransport)
+	require.Empty(t, mock.Calls())
+}
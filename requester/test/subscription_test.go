@@ -0,0 +1,70 @@
+package requester_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/lightsparkdev/go-sdk/experimental"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+)
+
+const testSubscription = "subscription PaymentEvents { payment_events { id } }"
+
+func TestExecuteGraphqlSubscription(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"graphql-transport-ws"}}
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var message map[string]interface{}
+		require.NoError(t, conn.ReadJSON(&message))
+		require.Equal(t, "connection_init", message["type"])
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"type": "connection_ack"}))
+		require.NoError(t, conn.ReadJSON(&message))
+		require.Equal(t, "subscribe", message["type"])
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"id": "1", "type": "next",
+			"payload": map[string]interface{}{"data": map[string]interface{}{"payment_events": map[string]interface{}{"id": "payment:1"}}}}))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"id": "1", "type": "complete"}))
+	})
+
+	_, err := r.ExecuteGraphqlSubscription(context.Background(), testSubscription, nil)
+	require.Error(t, err)
+
+	r.ExperimentalFeatures = experimental.NewFeatures(experimental.FeatureSubscriptions)
+	events, err := r.ExecuteGraphqlSubscription(context.Background(), testSubscription, nil)
+	require.NoError(t, err)
+	event := <-events
+	require.Equal(t, "payment:1", event["payment_events"].(map[string]interface{})["id"])
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestExecuteGraphqlSubscription_UsesTransportTLSConfig(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"graphql-transport-ws"}}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var message map[string]interface{}
+		require.NoError(t, conn.ReadJSON(&message))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"type": "connection_ack"}))
+		require.NoError(t, conn.ReadJSON(&message))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"id": "1", "type": "complete"}))
+	}))
+	t.Cleanup(server.Close)
+	r := requester.NewRequesterWithBaseUrl("client_id", "client_secret", &server.URL)
+	// The client of the test server trusts its self-signed certificate.
+	r.HTTPClient = server.Client()
+	r.ExperimentalFeatures = experimental.NewFeatures(experimental.FeatureSubscriptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := r.ExecuteGraphqlSubscription(ctx, testSubscription, nil)
+	require.NoError(t, err)
+	_, ok := <-events
+	require.False(t, ok)
+}
@@ -0,0 +1,133 @@
+package requester_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordingTracerProvider struct {
+	noop.TracerProvider
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (p *recordingTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p, name: name}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingTracerProvider
+	name     string
+}
+
+func (t recordingTracer) Start(ctx context.Context, spanName string, options ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &recordedSpan{
+		tracerName: t.name,
+		name:       spanName,
+		kind:       config.SpanKind(),
+		attributes: map[attribute.Key]attribute.Value{},
+	}
+	span.SetAttributes(config.Attributes()...)
+	t.provider.mutex.Lock()
+	t.provider.spans = append(t.provider.spans, span)
+	t.provider.mutex.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	noop.Span
+	tracerName string
+	name       string
+	kind       trace.SpanKind
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	errors     []error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	for _, kv := range attributes {
+		s.attributes[kv.Key] = kv.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordedSpan) RecordError(err error, options ...trace.EventOption) {
+	s.errors = append(s.errors, err)
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+func TestTracing_GraphqlSpans(t *testing.T) {
+	attempts := 0
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data": {"current_account": {"id": "account:1"}}}`))
+	})
+	tracerProvider := &recordingTracerProvider{}
+	r.TracerProvider = tracerProvider
+	r.RetryPolicy = &requester.RetryPolicy{MaxAttempts: 2, InitialBackoff: 1}
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	_, err = r.ExecuteGraphql("mutation CancelInvoice { cancel_invoice { invoice { id } } }",
+		map[string]interface{}{}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, tracerProvider.spans, 2)
+	span := tracerProvider.spans[0]
+	require.Equal(t, requester.TRACER_NAME, span.tracerName)
+	require.Equal(t, "graphql CurrentAccount", span.name)
+	require.Equal(t, trace.SpanKindClient, span.kind)
+	require.Equal(t, "CurrentAccount", span.attributes["graphql.operation.name"].AsString())
+	require.Equal(t, "query", span.attributes["graphql.operation.type"].AsString())
+	require.Equal(t, int64(1), span.attributes["lightspark.retry_count"].AsInt64())
+	require.Equal(t, int64(http.StatusOK), span.attributes["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Unset, span.status)
+	require.True(t, span.ended)
+
+	span = tracerProvider.spans[1]
+	require.Equal(t, "graphql CancelInvoice", span.name)
+	require.Equal(t, "mutation", span.attributes["graphql.operation.type"].AsString())
+	require.Equal(t, int64(0), span.attributes["lightspark.retry_count"].AsInt64())
+	require.True(t, span.ended)
+}
+
+func TestTracing_GraphqlSpanRecordsErrors(t *testing.T) {
+	r := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	tracerProvider := &recordingTracerProvider{}
+	r.TracerProvider = tracerProvider
+
+	_, err := r.ExecuteGraphql(testQuery, map[string]interface{}{}, nil)
+	require.Error(t, err)
+
+	require.Len(t, tracerProvider.spans, 1)
+	span := tracerProvider.spans[0]
+	require.Equal(t, int64(http.StatusInternalServerError), span.attributes["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Error, span.status)
+	require.Len(t, span.errors, 1)
+	require.True(t, span.ended)
+}
@@ -0,0 +1,68 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+)
+
+// WithClientCertificate authenticates requests with a TLS client certificate, for gateways requiring mutual TLS. The
+// certificate can be loaded with tls.LoadX509KeyPair. See WithHTTPClient for custom clients.
+func WithClientCertificate(certificate tls.Certificate) Option {
+	return func(r *Requester) {
+		r.configureTLS(func(config *tls.Config) {
+			config.Certificates = append(config.Certificates, certificate)
+		})
+	}
+}
+
+// WithRootCAs verifies the server certificate against the given certificate authorities instead of the system ones,
+// e.g. for a gateway with a private CA. See WithHTTPClient for custom clients.
+func WithRootCAs(rootCAs *x509.CertPool) Option {
+	return func(r *Requester) {
+		r.configureTLS(func(config *tls.Config) {
+			config.RootCAs = rootCAs
+		})
+	}
+}
+
+// configureTLS modifies the TLS configuration of a copy of the HTTP client and of its transport.
+func (r *Requester) configureTLS(configure func(config *tls.Config)) {
+	r.configureTransport(func(transport *http.Transport) {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		configure(transport.TLSClientConfig)
+	})
+}
+
+// ErrUnsupportedTransport is returned by NewRequesterWithOptions, and by the requests of the Requester, when an option
+// configuring the transport, e.g. WithClientCertificate or WithProxy, is applied to an HTTP client whose transport is
+// not an *http.Transport, e.g. a mock or an instrumented http.RoundTripper. Such transports are not replaced, since
+// the requests would silently bypass them: configure the transport wrapped by them instead.
+var ErrUnsupportedTransport = errors.New("the transport of the HTTP client is not an *http.Transport")
+
+// configureTransport modifies a copy of the HTTP client and of its transport, as documented on WithHTTPClient, for
+// the options configuring the transport. If the transport is set and is not an *http.Transport, the client is left
+// unchanged and ErrUnsupportedTransport is reported.
+func (r *Requester) configureTransport(configure func(transport *http.Transport)) {
+	httpClient := &http.Client{}
+	if r.HTTPClient != nil {
+		clientCopy := *r.HTTPClient
+		httpClient = &clientCopy
+	}
+	var transport *http.Transport
+	if httpClient.Transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	} else if httpTransport, ok := httpClient.Transport.(*http.Transport); ok {
+		transport = httpTransport.Clone()
+	} else {
+		r.optionErr = ErrUnsupportedTransport
+		return
+	}
+	configure(transport)
+	httpClient.Transport = transport
+	r.HTTPClient = httpClient
+}
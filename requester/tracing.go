@@ -0,0 +1,51 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TRACER_NAME is the instrumentation name of the spans created by the SDK.
+const TRACER_NAME = "github.com/lightsparkdev/go-sdk"
+
+// startGraphqlSpan starts the span of a GraphQL request if a TracerProvider is configured. The returned span is a
+// no-op span otherwise.
+func (r *Requester) startGraphqlSpan(ctx context.Context, request *GraphqlRequest) (context.Context, trace.Span) {
+	if r.TracerProvider == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	operationType := "query"
+	if request.IsMutation {
+		operationType = "mutation"
+	}
+	return r.TracerProvider.Tracer(TRACER_NAME).Start(ctx, "graphql "+request.OperationName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("graphql.operation.name", request.OperationName),
+			attribute.String("graphql.operation.type", operationType),
+			attribute.Int("lightspark.retry_count", 0),
+		))
+}
+
+// endGraphqlSpan records the outcome of a GraphQL request on its span and ends it.
+func endGraphqlSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordGraphqlAttempt records the HTTP status code of an attempt and the number of retries so far on the span of a
+// GraphQL request.
+func recordGraphqlAttempt(ctx context.Context, attempt int, statusCode int) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int("lightspark.retry_count", attempt-1))
+	if statusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+}
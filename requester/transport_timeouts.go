@@ -0,0 +1,46 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// DEFAULT_TCP_KEEP_ALIVE is the keep-alive period of the connections dialed with WithHandshakeTimeouts, like
+// http.DefaultTransport.
+const DEFAULT_TCP_KEEP_ALIVE = 30 * time.Second
+
+// WithForceHTTP2 negotiates HTTP/2 with the server on a transport which does not attempt it, e.g. an http.Transport
+// created for WithHTTPClient with a custom TLS configuration or dialer, which disables HTTP/2 unless ForceAttemptHTTP2
+// is set. The transports cloned from http.DefaultTransport already attempt HTTP/2. See WithHTTPClient for custom
+// clients.
+func WithForceHTTP2() Option {
+	return func(r *Requester) {
+		r.configureTransport(func(transport *http.Transport) {
+			transport.ForceAttemptHTTP2 = true
+		})
+	}
+}
+
+// WithHandshakeTimeouts bounds the time spent establishing connections, so that a slow connection fails fast enough
+// to be retried within the timeout of the request. Zero values keep the current timeouts. See WithHTTPClient for
+// custom clients.
+//
+// Args:
+//
+//	dialTimeout: the maximum duration of the TCP connection.
+//	tlsHandshakeTimeout: the maximum duration of the TLS handshake.
+func WithHandshakeTimeouts(dialTimeout time.Duration, tlsHandshakeTimeout time.Duration) Option {
+	return func(r *Requester) {
+		r.configureTransport(func(transport *http.Transport) {
+			if dialTimeout > 0 {
+				dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: DEFAULT_TCP_KEEP_ALIVE}
+				transport.DialContext = dialer.DialContext
+			}
+			if tlsHandshakeTimeout > 0 {
+				transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package requester
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// VariableValidationError is returned when the variables of a request do not match the variable definitions of its
+// operation.
+type VariableValidationError struct {
+	OperationName string
+	// Problems lists every mismatch found, e.g. `missing required variable $node_id`.
+	Problems []string
+}
+
+func (e *VariableValidationError) Error() string {
+	return "invalid variables for " + e.OperationName + ": " + strings.Join(e.Problems, "; ")
+}
+
+type variableDefinition struct {
+	name       string
+	typeName   string
+	hasDefault bool
+}
+
+// ValidateVariables checks the variables of a request against the variable definitions of its operation: required
+// variables must be present and not null, variables which are not defined are rejected, and values of the builtin
+// scalar types (String, ID, Int, Float, Boolean) must have a matching JSON type. Enums and input objects are not
+// type checked.
+func ValidateVariables(query string, variables map[string]interface{}) error {
+	operationName, definitions, err := parseVariableDefinitions(query)
+	if err != nil {
+		return err
+	}
+	normalizedVariables, err := normalizeVariables(variables)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	defined := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		defined[definition.name] = true
+		value, ok := normalizedVariables[definition.name]
+		if !ok || value == nil {
+			if strings.HasSuffix(definition.typeName, "!") && !definition.hasDefault {
+				problems = append(problems, "missing required variable $"+definition.name)
+			}
+			continue
+		}
+		if !valueMatchesType(value, definition.typeName) {
+			problems = append(problems, "variable $"+definition.name+" does not match type "+definition.typeName)
+		}
+	}
+	var extras []string
+	for name := range normalizedVariables {
+		if !defined[name] {
+			extras = append(extras, "unexpected variable $"+name)
+		}
+	}
+	sort.Strings(extras)
+	problems = append(problems, extras...)
+
+	if len(problems) > 0 {
+		return &VariableValidationError{OperationName: operationName, Problems: problems}
+	}
+	return nil
+}
+
+// parseVariableDefinitions parses the variable definitions of the first operation of a query, e.g.
+// `query GetEntity($id: ID!, $first: Int = 10)`. The query is tokenized, so that default values and directives
+// containing parentheses or dollar signs, e.g. in strings, are skipped.
+func parseVariableDefinitions(query string) (string, []variableDefinition, error) {
+	tokens, err := tokenizeGraphql(query)
+	if err != nil {
+		return "", nil, errors.New("invalid query payload")
+	}
+	operationIndex, start, end := variableDefinitionTokens(tokens)
+	if operationIndex < 0 {
+		return "", nil, errors.New("invalid query payload")
+	}
+	operationName := tokens[operationIndex].value
+	if end >= len(tokens) && start < end {
+		return "", nil, errors.New("invalid variable definitions in " + operationName)
+	}
+
+	var definitions []variableDefinition
+	for i := start; i < end; {
+		if !tokens[i].is("$") || i+2 >= end || tokens[i+1].kind != graphqlName || !tokens[i+2].is(":") {
+			return "", nil, errors.New("invalid variable definitions in " + operationName)
+		}
+		definition := variableDefinition{name: tokens[i+1].value}
+		i += 3
+		var typeName strings.Builder
+		for ; i < end && (tokens[i].kind == graphqlName || tokens[i].is("[") || tokens[i].is("]") ||
+			tokens[i].is("!")); i++ {
+			typeName.WriteString(tokens[i].value)
+		}
+		definition.typeName = typeName.String()
+		if definition.typeName == "" {
+			return "", nil, errors.New("invalid variable definitions in " + operationName)
+		}
+		// Skip the default value and the directives, up to the next definition.
+		nesting := 0
+		for ; i < end && (nesting > 0 || !tokens[i].is("$")); i++ {
+			switch {
+			case nesting == 0 && tokens[i].is("="):
+				definition.hasDefault = true
+			case tokens[i].is("(") || tokens[i].is("[") || tokens[i].is("{"):
+				nesting++
+			case tokens[i].is(")") || tokens[i].is("]") || tokens[i].is("}"):
+				nesting--
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+	return operationName, definitions, nil
+}
+
+// normalizeVariables round trips the variables through JSON, so that values are checked as the server sees them.
+func normalizeVariables(variables map[string]interface{}) (map[string]interface{}, error) {
+	encodedVariables, err := json.Marshal(variables)
+	if err != nil {
+		return nil, errors.New("error when encoding variables")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encodedVariables))
+	decoder.UseNumber()
+	var normalizedVariables map[string]interface{}
+	if err := decoder.Decode(&normalizedVariables); err != nil {
+		return nil, errors.New("error when encoding variables")
+	}
+	return normalizedVariables, nil
+}
+
+func valueMatchesType(value interface{}, typeName string) bool {
+	typeName = strings.TrimSuffix(typeName, "!")
+	if value == nil {
+		return true
+	}
+	if strings.HasPrefix(typeName, "[") && strings.HasSuffix(typeName, "]") {
+		itemType := typeName[1 : len(typeName)-1]
+		items, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one item.
+			return valueMatchesType(value, itemType)
+		}
+		for _, item := range items {
+			if item == nil && strings.HasSuffix(itemType, "!") {
+				return false
+			}
+			if !valueMatchesType(item, itemType) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch typeName {
+	case "String":
+		_, ok := value.(string)
+		return ok
+	case "ID":
+		switch value.(type) {
+		case string, json.Number:
+			return true
+		}
+		return false
+	case "Int":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		// Int is a signed 32-bit integer.
+		integer, err := number.Int64()
+		return err == nil && integer >= math.MinInt32 && integer <= math.MaxInt32
+	case "Float":
+		_, ok := value.(json.Number)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	}
+	return true
+}
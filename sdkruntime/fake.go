@@ -0,0 +1,80 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package sdkruntime
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock which only moves when it is set or advanced, for tests. It is safe for concurrent use.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFakeClock creates a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set sets the clock to now.
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+}
+
+// deterministicEntropy is a stream of bytes derived from a seed with SHA-256 in counter mode.
+type deterministicEntropy struct {
+	mutex   sync.Mutex
+	seed    []byte
+	counter uint64
+	buffer  []byte
+}
+
+// NewDeterministicEntropy returns a source of bytes which is the same for the same seed, for tests. It must never be
+// used outside of tests: its output is predictable.
+func NewDeterministicEntropy(seed string) io.Reader {
+	return &deterministicEntropy{seed: []byte(seed)}
+}
+
+func (e *deterministicEntropy) Read(b []byte) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for read := 0; read < len(b); {
+		if len(e.buffer) == 0 {
+			block := make([]byte, len(e.seed)+8)
+			copy(block, e.seed)
+			binary.BigEndian.PutUint64(block[len(e.seed):], e.counter)
+			e.counter++
+			sum := sha256.Sum256(block)
+			e.buffer = sum[:]
+		}
+		n := copy(b[read:], e.buffer)
+		e.buffer = e.buffer[n:]
+		read += n
+	}
+	return len(b), nil
+}
+
+// NewDeterministicRuntime returns a Runtime with a FakeClock set to now and entropy derived from seed, for tests.
+func NewDeterministicRuntime(now time.Time, seed string) (*Runtime, *FakeClock) {
+	clock := NewFakeClock(now)
+	return &Runtime{Clock: clock, Entropy: NewDeterministicEntropy(seed)}, clock
+}
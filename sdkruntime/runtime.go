@@ -0,0 +1,108 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+
+// Package sdkruntime centralizes the access of the SDK to the clock and to the source of cryptographic randomness, so
+// that integration tests can make nonces, timestamps and expiries deterministic by injecting a Runtime, e.g. with
+// requester.WithRuntime, instead of monkey-patching time.Now or crypto/rand.
+//
+// The clock drives the timestamps and expiries sent to the counterparties, and the cache lifetimes, cooldowns and
+// rate limits of the SDK. Timers, timeouts, network deadlines and measured durations always use the real clock.
+package sdkruntime
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/defaultruntime"
+)
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the real clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// Runtime is the clock and the source of randomness used by the SDK. A nil *Runtime, or a nil field, uses the
+// Default runtime, whose zero value uses the real clock and crypto/rand.
+type Runtime struct {
+	// Clock is the clock. Defaults to the clock of the Default runtime, or SystemClock.
+	Clock Clock
+	// Entropy is the source of random bytes, e.g. for nonces, tokens and keys. Defaults to the entropy of the Default
+	// runtime, or crypto/rand.Reader.
+	Entropy io.Reader
+}
+
+// Default returns the runtime used where none is injected: the real runtime, unless a test of the SDK replaced it.
+func Default() *Runtime {
+	if runtime, ok := defaultruntime.Load().(*Runtime); ok && runtime != nil {
+		return runtime
+	}
+	return &Runtime{}
+}
+
+// Now returns the current time of the clock.
+func (rt *Runtime) Now() time.Time {
+	if rt != nil && rt.Clock != nil {
+		return rt.Clock.Now()
+	}
+	if fallback := Default(); rt != fallback && fallback.Clock != nil {
+		return fallback.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Since returns the time elapsed since t according to the clock.
+func (rt *Runtime) Since(t time.Time) time.Duration {
+	return rt.Now().Sub(t)
+}
+
+// Until returns the duration until t according to the clock.
+func (rt *Runtime) Until(t time.Time) time.Duration {
+	return t.Sub(rt.Now())
+}
+
+// Reader returns the source of random bytes.
+func (rt *Runtime) Reader() io.Reader {
+	if rt != nil && rt.Entropy != nil {
+		return rt.Entropy
+	}
+	if fallback := Default(); rt != fallback && fallback.Entropy != nil {
+		return fallback.Entropy
+	}
+	return rand.Reader
+}
+
+// Read fills b with random bytes.
+func (rt *Runtime) Read(b []byte) (int, error) {
+	return io.ReadFull(rt.Reader(), b)
+}
+
+// Int returns a uniform random value in [0, max), like crypto/rand.Int.
+func (rt *Runtime) Int(max *big.Int) (*big.Int, error) {
+	return rand.Int(rt.Reader(), max)
+}
+
+// Now returns the current time of the Default runtime.
+func Now() time.Time {
+	return Default().Now()
+}
+
+// Since returns the time elapsed since t according to the Default runtime.
+func Since(t time.Time) time.Duration {
+	return Default().Since(t)
+}
+
+// Until returns the duration until t according to the Default runtime.
+func Until(t time.Time) time.Duration {
+	return Default().Until(t)
+}
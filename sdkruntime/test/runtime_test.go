@@ -0,0 +1,38 @@
+package sdkruntime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicRuntime(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	runtime, clock := sdkruntime.NewDeterministicRuntime(now, "seed")
+	otherRuntime, _ := sdkruntime.NewDeterministicRuntime(now, "seed")
+
+	bytes, otherBytes := make([]byte, 48), make([]byte, 48)
+	_, err := runtime.Read(bytes)
+	require.NoError(t, err)
+	_, err = otherRuntime.Read(otherBytes)
+	require.NoError(t, err)
+	require.Equal(t, bytes, otherBytes)
+	_, err = runtime.Read(otherBytes)
+	require.NoError(t, err)
+	require.NotEqual(t, bytes, otherBytes)
+
+	require.Equal(t, now, runtime.Now())
+	clock.Advance(time.Minute)
+	require.Equal(t, time.Minute, runtime.Since(now))
+
+	var nilRuntime *sdkruntime.Runtime
+	require.WithinDuration(t, time.Now(), nilRuntime.Now(), time.Second)
+	restore := sdkruntimetest.SetDefault(runtime)
+	require.Equal(t, now.Add(time.Minute), nilRuntime.Now())
+	require.Equal(t, now.Add(time.Minute), sdkruntime.Now())
+	restore()
+	require.WithinDuration(t, time.Now(), sdkruntime.Now(), time.Second)
+}
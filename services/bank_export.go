@@ -0,0 +1,238 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// CREDIT_NOTIFICATION_CURRENCY is the currency of CreditNotification amounts. Bitcoin has no ISO 4217 code, so the
+// common XBT code is used.
+const CREDIT_NOTIFICATION_CURRENCY = "XBT"
+
+// CreditNotification is a canonical notification of a settled incoming payment, modeled after the entries of ISO
+// 20022 camt.054 bank-to-customer debit/credit notifications, for feeding core banking systems. Its JSON encoding is
+// stable, and MarshalCamt054 encodes notifications as a camt.054 document.
+type CreditNotification struct {
+	// NotificationId identifies the notification. It is the id of the webhook event which produced it, so that a
+	// redelivered event produces the same notification.
+	NotificationId string `json:"notification_id"`
+	// AccountId is the id of the node which received the payment.
+	AccountId string `json:"account_id"`
+	// EntryReference is the id of the incoming payment.
+	EntryReference string `json:"entry_reference"`
+	// AmountMsats is the amount received, in millisatoshis.
+	AmountMsats int64 `json:"amount_msats"`
+	// BookingDate is the time the payment was settled.
+	BookingDate time.Time `json:"booking_date"`
+	// PaymentHash is the payment hash of the paid invoice, if any.
+	PaymentHash string `json:"payment_hash,omitempty"`
+	// RemittanceInformation is the memo of the paid invoice, if any.
+	RemittanceInformation string `json:"remittance_information,omitempty"`
+	// IsUma is true if the payment was received through UMA.
+	IsUma bool `json:"is_uma"`
+}
+
+// NewCreditNotification creates the CreditNotification of a settled incoming payment.
+//
+// Args:
+//
+//	notificationId: the id of the notification, e.g. the id of the webhook event.
+//	payment: the incoming payment, which must be successful.
+//	invoice: the paid invoice, if any, providing the payment hash and memo.
+func NewCreditNotification(notificationId string, payment objects.IncomingPayment,
+	invoice *objects.Invoice) (*CreditNotification, error) {
+	if payment.Status != objects.TransactionStatusSuccess {
+		return nil, errors.New("incoming payment is not settled: " + payment.Id)
+	}
+	amountMsats, err := utils.ValueMilliSatoshi(payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+	bookingDate := payment.UpdatedAt
+	if payment.ResolvedAt != nil {
+		bookingDate = *payment.ResolvedAt
+	}
+	notification := &CreditNotification{
+		NotificationId: notificationId,
+		AccountId:      payment.Destination.Id,
+		EntryReference: payment.Id,
+		AmountMsats:    amountMsats,
+		BookingDate:    bookingDate.UTC(),
+		IsUma:          payment.IsUma,
+	}
+	if invoice != nil {
+		notification.PaymentHash = invoice.Data.PaymentHash
+		if invoice.Data.Memo != nil {
+			notification.RemittanceInformation = *invoice.Data.Memo
+		}
+	}
+	return notification, nil
+}
+
+// GetCreditNotificationForWebhookEvent returns the CreditNotification of the incoming payment settled by a
+// PAYMENT_FINISHED or WALLET_INCOMING_PAYMENT_FINISHED webhook event. It returns nil for other events, and for
+// events about outgoing or failed payments.
+//
+// Args:
+//
+//	event: the verified webhook event.
+func (client *LightsparkClient) GetCreditNotificationForWebhookEvent(event *webhooks.WebhookEvent,
+) (*CreditNotification, error) {
+	if event.EventType != objects.WebhookEventTypePaymentFinished &&
+		event.EventType != objects.WebhookEventTypeWalletIncomingPaymentFinished {
+		return nil, nil
+	}
+	entity, err := client.GetEntity(event.EntityId)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, errors.New("entity not found: " + event.EntityId)
+	}
+	payment, ok := (*entity).(objects.IncomingPayment)
+	if !ok || payment.Status != objects.TransactionStatusSuccess {
+		return nil, nil
+	}
+	var invoice *objects.Invoice
+	if payment.PaymentRequest != nil {
+		entity, err = client.GetEntity(payment.PaymentRequest.Id)
+		if err != nil {
+			return nil, err
+		}
+		if entity != nil {
+			if paymentInvoice, ok := (*entity).(objects.Invoice); ok {
+				invoice = &paymentInvoice
+			}
+		}
+	}
+	return NewCreditNotification(event.EventId, payment, invoice)
+}
+
+// MarshalCamt054 encodes credit notifications as an ISO 20022 camt.054 document with one notification per account.
+// Amounts are in bitcoin with 11 decimals, so that millisatoshi amounts are exact, which exceeds the 5 decimals
+// allowed by the camt.054 schema: consumers validating the schema strictly need to round them.
+//
+// Args:
+//
+//	messageId: the id of the message, e.g. the id of the export batch.
+//	createdAt: the creation time of the message.
+//	notifications: the notifications to include.
+func MarshalCamt054(messageId string, createdAt time.Time, notifications []CreditNotification) ([]byte, error) {
+	document := camt054Document{
+		Xmlns: "urn:iso:std:iso:20022:tech:xsd:camt.054.001.08",
+		Notification: camt054BankToCustomerNotification{
+			GroupHeader: camt054GroupHeader{MessageId: messageId, CreatedAt: camt054DateTime(createdAt)},
+		},
+	}
+	accountNotifications := map[string]int{}
+	for _, notification := range notifications {
+		index, ok := accountNotifications[notification.AccountId]
+		if !ok {
+			index = len(document.Notification.Notifications)
+			accountNotifications[notification.AccountId] = index
+			document.Notification.Notifications = append(document.Notification.Notifications, camt054Notification{
+				Id:        messageId + "-" + strconv.Itoa(index+1),
+				CreatedAt: camt054DateTime(createdAt),
+				AccountId: notification.AccountId,
+			})
+		}
+		entry := camt054Entry{
+			EntryReference: notification.EntryReference,
+			Amount: camt054Amount{
+				Currency: CREDIT_NOTIFICATION_CURRENCY,
+				Value:    formatBtcAmount(notification.AmountMsats),
+			},
+			CreditDebitIndicator: "CRDT",
+			Status:               "BOOK",
+			BookingDate:          camt054DateTime(notification.BookingDate),
+			ValueDate:            camt054DateTime(notification.BookingDate),
+			EndToEndId:           notification.PaymentHash,
+			TransactionId:        notification.NotificationId,
+		}
+		if entry.EndToEndId == "" {
+			entry.EndToEndId = "NOTPROVIDED"
+		}
+		if notification.RemittanceInformation != "" {
+			entry.RemittanceInformation = &camt054RemittanceInformation{
+				Unstructured: notification.RemittanceInformation,
+			}
+		}
+		document.Notification.Notifications[index].Entries = append(
+			document.Notification.Notifications[index].Entries, entry)
+	}
+	encoded, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), encoded...), nil
+}
+
+// formatBtcAmount formats an amount in millisatoshis as bitcoin with 11 decimals.
+func formatBtcAmount(amountMsats int64) string {
+	const msatsPerBtc = 100_000_000_000
+	sign := ""
+	if amountMsats < 0 {
+		sign = "-"
+		amountMsats = -amountMsats
+	}
+	fraction := strconv.FormatInt(amountMsats%msatsPerBtc, 10)
+	for len(fraction) < 11 {
+		fraction = "0" + fraction
+	}
+	return sign + strconv.FormatInt(amountMsats/msatsPerBtc, 10) + "." + fraction
+}
+
+func camt054DateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+type camt054Document struct {
+	XMLName      xml.Name                          `xml:"Document"`
+	Xmlns        string                            `xml:"xmlns,attr"`
+	Notification camt054BankToCustomerNotification `xml:"BkToCstmrDbtCdtNtfctn"`
+}
+
+type camt054BankToCustomerNotification struct {
+	GroupHeader   camt054GroupHeader    `xml:"GrpHdr"`
+	Notifications []camt054Notification `xml:"Ntfctn"`
+}
+
+type camt054GroupHeader struct {
+	MessageId string `xml:"MsgId"`
+	CreatedAt string `xml:"CreDtTm"`
+}
+
+type camt054Notification struct {
+	Id        string         `xml:"Id"`
+	CreatedAt string         `xml:"CreDtTm"`
+	AccountId string         `xml:"Acct>Id>Othr>Id"`
+	Entries   []camt054Entry `xml:"Ntry"`
+}
+
+type camt054Entry struct {
+	EntryReference        string                        `xml:"NtryRef"`
+	Amount                camt054Amount                 `xml:"Amt"`
+	CreditDebitIndicator  string                        `xml:"CdtDbtInd"`
+	Status                string                        `xml:"Sts>Cd"`
+	BookingDate           string                        `xml:"BookgDt>DtTm"`
+	ValueDate             string                        `xml:"ValDt>DtTm"`
+	EndToEndId            string                        `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	TransactionId         string                        `xml:"NtryDtls>TxDtls>Refs>TxId"`
+	RemittanceInformation *camt054RemittanceInformation `xml:"NtryDtls>TxDtls>RmtInf,omitempty"`
+}
+
+type camt054RemittanceInformation struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+type camt054Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
@@ -0,0 +1,132 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"errors"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+// ErrInsufficientOutgoingLiquidity is returned when the usable channels of a node cannot cover a payment.
+var ErrInsufficientOutgoingLiquidity = errors.New("insufficient outgoing liquidity in the selected channels")
+
+// ChannelBalance is the spendable balance of one channel.
+type ChannelBalance struct {
+	ChannelId    string
+	RemoteNodeId string
+	Status       objects.ChannelStatus
+	// LocalBalanceMsats is the balance which can be sent through the channel.
+	LocalBalanceMsats int64
+}
+
+// ChannelLiquidity is the outgoing liquidity of a node over its usable channels.
+type ChannelLiquidity struct {
+	NodeId string
+	// SendableMsats is the sum of the local balances of Channels.
+	SendableMsats int64
+	Channels      []ChannelBalance
+}
+
+// GetChannelLiquidity returns the outgoing liquidity of a node over its usable channels (online, possibly
+// unbalanced), optionally restricted to channels with the given peers. A payment can still route through other
+// channels: the API does not support first hop constraints, so this is a pre-check rather than a guarantee.
+//
+// Args:
+//
+//	nodeId: the id of the node.
+//	remoteNodeIds: if not empty, only channels with these peers are included.
+func (client *LightsparkClient) GetChannelLiquidity(nodeId string, remoteNodeIds []string) (*ChannelLiquidity, error) {
+	entity, err := client.GetEntity(nodeId)
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[string]bool, len(remoteNodeIds))
+	for _, remoteNodeId := range remoteNodeIds {
+		peers[remoteNodeId] = true
+	}
+
+	statuses := []objects.ChannelStatus{objects.ChannelStatusOk, objects.ChannelStatusUnbalancedForSend,
+		objects.ChannelStatusUnbalancedForReceive}
+	first := int64(reportingPageSize)
+	liquidity := &ChannelLiquidity{NodeId: nodeId}
+	var after *string
+	for {
+		var connection *objects.LightsparkNodeToChannelsConnection
+		switch node := (*entity).(type) {
+		case objects.LightsparkNodeWithOSK:
+			connection, err = node.GetChannels(client.Requester, &first, &statuses, after)
+		case objects.LightsparkNodeWithRemoteSigning:
+			connection, err = node.GetChannels(client.Requester, &first, &statuses, after)
+		default:
+			return nil, errors.New("failed to cast entity to LightsparkNode")
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range connection.Entities {
+			balance := ChannelBalance{ChannelId: channel.Id}
+			if channel.RemoteNode != nil {
+				balance.RemoteNodeId = channel.RemoteNode.Id
+			}
+			if len(peers) > 0 && !peers[balance.RemoteNodeId] {
+				continue
+			}
+			if channel.Status != nil {
+				balance.Status = *channel.Status
+			}
+			if channel.LocalBalance != nil {
+				if balance.LocalBalanceMsats, err = utils.ValueMilliSatoshi(*channel.LocalBalance); err != nil {
+					return nil, err
+				}
+			}
+			liquidity.SendableMsats += balance.LocalBalanceMsats
+			liquidity.Channels = append(liquidity.Channels, balance)
+		}
+		if connection.PageInfo.HasNextPage == nil || !*connection.PageInfo.HasNextPage {
+			break
+		}
+		after = connection.PageInfo.EndCursor
+	}
+	return liquidity, nil
+}
+
+// PayUmaInvoiceWithLiquidityCheck pays an UMA invoice like PayUmaInvoice, after checking that the usable channels of
+// the node with the given peers can cover the amount and the maximum fees. It returns
+// ErrInsufficientOutgoingLiquidity otherwise, so that the caller can pick another node.
+//
+// Args:
+//
+//	nodeId: the id of the node that will pay the invoice.
+//	encodedInvoice: the encoded invoice to pay.
+//	timeoutSecs: the timeout of the payment in seconds.
+//	maximumFeesMsats: the maximum amount of fees that you want to pay for this payment to be sent.
+//	amountMsats: the amount to pay, for zero-amount invoices.
+//	firstHopNodeIds: if not empty, only channels with these peers are counted.
+func (client *LightsparkClient) PayUmaInvoiceWithLiquidityCheck(nodeId string, encodedInvoice string,
+	timeoutSecs int, maximumFeesMsats int64, amountMsats *int64, firstHopNodeIds []string,
+) (*objects.OutgoingPayment, error) {
+	paymentAmountMsats := int64(0)
+	if amountMsats != nil {
+		paymentAmountMsats = *amountMsats
+	} else {
+		paymentRequest, err := client.DecodePaymentRequest(encodedInvoice)
+		if err != nil {
+			return nil, err
+		}
+		invoiceData, ok := (*paymentRequest).(objects.InvoiceData)
+		if !ok {
+			return nil, errors.New("payment request is not an invoice")
+		}
+		paymentAmountMsats = utils.InvoiceDataAmountMsats(&invoiceData)
+	}
+
+	liquidity, err := client.GetChannelLiquidity(nodeId, firstHopNodeIds)
+	if err != nil {
+		return nil, err
+	}
+	if liquidity.SendableMsats < paymentAmountMsats+maximumFeesMsats {
+		return nil, ErrInsufficientOutgoingLiquidity
+	}
+	return client.PayUmaInvoice(nodeId, encodedInvoice, timeoutSecs, maximumFeesMsats, amountMsats)
+}
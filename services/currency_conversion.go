@@ -0,0 +1,32 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+// GetCurrencyAmountConversions converts many amounts to a currency unit at once, e.g. for reporting jobs converting
+// historical amounts to fiat estimates. The API has no conversion query: amounts in bitcoin denominated units are
+// converted exactly, and fiat estimates use the preferred currency values returned by the API with each amount, so
+// no request is sent. See utils.ConvertCurrencyAmount.
+//
+// Args:
+//
+//	amounts: the amounts to convert.
+//	targetUnit: the unit to convert the amounts to.
+func (client *LightsparkClient) GetCurrencyAmountConversions(amounts []objects.CurrencyAmount,
+	targetUnit objects.CurrencyUnit) ([]objects.CurrencyAmount, error) {
+	converted := make([]objects.CurrencyAmount, len(amounts))
+	for i, amount := range amounts {
+		convertedAmount, err := utils.ConvertCurrencyAmount(amount, targetUnit)
+		if err != nil {
+			return nil, errors.New("error converting amount " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		converted[i] = convertedAmount
+	}
+	return converted, nil
+}
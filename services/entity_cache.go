@@ -0,0 +1,163 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+)
+
+// EntityCache caches entities returned by GetEntity, keyed by id.
+type EntityCache interface {
+	// Get returns the cached entity with the given id, or nil if it is not cached.
+	Get(id string) objects.Entity
+	Set(id string, entity objects.Entity)
+	Invalidate(id string)
+}
+
+// DEFAULT_ENTITY_CACHE_CAPACITY is the default number of entities kept by an InMemoryEntityCache.
+const DEFAULT_ENTITY_CACHE_CAPACITY = 10_000
+
+// InMemoryEntityCache is an EntityCache which keeps up to a maximum number of entities in memory for a fixed time,
+// evicting the least recently used entities first. It is safe for concurrent use.
+type InMemoryEntityCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*entityCacheEntry
+	// order lists the entries by use, and ages by caching time, the oldest last.
+	order *list.List
+	ages  *list.List
+}
+
+type entityCacheEntry struct {
+	id       string
+	entity   objects.Entity
+	cachedAt time.Time
+	used     *list.Element
+	cached   *list.Element
+}
+
+// NewInMemoryEntityCache creates an InMemoryEntityCache keeping up to capacity entities for the given time. A capacity
+// below 1 uses DEFAULT_ENTITY_CACHE_CAPACITY. Since entities are also invalidated by webhook events, the ttl only
+// bounds staleness when an event is missed, or when an entity changed as a side effect of an event about another one.
+func NewInMemoryEntityCache(capacity int, ttl time.Duration) *InMemoryEntityCache {
+	if capacity < 1 {
+		capacity = DEFAULT_ENTITY_CACHE_CAPACITY
+	}
+	return &InMemoryEntityCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  map[string]*entityCacheEntry{},
+		order:    list.New(),
+		ages:     list.New(),
+	}
+}
+
+func (c *InMemoryEntityCache) Get(id string) objects.Entity {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evictExpired()
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(entry.used)
+	return entry.entity
+}
+
+func (c *InMemoryEntityCache) Set(id string, entity objects.Entity) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if entry, ok := c.entries[id]; ok {
+		c.remove(entry)
+	}
+	entry := &entityCacheEntry{id: id, entity: entity, cachedAt: sdkruntime.Now()}
+	entry.used = c.order.PushFront(entry)
+	entry.cached = c.ages.PushFront(entry)
+	c.entries[id] = entry
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back().Value.(*entityCacheEntry))
+	}
+}
+
+func (c *InMemoryEntityCache) Invalidate(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if entry, ok := c.entries[id]; ok {
+		c.remove(entry)
+	}
+}
+
+// Clear drops all the cached entities.
+func (c *InMemoryEntityCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[string]*entityCacheEntry{}
+	c.order.Init()
+	c.ages.Init()
+}
+
+// Len returns the number of cached entities, including expired ones which were not evicted yet.
+func (c *InMemoryEntityCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// evictExpired drops the expired entities, starting from the oldest one.
+func (c *InMemoryEntityCache) evictExpired() {
+	for oldest := c.ages.Back(); oldest != nil; oldest = c.ages.Back() {
+		entry := oldest.Value.(*entityCacheEntry)
+		if sdkruntime.Since(entry.cachedAt) <= c.ttl {
+			return
+		}
+		c.remove(entry)
+	}
+}
+
+func (c *InMemoryEntityCache) remove(entry *entityCacheEntry) {
+	c.order.Remove(entry.used)
+	c.ages.Remove(entry.cached)
+	delete(c.entries, entry.id)
+}
+
+// WithEntityCache makes GetEntity read through the given cache. Call InvalidateEntityForWebhookEvent from the webhook
+// handler to drop entities which changed.
+func WithEntityCache(cache EntityCache) Option {
+	return func(client *LightsparkClient) {
+		client.entityCache = cache
+	}
+}
+
+// InvalidateEntityForWebhookEvent drops the entities a webhook event refers to from the entity cache, if any: the
+// entity of the event and its wallet. Events about payments, withdrawals and funds of a node also change the balances
+// of the node and of the account, which they do not identify, so they clear the whole cache if it implements
+// `Clear()`, like InMemoryEntityCache.
+//
+// Args:
+//
+//	event: the verified webhook event.
+func (client *LightsparkClient) InvalidateEntityForWebhookEvent(event *webhooks.WebhookEvent) {
+	if client.entityCache == nil || event == nil {
+		return
+	}
+	switch event.EventType {
+	case objects.WebhookEventTypePaymentFinished, objects.WebhookEventTypeForceClosure,
+		objects.WebhookEventTypeWithdrawalFinished, objects.WebhookEventTypeFundsReceived,
+		objects.WebhookEventTypeNodeStatus, objects.WebhookEventTypeLowBalance, objects.WebhookEventTypeHighBalance:
+		if clearable, ok := client.entityCache.(interface{ Clear() }); ok {
+			clearable.Clear()
+			return
+		}
+	}
+	client.entityCache.Invalidate(event.EntityId)
+	if event.WalletId != nil {
+		client.entityCache.Invalidate(*event.WalletId)
+	}
+}
@@ -2,17 +2,23 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"os"
 	"regexp"
+	"strings"
 
 	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/experimental"
 	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/requester"
 	"github.com/lightsparkdev/go-sdk/scripts"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Option func(*LightsparkClient)
@@ -24,9 +30,110 @@ func WithHTTPClient(c *http.Client) Option {
 	}
 }
 
+// WithBaseUrl sets the base url of the Lightspark API. It is requester.WithBaseUrl applied to the requester of the
+// LightsparkClient.
+func WithBaseUrl(baseUrl string) Option {
+	return WithRequesterOptions(requester.WithBaseUrl(baseUrl))
+}
+
+// WithRetryPolicy retries the GraphQL requests of the LightsparkClient according to the given policy. It is
+// requester.WithRetryPolicy applied to the requester of the LightsparkClient.
+func WithRetryPolicy(policy *requester.RetryPolicy) Option {
+	return WithRequesterOptions(requester.WithRetryPolicy(policy))
+}
+
+// WithDryRun makes the LightsparkClient validate mutating operations, like payments or invoice creations, and return
+// them in a requester.DryRunError instead of sending them. Read operations are still sent.
+func WithDryRun() Option {
+	return func(client *LightsparkClient) {
+		client.Requester.DryRun = true
+	}
+}
+
+// WithRequesterOptions applies requester options to the requester of the LightsparkClient.
+func WithRequesterOptions(options ...requester.Option) Option {
+	return func(client *LightsparkClient) {
+		for _, option := range options {
+			option(client.Requester)
+		}
+	}
+}
+
+// WithExperimentalFeatures enables experimental features on the LightsparkClient and its requester.
+func WithExperimentalFeatures(features ...experimental.Feature) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.ExperimentalFeatures = client.Requester.ExperimentalFeatures.With(features...)
+	}
+}
+
+// WithEnvironment points the LightsparkClient requester to one of the preset environments, using its base URL and
+// default timeout. It overrides the baseUrl passed to NewLightsparkClient. A client set with a previous WithHTTPClient
+// requester option is kept, and only gets the default timeout if it has none.
+func WithEnvironment(environment requester.Environment) Option {
+	return func(client *LightsparkClient) {
+		config := environment.Config()
+		if config.BaseUrl == "" {
+			return
+		}
+		client.Requester.BaseUrl = &config.BaseUrl
+		if client.Requester.HTTPClient == nil || client.Requester.HTTPClient.Timeout == 0 {
+			requester.WithTimeout(config.Timeout)(client.Requester)
+		}
+	}
+}
+
+// WithQuotaBudgeter enforces per-feature request budgets on the LightsparkClient. Use ForFeature to get a client
+// whose requests are accounted to a given feature.
+func WithQuotaBudgeter(budgeter *requester.QuotaBudgeter) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.QuotaBudgeter = budgeter
+	}
+}
+
+// WithTracerProvider creates an OpenTelemetry span for each GraphQL request of the LightsparkClient, using the given
+// TracerProvider.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.TracerProvider = tracerProvider
+	}
+}
+
+// WithMetricsCollector reports the outcome of each GraphQL request of the LightsparkClient to the given collector.
+func WithMetricsCollector(collector requester.MetricsCollector) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.MetricsCollector = collector
+	}
+}
+
+// WithEventSink sends the lifecycle events of the GraphQL requests of the LightsparkClient to the sink.
+func WithEventSink(sink events.Sink) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.EventSink = sink
+	}
+}
+
+// WithLogger sends the network activity of the LightsparkClient to the given structured logger, e.g. a *slog.Logger.
+func WithLogger(logger requester.Logger) Option {
+	return func(client *LightsparkClient) {
+		client.Requester.Logger = logger
+	}
+}
+
+// WithIdempotencyKeys attaches a random idempotency key to every mutation of the LightsparkClient. Mutations are
+// still not retried, see requester.IDEMPOTENCY_KEY_HEADER. Use the WithIdempotencyKey variants of the payment methods
+// to reuse a key across calls.
+func WithIdempotencyKeys() Option {
+	return func(client *LightsparkClient) {
+		client.Requester.IdempotencyKeys = true
+	}
+}
+
 type LightsparkClient struct {
-	Requester *requester.Requester
-	nodeKeys  map[string]requester.SigningKey
+	Requester        *requester.Requester
+	nodeKeys         map[string]requester.SigningKey
+	reputationStore  ReputationStore
+	reputationPolicy ReputationPolicy
+	entityCache      EntityCache
 }
 
 // NewLightsparkClient creates a new LightsparkClient instance
@@ -38,13 +145,68 @@ type LightsparkClient struct {
 //	baseUrl: the base url of the Lightspark API. Should usually be nil to use the default value.
 func NewLightsparkClient(apiTokenClientId string, apiTokenClientSecret string,
 	baseUrl *string, options ...Option) *LightsparkClient {
-	gqlRequester := requester.NewRequesterWithBaseUrl(apiTokenClientId, apiTokenClientSecret, baseUrl)
+	if baseUrl != nil {
+		options = append([]Option{WithBaseUrl(*baseUrl)}, options...)
+	}
+	client, err := NewLightsparkClientWithOptions(apiTokenClientId, apiTokenClientSecret, options...)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// NewLightsparkClientWithOptions creates a new LightsparkClient instance configured with the given options. It
+// returns an error instead of panicking if the resulting base URL is invalid.
+//
+// Args:
+//
+//	apiTokenClientId: the client id of the API token
+//	apiTokenClientSecret: the client secret of the API token
+//	options: the options to apply, in order
+func NewLightsparkClientWithOptions(apiTokenClientId string, apiTokenClientSecret string,
+	options ...Option) (*LightsparkClient, error) {
+	gqlRequester := requester.NewRequester(apiTokenClientId, apiTokenClientSecret)
 	client := &LightsparkClient{Requester: gqlRequester, nodeKeys: map[string]requester.SigningKey{}}
 	for _, option := range options {
 		option(client)
 	}
+	if client.Requester.BaseUrl != nil {
+		if err := client.Requester.ValidateBaseUrl(*client.Requester.BaseUrl); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
 
-	return client
+// NewLightsparkClientFromEnv creates a new LightsparkClient instance with the API token and base URL read from the
+// environment, like requester.NewRequesterFromEnv.
+//
+// Args:
+//
+//	options: the options to apply, in order, after the configuration read from the environment
+func NewLightsparkClientFromEnv(options ...Option) (*LightsparkClient, error) {
+	apiTokenClientId, apiTokenClientSecret, err := requester.ApiTokenFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl := strings.TrimSpace(os.Getenv(requester.ENV_BASE_URL)); baseUrl != "" {
+		options = append([]Option{WithBaseUrl(baseUrl)}, options...)
+	}
+	return NewLightsparkClientWithOptions(apiTokenClientId, apiTokenClientSecret, options...)
+}
+
+// ForFeature returns a LightsparkClient sharing the configuration and node keys of this client, whose requests are
+// accounted to the given feature by the QuotaBudgeter (see WithQuotaBudgeter).
+//
+// Args:
+//
+//	feature: the logical feature name, e.g. "payments" or "reconciliation".
+func (client *LightsparkClient) ForFeature(feature string) *LightsparkClient {
+	featureRequester := client.Requester.Clone()
+	featureRequester.Feature = feature
+	featureClient := *client
+	featureClient.Requester = featureRequester
+	return &featureClient
 }
 
 // CreateApiToken creates a new API token that can be used to authenticate requests
@@ -75,19 +237,14 @@ func (client *LightsparkClient) CreateApiToken(name string, transact bool,
 		"name":        name,
 		"permissions": permissions,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_API_TOKEN_MUTATION, variables, nil)
+	output, err := requester.ExecuteField[struct {
+		ApiToken     objects.ApiToken `json:"api_token"`
+		ClientSecret string           `json:"client_secret"`
+	}](context.Background(), client.Requester, scripts.CREATE_API_TOKEN_MUTATION, variables, nil, "create_api_token")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_api_token"].(map[string]interface{})
-	var apiToken objects.ApiToken
-	apiTokenJson, err := json.Marshal(output["api_token"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing api token")
-	}
-	json.Unmarshal(apiTokenJson, &apiToken)
-	return &scripts.CreateApiTokenOutput{ApiToken: &apiToken, ClientSecret: output["client_secret"].(string)}, nil
+	return &scripts.CreateApiTokenOutput{ApiToken: &output.ApiToken, ClientSecret: output.ClientSecret}, nil
 }
 
 // CreateInvoice generates a Lightning Invoice (follows the Bolt 11 specification)
@@ -112,18 +269,11 @@ func (client *LightsparkClient) CreateInvoice(nodeId string, amountMsats int64,
 	if expirySecs != nil {
 		variables["expiry_secs"] = expirySecs
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_INVOICE_MUTATION, variables, nil)
+	invoice, err := requester.ExecuteField[objects.Invoice](context.Background(), client.Requester,
+		scripts.CREATE_INVOICE_MUTATION, variables, nil, "create_invoice.invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_invoice"].(map[string]interface{})
-	var invoice objects.Invoice
-	invoiceJson, err := json.Marshal(output["invoice"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invoice")
-	}
-	json.Unmarshal(invoiceJson, &invoice)
 	return &invoice, nil
 }
 
@@ -141,26 +291,38 @@ func (client *LightsparkClient) CreateInvoice(nodeId string, amountMsats int64,
 func (client *LightsparkClient) CreateLnurlInvoice(nodeId string, amountMsats int64,
 	metadata string, expirySecs *int32) (*objects.Invoice, error) {
 
+	return client.CreateLnurlInvoiceWithMetadataHash(nodeId, amountMsats, crypto.Sha256HexString(metadata), expirySecs)
+}
+
+// CreateLnurlInvoiceWithMetadataHash creates a new LNURL invoice like CreateLnurlInvoice, but takes the hex-encoded
+// SHA256 hash of the metadata directly. This is useful for integrations which compute the LNURL metadata and its
+// description hash themselves.
+//
+// Args:
+//
+//	nodeId: the id of the node that should be paid
+//	amountMsats: the amount of the invoice in millisatoshis
+//	metadataHash: the hex-encoded SHA256 hash of the metadata, used as the invoice description hash
+//	expirySecs: the expiry of the invoice in seconds. Default value is 86400 (1 day)
+func (client *LightsparkClient) CreateLnurlInvoiceWithMetadataHash(nodeId string, amountMsats int64,
+	metadataHash string, expirySecs *int32) (*objects.Invoice, error) {
+
+	if err := validateMetadataHash(metadataHash); err != nil {
+		return nil, err
+	}
 	variables := map[string]interface{}{
 		"amount_msats":  amountMsats,
 		"node_id":       nodeId,
-		"metadata_hash": crypto.Sha256HexString(metadata),
+		"metadata_hash": metadataHash,
 	}
 	if expirySecs != nil {
 		variables["expiry_secs"] = expirySecs
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_LNURL_INVOICE_MUTATION, variables, nil)
+	invoice, err := requester.ExecuteField[objects.Invoice](context.Background(), client.Requester,
+		scripts.CREATE_LNURL_INVOICE_MUTATION, variables, nil, "create_lnurl_invoice.invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_lnurl_invoice"].(map[string]interface{})
-	var invoice objects.Invoice
-	invoiceJson, err := json.Marshal(output["invoice"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invoice")
-	}
-	json.Unmarshal(invoiceJson, &invoice)
 	return &invoice, nil
 }
 
@@ -178,26 +340,37 @@ func (client *LightsparkClient) CreateLnurlInvoice(nodeId string, amountMsats in
 func (client *LightsparkClient) CreateUmaInvoice(nodeId string, amountMsats int64,
 	metadata string, expirySecs *int32) (*objects.Invoice, error) {
 
+	return client.CreateUmaInvoiceWithMetadataHash(nodeId, amountMsats, crypto.Sha256HexString(metadata), expirySecs)
+}
+
+// CreateUmaInvoiceWithMetadataHash creates a new invoice for the UMA protocol like CreateUmaInvoice, but takes the
+// hex-encoded SHA256 hash of the metadata directly.
+//
+// Args:
+//
+//	nodeId: the id of the node that should be paid
+//	amountMsats: the amount of the invoice in millisatoshis
+//	metadataHash: the hex-encoded SHA256 hash of the metadata, used as the invoice description hash
+//	expirySecs: the expiry of the invoice in seconds. Default value is 86400 (1 day)
+func (client *LightsparkClient) CreateUmaInvoiceWithMetadataHash(nodeId string, amountMsats int64,
+	metadataHash string, expirySecs *int32) (*objects.Invoice, error) {
+
+	if err := validateMetadataHash(metadataHash); err != nil {
+		return nil, err
+	}
 	variables := map[string]interface{}{
 		"amount_msats":  amountMsats,
 		"node_id":       nodeId,
-		"metadata_hash": crypto.Sha256HexString(metadata),
+		"metadata_hash": metadataHash,
 	}
 	if expirySecs != nil {
 		variables["expiry_secs"] = expirySecs
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_UMA_INVOICE_MUTATION, variables, nil)
+	invoice, err := requester.ExecuteField[objects.Invoice](context.Background(), client.Requester,
+		scripts.CREATE_UMA_INVOICE_MUTATION, variables, nil, "create_uma_invoice.invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_uma_invoice"].(map[string]interface{})
-	var invoice objects.Invoice
-	invoiceJson, err := json.Marshal(output["invoice"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invoice")
-	}
-	json.Unmarshal(invoiceJson, &invoice)
 	return &invoice, nil
 }
 
@@ -210,18 +383,11 @@ func (client *LightsparkClient) CancelInvoice(invoiceId string) (*objects.Invoic
 	variables := map[string]interface{}{
 		"invoice_id": invoiceId,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CANCEL_INVOICE_MUTATION, variables, nil)
+	invoice, err := requester.ExecuteField[objects.Invoice](context.Background(), client.Requester,
+		scripts.CANCEL_INVOICE_MUTATION, variables, nil, "cancel_invoice.invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["cancel_invoice"].(map[string]interface{})
-	var invoice objects.Invoice
-	invoiceJson, err := json.Marshal(output["invoice"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing canceled invoice")
-	}
-	json.Unmarshal(invoiceJson, &invoice)
 	return &invoice, nil
 }
 
@@ -233,30 +399,20 @@ func (client *LightsparkClient) CreateNodeWalletAddress(nodeId string) (string,
 	variables := map[string]interface{}{
 		"node_id": nodeId,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_NODE_WALLET_ADDRESS_MUTATION, variables, nil)
-	if err != nil {
-		return "", err
-	}
-	output := response["create_node_wallet_address"].(map[string]interface{})
-	walletAddress := output["wallet_address"].(string)
-	return walletAddress, nil
+	return requester.ExecuteField[string](context.Background(), client.Requester,
+		scripts.CREATE_NODE_WALLET_ADDRESS_MUTATION, variables, nil, "create_node_wallet_address.wallet_address")
 }
 
 func (client *LightsparkClient) CreateNodeWalletAddressWithKeys(nodeId string) (*objects.CreateNodeWalletAddressOutput, error) {
 	variables := map[string]interface{}{
 		"node_id": nodeId,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_NODE_WALLET_ADDRESS_WITH_KEYS_MUTATION, variables, nil)
+	walletAddress, err := requester.ExecuteField[objects.CreateNodeWalletAddressOutput](context.Background(),
+		client.Requester, scripts.CREATE_NODE_WALLET_ADDRESS_WITH_KEYS_MUTATION, variables, nil,
+		"create_node_wallet_address")
 	if err != nil {
 		return nil, err
 	}
-	output := response["create_node_wallet_address"].(map[string]interface{})
-	var walletAddress objects.CreateNodeWalletAddressOutput
-	walletAddressJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing wallet address")
-	}
-	json.Unmarshal(walletAddressJson, &walletAddress)
 	return &walletAddress, nil
 }
 
@@ -278,13 +434,11 @@ func (client *LightsparkClient) CreateTestModeInvoice(localNodeId string, amount
 		"memo":          memo,
 		"invoice_type":  invoiceType,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_TEST_MODE_INVOICE_MUTATION, variables, nil)
+	encodedInvoice, err := requester.ExecuteField[string](context.Background(), client.Requester,
+		scripts.CREATE_TEST_MODE_INVOICE_MUTATION, variables, nil, "create_test_mode_invoice.encoded_payment_request")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_test_mode_invoice"].(map[string]interface{})
-	encodedInvoice := output["encoded_payment_request"].(string)
 	return &encodedInvoice, nil
 }
 
@@ -308,18 +462,11 @@ func (client *LightsparkClient) CreateTestModePayment(localNodeId string,
 		variables["amount_msats"] = amountMsats
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_TEST_MODE_PAYMENT_MUTATION, variables, nil)
+	payment, err := requester.ExecuteField[objects.IncomingPayment](context.Background(), client.Requester,
+		scripts.CREATE_TEST_MODE_PAYMENT_MUTATION, variables, nil, "create_test_mode_payment.incoming_payment")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["create_test_mode_payment"].(map[string]interface{})
-	var payment objects.IncomingPayment
-	paymentJson, err := json.Marshal(output["incoming_payment"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing payment")
-	}
-	json.Unmarshal(paymentJson, &payment)
 	return &payment, nil
 }
 
@@ -353,7 +500,8 @@ func (client *LightsparkClient) DeleteApiToken(apiTokenId string) error {
 	variables := map[string]interface{}{
 		"api_token_id": apiTokenId,
 	}
-	_, err := client.Requester.ExecuteGraphql(scripts.DELETE_API_TOKEN_MUTATION, variables, nil)
+	_, err := requester.Execute[json.RawMessage](context.Background(), client.Requester,
+		scripts.DELETE_API_TOKEN_MUTATION, variables, nil)
 	return err
 }
 
@@ -378,18 +526,11 @@ func (client *LightsparkClient) FundNode(nodeId string, amountSats int64) (
 		return nil, err
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.FUND_NODE_MUTATION, variables, signingKey)
+	amount, err := requester.ExecuteField[objects.CurrencyAmount](context.Background(), client.Requester,
+		scripts.FUND_NODE_MUTATION, variables, signingKey, "fund_node.amount")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["fund_node"].(map[string]interface{})
-	var amount objects.CurrencyAmount
-	amountJson, err := json.Marshal(output["amount"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing amount")
-	}
-	json.Unmarshal(amountJson, &amount)
 	return &amount, nil
 }
 
@@ -402,36 +543,21 @@ func (client *LightsparkClient) GetBitcoinFeeEstimate(
 	bitcoinNetwork objects.BitcoinNetwork) (*objects.FeeEstimate, error) {
 
 	variables := map[string]interface{}{"bitcoin_network": bitcoinNetwork}
-	response, err := client.Requester.ExecuteGraphql(scripts.BITCOIN_FEE_ESTIMATE_QUERY, variables, nil)
+	feeEstimate, err := requester.ExecuteField[objects.FeeEstimate](context.Background(), client.Requester,
+		scripts.BITCOIN_FEE_ESTIMATE_QUERY, variables, nil, "bitcoin_fee_estimate")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["bitcoin_fee_estimate"].(map[string]interface{})
-	var feeEstimate objects.FeeEstimate
-	feeEstimateJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing fee estimate")
-	}
-	json.Unmarshal(feeEstimateJson, &feeEstimate)
 	return &feeEstimate, nil
 }
 
 // GetCurrentAccount returns the current connected account.
 func (client *LightsparkClient) GetCurrentAccount() (*objects.Account, error) {
-	variables := map[string]interface{}{}
-	response, err := client.Requester.ExecuteGraphql(scripts.CURRENT_ACCOUNT_QUERY, variables, nil)
+	account, err := requester.ExecuteField[objects.Account](context.Background(), client.Requester,
+		scripts.CURRENT_ACCOUNT_QUERY, nil, nil, "current_account")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["current_account"].(map[string]interface{})
-	var account objects.Account
-	accountJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing account")
-	}
-	json.Unmarshal(accountJson, &account)
 	return &account, nil
 }
 
@@ -452,18 +578,11 @@ func (client *LightsparkClient) GetLightningFeeEstimateForInvoice(nodeId string,
 		"encoded_payment_request": encodedInvoice,
 		"amount_msats":            amountMsats,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.LIGHTNING_FEE_ESTIMATE_FOR_INVOICE_QUERY, variables, nil)
+	feeEstimate, err := requester.ExecuteField[objects.LightningFeeEstimateOutput](context.Background(), client.Requester,
+		scripts.LIGHTNING_FEE_ESTIMATE_FOR_INVOICE_QUERY, variables, nil, "lightning_fee_estimate_for_invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["lightning_fee_estimate_for_invoice"].(map[string]interface{})
-	var feeEstimate objects.LightningFeeEstimateOutput
-	feeEstimateJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing fee estimate")
-	}
-	json.Unmarshal(feeEstimateJson, &feeEstimate)
 	return &feeEstimate, nil
 }
 
@@ -483,18 +602,11 @@ func (client *LightsparkClient) GetLightningFeeEstimateForNode(nodeId string,
 		"destination_node_public_key": destinationNodePublicKey,
 		"amount_msats":                amountMsats,
 	}
-	response, err := client.Requester.ExecuteGraphql(scripts.LIGHTNING_FEE_ESTIMATE_FOR_NODE_QUERY, variables, nil)
+	feeEstimate, err := requester.ExecuteField[objects.LightningFeeEstimateOutput](context.Background(), client.Requester,
+		scripts.LIGHTNING_FEE_ESTIMATE_FOR_NODE_QUERY, variables, nil, "lightning_fee_estimate_for_node")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["lightning_fee_estimate_for_node"].(map[string]interface{})
-	var feeEstimate objects.LightningFeeEstimateOutput
-	feeEstimateJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing fee estimate")
-	}
-	json.Unmarshal(feeEstimateJson, &feeEstimate)
 	return &feeEstimate, nil
 }
 
@@ -512,7 +624,30 @@ func (client *LightsparkClient) GetLightningFeeEstimateForNode(nodeId string,
 //		It should ONLY be set when the invoice amount is zero.
 func (client *LightsparkClient) PayInvoice(nodeId string, encodedInvoice string,
 	timeoutSecs int, maximumFeesMsats int64, amountMsats *int64) (*objects.OutgoingPayment, error) {
+	return client.PayInvoiceWithIdempotencyKey(nodeId, encodedInvoice, timeoutSecs, maximumFeesMsats, amountMsats, "")
+}
 
+// PayInvoiceWithIdempotencyKey pays an invoice like PayInvoice, attaching an idempotency key to the payment. The key
+// only correlates the attempts of the payment with your own records: the API does not deduplicate payments by it yet,
+// see requester.IDEMPOTENCY_KEY_HEADER, so calling it again with the same key can pay the invoice twice. Before
+// re-sending a payment whose outcome is unknown, e.g. after a network failure, look up the payments of the invoice
+// with FetchOutgoingPaymentsByInvoice, or the payment by its id with GetEntity, and only re-send if none was created.
+// The same applies to a requester.ResponseDecodeError: its Executed field is set, since the payment was sent even
+// though its response could not be decoded.
+//
+// Args:
+//
+//	nodeId: The node from where you want to send the payment.
+//	encodedInvoice: The invoice you want to pay (as defined by the BOLT11 standard).
+//	timeoutSecs: The number of seconds that you are willing to wait for the payment to complete.
+//	maximumFeesMsats: The maximum amount of fees that you are willing to pay for this payment, expressed in mSATs.
+//	amountMsats: The amount you will pay for this invoice, expressed in msats.
+//		It should ONLY be set when the invoice amount is zero.
+//	idempotencyKey: The idempotency key of the payment, e.g. from requester.NewIdempotencyKey. If empty, a key is
+//		only attached when the client was created with WithIdempotencyKeys.
+func (client *LightsparkClient) PayInvoiceWithIdempotencyKey(nodeId string, encodedInvoice string,
+	timeoutSecs int, maximumFeesMsats int64, amountMsats *int64, idempotencyKey string,
+) (*objects.OutgoingPayment, error) {
 	variables := map[string]interface{}{
 		"node_id":            nodeId,
 		"encoded_invoice":    encodedInvoice,
@@ -527,18 +662,12 @@ func (client *LightsparkClient) PayInvoice(nodeId string, encodedInvoice string,
 		return nil, err
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.PAY_INVOICE_MUTATION, variables, signingKey)
+	payment, err := requester.ExecuteField[objects.OutgoingPayment](context.Background(), client.Requester,
+		scripts.PAY_INVOICE_MUTATION, variables, signingKey, "pay_invoice.payment",
+		requester.WithIdempotencyKey(idempotencyKey))
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["pay_invoice"].(map[string]interface{})
-	var payment objects.OutgoingPayment
-	paymentJson, err := json.Marshal(output["payment"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing payment")
-	}
-	json.Unmarshal(paymentJson, &payment)
 	return &payment, nil
 }
 
@@ -556,7 +685,27 @@ func (client *LightsparkClient) PayInvoice(nodeId string, encodedInvoice string,
 //		It should ONLY be set when the invoice amount is zero.
 func (client *LightsparkClient) PayUmaInvoice(nodeId string, encodedInvoice string,
 	timeoutSecs int, maximumFeesMsats int64, amountMsats *int64) (*objects.OutgoingPayment, error) {
+	return client.PayUmaInvoiceWithIdempotencyKey(nodeId, encodedInvoice, timeoutSecs, maximumFeesMsats, amountMsats,
+		"")
+}
 
+// PayUmaInvoiceWithIdempotencyKey pays an UMA invoice like PayUmaInvoice, attaching an idempotency key to the
+// payment. As with PayInvoiceWithIdempotencyKey, the key is only for correlation and calling it again with the same
+// key can pay the invoice twice: look up the outcome of the payment before re-sending it.
+//
+// Args:
+//
+//	nodeId: The node from where you want to send the payment.
+//	encodedInvoice: The invoice you want to pay (as defined by the BOLT11 standard).
+//	timeoutSecs: The number of seconds that you are willing to wait for the payment to complete.
+//	maximumFeesMsats: The maximum amount of fees that you are willing to pay for this payment, expressed in mSATs.
+//	amountMsats: The amount you will pay for this invoice, expressed in msats.
+//		It should ONLY be set when the invoice amount is zero.
+//	idempotencyKey: The idempotency key of the payment. If empty, a key is only attached when the client was
+//		created with WithIdempotencyKeys.
+func (client *LightsparkClient) PayUmaInvoiceWithIdempotencyKey(nodeId string, encodedInvoice string,
+	timeoutSecs int, maximumFeesMsats int64, amountMsats *int64, idempotencyKey string,
+) (*objects.OutgoingPayment, error) {
 	variables := map[string]interface{}{
 		"node_id":            nodeId,
 		"encoded_invoice":    encodedInvoice,
@@ -571,18 +720,12 @@ func (client *LightsparkClient) PayUmaInvoice(nodeId string, encodedInvoice stri
 		return nil, err
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.PAY_UMA_INVOICE_MUTATION, variables, signingKey)
+	payment, err := requester.ExecuteField[objects.OutgoingPayment](context.Background(), client.Requester,
+		scripts.PAY_UMA_INVOICE_MUTATION, variables, signingKey, "pay_uma_invoice.payment",
+		requester.WithIdempotencyKey(idempotencyKey))
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["pay_uma_invoice"].(map[string]interface{})
-	var payment objects.OutgoingPayment
-	paymentJson, err := json.Marshal(output["payment"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing payment")
-	}
-	json.Unmarshal(paymentJson, &payment)
 	return &payment, nil
 }
 
@@ -603,7 +746,26 @@ func (client *LightsparkClient) PayUmaInvoice(nodeId string, encodedInvoice stri
 //	withdrawalMode: The mode that will be used to withdraw the funds.
 func (client *LightsparkClient) RequestWithdrawal(nodeId string, amountSats int64,
 	bitcoinAddress string, withdrawalMode objects.WithdrawalMode) (*objects.WithdrawalRequest, error) {
+	return client.RequestWithdrawalWithIdempotencyKey(nodeId, amountSats, bitcoinAddress, withdrawalMode, "")
+}
 
+// RequestWithdrawalWithIdempotencyKey requests a withdrawal like RequestWithdrawal, attaching an idempotency key to
+// the request. As with PayInvoiceWithIdempotencyKey, the key is only for correlation and calling it again with the
+// same key can withdraw the funds twice: look up the withdrawal requests of the account, or the withdrawal request by
+// its id with GetEntity, before re-sending it.
+//
+// Args:
+//
+//	nodeId: The node from which you'd like to make the withdrawal.
+//	amountSats: The amount you want to withdraw from this node in Satoshis.
+//		Use the special value -1 to withdrawal all funds from this node.
+//	bitcoinAddress: The bitcoin address where you want to receive the funds.
+//	withdrawalMode: The mode that will be used to withdraw the funds.
+//	idempotencyKey: The idempotency key of the withdrawal. If empty, a key is only attached when the client was
+//		created with WithIdempotencyKeys.
+func (client *LightsparkClient) RequestWithdrawalWithIdempotencyKey(nodeId string, amountSats int64,
+	bitcoinAddress string, withdrawalMode objects.WithdrawalMode, idempotencyKey string,
+) (*objects.WithdrawalRequest, error) {
 	variables := map[string]interface{}{
 		"node_id":         nodeId,
 		"amount_sats":     amountSats,
@@ -616,18 +778,12 @@ func (client *LightsparkClient) RequestWithdrawal(nodeId string, amountSats int6
 		return nil, err
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.REQUEST_WITHDRAWAL_MUTATION, variables, signingKey)
+	withdrawalRequest, err := requester.ExecuteField[objects.WithdrawalRequest](context.Background(), client.Requester,
+		scripts.REQUEST_WITHDRAWAL_MUTATION, variables, signingKey, "request_withdrawal.request",
+		requester.WithIdempotencyKey(idempotencyKey))
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["request_withdrawal"].(map[string]interface{})
-	var withdrawalRequest objects.WithdrawalRequest
-	withdrawalRequestJson, err := json.Marshal(output["request"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing withdrawal request")
-	}
-	json.Unmarshal(withdrawalRequestJson, &withdrawalRequest)
 	return &withdrawalRequest, nil
 }
 
@@ -656,18 +812,11 @@ func (client *LightsparkClient) SendPayment(nodeId string, destinationPublicKey
 		return nil, err
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.SEND_PAYMENT_MUTATION, variables, signingKey)
+	payment, err := requester.ExecuteField[objects.OutgoingPayment](context.Background(), client.Requester,
+		scripts.SEND_PAYMENT_MUTATION, variables, signingKey, "send_payment.payment")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["send_payment"].(map[string]interface{})
-	var payment objects.OutgoingPayment
-	paymentJson, err := json.Marshal(output["payment"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing payment")
-	}
-	json.Unmarshal(paymentJson, &payment)
 	return &payment, nil
 }
 
@@ -682,18 +831,11 @@ func (client *LightsparkClient) ScreenNode(
 	provider objects.ComplianceProvider, nodePubkey string) (*objects.RiskRating, error) {
 
 	variables := map[string]interface{}{"provider": provider, "node_pubkey": nodePubkey}
-	response, err := client.Requester.ExecuteGraphql(scripts.SCREEN_NODE_MUTATION, variables, nil)
+	rating, err := requester.ExecuteField[objects.RiskRating](context.Background(), client.Requester,
+		scripts.SCREEN_NODE_MUTATION, variables, nil, "screen_node.rating")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["screen_node"].(map[string]interface{})
-	ratingJson, err := json.Marshal(output["rating"].(string))
-	if err != nil {
-		return nil, errors.New("error parsing rating")
-	}
-	var rating objects.RiskRating
-	json.Unmarshal(ratingJson, &rating)
 	return &rating, nil
 }
 
@@ -716,7 +858,8 @@ func (client *LightsparkClient) RegisterPayment(provider objects.ComplianceProvi
 		"node_pubkey": nodePubkey,
 		"direction":   direction,
 	}
-	_, err := client.Requester.ExecuteGraphql(scripts.REGISTER_PAYMENT_MUTATION, variables, nil)
+	_, err := requester.Execute[json.RawMessage](context.Background(), client.Requester,
+		scripts.REGISTER_PAYMENT_MUTATION, variables, nil)
 	if err != nil {
 		return err
 	} else {
@@ -751,6 +894,11 @@ func (client *LightsparkClient) GetNodeChannelUtxos(nodeId string) ([]string, er
 //
 //	id: The unique ID of the entity.
 func (client *LightsparkClient) GetEntity(id string) (*objects.Entity, error) {
+	if client.entityCache != nil {
+		if entity := client.entityCache.Get(id); entity != nil {
+			return &entity, nil
+		}
+	}
 	variables := map[string]interface{}{
 		"id": id,
 	}
@@ -767,6 +915,9 @@ func (client *LightsparkClient) GetEntity(id string) (*objects.Entity, error) {
 	if err != nil {
 		return nil, err
 	}
+	if client.entityCache != nil {
+		client.entityCache.Set(id, entity)
+	}
 	return &entity, nil
 }
 
@@ -820,18 +971,8 @@ func (client *LightsparkClient) CreateUmaInvitation(inviterUma string) (*objects
 		"inviter_uma": inviterUma,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_UMA_INVITATION_MUTATION, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	output := response["create_uma_invitation"].(map[string]interface{})
-	var invitation objects.UmaInvitation
-	invitationJson, err := json.Marshal(output["invitation"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invitation")
-	}
-	err = json.Unmarshal(invitationJson, &invitation)
+	invitation, err := requester.ExecuteField[objects.UmaInvitation](context.Background(), client.Requester,
+		scripts.CREATE_UMA_INVITATION_MUTATION, variables, nil, "create_uma_invitation.invitation")
 	if err != nil {
 		return nil, err
 	}
@@ -863,18 +1004,9 @@ func (client *LightsparkClient) CreateUmaInvitationWithIncentives(
 		"inviter_region":     inviterRegion,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.CREATE_UMA_INVITATION_WITH_INCENTIVES_MUTATION, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	output := response["create_uma_invitation_with_incentives"].(map[string]interface{})
-	var invitation objects.UmaInvitation
-	invitationJson, err := json.Marshal(output["invitation"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invitation")
-	}
-	err = json.Unmarshal(invitationJson, &invitation)
+	invitation, err := requester.ExecuteField[objects.UmaInvitation](context.Background(), client.Requester,
+		scripts.CREATE_UMA_INVITATION_WITH_INCENTIVES_MUTATION, variables, nil,
+		"create_uma_invitation_with_incentives.invitation")
 	if err != nil {
 		return nil, err
 	}
@@ -897,18 +1029,8 @@ func (client *LightsparkClient) ClaimUmaInvitation(invitationCode string, invite
 		"invitee_uma":     inviteeUma,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.CLAIM_UMA_INVITATION_MUTATION, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	output := response["claim_uma_invitation"].(map[string]interface{})
-	var invitation objects.UmaInvitation
-	invitationJson, err := json.Marshal(output["invitation"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invitation")
-	}
-	err = json.Unmarshal(invitationJson, &invitation)
+	invitation, err := requester.ExecuteField[objects.UmaInvitation](context.Background(), client.Requester,
+		scripts.CLAIM_UMA_INVITATION_MUTATION, variables, nil, "claim_uma_invitation.invitation")
 	if err != nil {
 		return nil, err
 	}
@@ -943,18 +1065,9 @@ func (client *LightsparkClient) ClaimUmaInvitationWithIncentives(
 		"invitee_region":     inviteeRegion,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.CLAIM_UMA_INVITATION_WITH_INCENTIVES_MUTATION, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	output := response["claim_uma_invitation_with_incentives"].(map[string]interface{})
-	var invitation objects.UmaInvitation
-	invitationJson, err := json.Marshal(output["invitation"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invitation")
-	}
-	err = json.Unmarshal(invitationJson, &invitation)
+	invitation, err := requester.ExecuteField[objects.UmaInvitation](context.Background(), client.Requester,
+		scripts.CLAIM_UMA_INVITATION_WITH_INCENTIVES_MUTATION, variables, nil,
+		"claim_uma_invitation_with_incentives.invitation")
 	if err != nil {
 		return nil, err
 	}
@@ -975,17 +1088,8 @@ func (client *LightsparkClient) FetchUmaInvitation(invitationCode string) (*obje
 		"invitation_code": invitationCode,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.FETCH_UMA_INVITATION_QUERY, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var invitation objects.UmaInvitation
-	invitationJson, err := json.Marshal(response["uma_invitation_by_code"].(map[string]interface{}))
-	if err != nil {
-		return nil, errors.New("error parsing invitation")
-	}
-	err = json.Unmarshal(invitationJson, &invitation)
+	invitation, err := requester.ExecuteField[objects.UmaInvitation](context.Background(), client.Requester,
+		scripts.FETCH_UMA_INVITATION_QUERY, variables, nil, "uma_invitation_by_code")
 	if err != nil {
 		return nil, err
 	}
@@ -1000,18 +1104,11 @@ func (client *LightsparkClient) GetWithdrawalFeeEstimate(nodeId string, amountSa
 		"withdrawal_mode": withdrawMode,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.WITHDRAWAL_FEE_ESTIMATE_QUERY, variables, nil)
+	feeEstimate, err := requester.ExecuteField[objects.WithdrawalFeeEstimateOutput](context.Background(), client.Requester,
+		scripts.WITHDRAWAL_FEE_ESTIMATE_QUERY, variables, nil, "withdrawal_fee_estimate")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["withdrawal_fee_estimate"].(map[string]interface{})
-	var feeEstimate objects.WithdrawalFeeEstimateOutput
-	feeEstimateJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing fee estimate")
-	}
-	json.Unmarshal(feeEstimateJson, &feeEstimate)
 	return &feeEstimate, nil
 }
 
@@ -1022,18 +1119,11 @@ func (client *LightsparkClient) FetchOutgoingPaymentsByInvoice(encodedInvoice st
 		"statuses":        statuses,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.OUTGOING_PAYMENTS_FOR_INVOICE_QUERY, variables, nil)
+	payments, err := requester.ExecuteField[objects.OutgoingPaymentsForInvoiceQueryOutput](context.Background(),
+		client.Requester, scripts.OUTGOING_PAYMENTS_FOR_INVOICE_QUERY, variables, nil, "outgoing_payments_for_invoice")
 	if err != nil {
 		return nil, err
 	}
-
-	output := response["outgoing_payments_for_invoice"].(map[string]interface{})
-	var payments objects.OutgoingPaymentsForInvoiceQueryOutput
-	paymentsJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing payments")
-	}
-	json.Unmarshal(paymentsJson, &payments)
 	return &payments, nil
 }
 
@@ -1044,18 +1134,8 @@ func (client *LightsparkClient) FetchIncomingPaymentsByInvoice(invoiceId string,
 		"statuses":   statuses,
 	}
 
-	response, err := client.Requester.ExecuteGraphql(scripts.INCOMING_PAYMENTS_FOR_INVOICE_QUERY, variables, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	output := response["incoming_payments_for_invoice"].(map[string]interface{})
-	var payments objects.IncomingPaymentsForInvoiceQueryOutput
-	paymentsJson, err := json.Marshal(output)
-	if err != nil {
-		return nil, errors.New("error parsing payments")
-	}
-	err = json.Unmarshal(paymentsJson, &payments)
+	payments, err := requester.ExecuteField[objects.IncomingPaymentsForInvoiceQueryOutput](context.Background(),
+		client.Requester, scripts.INCOMING_PAYMENTS_FOR_INVOICE_QUERY, variables, nil, "incoming_payments_for_invoice")
 	if err != nil {
 		return nil, err
 	}
@@ -1075,6 +1155,14 @@ func hashPhoneNumber(e614PhoneNumber string) (*string, error) {
 	return &hashString, nil
 }
 
+func validateMetadataHash(metadataHash string) error {
+	hashBytes, err := hex.DecodeString(metadataHash)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return errors.New("the metadata hash must be a hex-encoded SHA256 hash")
+	}
+	return nil
+}
+
 // getNodeSigningKey returns the signing key of a node.
 //
 // Args:
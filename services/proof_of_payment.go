@@ -0,0 +1,144 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+// ProofOfPayment is a portable proof that an outgoing payment was settled, for dispute resolution and auditors. The
+// preimage hashes to the payment hash, which is committed to by the receiver's signature in the encoded invoice.
+type ProofOfPayment struct {
+	PaymentId      string     `json:"payment_id"`
+	EncodedInvoice *string    `json:"encoded_invoice,omitempty"`
+	PaymentHash    string     `json:"payment_hash"`
+	Preimage       string     `json:"preimage"`
+	AmountMsats    int64      `json:"amount_msats"`
+	FeesMsats      *int64     `json:"fees_msats,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SettledAt      *time.Time `json:"settled_at,omitempty"`
+	// Signature is an optional base64-encoded signature over the proof by the sender, added by Sign.
+	Signature *string `json:"signature,omitempty"`
+}
+
+// Verify checks that the preimage of the proof matches its payment hash.
+func (p *ProofOfPayment) Verify() error {
+	preimageBytes, err := hex.DecodeString(p.Preimage)
+	if err != nil {
+		return errors.New("the preimage is not hex encoded")
+	}
+	hash := sha256.Sum256(preimageBytes)
+	if hex.EncodeToString(hash[:]) != p.PaymentHash {
+		return errors.New("the preimage does not match the payment hash")
+	}
+	return nil
+}
+
+// SignablePayload returns the canonical bytes covered by the signature of the proof.
+func (p *ProofOfPayment) SignablePayload() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign adds a signature over the proof with the given key, e.g. the node's signing key.
+func (p *ProofOfPayment) Sign(signingKey requester.SigningKey) error {
+	payload, err := p.SignablePayload()
+	if err != nil {
+		return err
+	}
+	requester.ObserveSignablePayload(requester.SIGNABLE_PROOF_OF_PAYMENT, p.PaymentId, payload, false)
+	signature, err := signingKey.Sign(payload)
+	if err != nil {
+		return err
+	}
+	encodedSignature := base64.StdEncoding.EncodeToString(signature)
+	p.Signature = &encodedSignature
+	return nil
+}
+
+// GetOutgoingPaymentPreimage returns the hex-encoded preimage of a settled outgoing payment.
+//
+// Args:
+//
+//	paymentId: the id of the outgoing payment.
+func (client *LightsparkClient) GetOutgoingPaymentPreimage(paymentId string) (string, error) {
+	payment, err := client.getOutgoingPayment(paymentId)
+	if err != nil {
+		return "", err
+	}
+	if payment.Status != objects.TransactionStatusSuccess || payment.PaymentPreimage == nil {
+		return "", errors.New("the payment is not settled")
+	}
+	return *payment.PaymentPreimage, nil
+}
+
+// GetProofOfPayment builds a ProofOfPayment for a settled outgoing payment.
+//
+// Args:
+//
+//	paymentId: the id of the outgoing payment.
+func (client *LightsparkClient) GetProofOfPayment(paymentId string) (*ProofOfPayment, error) {
+	payment, err := client.getOutgoingPayment(paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != objects.TransactionStatusSuccess || payment.PaymentPreimage == nil {
+		return nil, errors.New("the payment is not settled")
+	}
+
+	amountMsats, err := utils.ValueMilliSatoshi(payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+	proof := &ProofOfPayment{
+		PaymentId:   payment.Id,
+		Preimage:    *payment.PaymentPreimage,
+		AmountMsats: amountMsats,
+		CreatedAt:   payment.CreatedAt,
+		SettledAt:   payment.ResolvedAt,
+	}
+	if payment.Fees != nil {
+		feesMsats, err := utils.ValueMilliSatoshi(*payment.Fees)
+		if err != nil {
+			return nil, err
+		}
+		proof.FeesMsats = &feesMsats
+	}
+	if payment.TransactionHash != nil {
+		proof.PaymentHash = *payment.TransactionHash
+	}
+	if payment.PaymentRequestData != nil {
+		if invoiceData, ok := (*payment.PaymentRequestData).(objects.InvoiceData); ok {
+			proof.EncodedInvoice = &invoiceData.EncodedPaymentRequest
+			proof.PaymentHash = invoiceData.PaymentHash
+		}
+	}
+	if err := proof.Verify(); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+func (client *LightsparkClient) getOutgoingPayment(paymentId string) (*objects.OutgoingPayment, error) {
+	entity, err := client.GetEntity(paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, errors.New("entity not found: " + paymentId)
+	}
+	payment, ok := (*entity).(objects.OutgoingPayment)
+	if !ok {
+		return nil, errors.New("failed to cast entity to OutgoingPayment")
+	}
+	return &payment, nil
+}
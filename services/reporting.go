@@ -0,0 +1,160 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+const reportingPageSize = 100
+
+// DailyVolume holds the aggregated payment volume of an account for one UTC day.
+type DailyVolume struct {
+	// Date is the start of the UTC day.
+	Date time.Time
+	// ReceivedMsats is the total amount received by successful incoming payments.
+	ReceivedMsats int64
+	// SentMsats is the total amount sent by successful outgoing payments, excluding fees.
+	SentMsats int64
+	// FeesPaidMsats is the total amount of fees paid for successful outgoing payments.
+	FeesPaidMsats int64
+	// IncomingPaymentCount is the number of successful incoming payments.
+	IncomingPaymentCount int64
+	// OutgoingPaymentCount is the number of successful outgoing payments.
+	OutgoingPaymentCount int64
+}
+
+// BalanceSnapshot holds the balances of a node at a given time.
+type BalanceSnapshot struct {
+	Timestamp                time.Time
+	NodeId                   string
+	OwnedBalanceMsats        int64
+	AvailableToSendMsats     int64
+	AvailableToWithdrawMsats int64
+}
+
+// GetDailyVolumes returns the daily received and sent volume and the fees paid by the current account over a date
+// range, one entry per UTC day which had at least one successful payment, sorted by date.
+//
+// Args:
+//
+//	afterDate: the start of the date range.
+//	beforeDate: the end of the date range.
+//	bitcoinNetwork: if set, only payments on this network are included.
+//	nodeId: if set, only payments of this node are included.
+func (client *LightsparkClient) GetDailyVolumes(afterDate time.Time, beforeDate time.Time,
+	bitcoinNetwork *objects.BitcoinNetwork, nodeId *string) ([]DailyVolume, error) {
+
+	account, err := client.GetCurrentAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	types := []objects.TransactionType{objects.TransactionTypeIncomingPayment, objects.TransactionTypeOutgoingPayment}
+	statuses := []objects.TransactionStatus{objects.TransactionStatusSuccess}
+	first := int64(reportingPageSize)
+	volumes := map[time.Time]*DailyVolume{}
+	var after *string
+	for {
+		connection, err := account.GetTransactions(client.Requester, &first, after, &types, &afterDate, &beforeDate,
+			bitcoinNetwork, nodeId, &statuses, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, transaction := range connection.Entities {
+			if err := addToDailyVolumes(volumes, transaction); err != nil {
+				return nil, err
+			}
+		}
+		if connection.PageInfo.HasNextPage == nil || !*connection.PageInfo.HasNextPage {
+			break
+		}
+		after = connection.PageInfo.EndCursor
+	}
+
+	result := make([]DailyVolume, 0, len(volumes))
+	for _, volume := range volumes {
+		result = append(result, *volume)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result, nil
+}
+
+// GetBalanceSnapshots returns the current balances of the given nodes. Reporting pipelines can call this periodically
+// and store the results to build a balance time series.
+//
+// Args:
+//
+//	nodeIds: the ids of the nodes to snapshot.
+func (client *LightsparkClient) GetBalanceSnapshots(nodeIds []string) ([]BalanceSnapshot, error) {
+	snapshots := make([]BalanceSnapshot, 0, len(nodeIds))
+	for _, nodeId := range nodeIds {
+		entity, err := client.GetEntity(nodeId)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return nil, errors.New("entity not found: " + nodeId)
+		}
+		node, ok := (*entity).(objects.LightsparkNode)
+		if !ok {
+			return nil, errors.New("failed to cast entity to LightsparkNode")
+		}
+		snapshot := BalanceSnapshot{Timestamp: client.Requester.Runtime.Now().UTC(), NodeId: nodeId}
+		if balances := node.GetBalances(); balances != nil {
+			snapshot.OwnedBalanceMsats, err = utils.ValueMilliSatoshi(balances.OwnedBalance)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.AvailableToSendMsats, err = utils.ValueMilliSatoshi(balances.AvailableToSendBalance)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.AvailableToWithdrawMsats, err = utils.ValueMilliSatoshi(balances.AvailableToWithdrawBalance)
+			if err != nil {
+				return nil, err
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func addToDailyVolumes(volumes map[time.Time]*DailyVolume, transaction objects.Transaction) error {
+	timestamp := transaction.GetCreatedAt()
+	if resolvedAt := transaction.GetResolvedAt(); resolvedAt != nil {
+		timestamp = *resolvedAt
+	}
+	timestamp = timestamp.UTC()
+	day := time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, time.UTC)
+	volume, ok := volumes[day]
+	if !ok {
+		volume = &DailyVolume{Date: day}
+		volumes[day] = volume
+	}
+
+	amountMsats, err := utils.ValueMilliSatoshi(transaction.GetAmount())
+	if err != nil {
+		return err
+	}
+	switch payment := transaction.(type) {
+	case objects.IncomingPayment:
+		volume.ReceivedMsats += amountMsats
+		volume.IncomingPaymentCount++
+	case objects.OutgoingPayment:
+		volume.SentMsats += amountMsats
+		volume.OutgoingPaymentCount++
+		if payment.Fees != nil {
+			feesMsats, err := utils.ValueMilliSatoshi(*payment.Fees)
+			if err != nil {
+				return err
+			}
+			volume.FeesPaidMsats += feesMsats
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+// ReputationOutcome is an observed outcome of an interaction with a counterparty.
+type ReputationOutcome int
+
+const (
+	ReputationOutcomeUndefined ReputationOutcome = iota
+
+	// ReputationOutcomePaymentSucceeded A payment to the counterparty succeeded.
+	ReputationOutcomePaymentSucceeded
+	// ReputationOutcomePaymentFailed A payment to the counterparty failed.
+	ReputationOutcomePaymentFailed
+	// ReputationOutcomeSignatureFailure A message from the counterparty failed signature verification.
+	ReputationOutcomeSignatureFailure
+	// ReputationOutcomeQuoteDeviation A quote from the counterparty deviated from the expected exchange rate.
+	ReputationOutcomeQuoteDeviation
+)
+
+// ReputationScore aggregates the recorded outcomes for one counterparty.
+type ReputationScore struct {
+	Counterparty      string
+	PaymentSuccesses  int64
+	PaymentFailures   int64
+	SignatureFailures int64
+	QuoteDeviations   int64
+}
+
+// PaymentFailureRate returns the fraction of recorded payments to the counterparty which failed, or 0 if no payments
+// have been recorded.
+func (s ReputationScore) PaymentFailureRate() float64 {
+	total := s.PaymentSuccesses + s.PaymentFailures
+	if total == 0 {
+		return 0
+	}
+	return float64(s.PaymentFailures) / float64(total)
+}
+
+// ReputationStore records per-counterparty outcomes and returns aggregated scores. Counterparties are usually
+// identified by their VASP domain, but any stable identifier works.
+type ReputationStore interface {
+	RecordOutcome(counterparty string, outcome ReputationOutcome) error
+	GetScore(counterparty string) (*ReputationScore, error)
+}
+
+// InMemoryReputationStore is a ReputationStore which keeps scores in memory. It is safe for concurrent use.
+type InMemoryReputationStore struct {
+	mutex  sync.Mutex
+	scores map[string]*ReputationScore
+}
+
+func NewInMemoryReputationStore() *InMemoryReputationStore {
+	return &InMemoryReputationStore{scores: map[string]*ReputationScore{}}
+}
+
+func (s *InMemoryReputationStore) RecordOutcome(counterparty string, outcome ReputationOutcome) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	score, ok := s.scores[counterparty]
+	if !ok {
+		score = &ReputationScore{Counterparty: counterparty}
+		s.scores[counterparty] = score
+	}
+	switch outcome {
+	case ReputationOutcomePaymentSucceeded:
+		score.PaymentSuccesses++
+	case ReputationOutcomePaymentFailed:
+		score.PaymentFailures++
+	case ReputationOutcomeSignatureFailure:
+		score.SignatureFailures++
+	case ReputationOutcomeQuoteDeviation:
+		score.QuoteDeviations++
+	default:
+		return errors.New("invalid reputation outcome")
+	}
+	return nil
+}
+
+func (s *InMemoryReputationStore) GetScore(counterparty string) (*ReputationScore, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	score, ok := s.scores[counterparty]
+	if !ok {
+		return &ReputationScore{Counterparty: counterparty}, nil
+	}
+	scoreCopy := *score
+	return &scoreCopy, nil
+}
+
+// ReputationVerdict is the decision taken by a ReputationPolicy for a counterparty.
+type ReputationVerdict int
+
+const (
+	// ReputationVerdictAllow The counterparty can be paid.
+	ReputationVerdictAllow ReputationVerdict = iota
+	// ReputationVerdictWarn The counterparty can be paid, but the caller should be cautious.
+	ReputationVerdictWarn
+	// ReputationVerdictBlock The counterparty should not be paid.
+	ReputationVerdictBlock
+)
+
+// ReputationPolicy decides whether a counterparty can be paid given its score.
+type ReputationPolicy interface {
+	Evaluate(score ReputationScore) ReputationVerdict
+}
+
+// ThresholdReputationPolicy is a ReputationPolicy based on fixed thresholds. A zero threshold disables that check.
+type ThresholdReputationPolicy struct {
+	// MinPayments is the number of recorded payments required before failure rates are taken into account.
+	MinPayments int64
+	// WarnPaymentFailureRate is the payment failure rate at or above which the verdict is a warning.
+	WarnPaymentFailureRate float64
+	// BlockPaymentFailureRate is the payment failure rate at or above which the counterparty is blocked.
+	BlockPaymentFailureRate float64
+	// BlockSignatureFailures is the number of signature failures at or above which the counterparty is blocked.
+	BlockSignatureFailures int64
+	// WarnQuoteDeviations is the number of quote deviations at or above which the verdict is a warning.
+	WarnQuoteDeviations int64
+}
+
+func (p ThresholdReputationPolicy) Evaluate(score ReputationScore) ReputationVerdict {
+	if p.BlockSignatureFailures > 0 && score.SignatureFailures >= p.BlockSignatureFailures {
+		return ReputationVerdictBlock
+	}
+	verdict := ReputationVerdictAllow
+	if score.PaymentSuccesses+score.PaymentFailures >= p.MinPayments {
+		failureRate := score.PaymentFailureRate()
+		if p.BlockPaymentFailureRate > 0 && failureRate >= p.BlockPaymentFailureRate {
+			return ReputationVerdictBlock
+		}
+		if p.WarnPaymentFailureRate > 0 && failureRate >= p.WarnPaymentFailureRate {
+			verdict = ReputationVerdictWarn
+		}
+	}
+	if p.WarnQuoteDeviations > 0 && score.QuoteDeviations >= p.WarnQuoteDeviations {
+		verdict = ReputationVerdictWarn
+	}
+	return verdict
+}
+
+// ErrCounterpartyBlocked is returned when the reputation policy blocks payments to a counterparty.
+var ErrCounterpartyBlocked = errors.New("counterparty is blocked by the reputation policy")
+
+// WithReputationStore sets the ReputationStore and ReputationPolicy used by the LightsparkClient when paying
+// counterparties.
+func WithReputationStore(store ReputationStore, policy ReputationPolicy) Option {
+	return func(client *LightsparkClient) {
+		client.reputationStore = store
+		client.reputationPolicy = policy
+	}
+}
+
+// CheckCounterpartyReputation evaluates the reputation policy for a counterparty. It returns ErrCounterpartyBlocked
+// if the counterparty should not be paid. If no ReputationStore is configured, all counterparties are allowed.
+//
+// Args:
+//
+//	counterparty: the identifier of the counterparty, usually its VASP domain.
+func (client *LightsparkClient) CheckCounterpartyReputation(counterparty string) (ReputationVerdict, error) {
+	if client.reputationStore == nil || client.reputationPolicy == nil {
+		return ReputationVerdictAllow, nil
+	}
+	score, err := client.reputationStore.GetScore(counterparty)
+	if err != nil {
+		return ReputationVerdictAllow, err
+	}
+	verdict := client.reputationPolicy.Evaluate(*score)
+	if verdict == ReputationVerdictBlock {
+		return verdict, ErrCounterpartyBlocked
+	}
+	return verdict, nil
+}
+
+// RecordCounterpartyOutcome records an outcome for a counterparty in the configured ReputationStore. It is a no-op if
+// no ReputationStore is configured.
+//
+// Args:
+//
+//	counterparty: the identifier of the counterparty, usually its VASP domain.
+//	outcome: the observed outcome.
+func (client *LightsparkClient) RecordCounterpartyOutcome(counterparty string, outcome ReputationOutcome) error {
+	if client.reputationStore == nil {
+		return nil
+	}
+	return client.reputationStore.RecordOutcome(counterparty, outcome)
+}
+
+// PayUmaInvoiceToCounterparty checks the reputation of the counterparty, pays the UMA invoice like PayUmaInvoice, and
+// records the outcome of the payment for the counterparty.
+//
+// Args:
+//
+//	counterparty: the identifier of the receiving counterparty, usually its VASP domain.
+//	nodeId: The node from where you want to send the payment.
+//	encodedInvoice: The invoice you want to pay (as defined by the BOLT11 standard).
+//	timeoutSecs: The number of seconds that you are willing to wait for the payment to complete.
+//	maximumFeesMsats: The maximum amount of fees that you are willing to pay for this payment, expressed in mSATs.
+//	amountMsats: The amount you will pay for this invoice, expressed in msats.
+//		It should ONLY be set when the invoice amount is zero.
+func (client *LightsparkClient) PayUmaInvoiceToCounterparty(counterparty string, nodeId string,
+	encodedInvoice string, timeoutSecs int, maximumFeesMsats int64, amountMsats *int64) (*objects.OutgoingPayment, error) {
+
+	if _, err := client.CheckCounterpartyReputation(counterparty); err != nil {
+		return nil, err
+	}
+	payment, err := client.PayUmaInvoice(nodeId, encodedInvoice, timeoutSecs, maximumFeesMsats, amountMsats)
+	if err != nil {
+		// Errors, e.g. network errors or errors of the Lightspark API, are not responses of the counterparty.
+		return nil, err
+	}
+	if payment.Status == objects.TransactionStatusFailed && isCounterpartyFailure(payment.FailureReason) {
+		client.RecordCounterpartyOutcome(counterparty, ReputationOutcomePaymentFailed)
+	} else if payment.Status == objects.TransactionStatusSuccess {
+		client.RecordCounterpartyOutcome(counterparty, ReputationOutcomePaymentSucceeded)
+	}
+	return payment, nil
+}
+
+// isCounterpartyFailure returns whether a payment failed because of the counterparty, rather than because of the
+// sending node, e.g. its balance.
+func isCounterpartyFailure(reason *objects.PaymentFailureReason) bool {
+	if reason == nil {
+		return true
+	}
+	switch *reason {
+	case objects.PaymentFailureReasonInsufficientBalance, objects.PaymentFailureReasonSelfPayment,
+		objects.PaymentFailureReasonRiskScreeningFailed:
+		return false
+	}
+	return true
+}
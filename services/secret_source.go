@@ -0,0 +1,114 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SecretSource is a store from which the SDK can read secrets such as the API token client secret or node signing
+// keys. Implementations can be backed by an OS keychain, HashiCorp Vault, AWS Secrets Manager, etc. so that secrets
+// never need to sit in environment variables or files on the host.
+type SecretSource interface {
+	// GetSecret returns the raw value of the secret identified by name.
+	GetSecret(name string) ([]byte, error)
+}
+
+// SecretSourceFunc adapts a plain function to the SecretSource interface. This is the easiest way to plug in a
+// keychain or cloud secret manager client without depending on it from this SDK.
+type SecretSourceFunc func(name string) ([]byte, error)
+
+func (f SecretSourceFunc) GetSecret(name string) ([]byte, error) {
+	return f(name)
+}
+
+// VaultSecretSource reads secrets from a HashiCorp Vault KV version 2 secrets engine over its HTTP API.
+type VaultSecretSource struct {
+	// Address is the address of the Vault server, e.g. https://vault.internal:8200.
+	Address string
+	// Token is the Vault token used to authenticate.
+	Token string
+	// MountPath is the mount path of the KV engine. Defaults to "secret".
+	MountPath string
+	// Field is the key within the secret's data holding the value. Defaults to "value".
+	Field string
+	// HTTPClient is the client used to talk to Vault. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (v *VaultSecretSource) GetSecret(name string) ([]byte, error) {
+	mountPath := v.MountPath
+	if mountPath == "" {
+		mountPath = "secret"
+	}
+	field := v.Field
+	if field == "" {
+		field = "value"
+	}
+	secretUrl, err := url.JoinPath(v.Address, "v1", mountPath, "data", name)
+	if err != nil {
+		return nil, errors.New("invalid vault address")
+	}
+
+	request, err := http.NewRequest("GET", secretUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("X-Vault-Token", v.Token)
+
+	httpClient := v.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, errors.New("vault request failed: " + response.Status)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.New("error parsing vault response")
+	}
+	value, ok := result.Data.Data[field].(string)
+	if !ok {
+		return nil, errors.New("secret " + name + " has no field " + field)
+	}
+	return []byte(value), nil
+}
+
+// NewLightsparkClientFromSecretSource creates a new LightsparkClient instance, reading the API token client secret
+// from a SecretSource instead of taking it as a plain argument.
+//
+// Args:
+//
+//	apiTokenClientId: the client id of the API token
+//	source: the SecretSource holding the client secret of the API token
+//	secretName: the name of the client secret in the source
+//	baseUrl: the base url of the Lightspark API. Should usually be nil to use the default value.
+func NewLightsparkClientFromSecretSource(apiTokenClientId string, source SecretSource, secretName string,
+	baseUrl *string, options ...Option) (*LightsparkClient, error) {
+	secret, err := source.GetSecret(secretName)
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl != nil {
+		options = append([]Option{WithBaseUrl(*baseUrl)}, options...)
+	}
+	return NewLightsparkClientWithOptions(apiTokenClientId, strings.TrimSpace(string(secret)), options...)
+}
@@ -0,0 +1,219 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/scripts"
+	lightspark_crypto "github.com/lightsparkdev/lightspark-crypto-uniffi/lightspark-crypto-go"
+)
+
+// SelfCheckConfig lists what SelfCheck verifies in addition to the API credentials.
+type SelfCheckConfig struct {
+	// NodeIds are the nodes which must be reachable, and whose signing keys must be loaded and working.
+	NodeIds []string
+	// WebhookSecret, if set, is checked to look like a webhook signing secret.
+	WebhookSecret string
+	// UmaReceiverAddresses, if set, are UMA addresses served by this integration, e.g. $alice@vasp.example.com. Their
+	// `.well-known/lnurlpubkey` and `.well-known/lnurlp` endpoints must be publicly served.
+	UmaReceiverAddresses []string
+	// HTTPClient is the client used to fetch the UMA endpoints. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// SelfCheckResult is the outcome of one check of SelfCheck.
+type SelfCheckResult struct {
+	// Name identifies the check, e.g. "credentials" or "signing_key:<node id>".
+	Name string
+	// Err is nil if the check passed.
+	Err error
+}
+
+// SelfCheckReport is the outcome of SelfCheck.
+type SelfCheckReport struct {
+	Results []SelfCheckResult
+}
+
+// Ok returns whether all checks passed.
+func (r *SelfCheckReport) Ok() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns the checks which failed.
+func (r *SelfCheckReport) Failures() []SelfCheckResult {
+	var failures []SelfCheckResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+func (r *SelfCheckReport) add(name string, err error) {
+	r.Results = append(r.Results, SelfCheckResult{Name: name, Err: err})
+}
+
+// SelfCheck verifies the configuration of the integration before it sends its first payment: the API credentials,
+// the reachability and signing keys of the nodes, the format of the webhook secret and the UMA well-known endpoints
+// of the receiver addresses. It runs every check even if some fail, and does not send any payment.
+//
+// Args:
+//
+//	ctx: the context of the API and UMA requests.
+//	config: the nodes, webhook secret and UMA addresses to check.
+func (client *LightsparkClient) SelfCheck(ctx context.Context, config SelfCheckConfig) *SelfCheckReport {
+	report := &SelfCheckReport{}
+	_, err := requester.ExecuteField[objects.Account](ctx, client.Requester, scripts.CURRENT_ACCOUNT_QUERY, nil, nil,
+		"current_account")
+	report.add("credentials", err)
+
+	for _, nodeId := range config.NodeIds {
+		report.add("node:"+nodeId, client.checkNode(ctx, nodeId))
+		report.add("signing_key:"+nodeId, client.checkSigningKey(nodeId))
+	}
+	if config.WebhookSecret != "" {
+		report.add("webhook_secret", checkWebhookSecret(config.WebhookSecret))
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	for _, address := range config.UmaReceiverAddresses {
+		user, domain, ok := strings.Cut(strings.TrimPrefix(address, "$"), "@")
+		if !ok {
+			report.add("uma:"+address, errors.New("invalid UMA address"))
+			continue
+		}
+		report.add("uma_pubkey:"+domain, checkUmaEndpoint(ctx, httpClient,
+			"https://"+domain+"/.well-known/lnurlpubkey", "signingPubKey", "signingCertChain"))
+		report.add("uma_lnurlp:"+address, checkUmaEndpoint(ctx, httpClient,
+			"https://"+domain+"/.well-known/lnurlp/"+user, "callback"))
+	}
+	return report
+}
+
+func (client *LightsparkClient) checkNode(ctx context.Context, nodeId string) error {
+	data, err := client.Requester.ExecuteGraphqlWithContext(ctx, objects.GetEntityQuery,
+		map[string]interface{}{"id": nodeId}, nil)
+	if err != nil {
+		return err
+	}
+	output, ok := data["entity"].(map[string]interface{})
+	if !ok {
+		return errors.New("node not found")
+	}
+	entity, err := objects.EntityUnmarshal(output)
+	if err != nil {
+		return err
+	}
+	switch entity.(type) {
+	case objects.LightsparkNodeWithOSK, objects.LightsparkNodeWithRemoteSigning:
+		return nil
+	}
+	return errors.New("entity is not a Lightspark node")
+}
+
+// checkSigningKey signs a random payload with the key of a node, and verifies the signature when the type of the key
+// is known.
+func (client *LightsparkClient) checkSigningKey(nodeId string) error {
+	signingKey, err := client.getNodeSigningKey(nodeId)
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, 32)
+	if _, err := client.Requester.Runtime.Read(payload); err != nil {
+		return err
+	}
+	signature, err := signingKey.Sign(payload)
+	if err != nil {
+		return errors.New("error signing with the node signing key: " + err.Error())
+	}
+
+	switch key := signingKey.(type) {
+	case *requester.Secp256k1SigningKey:
+		_, publicKey := btcec.PrivKeyFromBytes(key.PrivateKey)
+		valid, err := lightspark_crypto.VerifyEcdsa(payload, signature, publicKey.SerializeCompressed())
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("the node signing key produced an invalid signature")
+		}
+	case *requester.RsaSigningKey:
+		privateKey, err := x509.ParsePKCS8PrivateKey(key.PrivateKey)
+		if err != nil {
+			return err
+		}
+		rsaKey, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return errors.New("private key is not an RSA key")
+		}
+		hashed := sha256.Sum256(payload)
+		if err := rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, hashed[:], signature, nil); err != nil {
+			return errors.New("the node signing key produced an invalid signature")
+		}
+	}
+	return nil
+}
+
+func checkWebhookSecret(webhookSecret string) error {
+	if len(webhookSecret) < 16 {
+		return errors.New("webhook secret is too short")
+	}
+	for _, character := range webhookSecret {
+		if unicode.IsSpace(character) || !unicode.IsPrint(character) {
+			return errors.New("webhook secret contains whitespace or unprintable characters")
+		}
+	}
+	return nil
+}
+
+// checkUmaEndpoint fetches an UMA endpoint and checks that it returns a JSON object with one of the given fields.
+func checkUmaEndpoint(ctx context.Context, httpClient *http.Client, endpointUrl string, fields ...string) error {
+	request, err := http.NewRequestWithContext(ctx, "GET", endpointUrl, nil)
+	if err != nil {
+		return err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New(endpointUrl + " returned " + response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return errors.New(endpointUrl + " did not return a JSON object")
+	}
+	for _, field := range fields {
+		if _, ok := result[field]; ok {
+			return nil
+		}
+	}
+	return errors.New(endpointUrl + " is missing " + strings.Join(fields, " or "))
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/hex"
 	"errors"
+	"strings"
 
 	"github.com/lightsparkdev/go-sdk/crypto"
 	"github.com/lightsparkdev/go-sdk/objects"
@@ -15,6 +16,7 @@ type SigningKeyLoader struct {
 	cachedSigningKey     requester.SigningKey
 	masterSeedAndNetwork *masterSeedAndNetwork
 	idPasswordPair       *idPasswordPair
+	secretSourceKey      *secretSourceKey
 }
 
 // NewSigningKeyLoaderFromNodeIdAndPassword creates a new SigningKeyLoader from a node ID and password.
@@ -37,6 +39,19 @@ func NewSigningKeyLoaderFromSignerMasterSeed(masterSeedBytes []byte, network obj
 	}
 }
 
+// NewSigningKeyLoaderFromRsaPrivateKeySecret creates a new SigningKeyLoader which reads a hex-encoded RSA private key
+// from a SecretSource the first time the key is loaded.
+func NewSigningKeyLoaderFromRsaPrivateKeySecret(source SecretSource, secretName string) *SigningKeyLoader {
+	return &SigningKeyLoader{secretSourceKey: &secretSourceKey{source: source, name: secretName}}
+}
+
+// NewSigningKeyLoaderFromSignerMasterSeedSecret creates a new SigningKeyLoader which reads a hex-encoded master seed
+// from a SecretSource the first time the key is loaded. This should be used if you are using remote signing.
+func NewSigningKeyLoaderFromSignerMasterSeedSecret(source SecretSource, secretName string,
+	network objects.BitcoinNetwork) *SigningKeyLoader {
+	return &SigningKeyLoader{secretSourceKey: &secretSourceKey{source: source, name: secretName, network: &network}}
+}
+
 func (s *SigningKeyLoader) LoadSigningKey(req requester.Requester) (requester.SigningKey, error) {
 	if s.cachedSigningKey != nil {
 		return s.cachedSigningKey, nil
@@ -60,9 +75,34 @@ func (s *SigningKeyLoader) LoadSigningKey(req requester.Requester) (requester.Si
 		return key, nil
 	}
 
+	if s.secretSourceKey != nil {
+		key, err := s.loadSigningKeyFromSecretSource()
+		if err != nil {
+			return nil, err
+		}
+		s.cachedSigningKey = key
+		return key, nil
+	}
+
 	return nil, errors.New("invalid signing key loader")
 }
 
+func (s *SigningKeyLoader) loadSigningKeyFromSecretSource() (requester.SigningKey, error) {
+	secret, err := s.secretSourceKey.source.GetSecret(s.secretSourceKey.name)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := hex.DecodeString(strings.TrimSpace(string(secret)))
+	if err != nil {
+		return nil, errors.New("signing key secret is not hex encoded")
+	}
+	if s.secretSourceKey.network == nil {
+		return &requester.RsaSigningKey{PrivateKey: keyBytes}, nil
+	}
+	s.masterSeedAndNetwork = &masterSeedAndNetwork{masterSeed: keyBytes, network: *s.secretSourceKey.network}
+	return s.loadSigningKeyFromMasterSeed()
+}
+
 func (s *SigningKeyLoader) loadSigningKeyFromMasterSeed() (requester.SigningKey, error) {
 	if s.masterSeedAndNetwork == nil {
 		return nil, errors.New("invalid signing key loader")
@@ -120,6 +160,12 @@ type idPasswordPair struct {
 	password string
 }
 
+type secretSourceKey struct {
+	source  SecretSource
+	name    string
+	network *objects.BitcoinNetwork
+}
+
 type masterSeedAndNetwork struct {
 	masterSeed []byte
 	network    objects.BitcoinNetwork
@@ -0,0 +1,278 @@
+package bankexport
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/types"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func incomingPayment(status objects.TransactionStatus, amountMsats int64) objects.IncomingPayment {
+	return objects.IncomingPayment{
+		Id:          "payment:1",
+		UpdatedAt:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Status:      status,
+		Amount:      objects.CurrencyAmount{OriginalValue: amountMsats, OriginalUnit: objects.CurrencyUnitMillisatoshi},
+		IsUma:       true,
+		Destination: types.EntityWrapper{Id: "node:1"},
+	}
+}
+
+func TestNewCreditNotification(t *testing.T) {
+	_, err := services.NewCreditNotification("event:1", incomingPayment(objects.TransactionStatusPending, 1000), nil)
+	require.Error(t, err)
+
+	payment := incomingPayment(objects.TransactionStatusSuccess, 1000)
+	notification, err := services.NewCreditNotification("event:1", payment, nil)
+	require.NoError(t, err)
+	require.Equal(t, services.CreditNotification{
+		NotificationId: "event:1",
+		AccountId:      "node:1",
+		EntryReference: "payment:1",
+		AmountMsats:    1000,
+		BookingDate:    payment.UpdatedAt,
+		IsUma:          true,
+	}, *notification)
+
+	resolvedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	payment.ResolvedAt = &resolvedAt
+	memo := "rent"
+	notification, err = services.NewCreditNotification("event:1", payment,
+		&objects.Invoice{Data: objects.InvoiceData{PaymentHash: "hash", Memo: &memo}})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), notification.BookingDate)
+	require.Equal(t, "hash", notification.PaymentHash)
+	require.Equal(t, "rent", notification.RemittanceInformation)
+
+	encoded, err := json.Marshal(notification)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"notification_id": "event:1",
+		"account_id": "node:1",
+		"entry_reference": "payment:1",
+		"amount_msats": 1000,
+		"booking_date": "2024-01-01T11:00:00Z",
+		"payment_hash": "hash",
+		"remittance_information": "rent",
+		"is_uma": true
+	}`, string(encoded))
+}
+
+func TestMarshalCamt054(t *testing.T) {
+	bookingDate := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	encoded, err := services.MarshalCamt054("export:1", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		[]services.CreditNotification{
+			{NotificationId: "event:1", AccountId: "node:1", EntryReference: "payment:1", AmountMsats: 1,
+				BookingDate: bookingDate, PaymentHash: "hash", RemittanceInformation: "rent"},
+			{NotificationId: "event:2", AccountId: "node:2", EntryReference: "payment:2",
+				AmountMsats: 150_000_000_000, BookingDate: bookingDate},
+			{NotificationId: "event:3", AccountId: "node:1", EntryReference: "payment:3",
+				AmountMsats: -2_100_000_000_000_000, BookingDate: bookingDate},
+		})
+	require.NoError(t, err)
+
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.08">
+  <BkToCstmrDbtCdtNtfctn>
+    <GrpHdr>
+      <MsgId>export:1</MsgId>
+      <CreDtTm>2024-01-02T00:00:00Z</CreDtTm>
+    </GrpHdr>
+    <Ntfctn>
+      <Id>export:1-1</Id>
+      <CreDtTm>2024-01-02T00:00:00Z</CreDtTm>
+      <Acct>
+        <Id>
+          <Othr>
+            <Id>node:1</Id>
+          </Othr>
+        </Id>
+      </Acct>
+      <Ntry>
+        <NtryRef>payment:1</NtryRef>
+        <Amt Ccy="XBT">0.00000000001</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>
+          <Cd>BOOK</Cd>
+        </Sts>
+        <BookgDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </BookgDt>
+        <ValDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </ValDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs>
+              <EndToEndId>hash</EndToEndId>
+              <TxId>event:1</TxId>
+            </Refs>
+            <RmtInf>
+              <Ustrd>rent</Ustrd>
+            </RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <NtryRef>payment:3</NtryRef>
+        <Amt Ccy="XBT">-21000.00000000000</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>
+          <Cd>BOOK</Cd>
+        </Sts>
+        <BookgDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </BookgDt>
+        <ValDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </ValDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs>
+              <EndToEndId>NOTPROVIDED</EndToEndId>
+              <TxId>event:3</TxId>
+            </Refs>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Ntfctn>
+    <Ntfctn>
+      <Id>export:1-2</Id>
+      <CreDtTm>2024-01-02T00:00:00Z</CreDtTm>
+      <Acct>
+        <Id>
+          <Othr>
+            <Id>node:2</Id>
+          </Othr>
+        </Id>
+      </Acct>
+      <Ntry>
+        <NtryRef>payment:2</NtryRef>
+        <Amt Ccy="XBT">1.50000000000</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>
+          <Cd>BOOK</Cd>
+        </Sts>
+        <BookgDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </BookgDt>
+        <ValDt>
+          <DtTm>2024-01-01T11:00:00Z</DtTm>
+        </ValDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs>
+              <EndToEndId>NOTPROVIDED</EndToEndId>
+              <TxId>event:2</TxId>
+            </Refs>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>`, string(encoded))
+}
+
+func TestMarshalCamt054_AmountPrecision(t *testing.T) {
+	for amountMsats, expected := range map[int64]string{
+		0:               "0.00000000000",
+		999:             "0.00000000999",
+		100_000_000_000: "1.00000000000",
+		123_456_789_012: "1.23456789012",
+		-1:              "-0.00000000001",
+	} {
+		encoded, err := services.MarshalCamt054("export:1", time.Time{}, []services.CreditNotification{
+			{AccountId: "node:1", AmountMsats: amountMsats},
+		})
+		require.NoError(t, err)
+		require.Contains(t, string(encoded), `<Amt Ccy="XBT">`+expected+`</Amt>`)
+	}
+}
+
+func TestGetCreditNotificationForWebhookEvent(t *testing.T) {
+	mock := requestertest.NewMock().
+		Handle("GetEntity", func(call requestertest.Call) requestertest.Response {
+			switch call.Variables["id"] {
+			case "payment:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":                       "IncomingPayment",
+					"incoming_payment_id":              "payment:1",
+					"incoming_payment_status":          "SUCCESS",
+					"incoming_payment_updated_at":      "2024-01-01T11:00:00Z",
+					"incoming_payment_destination":     map[string]interface{}{"id": "node:1"},
+					"incoming_payment_payment_request": map[string]interface{}{"id": "invoice:1"},
+					"incoming_payment_amount": map[string]interface{}{
+						"currency_amount_original_value": 1000,
+						"currency_amount_original_unit":  "MILLISATOSHI",
+					},
+				}}}
+			case "payment:2":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":              "IncomingPayment",
+					"incoming_payment_id":     "payment:2",
+					"incoming_payment_status": "FAILED",
+				}}}
+			case "invoice:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename": "Invoice",
+					"invoice_id": "invoice:1",
+					"invoice_data": map[string]interface{}{
+						"__typename":                "InvoiceData",
+						"invoice_data_payment_hash": "hash",
+						"invoice_data_memo":         "rent",
+					},
+				}}}
+			}
+			return requestertest.Response{Data: map[string]interface{}{"entity": nil}}
+		})
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+
+	notification, err := client.GetCreditNotificationForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypePaymentFinished,
+		EventId:   "event:1",
+		EntityId:  "payment:1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, services.CreditNotification{
+		NotificationId:        "event:1",
+		AccountId:             "node:1",
+		EntryReference:        "payment:1",
+		AmountMsats:           1000,
+		BookingDate:           time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+		PaymentHash:           "hash",
+		RemittanceInformation: "rent",
+	}, *notification)
+
+	notification, err = client.GetCreditNotificationForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypePaymentFinished,
+		EntityId:  "payment:2",
+	})
+	require.NoError(t, err)
+	require.Nil(t, notification)
+
+	_, err = client.GetCreditNotificationForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeWalletIncomingPaymentFinished,
+		EntityId:  "payment:3",
+	})
+	require.Error(t, err)
+
+	callCount := len(mock.Calls())
+	notification, err = client.GetCreditNotificationForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeNodeStatus,
+		EntityId:  "payment:1",
+	})
+	require.NoError(t, err)
+	require.Nil(t, notification)
+	require.Len(t, mock.Calls(), callCount)
+}
@@ -0,0 +1,61 @@
+package entitycache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryEntityCache_Capacity(t *testing.T) {
+	cache := services.NewInMemoryEntityCache(2, time.Minute)
+	cache.Set("wallet:1", objects.Wallet{Id: "wallet:1"})
+	cache.Set("wallet:2", objects.Wallet{Id: "wallet:2"})
+	require.NotNil(t, cache.Get("wallet:1"))
+	cache.Set("wallet:3", objects.Wallet{Id: "wallet:3"})
+	require.Equal(t, 2, cache.Len())
+	require.NotNil(t, cache.Get("wallet:1"))
+	require.Nil(t, cache.Get("wallet:2"))
+	require.NotNil(t, cache.Get("wallet:3"))
+}
+
+func TestInMemoryEntityCache_EvictsExpiredEntitiesOnRead(t *testing.T) {
+	cache := services.NewInMemoryEntityCache(10, 20*time.Millisecond)
+	cache.Set("wallet:1", objects.Wallet{Id: "wallet:1"})
+	cache.Set("wallet:2", objects.Wallet{Id: "wallet:2"})
+	time.Sleep(30 * time.Millisecond)
+	cache.Set("wallet:3", objects.Wallet{Id: "wallet:3"})
+	require.NotNil(t, cache.Get("wallet:3"))
+	require.Equal(t, 1, cache.Len())
+	require.Nil(t, cache.Get("wallet:1"))
+}
+
+func TestInvalidateEntityForWebhookEvent(t *testing.T) {
+	cache := services.NewInMemoryEntityCache(10, time.Minute)
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithEntityCache(cache))
+	require.NoError(t, err)
+	for _, id := range []string{"payment:1", "wallet:1", "node:1"} {
+		cache.Set(id, objects.Wallet{Id: id})
+	}
+
+	walletId := "wallet:1"
+	client.InvalidateEntityForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypeWalletIncomingPaymentFinished,
+		EntityId:  "payment:1",
+		WalletId:  &walletId,
+	})
+	require.Nil(t, cache.Get("payment:1"))
+	require.Nil(t, cache.Get("wallet:1"))
+	require.NotNil(t, cache.Get("node:1"))
+
+	// A node payment also changes the balances of the node, which the event does not identify.
+	client.InvalidateEntityForWebhookEvent(&webhooks.WebhookEvent{
+		EventType: objects.WebhookEventTypePaymentFinished,
+		EntityId:  "payment:2",
+	})
+	require.Equal(t, 0, cache.Len())
+}
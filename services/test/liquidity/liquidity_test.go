@@ -0,0 +1,125 @@
+package liquidity
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSigningKey struct{}
+
+func (fakeSigningKey) Sign(payload []byte) ([]byte, error) {
+	return []byte("signature"), nil
+}
+
+func msats(value int64) map[string]interface{} {
+	return map[string]interface{}{
+		"currency_amount_original_value": value,
+		"currency_amount_original_unit":  "MILLISATOSHI",
+	}
+}
+
+func channel(id string, remoteNodeId string, status string, localBalanceMsats int64) map[string]interface{} {
+	return map[string]interface{}{
+		"__typename":            "Channel",
+		"channel_id":            id,
+		"channel_status":        status,
+		"channel_local_balance": msats(localBalanceMsats),
+		"channel_remote_node":   map[string]interface{}{"id": remoteNodeId},
+		"channel_local_node":    map[string]interface{}{"id": "node:1"},
+	}
+}
+
+func channelsPage(hasNextPage bool, endCursor string, channels ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"entity": map[string]interface{}{"channels": map[string]interface{}{
+		"__typename": "LightsparkNodeToChannelsConnection",
+		"lightspark_node_to_channels_connection_count": len(channels),
+		"lightspark_node_to_channels_connection_page_info": map[string]interface{}{
+			"page_info_has_next_page": hasNextPage,
+			"page_info_end_cursor":    endCursor,
+		},
+		"lightspark_node_to_channels_connection_entities": channels,
+	}}}
+}
+
+func newMockClient(t *testing.T) (*services.LightsparkClient, *requestertest.Mock) {
+	mock := requestertest.NewMock()
+	mock.RespondData("GetEntity", map[string]interface{}{"entity": map[string]interface{}{
+		"__typename":                    "LightsparkNodeWithOSK",
+		"lightspark_node_with_o_s_k_id": "node:1",
+	}})
+	mock.Handle("FetchLightsparkNodeToChannelsConnection", func(call requestertest.Call) requestertest.Response {
+		if call.Variables["after"] == nil {
+			return requestertest.Response{Data: channelsPage(true, "cursor:1",
+				channel("channel:1", "node:2", "OK", 3000),
+				channel("channel:2", "node:3", "UNBALANCED_FOR_SEND", 2000))}
+		}
+		return requestertest.Response{Data: channelsPage(false, "cursor:2", channel("channel:3", "node:2", "OK", 1000))}
+	})
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+	client.SetNodeSigningKey("node:1", fakeSigningKey{})
+	return client, mock
+}
+
+func TestGetChannelLiquidity(t *testing.T) {
+	client, mock := newMockClient(t)
+
+	liquidity, err := client.GetChannelLiquidity("node:1", nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(6000), liquidity.SendableMsats)
+	require.Len(t, liquidity.Channels, 3)
+	require.Equal(t, "node:3", liquidity.Channels[1].RemoteNodeId)
+	require.Equal(t, int64(2000), liquidity.Channels[1].LocalBalanceMsats)
+
+	calls := mock.Calls()
+	require.Len(t, calls, 3)
+	require.Nil(t, calls[1].Variables["after"])
+	require.Equal(t, "cursor:1", calls[2].Variables["after"])
+	require.ElementsMatch(t, []interface{}{"OK", "UNBALANCED_FOR_SEND", "UNBALANCED_FOR_RECEIVE"},
+		calls[1].Variables["statuses"])
+}
+
+func TestGetChannelLiquidity_FiltersPeers(t *testing.T) {
+	client, _ := newMockClient(t)
+
+	liquidity, err := client.GetChannelLiquidity("node:1", []string{"node:2"})
+	require.NoError(t, err)
+	require.Equal(t, int64(4000), liquidity.SendableMsats)
+	require.Len(t, liquidity.Channels, 2)
+}
+
+func TestPayUmaInvoiceWithLiquidityCheck(t *testing.T) {
+	client, mock := newMockClient(t)
+	mock.RespondData("DecodedPaymentRequest", map[string]interface{}{"decoded_payment_request": map[string]interface{}{
+		"__typename":                           "InvoiceData",
+		"invoice_data_encoded_payment_request": "lnbc1",
+		"invoice_data_amount":                  msats(3000),
+	}})
+	mock.RespondData("PayUmaInvoice", map[string]interface{}{"pay_uma_invoice": map[string]interface{}{
+		"payment": map[string]interface{}{
+			"__typename":              "OutgoingPayment",
+			"outgoing_payment_id":     "payment:1",
+			"outgoing_payment_status": "PENDING",
+		},
+	}})
+
+	_, err := client.PayUmaInvoiceWithLiquidityCheck("node:1", "lnbc1", 60, 1000, nil, []string{"node:2"})
+	require.NoError(t, err)
+	require.Equal(t, "PayUmaInvoice", mock.Calls()[len(mock.Calls())-1].OperationName)
+
+	// 3001 msats and 1000 msats of fees exceed the 4000 msats of the channels with node:2.
+	callCount := len(mock.Calls())
+	amountMsats := int64(3001)
+	_, err = client.PayUmaInvoiceWithLiquidityCheck("node:1", "lnbc1", 60, 1000, &amountMsats, []string{"node:2"})
+	require.ErrorIs(t, err, services.ErrInsufficientOutgoingLiquidity)
+	for _, call := range mock.Calls()[callCount:] {
+		require.NotEqual(t, "PayUmaInvoice", call.OperationName)
+	}
+}
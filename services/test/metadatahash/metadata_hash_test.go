@@ -0,0 +1,68 @@
+package metadatahash
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func invoiceData(field string) map[string]interface{} {
+	return map[string]interface{}{field: map[string]interface{}{
+		"invoice": map[string]interface{}{
+			"__typename": "Invoice",
+			"invoice_id": "invoice:1",
+		},
+	}}
+}
+
+func TestCreateInvoiceWithMetadataHash(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("CreateLnurlInvoice", invoiceData("create_lnurl_invoice")).
+		RespondData("CreateUmaInvoice", invoiceData("create_uma_invoice"))
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+	metadataHash := crypto.Sha256HexString(`[["text/plain", "Pay alice"]]`)
+	createInvoices := map[string]func(metadataHash string) (*objects.Invoice, error){
+		"CreateLnurlInvoice": func(metadataHash string) (*objects.Invoice, error) {
+			return client.CreateLnurlInvoiceWithMetadataHash("node:1", 1000, metadataHash, nil)
+		},
+		"CreateUmaInvoice": func(metadataHash string) (*objects.Invoice, error) {
+			return client.CreateUmaInvoiceWithMetadataHash("node:1", 1000, metadataHash, nil)
+		},
+	}
+
+	for operationName, createInvoice := range createInvoices {
+		t.Run(operationName, func(t *testing.T) {
+			calls := len(mock.Calls())
+			invoice, err := createInvoice(metadataHash)
+			require.NoError(t, err)
+			require.Equal(t, "invoice:1", invoice.Id)
+			// The hash is sent as given, not hashed again like the metadata of CreateLnurlInvoice and CreateUmaInvoice.
+			call := mock.Calls()[calls]
+			require.Equal(t, operationName, call.OperationName)
+			require.Equal(t, metadataHash, call.Variables["metadata_hash"])
+			require.NotContains(t, call.Variables, "metadata")
+
+			for _, invalidHash := range []string{
+				"",
+				metadataHash[:62],
+				metadataHash + "00",
+				strings.Repeat("zz", 32),
+				"0x" + metadataHash[:62],
+			} {
+				_, err = createInvoice(invalidHash)
+				require.EqualError(t, err, "the metadata hash must be a hex-encoded SHA256 hash")
+			}
+			require.Len(t, mock.Calls(), calls+1)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package options
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEnvironment_KeepsHTTPClient(t *testing.T) {
+	httpClient := &http.Client{Transport: http.DefaultTransport}
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithRequesterOptions(requester.WithHTTPClient(httpClient)),
+		services.WithEnvironment(requester.EnvironmentDev))
+	require.NoError(t, err)
+	require.Equal(t, requester.EnvironmentDev.Config().BaseUrl, *client.Requester.BaseUrl)
+	require.Same(t, http.DefaultTransport, client.Requester.HTTPClient.Transport)
+	require.Equal(t, requester.EnvironmentDev.Config().Timeout, client.Requester.HTTPClient.Timeout)
+	require.Zero(t, httpClient.Timeout)
+
+	httpClient = &http.Client{Timeout: time.Second}
+	client, err = services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithRequesterOptions(requester.WithHTTPClient(httpClient)),
+		services.WithEnvironment(requester.EnvironmentProduction))
+	require.NoError(t, err)
+	require.Same(t, httpClient, client.Requester.HTTPClient)
+
+	client, err = services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithEnvironment(requester.EnvironmentProduction))
+	require.NoError(t, err)
+	require.Equal(t, requester.EnvironmentProduction.Config().Timeout, client.Requester.HTTPClient.Timeout)
+}
+
+func TestNewLightsparkClientWithOptions(t *testing.T) {
+	retryPolicy := requester.DefaultRetryPolicy()
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl("https://api.example.com/graphql/server/v1"),
+		services.WithRetryPolicy(retryPolicy),
+		services.WithRequesterOptions(requester.WithTimeout(time.Second)))
+	require.NoError(t, err)
+	require.Equal(t, "https://api.example.com/graphql/server/v1", *client.Requester.BaseUrl)
+	require.Same(t, retryPolicy, client.Requester.RetryPolicy)
+	require.Equal(t, time.Second, client.Requester.HTTPClient.Timeout)
+
+	// Later options override earlier ones.
+	client, err = services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl("https://api.example.com/graphql/server/v1"),
+		services.WithBaseUrl("https://other.example.com/graphql/server/v1"))
+	require.NoError(t, err)
+	require.Equal(t, "https://other.example.com/graphql/server/v1", *client.Requester.BaseUrl)
+
+	_, err = services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl("http://api.example.com/graphql/server/v1"))
+	require.Error(t, err)
+}
+
+func TestNewLightsparkClient_PanicsOnInvalidBaseUrl(t *testing.T) {
+	baseUrl := "https://api.example.com/graphql/server/v1"
+	client := services.NewLightsparkClient("client_id", "client_secret", &baseUrl,
+		services.WithRetryPolicy(requester.DefaultRetryPolicy()))
+	require.Equal(t, baseUrl, *client.Requester.BaseUrl)
+	require.NotNil(t, client.Requester.RetryPolicy)
+
+	insecureBaseUrl := "http://api.example.com/graphql/server/v1"
+	require.Panics(t, func() {
+		services.NewLightsparkClient("client_id", "client_secret", &insecureBaseUrl)
+	})
+	require.Panics(t, func() {
+		requester.NewRequesterWithBaseUrl("client_id", "client_secret", &insecureBaseUrl)
+	})
+}
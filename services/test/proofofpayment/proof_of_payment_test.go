@@ -0,0 +1,151 @@
+package proofofpayment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	preimage    = "707265696d616765"
+	paymentHash = "107661134f21fc7c02223d50ab9eb3600bc3ffc3712423a1e47bb1f9a9dbf55f"
+)
+
+type recordingSigningKey struct {
+	payload []byte
+}
+
+func (k *recordingSigningKey) Sign(payload []byte) ([]byte, error) {
+	k.payload = payload
+	return []byte("signature"), nil
+}
+
+func msats(value int64) map[string]interface{} {
+	return map[string]interface{}{
+		"currency_amount_original_value": value,
+		"currency_amount_original_unit":  "MILLISATOSHI",
+	}
+}
+
+func newMockClient(t *testing.T) *services.LightsparkClient {
+	mock := requestertest.NewMock().
+		Handle("GetEntity", func(call requestertest.Call) requestertest.Response {
+			payment := map[string]interface{}{
+				"__typename":                  "OutgoingPayment",
+				"outgoing_payment_id":         call.Variables["id"],
+				"outgoing_payment_status":     "SUCCESS",
+				"outgoing_payment_created_at": "2024-01-01T00:00:00Z",
+				"outgoing_payment_amount":     msats(1000),
+			}
+			switch call.Variables["id"] {
+			case "payment:1":
+				payment["outgoing_payment_resolved_at"] = "2024-01-01T00:00:05Z"
+				payment["outgoing_payment_fees"] = msats(10)
+				payment["outgoing_payment_payment_preimage"] = preimage
+				payment["outgoing_payment_payment_request_data"] = map[string]interface{}{
+					"__typename":                           "InvoiceData",
+					"invoice_data_encoded_payment_request": "lnbc1",
+					"invoice_data_payment_hash":            paymentHash,
+				}
+			case "payment:2":
+				payment["outgoing_payment_status"] = "PENDING"
+			case "payment:3":
+				payment["outgoing_payment_payment_preimage"] = preimage
+				payment["outgoing_payment_transaction_hash"] = "00"
+			case "invoice:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename": "Invoice",
+					"invoice_id": "invoice:1",
+				}}}
+			default:
+				return requestertest.Response{Data: map[string]interface{}{"entity": nil}}
+			}
+			return requestertest.Response{Data: map[string]interface{}{"entity": payment}}
+		})
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+	return client
+}
+
+func TestGetProofOfPayment(t *testing.T) {
+	client := newMockClient(t)
+
+	proof, err := client.GetProofOfPayment("payment:1")
+	require.NoError(t, err)
+	encodedInvoice := "lnbc1"
+	feesMsats := int64(10)
+	settledAt := time.Date(2024, 1, 1, 0, 0, 5, 0, time.UTC)
+	require.Equal(t, services.ProofOfPayment{
+		PaymentId:      "payment:1",
+		EncodedInvoice: &encodedInvoice,
+		PaymentHash:    paymentHash,
+		Preimage:       preimage,
+		AmountMsats:    1000,
+		FeesMsats:      &feesMsats,
+		CreatedAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		SettledAt:      &settledAt,
+	}, *proof)
+
+	returnedPreimage, err := client.GetOutgoingPaymentPreimage("payment:1")
+	require.NoError(t, err)
+	require.Equal(t, preimage, returnedPreimage)
+
+	_, err = client.GetProofOfPayment("payment:2")
+	require.EqualError(t, err, "the payment is not settled")
+	_, err = client.GetOutgoingPaymentPreimage("payment:2")
+	require.EqualError(t, err, "the payment is not settled")
+	// Without invoice data, the transaction hash is used as the payment hash, which must match the preimage.
+	_, err = client.GetProofOfPayment("payment:3")
+	require.EqualError(t, err, "the preimage does not match the payment hash")
+	_, err = client.GetProofOfPayment("invoice:1")
+	require.EqualError(t, err, "failed to cast entity to OutgoingPayment")
+	_, err = client.GetProofOfPayment("payment:4")
+	require.Error(t, err)
+}
+
+func TestProofOfPayment_Verify(t *testing.T) {
+	proof := services.ProofOfPayment{PaymentHash: paymentHash, Preimage: preimage}
+	require.NoError(t, proof.Verify())
+
+	proof.Preimage = "not hex"
+	require.EqualError(t, proof.Verify(), "the preimage is not hex encoded")
+	proof.Preimage = "00"
+	require.EqualError(t, proof.Verify(), "the preimage does not match the payment hash")
+}
+
+func TestProofOfPayment_Sign(t *testing.T) {
+	proof := services.ProofOfPayment{
+		PaymentId:   "payment:1",
+		PaymentHash: paymentHash,
+		Preimage:    preimage,
+		AmountMsats: 1000,
+		CreatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	signingKey := &recordingSigningKey{}
+	require.NoError(t, proof.Sign(signingKey))
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("signature")), *proof.Signature)
+	require.JSONEq(t, `{
+		"payment_id": "payment:1",
+		"payment_hash": "`+paymentHash+`",
+		"preimage": "`+preimage+`",
+		"amount_msats": 1000,
+		"created_at": "2024-01-01T00:00:00Z"
+	}`, string(signingKey.payload))
+
+	// The signature is not part of the signed payload, so signing again covers the same bytes.
+	payload, err := proof.SignablePayload()
+	require.NoError(t, err)
+	require.Equal(t, signingKey.payload, payload)
+	encoded, err := json.Marshal(proof)
+	require.NoError(t, err)
+	require.Contains(t, string(encoded), `"signature":"c2lnbmF0dXJl"`)
+}
@@ -0,0 +1,146 @@
+package reporting
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+func msats(value int64) map[string]interface{} {
+	return map[string]interface{}{
+		"currency_amount_original_value": value,
+		"currency_amount_original_unit":  "MILLISATOSHI",
+	}
+}
+
+func transactionsPage(hasNextPage bool, endCursor string, transactions ...map[string]interface{},
+) map[string]interface{} {
+	return map[string]interface{}{"entity": map[string]interface{}{"transactions": map[string]interface{}{
+		"__typename": "AccountToTransactionsConnection",
+		"account_to_transactions_connection_count": len(transactions),
+		"account_to_transactions_connection_page_info": map[string]interface{}{
+			"page_info_has_next_page": hasNextPage,
+			"page_info_end_cursor":    endCursor,
+		},
+		"account_to_transactions_connection_entities": transactions,
+	}}}
+}
+
+func newMockClient(t *testing.T, mock *requestertest.Mock, options ...requester.Option) *services.LightsparkClient {
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(append(options, requester.WithHTTPClient(&http.Client{Transport: mock}))...))
+	require.NoError(t, err)
+	return client
+}
+
+func TestGetDailyVolumes(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("GetCurrentAccount", map[string]interface{}{"current_account": map[string]interface{}{
+			"__typename": "Account",
+			"account_id": "account:1",
+		}}).
+		Handle("FetchAccountToTransactionsConnection", func(call requestertest.Call) requestertest.Response {
+			if call.Variables["after"] == nil {
+				return requestertest.Response{Data: transactionsPage(true, "cursor:1",
+					map[string]interface{}{
+						"__typename":                   "IncomingPayment",
+						"incoming_payment_id":          "payment:1",
+						"incoming_payment_created_at":  "2024-01-02T09:00:00Z",
+						"incoming_payment_resolved_at": "2024-01-02T01:00:00+02:00",
+						"incoming_payment_amount":      msats(1000),
+					},
+					map[string]interface{}{
+						"__typename":                  "OutgoingPayment",
+						"outgoing_payment_id":         "payment:2",
+						"outgoing_payment_created_at": "2024-01-02T10:00:00Z",
+						"outgoing_payment_amount":     msats(500),
+						"outgoing_payment_fees":       msats(10),
+					})}
+			}
+			return requestertest.Response{Data: transactionsPage(false, "cursor:2",
+				map[string]interface{}{
+					"__typename":                  "IncomingPayment",
+					"incoming_payment_id":         "payment:3",
+					"incoming_payment_created_at": "2024-01-02T12:00:00Z",
+					"incoming_payment_amount":     msats(2000),
+				},
+				map[string]interface{}{
+					"__typename":                  "OutgoingPayment",
+					"outgoing_payment_id":         "payment:4",
+					"outgoing_payment_created_at": "2024-01-03T12:00:00Z",
+					"outgoing_payment_amount":     msats(300),
+				})}
+		})
+	client := newMockClient(t, mock)
+
+	afterDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	beforeDate := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	volumes, err := client.GetDailyVolumes(afterDate, beforeDate, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []services.DailyVolume{
+		// payment:1 counts on the UTC day it was resolved, not on the day it was created.
+		{Date: afterDate, ReceivedMsats: 1000, IncomingPaymentCount: 1},
+		{Date: afterDate.AddDate(0, 0, 1), ReceivedMsats: 2000, SentMsats: 500, FeesPaidMsats: 10,
+			IncomingPaymentCount: 1, OutgoingPaymentCount: 1},
+		{Date: afterDate.AddDate(0, 0, 2), SentMsats: 300, OutgoingPaymentCount: 1},
+	}, volumes)
+
+	calls := mock.Calls()
+	require.Len(t, calls, 3)
+	require.Nil(t, calls[1].Variables["after"])
+	require.Equal(t, "cursor:1", calls[2].Variables["after"])
+	require.Equal(t, []interface{}{"SUCCESS"}, calls[1].Variables["statuses"])
+	require.ElementsMatch(t, []interface{}{"INCOMING_PAYMENT", "OUTGOING_PAYMENT"}, calls[1].Variables["types"])
+}
+
+func TestGetBalanceSnapshots(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	runtime, _ := sdkruntime.NewDeterministicRuntime(now, "seed")
+	mock := requestertest.NewMock().
+		Handle("GetEntity", func(call requestertest.Call) requestertest.Response {
+			switch call.Variables["id"] {
+			case "node:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":                    "LightsparkNodeWithOSK",
+					"lightspark_node_with_o_s_k_id": "node:1",
+					"lightspark_node_with_o_s_k_balances": map[string]interface{}{
+						"balances_owned_balance":                 msats(3000),
+						"balances_available_to_send_balance":     msats(2000),
+						"balances_available_to_withdraw_balance": msats(1000),
+					},
+				}}}
+			case "node:2":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":                    "LightsparkNodeWithOSK",
+					"lightspark_node_with_o_s_k_id": "node:2",
+				}}}
+			case "invoice:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename": "Invoice",
+					"invoice_id": "invoice:1",
+				}}}
+			}
+			return requestertest.Response{Data: map[string]interface{}{"entity": nil}}
+		})
+	client := newMockClient(t, mock, requester.WithRuntime(runtime))
+
+	snapshots, err := client.GetBalanceSnapshots([]string{"node:1", "node:2"})
+	require.NoError(t, err)
+	require.Equal(t, []services.BalanceSnapshot{
+		{Timestamp: now, NodeId: "node:1", OwnedBalanceMsats: 3000, AvailableToSendMsats: 2000,
+			AvailableToWithdrawMsats: 1000},
+		{Timestamp: now, NodeId: "node:2"},
+	}, snapshots)
+
+	_, err = client.GetBalanceSnapshots([]string{"node:1", "invoice:1"})
+	require.EqualError(t, err, "failed to cast entity to LightsparkNode")
+	_, err = client.GetBalanceSnapshots([]string{"node:3"})
+	require.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package reputation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSigningKey struct{}
+
+func (fakeSigningKey) Sign(payload []byte) ([]byte, error) {
+	return []byte("signature"), nil
+}
+
+func newClient(t *testing.T, url string, store services.ReputationStore) *services.LightsparkClient {
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(url),
+		services.WithReputationStore(store, services.ThresholdReputationPolicy{BlockPaymentFailureRate: 1}))
+	require.NoError(t, err)
+	client.SetNodeSigningKey("node:1", fakeSigningKey{})
+	return client
+}
+
+func TestPayUmaInvoiceToCounterparty_RecordsCounterpartyResponses(t *testing.T) {
+	var status, failureReason string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"data": {"pay_uma_invoice": {"payment": {"__typename": "OutgoingPayment", ` +
+			`"outgoing_payment_id": "payment:1", "outgoing_payment_status": "` + status + `", ` +
+			`"outgoing_payment_failure_reason": ` + failureReason + `}}}}`))
+	}))
+	t.Cleanup(server.Close)
+	store := services.NewInMemoryReputationStore()
+	client := newClient(t, server.URL, store)
+
+	status, failureReason = "SUCCESS", "null"
+	_, err := client.PayUmaInvoiceToCounterparty("vasp.com", "node:1", "lnbc1", 60, 1000, nil)
+	require.NoError(t, err)
+
+	status, failureReason = "FAILED", `"INSUFFICIENT_BALANCE"`
+	payment, err := client.PayUmaInvoiceToCounterparty("vasp.com", "node:1", "lnbc1", 60, 1000, nil)
+	require.NoError(t, err)
+	require.Equal(t, objects.TransactionStatusFailed, payment.Status)
+
+	status, failureReason = "FAILED", `"NO_ROUTE"`
+	_, err = client.PayUmaInvoiceToCounterparty("vasp.com", "node:1", "lnbc1", 60, 1000, nil)
+	require.NoError(t, err)
+
+	score, err := store.GetScore("vasp.com")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), score.PaymentSuccesses)
+	require.Equal(t, int64(1), score.PaymentFailures)
+}
+
+func TestPayUmaInvoiceToCounterparty_IgnoresNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	server.Close()
+	store := services.NewInMemoryReputationStore()
+	client := newClient(t, server.URL, store)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.PayUmaInvoiceToCounterparty("vasp.com", "node:1", "lnbc1", 60, 1000, nil)
+		require.Error(t, err)
+	}
+
+	score, err := store.GetScore("vasp.com")
+	require.NoError(t, err)
+	require.Zero(t, score.PaymentFailures)
+	verdict, err := client.CheckCounterpartyReputation("vasp.com")
+	require.NoError(t, err)
+	require.Equal(t, services.ReputationVerdictAllow, verdict)
+}
@@ -0,0 +1,128 @@
+package secretsource
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+const masterSeed = "3e7a9cd1ac6393f1ebaed4bf25e3052b3e2ff7a1a259a0d2da0a19e2f6e4e0c4"
+
+// newVaultSecretSource returns a source reading from a Vault server holding the given secrets in a KV version 2
+// engine mounted at "kv".
+func newVaultSecretSource(t *testing.T, secrets map[string]string) *services.VaultSecretSource {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "GET", req.Method)
+		if req.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch req.URL.Path {
+		case "/v1/kv/data/lightspark/legacy":
+			// KV version 1 engines return the fields of the secret directly under "data".
+			w.Write([]byte(`{"data": {"value": "client_secret"}}`))
+			return
+		case "/v1/kv/data/lightspark/invalid":
+			w.Write([]byte(`not json`))
+			return
+		}
+		value, ok := secrets[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"value": "` + value + `"}, "metadata": {"version": 1}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return &services.VaultSecretSource{Address: server.URL, Token: "vault-token", MountPath: "kv"}
+}
+
+func TestVaultSecretSource(t *testing.T) {
+	source := newVaultSecretSource(t, map[string]string{"/v1/kv/data/lightspark/client-secret": "client_secret"})
+
+	secret, err := source.GetSecret("lightspark/client-secret")
+	require.NoError(t, err)
+	require.Equal(t, []byte("client_secret"), secret)
+
+	_, err = source.GetSecret("lightspark/missing")
+	require.EqualError(t, err, "vault request failed: 404 Not Found")
+	_, err = source.GetSecret("lightspark/legacy")
+	require.EqualError(t, err, "secret lightspark/legacy has no field value")
+	_, err = source.GetSecret("lightspark/invalid")
+	require.EqualError(t, err, "error parsing vault response")
+
+	source.Field = "password"
+	_, err = source.GetSecret("lightspark/client-secret")
+	require.EqualError(t, err, "secret lightspark/client-secret has no field password")
+
+	source.Token = "other-token"
+	_, err = source.GetSecret("lightspark/client-secret")
+	require.EqualError(t, err, "vault request failed: 403 Forbidden")
+}
+
+func TestNewLightsparkClientFromSecretSource(t *testing.T) {
+	source := newVaultSecretSource(t, map[string]string{"/v1/kv/data/lightspark/client-secret": "client_secret\\n"})
+	baseUrl := "https://api.example.com/graphql/server/2023-09-13"
+
+	client, err := services.NewLightsparkClientFromSecretSource("client_id", source, "lightspark/client-secret",
+		&baseUrl)
+	require.NoError(t, err)
+	require.Equal(t, "client_id", client.Requester.ApiTokenClientId)
+	require.Equal(t, "client_secret", client.Requester.ApiTokenClientSecret)
+	require.Equal(t, baseUrl, *client.Requester.BaseUrl)
+
+	_, err = services.NewLightsparkClientFromSecretSource("client_id", source, "lightspark/missing", nil)
+	require.EqualError(t, err, "vault request failed: 404 Not Found")
+}
+
+func TestNewSigningKeyLoaderFromSecret(t *testing.T) {
+	source := newVaultSecretSource(t, map[string]string{
+		"/v1/kv/data/lightspark/rsa-key":     "00010203",
+		"/v1/kv/data/lightspark/master-seed": masterSeed,
+		"/v1/kv/data/lightspark/not-hex":     "not hex",
+	})
+
+	loader := services.NewSigningKeyLoaderFromRsaPrivateKeySecret(source, "lightspark/rsa-key")
+	key, err := loader.LoadSigningKey(requester.Requester{})
+	require.NoError(t, err)
+	require.Equal(t, &requester.RsaSigningKey{PrivateKey: []byte{0, 1, 2, 3}}, key)
+
+	loader = services.NewSigningKeyLoaderFromSignerMasterSeedSecret(source, "lightspark/master-seed",
+		objects.BitcoinNetworkRegtest)
+	key, err = loader.LoadSigningKey(requester.Requester{})
+	require.NoError(t, err)
+	require.IsType(t, &requester.Secp256k1SigningKey{}, key)
+
+	loader = services.NewSigningKeyLoaderFromRsaPrivateKeySecret(source, "lightspark/not-hex")
+	_, err = loader.LoadSigningKey(requester.Requester{})
+	require.EqualError(t, err, "signing key secret is not hex encoded")
+	loader = services.NewSigningKeyLoaderFromRsaPrivateKeySecret(source, "lightspark/missing")
+	_, err = loader.LoadSigningKey(requester.Requester{})
+	require.EqualError(t, err, "vault request failed: 404 Not Found")
+}
+
+func TestNewSigningKeyLoaderFromSecret_CachesKey(t *testing.T) {
+	reads := 0
+	source := services.SecretSourceFunc(func(name string) ([]byte, error) {
+		reads++
+		if reads > 1 {
+			return nil, errors.New("the secret was read again")
+		}
+		return []byte("00010203\n"), nil
+	})
+
+	loader := services.NewSigningKeyLoaderFromRsaPrivateKeySecret(source, "rsa-key")
+	for i := 0; i < 2; i++ {
+		_, err := loader.LoadSigningKey(requester.Requester{})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, reads)
+}
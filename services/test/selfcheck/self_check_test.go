@@ -0,0 +1,98 @@
+package selfcheck
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSigningKey struct{}
+
+func (failingSigningKey) Sign(payload []byte) ([]byte, error) {
+	return nil, errors.New("hardware key unavailable")
+}
+
+func newRsaSigningKey(t *testing.T) *requester.RsaSigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	encoded, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	return &requester.RsaSigningKey{PrivateKey: encoded}
+}
+
+func TestSelfCheck(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("GetCurrentAccount", map[string]interface{}{"current_account": map[string]interface{}{
+			"__typename": "Account",
+			"account_id": "account:1",
+		}}).
+		Handle("GetEntity", func(call requestertest.Call) requestertest.Response {
+			if call.Variables["id"] == "node:1" {
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":                    "LightsparkNodeWithOSK",
+					"lightspark_node_with_o_s_k_id": "node:1",
+				}}}
+			}
+			return requestertest.Response{Data: map[string]interface{}{"entity": nil}}
+		})
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+	client.SetNodeSigningKey("node:1", newRsaSigningKey(t))
+	client.SetNodeSigningKey("node:2", failingSigningKey{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/lnurlpubkey":
+			w.Write([]byte(`{"signingPubKey": "02", "encryptionPubKey": "03"}`))
+		case "/.well-known/lnurlp/alice":
+			w.Write([]byte(`{"callback": "https://vasp.example/api/uma/payreq/alice"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	domain := strings.TrimPrefix(server.URL, "https://")
+
+	report := client.SelfCheck(context.Background(), services.SelfCheckConfig{
+		NodeIds:              []string{"node:1", "node:2"},
+		WebhookSecret:        "short",
+		UmaReceiverAddresses: []string{"$alice@" + domain, "$bob@" + domain, "carol"},
+		HTTPClient:           server.Client(),
+	})
+
+	require.False(t, report.Ok())
+	results := map[string]error{}
+	for _, result := range report.Results {
+		results[result.Name] = result.Err
+	}
+	require.Len(t, results, 10)
+	require.NoError(t, results["credentials"])
+	require.NoError(t, results["node:node:1"])
+	require.NoError(t, results["signing_key:node:1"])
+	require.EqualError(t, results["node:node:2"], "node not found")
+	require.ErrorContains(t, results["signing_key:node:2"], "hardware key unavailable")
+	require.EqualError(t, results["webhook_secret"], "webhook secret is too short")
+	require.NoError(t, results["uma_pubkey:"+domain])
+	require.NoError(t, results["uma_lnurlp:$alice@"+domain])
+	require.ErrorContains(t, results["uma_lnurlp:$bob@"+domain], "404")
+	require.EqualError(t, results["uma:carol"], "invalid UMA address")
+
+	failures := report.Failures()
+	require.Len(t, failures, 5)
+	for _, failure := range failures {
+		require.Error(t, failure.Err)
+	}
+}
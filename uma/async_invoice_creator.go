@@ -0,0 +1,163 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// UmaInvoiceCreator mirrors the invoice creator interface of the UMA SDK. LightsparkClientUmaInvoiceCreator
+// implements it.
+type UmaInvoiceCreator interface {
+	CreateUmaInvoice(amountMsats int64, metadata string) (*string, error)
+}
+
+// ErrInvoicePending is returned by PollUmaInvoice while the invoice is still being created.
+var ErrInvoicePending = errors.New("invoice creation is still pending")
+
+// ErrUnknownInvoiceToken is returned when polling a token which was never issued or has expired.
+var ErrUnknownInvoiceToken = errors.New("unknown or expired invoice token")
+
+// AsyncUmaInvoiceCreator creates invoices in the background for receivers whose invoice creation is slow (e.g.
+// because of remote signing round trips). The payreq handler calls StartUmaInvoice and returns the polling token to
+// the sender instead of holding the HTTP request open, then the sender's callback polls with PollUmaInvoice until the
+// invoice is ready. It is safe for concurrent use.
+type AsyncUmaInvoiceCreator struct {
+	// Creator is the underlying invoice creator.
+	Creator UmaInvoiceCreator
+	// ResultTtl is how long a finished result is kept for polling. Defaults to 10 minutes.
+	ResultTtl time.Duration
+
+	mutex   sync.Mutex
+	pending map[string]*pendingInvoice
+}
+
+type pendingInvoice struct {
+	done       chan struct{}
+	invoice    *string
+	err        error
+	finishedAt time.Time
+}
+
+func NewAsyncUmaInvoiceCreator(creator UmaInvoiceCreator) *AsyncUmaInvoiceCreator {
+	return &AsyncUmaInvoiceCreator{Creator: creator, pending: map[string]*pendingInvoice{}}
+}
+
+// StartUmaInvoice starts creating an invoice in the background and returns the token to poll for it.
+func (a *AsyncUmaInvoiceCreator) StartUmaInvoice(amountMsats int64, metadata string) (string, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := sdkruntime.Default().Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	invoice := &pendingInvoice{done: make(chan struct{})}
+
+	a.mutex.Lock()
+	a.sweepLocked()
+	a.pending[token] = invoice
+	a.mutex.Unlock()
+
+	go func() {
+		encodedInvoice, err := a.Creator.CreateUmaInvoice(amountMsats, metadata)
+		a.mutex.Lock()
+		invoice.invoice = encodedInvoice
+		invoice.err = err
+		invoice.finishedAt = sdkruntime.Now()
+		a.mutex.Unlock()
+		close(invoice.done)
+	}()
+	return token, nil
+}
+
+// PollUmaInvoice returns the encoded invoice for a token once it is ready, ErrInvoicePending if it is not ready yet,
+// or the error returned by the underlying creator.
+func (a *AsyncUmaInvoiceCreator) PollUmaInvoice(token string) (*string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	invoice, ok := a.pending[token]
+	if !ok {
+		return nil, ErrUnknownInvoiceToken
+	}
+	select {
+	case <-invoice.done:
+		return invoice.invoice, invoice.err
+	default:
+		return nil, ErrInvoicePending
+	}
+}
+
+// WaitUmaInvoice blocks until the invoice for a token is ready or the context is done.
+func (a *AsyncUmaInvoiceCreator) WaitUmaInvoice(ctx context.Context, token string) (*string, error) {
+	a.mutex.Lock()
+	invoice, ok := a.pending[token]
+	a.mutex.Unlock()
+	if !ok {
+		return nil, ErrUnknownInvoiceToken
+	}
+	select {
+	case <-invoice.done:
+		return a.PollUmaInvoice(token)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ArchivedInvoiceResult is the archived result of an invoice created by an AsyncUmaInvoiceCreator.
+type ArchivedInvoiceResult struct {
+	Token          string    `json:"token"`
+	EncodedInvoice *string   `json:"encoded_invoice,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// Sweep removes the results which expired after ResultTtl, passing them to archive as ArchivedInvoiceResult first if
+// it is not nil. Expired results are also removed whenever an invoice is started.
+func (a *AsyncUmaInvoiceCreator) Sweep(archive ArchiveFunc) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	removed := 0
+	for token, invoice := range a.pending {
+		if !a.isExpired(invoice) {
+			continue
+		}
+		if archive != nil {
+			result := ArchivedInvoiceResult{Token: token, EncodedInvoice: invoice.invoice, FinishedAt: invoice.finishedAt}
+			if invoice.err != nil {
+				result.Error = invoice.err.Error()
+			}
+			if err := archive(result); err != nil {
+				return removed, err
+			}
+		}
+		delete(a.pending, token)
+		removed++
+	}
+	return removed, nil
+}
+
+func (a *AsyncUmaInvoiceCreator) Len() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.pending)
+}
+
+func (a *AsyncUmaInvoiceCreator) sweepLocked() {
+	for token, invoice := range a.pending {
+		if a.isExpired(invoice) {
+			delete(a.pending, token)
+		}
+	}
+}
+
+func (a *AsyncUmaInvoiceCreator) isExpired(invoice *pendingInvoice) bool {
+	ttl := a.ResultTtl
+	if ttl == 0 {
+		ttl = 10 * time.Minute
+	}
+	return !invoice.finishedAt.IsZero() && sdkruntime.Since(invoice.finishedAt) > ttl
+}
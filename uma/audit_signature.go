@@ -0,0 +1,148 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// AuditDirection is the direction of an archived protocol message.
+type AuditDirection string
+
+const (
+	AuditDirectionInbound  AuditDirection = "INBOUND"
+	AuditDirectionOutbound AuditDirection = "OUTBOUND"
+)
+
+// AuditSignature is a detached signature by the operator over an archived protocol message (lnurlp request/response,
+// payreq, payreq response, ...). It is independent of the UMA protocol signatures, and proves that the stored record
+// has not been tampered with since it was archived.
+type AuditSignature struct {
+	Direction   AuditDirection `json:"direction"`
+	MessageType string         `json:"message_type"`
+	RecordedAt  time.Time      `json:"recorded_at"`
+	// MessageDigest is the hex-encoded SHA256 digest of the archived message bytes.
+	MessageDigest string `json:"message_digest"`
+	// KeyId identifies the operator key used to sign, so that keys can be rotated.
+	KeyId string `json:"key_id"`
+	// Signature is the hex-encoded DER secp256k1 ECDSA signature.
+	Signature string `json:"signature"`
+}
+
+// AuditSigner signs archived protocol messages with the operator's secp256k1 audit key.
+type AuditSigner struct {
+	privateKey *btcec.PrivateKey
+	keyId      string
+}
+
+// NewAuditSigner creates an AuditSigner, returning an error if the key is not a valid secp256k1 private key.
+//
+// Args:
+//
+//	auditPrivateKey: the operator's secp256k1 audit private key.
+//	keyId: the identifier of the audit key.
+func NewAuditSigner(auditPrivateKey []byte, keyId string) (*AuditSigner, error) {
+	if len(auditPrivateKey) != btcec.PrivKeyBytesLen {
+		return nil, errors.New("invalid audit private key: must be " + strconv.Itoa(btcec.PrivKeyBytesLen) + " bytes")
+	}
+	var scalar btcec.ModNScalar
+	if overflow := scalar.SetByteSlice(auditPrivateKey); overflow || scalar.IsZero() {
+		return nil, errors.New("invalid audit private key: not a valid secp256k1 scalar")
+	}
+	if keyId == "" {
+		return nil, errors.New("missing audit key id")
+	}
+	return &AuditSigner{privateKey: btcec.PrivKeyFromScalar(&scalar), keyId: keyId}, nil
+}
+
+// Sign produces an AuditSignature over a protocol message.
+//
+// Args:
+//
+//	message: the exact bytes of the message as stored.
+//	direction: whether the message was received or sent.
+//	messageType: the type of the message, e.g. "payreq".
+func (s *AuditSigner) Sign(message []byte, direction AuditDirection, messageType string) (*AuditSignature, error) {
+	if direction != AuditDirectionInbound && direction != AuditDirectionOutbound {
+		return nil, errors.New("invalid audit direction: " + string(direction))
+	}
+	if messageType == "" {
+		return nil, errors.New("missing audit message type")
+	}
+	digest := sha256.Sum256(message)
+	auditSignature := &AuditSignature{
+		Direction:     direction,
+		MessageType:   messageType,
+		RecordedAt:    sdkruntime.Now().UTC(),
+		MessageDigest: hex.EncodeToString(digest[:]),
+		KeyId:         s.keyId,
+	}
+	hash := auditSignature.signedHash(false)
+	auditSignature.Signature = hex.EncodeToString(ecdsa.Sign(s.privateKey, hash[:]).Serialize())
+	return auditSignature, nil
+}
+
+// SignAuditRecord produces an AuditSignature over a protocol message with the operator's secp256k1 audit key. It
+// returns an error if the key is invalid. Use an AuditSigner to validate the key once for many messages.
+//
+// Args:
+//
+//	message: the exact bytes of the message as stored.
+//	direction: whether the message was received or sent.
+//	messageType: the type of the message, e.g. "payreq".
+//	auditPrivateKey: the operator's secp256k1 audit private key.
+//	keyId: the identifier of the audit key.
+func SignAuditRecord(message []byte, direction AuditDirection, messageType string, auditPrivateKey []byte,
+	keyId string) (*AuditSignature, error) {
+	signer, err := NewAuditSigner(auditPrivateKey, keyId)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(message, direction, messageType)
+}
+
+// VerifyAuditRecord checks that an AuditSignature is valid for the stored message and the operator's audit public key.
+func VerifyAuditRecord(message []byte, auditSignature AuditSignature, auditPublicKey []byte) error {
+	digest := sha256.Sum256(message)
+	if hex.EncodeToString(digest[:]) != auditSignature.MessageDigest {
+		return errors.New("the message does not match the audited digest")
+	}
+	publicKey, err := btcec.ParsePubKey(auditPublicKey)
+	if err != nil {
+		return err
+	}
+	signatureBytes, err := hex.DecodeString(auditSignature.Signature)
+	if err != nil {
+		return errors.New("the audit signature is not hex encoded")
+	}
+	signature, err := ecdsa.ParseDERSignature(signatureBytes)
+	if err != nil {
+		return err
+	}
+	hash := auditSignature.signedHash(true)
+	if !signature.Verify(hash[:], publicKey) {
+		return errors.New("invalid audit signature")
+	}
+	return nil
+}
+
+func (s *AuditSignature) signedHash(verifying bool) [32]byte {
+	payload := strings.Join([]string{
+		string(s.Direction),
+		s.MessageType,
+		s.RecordedAt.UTC().Format(time.RFC3339Nano),
+		s.MessageDigest,
+		s.KeyId,
+	}, "|")
+	requester.ObserveSignablePayload(requester.SIGNABLE_AUDIT_RECORD, s.MessageType, []byte(payload), verifying)
+	return sha256.Sum256([]byte(payload))
+}
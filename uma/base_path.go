@@ -0,0 +1,63 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"net/http"
+	"strings"
+)
+
+const lnurlpWellKnownPath = "/.well-known/lnurlp/"
+
+// BasePathOptions configures BasePathMiddleware.
+type BasePathOptions struct {
+	// BasePath is the path prefix added by the gateway in front of the UMA endpoints, e.g. "/payments". It is
+	// stripped from request paths.
+	BasePath string
+	// TrustForwardedHost uses the `X-Forwarded-Host` header set by the gateway as the request host, so that the
+	// receiver domain seen by ParseLnurlpRequest is the public one. It must only be set behind a gateway which
+	// overwrites this header.
+	TrustForwardedHost bool
+}
+
+// BasePathMiddleware wraps the UMA HTTP handlers of a receiving VASP mounted behind a gateway adding a path prefix,
+// so that the well-known paths have the exact shape expected by ParseLnurlpRequest
+// (`/.well-known/lnurlp/<username>`).
+func BasePathMiddleware(options BasePathOptions) func(http.Handler) http.Handler {
+	basePath := "/" + strings.Trim(options.BasePath, "/")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			request = request.Clone(request.Context())
+			if basePath != "/" {
+				if request.URL.Path == basePath {
+					request.URL.Path = "/"
+				} else if strings.HasPrefix(request.URL.Path, basePath+"/") {
+					request.URL.Path = strings.TrimPrefix(request.URL.Path, basePath)
+				}
+				request.URL.RawPath = ""
+			}
+			if options.TrustForwardedHost {
+				if forwardedHost := request.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+					// The header may list several hosts when there are several proxies; the first is the client's.
+					forwardedHost = strings.TrimSpace(strings.Split(forwardedHost, ",")[0])
+					request.Host = forwardedHost
+					request.URL.Host = forwardedHost
+				}
+			}
+			next.ServeHTTP(w, request)
+		})
+	}
+}
+
+// MatchLnurlpPath returns the username of a lnurlp request path, tolerating any prefix before the well-known path
+// and a trailing slash, e.g. `/payments/.well-known/lnurlp/alice/` returns `alice`.
+func MatchLnurlpPath(path string) (string, bool) {
+	index := strings.LastIndex(path, lnurlpWellKnownPath)
+	if index < 0 {
+		return "", false
+	}
+	username := strings.TrimSuffix(path[index+len(lnurlpWellKnownPath):], "/")
+	if username == "" || strings.Contains(username, "/") {
+		return "", false
+	}
+	return username, true
+}
@@ -0,0 +1,82 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CorsOptions configures CorsMiddleware.
+type CorsOptions struct {
+	// AllowedOrigins are the origins allowed to call the endpoints. "*" allows any origin. Defaults to "*", since the
+	// UMA well-known endpoints are public.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed in cross-origin requests. Defaults to "Content-Type".
+	AllowedHeaders []string
+	// AllowedMethods are the methods allowed in cross-origin requests. Defaults to GET, POST, HEAD and OPTIONS.
+	AllowedMethods []string
+	// MaxAge is how long browsers may cache preflight responses. Defaults to 10 minutes.
+	MaxAge time.Duration
+}
+
+// CorsMiddleware wraps the UMA HTTP handlers (lnurlp, payreq, pubkey) so that browser-based senders can call the
+// receiving VASP directly. It answers OPTIONS preflight requests itself, adds the CORS headers to the other
+// responses, and serves HEAD requests with the GET handler.
+func CorsMiddleware(options CorsOptions) func(http.Handler) http.Handler {
+	allowedOrigins := options.AllowedOrigins
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+	}
+	allowedHeaders := options.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = []string{"Content-Type"}
+	}
+	allowedMethods := options.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = []string{"GET", "POST", "HEAD", "OPTIONS"}
+	}
+	maxAge := options.MaxAge
+	if maxAge == 0 {
+		maxAge = 10 * time.Minute
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if allowedOrigin, ok := matchOrigin(origin, allowedOrigins); ok {
+				w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+				if allowedOrigin != "*" {
+					w.Header().Add("Vary", "Origin")
+				}
+			}
+
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if r.Method == http.MethodHead {
+				// The server discards the body of responses to HEAD requests, so the GET handler can serve them.
+				r = r.Clone(r.Context())
+				r.Method = http.MethodGet
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchOrigin(origin string, allowedOrigins []string) (string, bool) {
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == "*" {
+			return "*", true
+		}
+		if origin != "" && strings.EqualFold(allowedOrigin, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
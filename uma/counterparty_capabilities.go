@@ -0,0 +1,315 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const umaConfigurationWellKnownPath = "/.well-known/uma-configuration"
+
+// maxUmaConfigurationBytes caps the size of the UMA configuration documents read by FetchCounterpartyCapabilities.
+const maxUmaConfigurationBytes = 64 * 1024
+
+// CounterpartyCurrency is a currency a receiving VASP accepts, as advertised in its lnurlp response.
+type CounterpartyCurrency struct {
+	// Code is the ISO 4217 code of the currency, e.g. USD.
+	Code string
+	// Name is the name of the currency, e.g. US Dollar.
+	Name string
+	// Symbol is the symbol of the currency, e.g. $.
+	Symbol string
+	// Decimals is the number of digits after the decimal point of the currency.
+	Decimals int
+	// MinSendable and MaxSendable are the bounds of the amounts which can be sent, in the smallest unit of the
+	// currency. They are 0 when they are not advertised.
+	MinSendable int64
+	MaxSendable int64
+}
+
+// CounterpartyCapabilities is what a receiving VASP supports for one receiver, built from its lnurlp response and its
+// UMA configuration document, so that the sending wallet can adapt its UX per destination, e.g. hide the comment
+// field or ask for the payer data the receiver requires.
+type CounterpartyCapabilities struct {
+	// UmaVersion is the UMA version of the lnurlp response, or empty if the receiver is not an UMA receiver.
+	UmaVersion string
+	// UmaMajorVersions are the major UMA versions supported by the VASP, from its configuration document.
+	UmaMajorVersions []int
+	// Currencies are the currencies the receiver accepts.
+	Currencies []CounterpartyCurrency
+	// MinSendableMsats and MaxSendableMsats are the bounds of the amounts which can be sent, in millisatoshis.
+	MinSendableMsats int64
+	MaxSendableMsats int64
+	// CommentMaxLength is the maximum length of a payment comment (LUD-12), or 0 if comments are not supported.
+	CommentMaxLength int
+	// AllowsNostr is true if the receiver publishes zap receipts (NIP-57) with NostrPubkey.
+	AllowsNostr bool
+	NostrPubkey string
+	// RequiredPayerData and OptionalPayerData are the names of the payer data fields the receiver requires or
+	// accepts, e.g. `identifier` or `compliance`, sorted.
+	RequiredPayerData []string
+	OptionalPayerData []string
+}
+
+// IsUma returns true if the receiver answered as an UMA receiver rather than a plain LNURL receiver.
+func (c *CounterpartyCapabilities) IsUma() bool {
+	return c.UmaVersion != ""
+}
+
+// SupportsComments returns true if the receiver accepts payment comments.
+func (c *CounterpartyCapabilities) SupportsComments() bool {
+	return c.CommentMaxLength > 0
+}
+
+// Currency returns the currency of the receiver with the given code, if it is accepted.
+func (c *CounterpartyCapabilities) Currency(code string) (CounterpartyCurrency, bool) {
+	for _, currency := range c.Currencies {
+		if strings.EqualFold(currency.Code, code) {
+			return currency, true
+		}
+	}
+	return CounterpartyCurrency{}, false
+}
+
+// RequiresPayerData returns true if the receiver requires the payer data field with the given name.
+func (c *CounterpartyCapabilities) RequiresPayerData(field string) bool {
+	for _, required := range c.RequiredPayerData {
+		if required == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsUmaMajorVersion returns true if the VASP supports the major UMA version. It is only known when the
+// capabilities were built with the configuration document of the VASP.
+func (c *CounterpartyCapabilities) SupportsUmaMajorVersion(majorVersion int) bool {
+	for _, supported := range c.UmaMajorVersions {
+		if supported == majorVersion {
+			return true
+		}
+	}
+	return false
+}
+
+type lnurlpCapabilitiesResponse struct {
+	UmaVersion     string `json:"umaVersion"`
+	MinSendable    int64  `json:"minSendable"`
+	MaxSendable    int64  `json:"maxSendable"`
+	CommentAllowed int    `json:"commentAllowed"`
+	AllowsNostr    bool   `json:"allowsNostr"`
+	NostrPubkey    string `json:"nostrPubkey"`
+	Currencies     []struct {
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+		Symbol   string `json:"symbol"`
+		Decimals int    `json:"decimals"`
+		// UMA v0 puts the bounds on the currency, UMA v1 under `convertible`.
+		MinSendable int64 `json:"minSendable"`
+		MaxSendable int64 `json:"maxSendable"`
+		Convertible *struct {
+			Min int64 `json:"min"`
+			Max int64 `json:"max"`
+		} `json:"convertible"`
+	} `json:"currencies"`
+	PayerData map[string]struct {
+		Mandatory bool `json:"mandatory"`
+	} `json:"payerData"`
+}
+
+type umaConfiguration struct {
+	UmaMajorVersions []int `json:"uma_major_versions"`
+}
+
+// ParseCounterpartyCapabilities builds the capabilities of a receiver from its lnurlp response and, optionally, the
+// UMA configuration document of its VASP (`/.well-known/uma-configuration`).
+//
+// Args:
+//
+//	lnurlpResponse: the body of the lnurlp response of the receiver.
+//	configuration: the body of the UMA configuration document, or nil if it is not known.
+func ParseCounterpartyCapabilities(lnurlpResponse []byte, configuration []byte) (*CounterpartyCapabilities, error) {
+	var response lnurlpCapabilitiesResponse
+	if err := json.Unmarshal(lnurlpResponse, &response); err != nil {
+		return nil, errors.New("invalid lnurlp response: " + err.Error())
+	}
+	capabilities := &CounterpartyCapabilities{
+		UmaVersion:       response.UmaVersion,
+		MinSendableMsats: response.MinSendable,
+		MaxSendableMsats: response.MaxSendable,
+		CommentMaxLength: response.CommentAllowed,
+		AllowsNostr:      response.AllowsNostr && response.NostrPubkey != "",
+		NostrPubkey:      response.NostrPubkey,
+	}
+	for _, currency := range response.Currencies {
+		counterpartyCurrency := CounterpartyCurrency{
+			Code:        currency.Code,
+			Name:        currency.Name,
+			Symbol:      currency.Symbol,
+			Decimals:    currency.Decimals,
+			MinSendable: currency.MinSendable,
+			MaxSendable: currency.MaxSendable,
+		}
+		if currency.Convertible != nil {
+			counterpartyCurrency.MinSendable = currency.Convertible.Min
+			counterpartyCurrency.MaxSendable = currency.Convertible.Max
+		}
+		capabilities.Currencies = append(capabilities.Currencies, counterpartyCurrency)
+	}
+	for field, options := range response.PayerData {
+		if options.Mandatory {
+			capabilities.RequiredPayerData = append(capabilities.RequiredPayerData, field)
+		} else {
+			capabilities.OptionalPayerData = append(capabilities.OptionalPayerData, field)
+		}
+	}
+	sort.Strings(capabilities.RequiredPayerData)
+	sort.Strings(capabilities.OptionalPayerData)
+
+	if configuration != nil {
+		var document umaConfiguration
+		if err := json.Unmarshal(configuration, &document); err != nil {
+			return nil, errors.New("invalid UMA configuration: " + err.Error())
+		}
+		capabilities.UmaMajorVersions = document.UmaMajorVersions
+	}
+	return capabilities, nil
+}
+
+// FetchCounterpartyCapabilities fetches the UMA configuration document of a receiving VASP, and builds the
+// capabilities of a receiver with it and the lnurlp response already fetched by the sending client. The capabilities
+// are built from the lnurlp response alone if the VASP does not serve a configuration document.
+//
+// Args:
+//
+//	client: the client to fetch the document with, e.g. one returned by NewCounterpartyHTTPClient.
+//	domain: the domain of the receiving VASP, e.g. vasp.com.
+//	lnurlpResponse: the body of the lnurlp response of the receiver.
+func FetchCounterpartyCapabilities(ctx context.Context, client *http.Client, domain string, lnurlpResponse []byte,
+) (*CounterpartyCapabilities, error) {
+	scheme := "https://"
+	if isLocalDomain(domain) {
+		scheme = "http://"
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", scheme+domain+umaConfigurationWellKnownPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return ParseCounterpartyCapabilities(lnurlpResponse, nil)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status fetching the UMA configuration: " + strconv.Itoa(response.StatusCode))
+	}
+	configuration, err := io.ReadAll(io.LimitReader(response.Body, maxUmaConfigurationBytes))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCounterpartyCapabilities(lnurlpResponse, configuration)
+}
+
+// ErrCapabilitiesNotFound is returned by CounterpartyCapabilitiesStore.Capabilities for receivers whose lnurlp
+// response was not fetched.
+var ErrCapabilitiesNotFound = errors.New("no lnurlp response fetched for the receiver")
+
+// CounterpartyCapabilitiesStore records the last lnurlp response of each receiver fetched by the client returned by
+// NewCounterpartyHTTPClient, so that the sending wallet can get the capabilities of a receiver after the lnurlp
+// request. It is installed with CounterpartyHTTPClientConfig.CapabilitiesStore, and is safe for concurrent use.
+type CounterpartyCapabilitiesStore struct {
+	mutex           sync.Mutex
+	client          *http.Client
+	lnurlpResponses map[string][]byte
+}
+
+// NewCounterpartyCapabilitiesStore creates an empty CounterpartyCapabilitiesStore.
+func NewCounterpartyCapabilitiesStore() *CounterpartyCapabilitiesStore {
+	return &CounterpartyCapabilitiesStore{}
+}
+
+// Capabilities returns the capabilities of a receiver, e.g. $alice@vasp.com, built from its last lnurlp response and
+// the UMA configuration document of its VASP, fetched with the client the store is installed in. It returns
+// ErrCapabilitiesNotFound if no lnurlp response of the receiver was fetched.
+func (s *CounterpartyCapabilitiesStore) Capabilities(ctx context.Context, receiverAddress string,
+) (*CounterpartyCapabilities, error) {
+	user, domain, ok := strings.Cut(strings.TrimPrefix(receiverAddress, "$"), "@")
+	if !ok || user == "" || domain == "" {
+		return nil, errors.New("invalid receiver address: " + receiverAddress)
+	}
+	s.mutex.Lock()
+	lnurlpResponse, found := s.lnurlpResponses[capabilitiesKey(user, domain)]
+	client := s.client
+	s.mutex.Unlock()
+	if !found {
+		return nil, ErrCapabilitiesNotFound
+	}
+	if client == nil {
+		return ParseCounterpartyCapabilities(lnurlpResponse, nil)
+	}
+	return FetchCounterpartyCapabilities(ctx, client, strings.ToLower(domain), lnurlpResponse)
+}
+
+func (s *CounterpartyCapabilitiesStore) setClient(client *http.Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.client = client
+}
+
+func (s *CounterpartyCapabilitiesStore) record(user string, domain string, lnurlpResponse []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.lnurlpResponses == nil {
+		s.lnurlpResponses = map[string][]byte{}
+	}
+	s.lnurlpResponses[capabilitiesKey(user, domain)] = lnurlpResponse
+}
+
+func capabilitiesKey(user string, domain string) string {
+	return user + "@" + strings.ToLower(domain)
+}
+
+// capabilitiesRoundTripper records the lnurlp responses in a CounterpartyCapabilitiesStore.
+type capabilitiesRoundTripper struct {
+	next  http.RoundTripper
+	store *CounterpartyCapabilitiesStore
+}
+
+func (c *capabilitiesRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	user := strings.TrimPrefix(request.URL.Path, "/.well-known/lnurlp/")
+	if request.Method != http.MethodGet || user == request.URL.Path || user == "" || strings.Contains(user, "/") {
+		return c.next.RoundTrip(request)
+	}
+	response, err := c.next.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	c.store.record(user, request.URL.Host, body)
+	return response, nil
+}
+
+// isLocalDomain returns true for the domains served over plain HTTP in local development, like the UMA SDK.
+func isLocalDomain(domain string) bool {
+	host := domain
+	if index := strings.LastIndex(domain, ":"); index >= 0 {
+		host = domain[:index]
+	}
+	return host == "localhost" || host == "127.0.0.1"
+}
@@ -0,0 +1,154 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"golang.org/x/crypto/hkdf"
+)
+
+// counterpartyEncryptionKeySalt separates the per-counterparty encryption keys from other keys derived from the
+// master encryption key.
+const counterpartyEncryptionKeySalt = "uma-counterparty-encryption-key"
+
+// CounterpartyEncryptionKeys derives a separate encryption key pair for each counterparty VASP from the master
+// encryption key of the VASP, with HKDF-SHA256 using the counterparty domain as info. The derived public key of a
+// counterparty is the one published to it, so each counterparty encrypts its travel rule info and memos for its own
+// key, and compromising one derived private key or ciphertext does not expose the messages of other counterparties.
+// The derivation is deterministic, so the derived keys do not need to be stored.
+type CounterpartyEncryptionKeys struct {
+	// MasterPrivateKey is the master secp256k1 encryption private key of the VASP.
+	MasterPrivateKey []byte
+	// AcceptMasterKey also decrypts the messages encrypted for the master public key, for the counterparties which
+	// were given the master public key before per-counterparty keys were enabled.
+	AcceptMasterKey bool
+}
+
+// PrivateKey returns the encryption private key derived for a counterparty.
+//
+// Args:
+//
+//	counterpartyDomain: the domain of the counterparty VASP, e.g. vasp.com.
+func (k CounterpartyEncryptionKeys) PrivateKey(counterpartyDomain string) ([]byte, error) {
+	if len(k.MasterPrivateKey) == 0 {
+		return nil, errors.New("missing master encryption private key")
+	}
+	domain := strings.ToLower(strings.TrimSpace(counterpartyDomain))
+	if domain == "" {
+		return nil, errors.New("missing counterparty domain")
+	}
+	reader := hkdf.New(sha256.New, k.MasterPrivateKey, []byte(counterpartyEncryptionKeySalt), []byte(domain))
+	// Outputs which are not valid secp256k1 scalars are skipped, which happens with negligible probability.
+	candidate := make([]byte, btcec.PrivKeyBytesLen)
+	for {
+		if _, err := io.ReadFull(reader, candidate); err != nil {
+			return nil, err
+		}
+		var scalar btcec.ModNScalar
+		if overflow := scalar.SetByteSlice(candidate); !overflow && !scalar.IsZero() {
+			return candidate, nil
+		}
+	}
+}
+
+// PubKey returns the compressed encryption public key derived for a counterparty, to publish to it in place of the
+// master public key.
+//
+// Args:
+//
+//	counterpartyDomain: the domain of the counterparty VASP, e.g. vasp.com.
+func (k CounterpartyEncryptionKeys) PubKey(counterpartyDomain string) ([]byte, error) {
+	privateKey, err := k.PrivateKey(counterpartyDomain)
+	if err != nil {
+		return nil, err
+	}
+	_, publicKey := btcec.PrivKeyFromBytes(privateKey)
+	return publicKey.SerializeCompressed(), nil
+}
+
+// Decrypt decrypts a message encrypted by a counterparty with crypto.EciesEncrypt for its derived public key, or for
+// the master public key if AcceptMasterKey is set.
+//
+// Args:
+//
+//	counterpartyDomain: the domain of the counterparty VASP which encrypted the message.
+//	encrypted: the encrypted message.
+func (k CounterpartyEncryptionKeys) Decrypt(counterpartyDomain string, encrypted []byte) ([]byte, error) {
+	privateKey, err := k.PrivateKey(counterpartyDomain)
+	if err != nil {
+		return nil, err
+	}
+	message, err := crypto.EciesDecrypt(privateKey, encrypted)
+	if err != nil && k.AcceptMasterKey {
+		return crypto.EciesDecrypt(k.MasterPrivateKey, encrypted)
+	}
+	return message, err
+}
+
+// DecryptMemo decrypts a hex-encoded memo produced by EncryptMemo by a counterparty, like the package-level
+// DecryptMemo function with the key derived for the counterparty.
+//
+// Args:
+//
+//	counterpartyDomain: the domain of the counterparty VASP which encrypted the memo.
+//	encryptedMemo: the hex-encoded encrypted memo.
+func (k CounterpartyEncryptionKeys) DecryptMemo(counterpartyDomain string, encryptedMemo string) (string, error) {
+	encrypted, err := hex.DecodeString(encryptedMemo)
+	if err != nil {
+		return "", errors.New("encrypted memo is not hex encoded")
+	}
+	memo, err := k.Decrypt(counterpartyDomain, encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(memo) > MaxEncryptedMemoLength {
+		return "", errors.New("memo exceeds the maximum length of " + strconv.Itoa(MaxEncryptedMemoLength) + " bytes")
+	}
+	return string(memo), nil
+}
+
+// DecryptTravelRuleInfo decrypts the hex-encoded `encryptedTravelRuleInfo` of the compliance payer data of a payreq
+// sent by a counterparty.
+//
+// Args:
+//
+//	counterpartyDomain: the domain of the sending VASP, from the identifier of the payer.
+//	encryptedTravelRuleInfo: the hex-encoded encrypted travel rule info.
+func (k CounterpartyEncryptionKeys) DecryptTravelRuleInfo(counterpartyDomain string, encryptedTravelRuleInfo string,
+) (string, error) {
+	encrypted, err := hex.DecodeString(encryptedTravelRuleInfo)
+	if err != nil {
+		return "", errors.New("encrypted travel rule info is not hex encoded")
+	}
+	travelRuleInfo, err := k.Decrypt(counterpartyDomain, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(travelRuleInfo), nil
+}
+
+// PubKeyForRequest returns the encryption public key to publish in the response of a pubkey request: the key
+// derived for the counterparty named by its `vaspDomain` query parameter, e.g.
+// /.well-known/lnurlpubkey?vaspDomain=vasp.com. Requests which do not name their counterparty get the master public
+// key if AcceptMasterKey is set, and an error otherwise.
+func (k CounterpartyEncryptionKeys) PubKeyForRequest(request *http.Request) ([]byte, error) {
+	if counterpartyDomain := request.URL.Query().Get("vaspDomain"); counterpartyDomain != "" {
+		return k.PubKey(counterpartyDomain)
+	}
+	if !k.AcceptMasterKey {
+		return nil, errors.New("pubkey request does not name the counterparty in its vaspDomain parameter")
+	}
+	if len(k.MasterPrivateKey) == 0 {
+		return nil, errors.New("missing master encryption private key")
+	}
+	_, publicKey := btcec.PrivKeyFromBytes(k.MasterPrivateKey)
+	return publicKey.SerializeCompressed(), nil
+}
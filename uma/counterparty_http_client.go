@@ -0,0 +1,374 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/requester"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Resolver resolves host names to IP addresses. *net.Resolver implements this interface.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DohResolver is a Resolver using DNS-over-HTTPS with the JSON API (application/dns-json) supported by the major
+// public DoH providers. It can be used to defend counterparty lookups against local DNS tampering.
+type DohResolver struct {
+	// Endpoint is the DoH endpoint, e.g. https://cloudflare-dns.com/dns-query.
+	Endpoint string
+	// HTTPClient is the client used to query the endpoint. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// DNS record types queried by the DohResolver.
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// DNS response codes of the Status of DoH responses.
+const (
+	dnsStatusNoError  = 0
+	dnsStatusNXDomain = 3
+)
+
+func (r *DohResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	// As with the system resolver, the addresses of one record type are returned when the lookup of the other one
+	// fails, e.g. for hosts without IPv6 on resolvers failing AAAA queries.
+	var addresses []net.IPAddr
+	var lookupErr error
+	for _, recordType := range []int{dnsTypeA, dnsTypeAAAA} {
+		recordAddresses, err := r.lookup(ctx, host, recordType)
+		if err != nil {
+			if lookupErr == nil {
+				lookupErr = err
+			}
+			continue
+		}
+		addresses = append(addresses, recordAddresses...)
+	}
+	if len(addresses) > 0 {
+		return addresses, nil
+	}
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r *DohResolver) lookup(ctx context.Context, host string, recordType int) ([]net.IPAddr, error) {
+	query := url.Values{}
+	query.Set("name", host)
+	if recordType == dnsTypeA {
+		query.Set("type", "A")
+	} else {
+		query.Set("type", "AAAA")
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", r.Endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Accept", "application/dns-json")
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("DoH request failed: " + response.Status)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Status int `json:"Status"`
+		Answer []struct {
+			Type int    `json:"type"`
+			Data string `json:"data"`
+		} `json:"Answer"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.New("error parsing DoH response")
+	}
+	switch result.Status {
+	case dnsStatusNoError:
+	case dnsStatusNXDomain:
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{
+			Err:         "DoH lookup failed with DNS status " + strconv.Itoa(result.Status),
+			Name:        host,
+			IsTemporary: true,
+		}
+	}
+	var addresses []net.IPAddr
+	for _, answer := range result.Answer {
+		if answer.Type != recordType {
+			continue
+		}
+		if ip := net.ParseIP(answer.Data); ip != nil {
+			addresses = append(addresses, net.IPAddr{IP: ip})
+		}
+	}
+	return addresses, nil
+}
+
+// CounterpartyHTTPClientConfig configures the HTTP client returned by NewCounterpartyHTTPClient.
+type CounterpartyHTTPClientConfig struct {
+	// Resolver is used to resolve counterparty domains. Defaults to the system resolver.
+	Resolver Resolver
+	// Timeout is the overall timeout of a request. Defaults to 20 seconds.
+	Timeout time.Duration
+	// MaxRedirects is the maximum number of redirects followed. Defaults to 3. A negative value disables redirects.
+	MaxRedirects int
+	// AllowCrossDomainRedirects allows redirects to a host other than the requested domain.
+	AllowCrossDomainRedirects bool
+	// AllowPrivateAddresses allows connections to loopback, private and link-local addresses. It should only be set
+	// for local development.
+	AllowPrivateAddresses bool
+	// TracerProvider, if set, is used to create an OpenTelemetry span for each counterparty request (pubkey, lnurlp
+	// and payreq fetches).
+	TracerProvider trace.TracerProvider
+	// Logger, if set, receives the method, host, path, status and duration of each counterparty request. The receiver
+	// usernames of lnurlp paths and the URLs of errors are not logged.
+	Logger requester.Logger
+	// HandshakeSLA, if set, measures the pubkey, lnurlp and payreq round trips against SLA thresholds.
+	HandshakeSLA *HandshakeSLA
+	// LnurlpCache, if set, caches the lnurlp responses of receivers, so that repeat payments skip the lnurlp round
+	// trip.
+	LnurlpCache *LnurlpCache
+	// CapabilitiesStore, if set, records the lnurlp responses of receivers, so that the sending wallet can get their
+	// CounterpartyCapabilities.
+	CapabilitiesStore *CounterpartyCapabilitiesStore
+}
+
+// ErrPrivateAddress is returned when a counterparty domain resolves to an address which is not publicly routable.
+var ErrPrivateAddress = errors.New("counterparty address is not publicly routable")
+
+// NewCounterpartyHTTPClient returns a hardened HTTP client for fetching counterparty VASP endpoints (pubkey, lnurlp,
+// payreq). It resolves domains with the configured Resolver, refuses to connect to private addresses (including
+// through DNS rebinding), caps the number of redirects and only follows redirects staying on the requested domain.
+//
+// Pass the client to the calls fetching counterparty endpoints. It should not replace http.DefaultClient, which would
+// apply its address checks and redirect policy to every request of the process.
+func NewCounterpartyHTTPClient(config CounterpartyHTTPClientConfig) *http.Client {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 20 * time.Second
+	}
+	resolver := config.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	maxRedirects := config.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = 3
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The transport must not bypass the address checks by connecting through an environment proxy.
+	transport.Proxy = nil
+	transport.DialContext = resolvingDialContext(resolver, config.AllowPrivateAddresses)
+	var roundTripper http.RoundTripper = transport
+	if config.HandshakeSLA != nil {
+		roundTripper = &slaRoundTripper{next: roundTripper, sla: config.HandshakeSLA}
+	}
+	if config.LnurlpCache != nil {
+		roundTripper = &lnurlpCacheRoundTripper{next: roundTripper, cache: config.LnurlpCache}
+	}
+	if config.CapabilitiesStore != nil {
+		roundTripper = &capabilitiesRoundTripper{next: roundTripper, store: config.CapabilitiesStore}
+	}
+	if config.Logger != nil {
+		roundTripper = &loggingRoundTripper{next: roundTripper, logger: config.Logger}
+	}
+	if config.TracerProvider != nil {
+		roundTripper = &tracingRoundTripper{next: roundTripper, tracer: config.TracerProvider.Tracer(requester.TRACER_NAME)}
+	}
+	client := &http.Client{
+		Transport: roundTripper,
+		Timeout:   timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return errors.New("stopped after too many redirects")
+			}
+			if request.URL.Scheme != via[0].URL.Scheme {
+				return errors.New("refusing to follow a redirect changing the scheme")
+			}
+			if !config.AllowCrossDomainRedirects && !strings.EqualFold(request.URL.Hostname(), via[0].URL.Hostname()) {
+				return errors.New("refusing to follow a redirect to another domain: " + request.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	if config.CapabilitiesStore != nil {
+		config.CapabilitiesStore.setClient(client)
+	}
+	return client
+}
+
+func resolvingDialContext(resolver Resolver, allowPrivateAddresses bool) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addresses, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		for _, ipAddress := range addresses {
+			if !allowPrivateAddresses && !isPublicAddress(ipAddress.IP) {
+				lastErr = ErrPrivateAddress
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ipAddress.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// nonPublicNetworks are the special-purpose networks of the IANA IPv4 and IPv6 special-purpose address registries
+// which are not globally reachable, and the transition prefixes embedding an IPv4 address which may be private.
+// IPv4-mapped IPv6 addresses are matched against the IPv4 networks.
+var nonPublicNetworks = parseNetworks(
+	// IPv4.
+	"0.0.0.0/8",       // "This network".
+	"10.0.0.0/8",      // Private use.
+	"100.64.0.0/10",   // Shared address space (carrier-grade NAT).
+	"127.0.0.0/8",     // Loopback.
+	"169.254.0.0/16",  // Link local.
+	"172.16.0.0/12",   // Private use.
+	"192.0.0.0/24",    // IETF protocol assignments.
+	"192.0.2.0/24",    // Documentation (TEST-NET-1).
+	"192.88.99.0/24",  // 6to4 relay anycast.
+	"192.168.0.0/16",  // Private use.
+	"198.18.0.0/15",   // Benchmarking.
+	"198.51.100.0/24", // Documentation (TEST-NET-2).
+	"203.0.113.0/24",  // Documentation (TEST-NET-3).
+	"224.0.0.0/4",     // Multicast.
+	"240.0.0.0/4",     // Reserved, including the limited broadcast address.
+	// IPv6.
+	"::/128",         // Unspecified.
+	"::1/128",        // Loopback.
+	"64:ff9b::/96",   // IPv4/IPv6 translation (NAT64).
+	"64:ff9b:1::/48", // Local-use IPv4/IPv6 translation.
+	"100::/64",       // Discard-only.
+	"2001::/23",      // IETF protocol assignments, including Teredo.
+	"2001:db8::/32",  // Documentation.
+	"2002::/16",      // 6to4.
+	"fc00::/7",       // Unique local.
+	"fe80::/10",      // Link local.
+	"fec0::/10",      // Deprecated site local.
+	"ff00::/8",       // Multicast.
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicAddress returns whether an IP address is globally reachable, so that counterparty domains cannot make the
+// client connect to internal services.
+func isPublicAddress(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// tracingRoundTripper creates a span for each counterparty request.
+type tracingRoundTripper struct {
+	next   http.RoundTripper
+	tracer trace.Tracer
+}
+
+func (t *tracingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(request.Context(), "uma "+request.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", request.Method),
+			attribute.String("server.address", request.URL.Hostname()),
+			attribute.String("url.path", request.URL.Path),
+		))
+	defer span.End()
+	response, err := t.next.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+	if response.StatusCode >= 400 {
+		span.SetStatus(codes.Error, response.Status)
+	}
+	return response, nil
+}
+
+// loggingRoundTripper logs each counterparty request.
+type loggingRoundTripper struct {
+	next   http.RoundTripper
+	logger requester.Logger
+}
+
+func (l *loggingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	startedAt := time.Now()
+	response, err := l.next.RoundTrip(request)
+	if err != nil {
+		l.logger.Warn("counterparty request failed", "method", request.Method, "host", request.URL.Hostname(),
+			"path", loggedPath(request), "duration", time.Since(startedAt), "error", requester.RedactError(err))
+		return nil, err
+	}
+	l.logger.Debug("counterparty request", "method", request.Method, "host", request.URL.Hostname(),
+		"path", loggedPath(request), "status", response.StatusCode, "duration", time.Since(startedAt))
+	return response, nil
+}
+
+// loggedPath returns the path of a counterparty request without the receiver username of lnurlp requests.
+func loggedPath(request *http.Request) string {
+	if strings.HasPrefix(request.URL.Path, "/.well-known/lnurlp/") {
+		return "/.well-known/lnurlp/"
+	}
+	return request.URL.Path
+}
@@ -0,0 +1,131 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// RateSource fetches the conversion rate of a currency, in millisatoshis per smallest unit of the currency (the UMA
+// payreq `multiplier`). The Lightspark API does not provide conversion rates, so this is usually backed by the
+// VASP's own rate oracle.
+type RateSource interface {
+	FetchRate(ctx context.Context, currencyCode string) (float64, error)
+}
+
+// RateSourceFunc adapts a function to the RateSource interface.
+type RateSourceFunc func(ctx context.Context, currencyCode string) (float64, error)
+
+func (f RateSourceFunc) FetchRate(ctx context.Context, currencyCode string) (float64, error) {
+	return f(ctx, currencyCode)
+}
+
+// ErrRateUnavailable is returned when a currency has no cached rate, or its cached rate is older than MaxStaleness.
+var ErrRateUnavailable = errors.New("no fresh conversion rate available for currency")
+
+// CurrencyRateCache keeps the conversion rates of a set of currencies warm in the background, so that payreq
+// handling reads them from memory instead of blocking on a fetch. Failed refreshes are retried with an exponential
+// backoff, capped at RefreshInterval. It is safe for concurrent use.
+type CurrencyRateCache struct {
+	// Source is the source of the rates.
+	Source RateSource
+	// CurrencyCodes are the currencies kept warm.
+	CurrencyCodes []string
+	// RefreshInterval is the interval between successful refreshes. Defaults to 1 minute.
+	RefreshInterval time.Duration
+	// MaxStaleness is the age above which a cached rate is no longer served. Defaults to 5 minutes.
+	MaxStaleness time.Duration
+	// MinRetryInterval is the first retry delay after a failed refresh. Defaults to 1 second.
+	MinRetryInterval time.Duration
+
+	mutex sync.RWMutex
+	rates map[string]cachedRate
+}
+
+type cachedRate struct {
+	rate      float64
+	fetchedAt time.Time
+}
+
+func NewCurrencyRateCache(source RateSource, currencyCodes ...string) *CurrencyRateCache {
+	return &CurrencyRateCache{Source: source, CurrencyCodes: currencyCodes, rates: map[string]cachedRate{}}
+}
+
+// GetRate returns the cached rate of a currency, or ErrRateUnavailable if it is missing or stale.
+func (c *CurrencyRateCache) GetRate(currencyCode string) (float64, error) {
+	c.mutex.RLock()
+	cached, ok := c.rates[currencyCode]
+	c.mutex.RUnlock()
+	if !ok || sdkruntime.Since(cached.fetchedAt) > c.maxStaleness() {
+		return 0, ErrRateUnavailable
+	}
+	return cached.rate, nil
+}
+
+// Refresh fetches the rates of all currencies once. It returns the first error encountered, after trying every
+// currency.
+func (c *CurrencyRateCache) Refresh(ctx context.Context) error {
+	var firstErr error
+	for _, currencyCode := range c.CurrencyCodes {
+		rate, err := c.Source.FetchRate(ctx, currencyCode)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.mutex.Lock()
+		if c.rates == nil {
+			c.rates = map[string]cachedRate{}
+		}
+		c.rates[currencyCode] = cachedRate{rate: rate, fetchedAt: sdkruntime.Now()}
+		c.mutex.Unlock()
+	}
+	return firstErr
+}
+
+// Run refreshes the rates until the context is done. It refreshes once before waiting, so callers can start it in
+// a goroutine at startup.
+func (c *CurrencyRateCache) Run(ctx context.Context) {
+	refreshInterval := c.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = time.Minute
+	}
+	minRetryInterval := c.MinRetryInterval
+	if minRetryInterval == 0 {
+		minRetryInterval = time.Second
+	}
+
+	retryInterval := minRetryInterval
+	for {
+		delay := refreshInterval
+		if err := c.Refresh(ctx); err != nil {
+			delay = retryInterval
+			retryInterval *= 2
+			if retryInterval > refreshInterval {
+				retryInterval = refreshInterval
+			}
+		} else {
+			retryInterval = minRetryInterval
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *CurrencyRateCache) maxStaleness() time.Duration {
+	if c.MaxStaleness == 0 {
+		return 5 * time.Minute
+	}
+	return c.MaxStaleness
+}
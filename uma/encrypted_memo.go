@@ -0,0 +1,53 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+)
+
+// MaxEncryptedMemoLength is the maximum length in bytes of a plaintext memo. It is kept separate from the travel rule
+// info size limit since the memo is an independent field.
+const MaxEncryptedMemoLength = 1024
+
+// EncryptMemo encrypts a private payment memo for the receiving VASP using its encryption public key, and returns the
+// hex-encoded ciphertext to send alongside the travel rule info. Only the receiving VASP can read the memo.
+//
+// Args:
+//
+//	memo: the plaintext memo.
+//	receiverEncryptionPubKey: the encryption public key of the receiving VASP, as fetched from its pubkey endpoint.
+func EncryptMemo(memo string, receiverEncryptionPubKey []byte) (string, error) {
+	if len(memo) > MaxEncryptedMemoLength {
+		return "", errors.New("memo exceeds the maximum length of " + strconv.Itoa(MaxEncryptedMemoLength) + " bytes")
+	}
+	encrypted, err := crypto.EciesEncrypt(receiverEncryptionPubKey, []byte(memo))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(encrypted), nil
+}
+
+// DecryptMemo decrypts a hex-encoded memo produced by EncryptMemo using the receiving VASP's encryption private key.
+//
+// Args:
+//
+//	encryptedMemo: the hex-encoded encrypted memo.
+//	encryptionPrivateKey: the encryption private key of the receiving VASP.
+func DecryptMemo(encryptedMemo string, encryptionPrivateKey []byte) (string, error) {
+	encrypted, err := hex.DecodeString(encryptedMemo)
+	if err != nil {
+		return "", errors.New("encrypted memo is not hex encoded")
+	}
+	memo, err := crypto.EciesDecrypt(encryptionPrivateKey, encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(memo) > MaxEncryptedMemoLength {
+		return "", errors.New("memo exceeds the maximum length of " + strconv.Itoa(MaxEncryptedMemoLength) + " bytes")
+	}
+	return string(memo), nil
+}
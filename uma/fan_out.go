@@ -0,0 +1,67 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LookupFunc looks up one receiver address, e.g. by fetching the receiving VASP's pubkeys and sending it a lnurlp
+// request through the UMA SDK.
+type LookupFunc[T any] func(ctx context.Context, address string) (T, error)
+
+// LookupResult is the outcome of the lookup of one address.
+type LookupResult[T any] struct {
+	Address  string
+	Value    T
+	Err      error
+	Duration time.Duration
+}
+
+// LookupAllOptions configures LookupAll.
+type LookupAllOptions struct {
+	// MaxConcurrency is the maximum number of lookups running at the same time. Defaults to 8.
+	MaxConcurrency int
+	// PerTargetTimeout is the timeout of each individual lookup. Defaults to 10 seconds.
+	PerTargetTimeout time.Duration
+}
+
+// LookupAll resolves several receiver addresses in parallel with bounded concurrency and a timeout per address. It
+// returns one result per address, in the same order as the addresses, and never fails as a whole: the error of each
+// lookup is reported in its result. Lookups which have not started when ctx is done fail with the context error.
+func LookupAll[T any](ctx context.Context, addresses []string, lookup LookupFunc[T], options LookupAllOptions) []LookupResult[T] {
+	maxConcurrency := options.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 8
+	}
+	perTargetTimeout := options.PerTargetTimeout
+	if perTargetTimeout <= 0 {
+		perTargetTimeout = 10 * time.Second
+	}
+
+	results := make([]LookupResult[T], len(addresses))
+	semaphore := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		results[i].Address = address
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *LookupResult[T]) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			lookupCtx, cancel := context.WithTimeout(ctx, perTargetTimeout)
+			defer cancel()
+			start := time.Now()
+			result.Value, result.Err = lookup(lookupCtx, result.Address)
+			result.Duration = time.Since(start)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,189 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HandshakeStep is a round trip of the UMA handshake with the receiving VASP.
+type HandshakeStep string
+
+const (
+	HandshakeStepPubKey HandshakeStep = "pubkey"
+	HandshakeStepLnurlp HandshakeStep = "lnurlp"
+	HandshakeStepPayReq HandshakeStep = "payreq"
+)
+
+// ErrReceiverUnavailable is wrapped by SLAExceededError, so that senders can surface a "receiver unavailable" error
+// to their users with errors.Is.
+var ErrReceiverUnavailable = errors.New("receiving VASP unavailable")
+
+// SLAExceededError is reported when a round trip of the UMA handshake takes longer than its SLA threshold.
+type SLAExceededError struct {
+	Step      HandshakeStep
+	Host      string
+	Duration  time.Duration
+	Threshold time.Duration
+}
+
+func (e *SLAExceededError) Error() string {
+	return "uma " + string(e.Step) + " request to " + e.Host + " took " + e.Duration.String() +
+		" (SLA " + e.Threshold.String() + ")"
+}
+
+func (e *SLAExceededError) Unwrap() error {
+	return ErrReceiverUnavailable
+}
+
+// HandshakeSLA sets the SLA thresholds of the UMA handshake round trips made by the client returned by
+// NewCounterpartyHTTPClient. A zero threshold disables the SLA of its step.
+type HandshakeSLA struct {
+	PubKey time.Duration
+	Lnurlp time.Duration
+	PayReq time.Duration
+	// OnExceeded, if set, is called for each round trip exceeding its threshold.
+	OnExceeded func(err *SLAExceededError)
+	// Enforce aborts round trips when they exceed their threshold, failing them with a SLAExceededError. Otherwise
+	// round trips are only measured.
+	Enforce bool
+}
+
+func (s *HandshakeSLA) threshold(step HandshakeStep) time.Duration {
+	switch step {
+	case HandshakeStepPubKey:
+		return s.PubKey
+	case HandshakeStepLnurlp:
+		return s.Lnurlp
+	case HandshakeStepPayReq:
+		return s.PayReq
+	}
+	return 0
+}
+
+type handshakeStepKey struct{}
+
+// WithHandshakeStep marks the requests made with the returned context as a step of the UMA handshake. It identifies
+// the payreq requests sent to callbacks whose path has no payreq segment.
+func WithHandshakeStep(ctx context.Context, step HandshakeStep) context.Context {
+	return context.WithValue(ctx, handshakeStepKey{}, step)
+}
+
+// HandshakeStepForRequest returns the step of the UMA handshake made by a counterparty request: the step set with
+// WithHandshakeStep if any, otherwise pubkey and lnurlp requests are identified by their well-known paths, and payreq
+// requests by a `payreq` segment in their path, e.g. /api/uma/payreq/{uuid}. It returns false for other requests.
+func HandshakeStepForRequest(request *http.Request) (HandshakeStep, bool) {
+	if step, ok := request.Context().Value(handshakeStepKey{}).(HandshakeStep); ok {
+		return step, true
+	}
+	path := request.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/.well-known/lnurlpubkey"):
+		return HandshakeStepPubKey, true
+	case strings.HasPrefix(path, "/.well-known/lnurlp/"):
+		return HandshakeStepLnurlp, true
+	case hasPayReqSegment(path):
+		return HandshakeStepPayReq, true
+	}
+	return "", false
+}
+
+func hasPayReqSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.EqualFold(segment, "payreq") {
+			return true
+		}
+	}
+	return false
+}
+
+// slaRoundTripper measures each handshake round trip against its SLA threshold.
+type slaRoundTripper struct {
+	next http.RoundTripper
+	sla  *HandshakeSLA
+}
+
+func (s *slaRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	step, ok := HandshakeStepForRequest(request)
+	threshold := s.sla.threshold(step)
+	if !ok || threshold <= 0 {
+		return s.next.RoundTrip(request)
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if s.sla.Enforce {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(request.Context(), threshold)
+		request = request.WithContext(ctx)
+	}
+	startedAt := time.Now()
+	response, err := s.next.RoundTrip(request)
+	duration := time.Since(startedAt)
+	if duration > threshold {
+		slaErr := s.exceeded(request, step, duration, threshold)
+		if s.sla.Enforce {
+			cancel()
+			if response != nil {
+				response.Body.Close()
+			}
+			return nil, slaErr
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The body is read after the round trip, so the deadline is only released when it is closed, and reads failing
+	// with the deadline are reported as exceeding the SLA.
+	response.Body = &slaBody{ReadCloser: response.Body, cancel: cancel, onError: func() error {
+		duration := time.Since(startedAt)
+		if !s.sla.Enforce || duration <= threshold {
+			return nil
+		}
+		return s.exceeded(request, step, duration, threshold)
+	}}
+	return response, nil
+}
+
+func (s *slaRoundTripper) exceeded(
+	request *http.Request, step HandshakeStep, duration time.Duration, threshold time.Duration,
+) *SLAExceededError {
+	slaErr := &SLAExceededError{Step: step, Host: request.URL.Hostname(), Duration: duration, Threshold: threshold}
+	if s.sla.OnExceeded != nil {
+		s.sla.OnExceeded(slaErr)
+	}
+	return slaErr
+}
+
+// slaBody releases the deadline of a round trip when it is closed, and fails the reads interrupted after the threshold
+// with a SLAExceededError.
+type slaBody struct {
+	io.ReadCloser
+	cancel  context.CancelFunc
+	onError func() error
+	slaErr  error
+}
+
+func (b *slaBody) Read(p []byte) (int, error) {
+	if b.slaErr != nil {
+		return 0, b.slaErr
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		// The transport reports the deadline with different errors depending on when it interrupts the read.
+		if slaErr := b.onError(); slaErr != nil {
+			b.slaErr = slaErr
+			return n, slaErr
+		}
+	}
+	return n, err
+}
+
+func (b *slaBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,158 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// identifierSaltContext separates the identifier salt from other uses of the ECDH shared secret of two VASPs.
+const identifierSaltContext = "uma-identifier-salt"
+
+// identifierKeyContext separates the identifier keys of a VASP from its encryption keys.
+const identifierKeyContext = "uma-identifier-key"
+
+// hashedIdentifierLength is the number of hex characters of a hashed user name.
+const hashedIdentifierLength = 32
+
+// IDENTIFIER_SALT_NONCE_LENGTH is the length in bytes of the nonces returned by NewIdentifierSaltNonce.
+const IDENTIFIER_SALT_NONCE_LENGTH = 16
+
+// NewIdentifierSaltNonce returns a random nonce for DeriveIdentifierSalt. The sending VASP picks a new nonce for each
+// payment and sends it to the receiving VASP along with the hashed identifiers.
+func NewIdentifierSaltNonce() ([]byte, error) {
+	nonce := make([]byte, IDENTIFIER_SALT_NONCE_LENGTH)
+	if _, err := sdkruntime.Default().Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// DeriveIdentifierSalt derives the salt used to hash the identifiers exchanged by two VASPs for one payment. It is
+// computed with ECDH between identifier keys derived from the encryption keys of the VASPs, as fetched from their
+// pubkey endpoints, and the nonce of the payment. Both VASPs derive the same salt without exchanging it, the salt
+// differs for each payment, so that hashed identifiers cannot be linked across payments, and the shared secret differs
+// from the one of the encryption keys.
+//
+// Args:
+//
+//	encryptionPrivateKey: the encryption private key of this VASP.
+//	counterpartyEncryptionPubKey: the encryption public key of the counterparty VASP.
+//	nonce: the nonce of the payment, from NewIdentifierSaltNonce.
+func DeriveIdentifierSalt(encryptionPrivateKey []byte, counterpartyEncryptionPubKey []byte, nonce []byte,
+) ([]byte, error) {
+	if len(nonce) < IDENTIFIER_SALT_NONCE_LENGTH {
+		return nil, errors.New("the identifier salt nonce must be at least 16 bytes long")
+	}
+	privateKey, publicKey := btcec.PrivKeyFromBytes(encryptionPrivateKey)
+	counterpartyPublicKey, err := btcec.ParsePubKey(counterpartyEncryptionPubKey)
+	if err != nil {
+		return nil, errors.New("invalid counterparty encryption public key")
+	}
+	identifierPrivateKey := identifierPrivateKey(privateKey, publicKey)
+	sharedSecret := btcec.GenerateSharedSecret(identifierPrivateKey, identifierPublicKey(counterpartyPublicKey))
+	mac := hmac.New(sha256.New, sharedSecret)
+	mac.Write([]byte(identifierSaltContext))
+	mac.Write(nonce)
+	return mac.Sum(nil), nil
+}
+
+// identifierKeyTweak returns the scalar added to an encryption key to derive the identifier key of a VASP. It only
+// depends on the public key, so that the counterparty can derive the identifier public key.
+func identifierKeyTweak(publicKey *btcec.PublicKey) *btcec.ModNScalar {
+	hash := sha256.Sum256(append([]byte(identifierKeyContext), publicKey.SerializeCompressed()...))
+	var tweak btcec.ModNScalar
+	tweak.SetBytes(&hash)
+	return &tweak
+}
+
+func identifierPrivateKey(privateKey *btcec.PrivateKey, publicKey *btcec.PublicKey) *btcec.PrivateKey {
+	var key btcec.ModNScalar
+	key.Set(&privateKey.Key)
+	key.Add(identifierKeyTweak(publicKey))
+	return btcec.PrivKeyFromScalar(&key)
+}
+
+func identifierPublicKey(publicKey *btcec.PublicKey) *btcec.PublicKey {
+	var point, tweakPoint btcec.JacobianPoint
+	publicKey.AsJacobian(&point)
+	btcec.ScalarBaseMultNonConst(identifierKeyTweak(publicKey), &tweakPoint)
+	btcec.AddNonConst(&point, &tweakPoint, &point)
+	point.ToAffine()
+	return btcec.NewPublicKey(&point.X, &point.Y)
+}
+
+// HashIdentifier replaces the user name of a UMA address with its salted hash, e.g. $alice@vasp.com becomes
+// $3a1f...@vasp.com. The result is still a valid UMA address, so it can be sent in the identifier fields of
+// compliance payloads where the full identifier is not required. The domain is kept for routing and screening.
+//
+// Args:
+//
+//	identifier: the UMA address to hash.
+//	salt: the salt derived with DeriveIdentifierSalt.
+func HashIdentifier(identifier string, salt []byte) (string, error) {
+	user, domain, err := splitIdentifier(identifier)
+	if err != nil {
+		return "", err
+	}
+	return "$" + hashUser(user, salt) + "@" + domain, nil
+}
+
+// MatchHashedIdentifier returns whether a hashed identifier received from a counterparty is the hash of an
+// identifier, e.g. to look up the user a hashed payee identifier refers to.
+//
+// Args:
+//
+//	hashedIdentifier: the identifier received, as returned by HashIdentifier.
+//	identifier: the full UMA address to compare with.
+//	salt: the salt derived with DeriveIdentifierSalt.
+func MatchHashedIdentifier(hashedIdentifier string, identifier string, salt []byte) bool {
+	hashedUser, hashedDomain, err := splitIdentifier(hashedIdentifier)
+	if err != nil {
+		return false
+	}
+	user, domain, err := splitIdentifier(identifier)
+	if err != nil || !strings.EqualFold(hashedDomain, domain) {
+		return false
+	}
+	return hmac.Equal([]byte(strings.ToLower(hashedUser)), []byte(hashUser(user, salt)))
+}
+
+// PrivateIdentifier returns the identifier to send for a payment: the full identifier when the travel rule policy
+// requires travel rule information for the payment, and its salted hash otherwise.
+//
+// Args:
+//
+//	identifier: the UMA address of the payer or receiver.
+//	salt: the salt derived with DeriveIdentifierSalt.
+//	policy: the travel rule policy of the sending VASP.
+//	jurisdiction: the jurisdiction of the payment, e.g. the receiving VASP's country code.
+//	currencyCode: the currency the amount is expressed in.
+//	amount: the amount of the payment, in the smallest unit of the currency.
+func PrivateIdentifier(identifier string, salt []byte, policy TravelRulePolicy, jurisdiction string,
+	currencyCode string, amount int64) (string, error) {
+	if policy.IsRequired(jurisdiction, currencyCode, amount) {
+		return identifier, nil
+	}
+	return HashIdentifier(identifier, salt)
+}
+
+func splitIdentifier(identifier string) (string, string, error) {
+	user, domain, ok := strings.Cut(strings.TrimPrefix(identifier, "$"), "@")
+	if !ok || user == "" || domain == "" {
+		return "", "", errors.New("invalid UMA address: " + identifier)
+	}
+	return user, domain, nil
+}
+
+func hashUser(user string, salt []byte) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(strings.ToLower(user)))
+	return hex.EncodeToString(mac.Sum(nil))[:hashedIdentifierLength]
+}
@@ -0,0 +1,72 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/services"
+)
+
+// INVOICE_DEADLINE_RETRY_AFTER_SECS is the Retry-After delay sent with InvoiceDeadlineExceededError responses.
+const INVOICE_DEADLINE_RETRY_AFTER_SECS = 1
+
+// InvoiceDeadlineExceededError is returned by the invoice creators when the creation of an invoice exceeds their
+// InvoiceDeadline. The underlying request is cancelled. The payreq can be retried by the sender, so the payreq
+// handler should answer with WriteResponse instead of a generic failure.
+type InvoiceDeadlineExceededError struct {
+	Err error
+}
+
+func (e *InvoiceDeadlineExceededError) Error() string {
+	return "invoice creation exceeded its deadline: " + e.Err.Error()
+}
+
+func (e *InvoiceDeadlineExceededError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns true: the sender may send the payreq again.
+func (e *InvoiceDeadlineExceededError) Retryable() bool {
+	return true
+}
+
+// WriteResponse answers the payreq with 503 Service Unavailable, a Retry-After header and an LNURL error body, so
+// that the sender retries the payreq instead of failing with an opaque timeout.
+func (e *InvoiceDeadlineExceededError) WriteResponse(w http.ResponseWriter) {
+	body, _ := json.Marshal(map[string]string{
+		"status": "ERROR",
+		"reason": "invoice creation timed out, please retry",
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(INVOICE_DEADLINE_RETRY_AFTER_SECS))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = w.Write(body)
+}
+
+// clientWithDeadline returns a copy of client whose requester cancels calls after deadline, if it is shorter than
+// the timeout of the requester.
+func clientWithDeadline(client services.LightsparkClient, deadline time.Duration) services.LightsparkClient {
+	if deadline <= 0 || client.Requester == nil {
+		return client
+	}
+	if client.Requester.Timeout > 0 && client.Requester.Timeout <= deadline {
+		return client
+	}
+	requesterCopy := *client.Requester
+	requesterCopy.Timeout = deadline
+	client.Requester = &requesterCopy
+	return client
+}
+
+// deadlineError wraps the errors caused by the InvoiceDeadline in an InvoiceDeadlineExceededError.
+func deadlineError(err error, deadline time.Duration) error {
+	if err != nil && deadline > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return &InvoiceDeadlineExceededError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/services"
+)
+
+// LnurlInvoiceCreator creates invoices for plain LNURL-pay requests. It takes no key material: invoice creation is the
+// same for custodial (OSK) nodes and remote signing nodes, whose invoices are signed through the remote signing
+// webhook flow instead.
+type LnurlInvoiceCreator interface {
+	CreateLnurlInvoice(amountMsats int64, metadata string) (*string, error)
+}
+
+// LightsparkClientLnurlInvoiceCreator is a wrapper around the LightsparkClient that implements the LnurlInvoiceCreator
+// interface.
+type LightsparkClientLnurlInvoiceCreator struct {
+	LightsparkClient services.LightsparkClient
+	// NodeId: the node ID of the receiver.
+	NodeId string
+	// NodeSelector: if set, chooses the node issuing each invoice instead of NodeId.
+	NodeSelector NodeSelector
+	// ExpirySecs: the number of seconds until the invoice expires.
+	ExpirySecs *int32
+	// ExpiryJitterSecs: if set, each invoice expires up to this number of seconds earlier, chosen at random. See
+	// JitterExpirySecs.
+	ExpiryJitterSecs int32
+	// InvoiceDeadline: if set, the invoice creation is cancelled after this duration, usually the timeout budget of
+	// the counterparty minus a margin, and fails with an InvoiceDeadlineExceededError.
+	InvoiceDeadline time.Duration
+}
+
+func (l LightsparkClientLnurlInvoiceCreator) CreateLnurlInvoice(amountMsats int64, metadata string) (*string, error) {
+	nodeId, err := selectNode(l.NodeSelector, l.NodeId, amountMsats, metadata)
+	if err != nil {
+		return nil, err
+	}
+	client := clientWithDeadline(l.LightsparkClient, l.InvoiceDeadline)
+	invoice, err := client.CreateLnurlInvoice(nodeId, amountMsats, metadata,
+		JitterExpirySecs(l.ExpirySecs, l.ExpiryJitterSecs))
+	if err != nil {
+		return nil, deadlineError(err, l.InvoiceDeadline)
+	}
+	return &invoice.Data.EncodedPaymentRequest, nil
+}
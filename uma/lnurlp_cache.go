@@ -0,0 +1,207 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_LNURLP_CACHE_TTL is the default time lnurlp responses are cached by an LnurlpCache.
+const DEFAULT_LNURLP_CACHE_TTL = time.Minute
+
+// LnurlpCache caches the lnurlp responses of receiving VASPs per receiver address and query parameters, so that
+// repeat payments to the same receiver within a short window skip the lnurlp round trip. It is installed with
+// CounterpartyHTTPClientConfig.LnurlpCache, and is safe for concurrent use.
+//
+// Signed UMA lnurlp exchanges are never cached: a cached response would replay the nonce of the receiver's signature,
+// which senders checking nonces reject. Only the unsigned LNURL exchanges are cached.
+//
+// Cached responses of a domain are dropped when its pubkey response changes, i.e. when the receiving VASP rotates
+// its signing keys. Invalidate and InvalidateDomain drop responses explicitly, e.g. after the signature of a cached
+// response failed to verify.
+type LnurlpCache struct {
+	// TTL is the time a response is cached. Defaults to DEFAULT_LNURLP_CACHE_TTL.
+	TTL time.Duration
+
+	mutex        sync.Mutex
+	entries      map[string]lnurlpCacheEntry
+	pubKeyHashes map[string][sha256.Size]byte
+}
+
+type lnurlpCacheEntry struct {
+	domain     string
+	path       string
+	statusCode int
+	header     http.Header
+	body       []byte
+	cachedAt   time.Time
+}
+
+// NewLnurlpCache creates an LnurlpCache caching responses for the given time.
+func NewLnurlpCache(ttl time.Duration) *LnurlpCache {
+	return &LnurlpCache{TTL: ttl}
+}
+
+// Invalidate drops the cached responses for a receiver address, e.g. $alice@vasp.com, for all UMA versions.
+func (c *LnurlpCache) Invalidate(receiverAddress string) error {
+	user, domain, ok := strings.Cut(strings.TrimPrefix(receiverAddress, "$"), "@")
+	if !ok || user == "" || domain == "" {
+		return errors.New("invalid receiver address: " + receiverAddress)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	path := "/.well-known/lnurlp/" + user
+	for key, entry := range c.entries {
+		if entry.domain == strings.ToLower(domain) && entry.path == path {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// InvalidateDomain drops the cached responses of all the receivers of a domain.
+func (c *LnurlpCache) InvalidateDomain(domain string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidateDomain(strings.ToLower(domain))
+}
+
+func (c *LnurlpCache) invalidateDomain(domain string) {
+	for key, entry := range c.entries {
+		if entry.domain == domain {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *LnurlpCache) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DEFAULT_LNURLP_CACHE_TTL
+}
+
+// lnurlpCacheKey returns the cache key of an lnurlp request: the receiver and all the query parameters, e.g. the
+// vaspDomain of the sending identity, so that a response is only served for the request it answered.
+func lnurlpCacheKey(request *http.Request) (string, string) {
+	domain := strings.ToLower(request.URL.Host)
+	return domain + request.URL.Path + "?" + request.URL.Query().Encode(), domain
+}
+
+// isSignedLnurlpExchange returns whether an lnurlp request or its response carries an UMA signature.
+func isSignedLnurlpExchange(request *http.Request, body []byte) bool {
+	if request.URL.Query().Get("signature") != "" {
+		return true
+	}
+	var response struct {
+		Compliance *struct {
+			Signature string `json:"signature"`
+		} `json:"compliance"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		// Bodies which cannot be inspected are not cached.
+		return true
+	}
+	return response.Signature != "" || (response.Compliance != nil && response.Compliance.Signature != "")
+}
+
+func (c *LnurlpCache) get(key string) *http.Response {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if sdkruntime.Since(entry.cachedAt) > c.ttl() {
+		delete(c.entries, key)
+		return nil
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(entry.statusCode) + " " + http.StatusText(entry.statusCode),
+		StatusCode:    entry.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+	}
+}
+
+func (c *LnurlpCache) set(key string, entry lnurlpCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]lnurlpCacheEntry{}
+	}
+	c.entries[key] = entry
+}
+
+// observePubKey drops the cached responses of a domain if its pubkey response changed.
+func (c *LnurlpCache) observePubKey(domain string, body []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pubKeyHashes == nil {
+		c.pubKeyHashes = map[string][sha256.Size]byte{}
+	}
+	hash := sha256.Sum256(body)
+	if previous, ok := c.pubKeyHashes[domain]; ok && previous != hash {
+		c.invalidateDomain(domain)
+	}
+	c.pubKeyHashes[domain] = hash
+}
+
+// lnurlpCacheRoundTripper serves lnurlp requests from an LnurlpCache.
+type lnurlpCacheRoundTripper struct {
+	next  http.RoundTripper
+	cache *LnurlpCache
+}
+
+func (l *lnurlpCacheRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	step, ok := HandshakeStepForRequest(request)
+	if !ok || request.Method != http.MethodGet || step == HandshakeStepPayReq {
+		return l.next.RoundTrip(request)
+	}
+	key, domain := lnurlpCacheKey(request)
+	if step == HandshakeStepLnurlp && request.URL.Query().Get("signature") == "" {
+		if response := l.cache.get(key); response != nil {
+			response.Request = request
+			return response, nil
+		}
+	}
+
+	response, err := l.next.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	if step == HandshakeStepPubKey {
+		l.cache.observePubKey(domain, body)
+	} else if !isSignedLnurlpExchange(request, body) {
+		l.cache.set(key, lnurlpCacheEntry{
+			domain:     domain,
+			path:       request.URL.Path,
+			statusCode: response.StatusCode,
+			header:     response.Header.Clone(),
+			body:       body,
+			cachedAt:   sdkruntime.Now(),
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,213 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_INVOICE_EXPIRY_SECS is the expiry applied by the API to invoices created without an explicit expiry.
+const DEFAULT_INVOICE_EXPIRY_SECS = 86400
+
+// JitterExpirySecs shortens an invoice expiry by a random number of seconds in [0, jitterSecs], so that the invoices
+// of a receiving VASP do not all carry the same expiry, which would fingerprint the VASP issuing them. It does not
+// hide when an invoice was created: BOLT11 invoices encode their creation timestamp. A nil expirySecs is jittered
+// from DEFAULT_INVOICE_EXPIRY_SECS. The expiry is never shortened below one second.
+func JitterExpirySecs(expirySecs *int32, jitterSecs int32) *int32 {
+	if jitterSecs <= 0 {
+		return expirySecs
+	}
+	expiry := int32(DEFAULT_INVOICE_EXPIRY_SECS)
+	if expirySecs != nil {
+		expiry = *expirySecs
+	}
+	jitter, err := sdkruntime.Default().Int(big.NewInt(int64(jitterSecs) + 1))
+	if err != nil {
+		return expirySecs
+	}
+	expiry -= int32(jitter.Int64())
+	if expiry < 1 {
+		expiry = 1
+	}
+	return &expiry
+}
+
+// LookupGuardOptions configures LookupGuardMiddleware.
+type LookupGuardOptions struct {
+	// MinResponseTime is the minimum duration of every lnurlp response, found or not, so that the response time does
+	// not reveal whether a user exists. It should be longer than the slowest lookup. Defaults to 200 milliseconds.
+	MinResponseTime time.Duration
+	// MaxNotFound is the number of 404 responses a client may receive per NotFoundWindow. Further lnurlp requests
+	// of the client are rejected with 429 Too Many Requests until the window ends. Defaults to 20.
+	MaxNotFound int
+	// NotFoundWindow is the period over which 404 responses are counted. Defaults to 1 minute.
+	NotFoundWindow time.Duration
+	// ClientKey returns the key identifying the client of a request. Defaults to the host of the remote address.
+	// Behind a reverse proxy or load balancer the remote address is the one of the proxy, so that the default key
+	// rate limits all the clients together: use ForwardedForClientKey, or read the client address set by the proxy.
+	ClientKey func(request *http.Request) string
+}
+
+// ForwardedForClientKey returns a LookupGuardOptions.ClientKey reading the client address from the X-Forwarded-For
+// header, for handlers behind trustedProxies reverse proxies each appending the address they received the request
+// from. Entries before the one appended by the outermost trusted proxy are set by the client and ignored. Requests
+// with fewer entries are keyed by their remote address.
+func ForwardedForClientKey(trustedProxies int) func(request *http.Request) string {
+	return func(request *http.Request) string {
+		var entries []string
+		for _, header := range request.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				entries = append(entries, strings.TrimSpace(entry))
+			}
+		}
+		if trustedProxies <= 0 || len(entries) < trustedProxies || entries[len(entries)-trustedProxies] == "" {
+			return remoteHost(request)
+		}
+		return entries[len(entries)-trustedProxies]
+	}
+}
+
+type notFoundCount struct {
+	count   int
+	resetAt time.Time
+}
+
+// LookupGuardMiddleware wraps the lnurlp handler of a receiving VASP to mitigate user enumeration. lnurlp responses
+// are delayed to MinResponseTime, and clients receiving too many 404 responses are rate limited. Other requests are
+// passed through.
+func LookupGuardMiddleware(options LookupGuardOptions) func(http.Handler) http.Handler {
+	minResponseTime := options.MinResponseTime
+	if minResponseTime <= 0 {
+		minResponseTime = 200 * time.Millisecond
+	}
+	maxNotFound := options.MaxNotFound
+	if maxNotFound <= 0 {
+		maxNotFound = 20
+	}
+	window := options.NotFoundWindow
+	if window <= 0 {
+		window = time.Minute
+	}
+	clientKey := options.ClientKey
+	if clientKey == nil {
+		clientKey = remoteHost
+	}
+	var mutex sync.Mutex
+	counts := map[string]*notFoundCount{}
+	// Expired counts are swept once per window rather than on every request.
+	var nextSweep time.Time
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			if _, ok := MatchLnurlpPath(request.URL.Path); !ok {
+				next.ServeHTTP(w, request)
+				return
+			}
+			deadline := time.Now().Add(minResponseTime)
+			key := clientKey(request)
+
+			mutex.Lock()
+			now := sdkruntime.Now()
+			if !now.Before(nextSweep) {
+				for otherKey, count := range counts {
+					if !now.Before(count.resetAt) {
+						delete(counts, otherKey)
+					}
+				}
+				nextSweep = now.Add(window)
+			}
+			if count := counts[key]; count != nil && !now.Before(count.resetAt) {
+				delete(counts, key)
+			}
+			limited := counts[key] != nil && counts[key].count >= maxNotFound
+			var retryAfter time.Duration
+			if limited {
+				retryAfter = counts[key].resetAt.Sub(now)
+			}
+			mutex.Unlock()
+
+			if limited {
+				waitUntil(request, deadline)
+				w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			recorder := &bufferedResponseWriter{header: http.Header{}, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, request)
+
+			if recorder.statusCode == http.StatusNotFound {
+				mutex.Lock()
+				count := counts[key]
+				now := sdkruntime.Now()
+				if count == nil || !now.Before(count.resetAt) {
+					count = &notFoundCount{resetAt: now.Add(window)}
+					counts[key] = count
+				}
+				count.count++
+				mutex.Unlock()
+			}
+
+			waitUntil(request, deadline)
+			for name, values := range recorder.header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(recorder.statusCode)
+			_, _ = w.Write(recorder.body.Bytes())
+		})
+	}
+}
+
+// waitUntil sleeps until deadline, or until the request is canceled.
+func waitUntil(request *http.Request, deadline time.Time) {
+	delay := time.Until(deadline)
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-request.Context().Done():
+	}
+}
+
+func remoteHost(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// bufferedResponseWriter holds a response until it can be sent, so that its timing does not depend on the handler.
+type bufferedResponseWriter struct {
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(data)
+}
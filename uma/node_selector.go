@@ -0,0 +1,97 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+// NodeSelector chooses which node issues the invoice for a payreq, for accounts with several receiving nodes. It
+// returns an error if no node can issue the invoice: an empty node id fails the invoice creation.
+type NodeSelector interface {
+	SelectNode(amountMsats int64, metadata string) (string, error)
+}
+
+// NodeSelectorFunc adapts a plain function to the NodeSelector interface.
+type NodeSelectorFunc func(amountMsats int64, metadata string) (string, error)
+
+func (f NodeSelectorFunc) SelectNode(amountMsats int64, metadata string) (string, error) {
+	return f(amountMsats, metadata)
+}
+
+// StaticNodeSelector always selects the same node.
+type StaticNodeSelector string
+
+func (s StaticNodeSelector) SelectNode(amountMsats int64, metadata string) (string, error) {
+	return string(s), nil
+}
+
+// RoundRobinNodeSelector spreads invoices evenly over a list of nodes. It is safe for concurrent use.
+type RoundRobinNodeSelector struct {
+	NodeIds []string
+	next    uint64
+}
+
+func (s *RoundRobinNodeSelector) SelectNode(amountMsats int64, metadata string) (string, error) {
+	if len(s.NodeIds) == 0 {
+		return "", errors.New("no node to select from")
+	}
+	index := atomic.AddUint64(&s.next, 1) - 1
+	return s.NodeIds[index%uint64(len(s.NodeIds))], nil
+}
+
+// InboundLiquidityNodeSelector selects the node with the most inbound liquidity (remote balance), so that the
+// payment is most likely to be routable. It fails if no node has enough inbound liquidity for the amount.
+type InboundLiquidityNodeSelector struct {
+	LightsparkClient *services.LightsparkClient
+	NodeIds          []string
+}
+
+func (s InboundLiquidityNodeSelector) SelectNode(amountMsats int64, metadata string) (string, error) {
+	var selectedNodeId string
+	var selectedRemoteBalanceMsats int64 = -1
+	for _, nodeId := range s.NodeIds {
+		entity, err := s.LightsparkClient.GetEntity(nodeId)
+		if err != nil {
+			return "", err
+		}
+		node, ok := (*entity).(objects.LightsparkNode)
+		if !ok {
+			return "", errors.New("failed to cast entity to LightsparkNode")
+		}
+		if node.GetRemoteBalance() == nil {
+			continue
+		}
+		remoteBalanceMsats, err := utils.ValueMilliSatoshi(*node.GetRemoteBalance())
+		if err != nil {
+			return "", err
+		}
+		if remoteBalanceMsats > selectedRemoteBalanceMsats {
+			selectedNodeId = nodeId
+			selectedRemoteBalanceMsats = remoteBalanceMsats
+		}
+	}
+	if selectedNodeId == "" || selectedRemoteBalanceMsats < amountMsats {
+		return "", errors.New("no node has enough inbound liquidity for the payment")
+	}
+	return selectedNodeId, nil
+}
+
+// selectNode returns the node chosen by the selector, or the given node id if there is no selector.
+func selectNode(selector NodeSelector, nodeId string, amountMsats int64, metadata string) (string, error) {
+	if selector == nil {
+		return nodeId, nil
+	}
+	selectedNodeId, err := selector.SelectNode(amountMsats, metadata)
+	if err != nil {
+		return "", err
+	}
+	if selectedNodeId == "" {
+		return "", errors.New("the node selector returned no node")
+	}
+	return selectedNodeId, nil
+}
@@ -0,0 +1,177 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/services"
+)
+
+// ErrAttributionNotFound is returned when no payer attribution is stored for a payment hash.
+var ErrAttributionNotFound = errors.New("payer attribution not found")
+
+// PayerAttribution is the payer data received in a payreq, recorded against the payment hash of the invoice created
+// for it, so that the payment can be attributed to its payer when it settles.
+type PayerAttribution struct {
+	PaymentHash string `json:"payment_hash"`
+	// PayerIdentifier is the UMA address of the payer, e.g. $alice@vasp1.com.
+	PayerIdentifier  string  `json:"payer_identifier"`
+	PayerName        *string `json:"payer_name,omitempty"`
+	PayerEmail       *string `json:"payer_email,omitempty"`
+	SenderVaspDomain string  `json:"sender_vasp_domain"`
+	// Compliance is the compliance payer data of the payreq, as received.
+	Compliance  json.RawMessage `json:"compliance,omitempty"`
+	AmountMsats int64           `json:"amount_msats"`
+	RecordedAt  time.Time       `json:"recorded_at"`
+}
+
+// AttributionStore stores payer attributions by payment hash. Implementations should persist them for at least the
+// expiry of the invoices.
+type AttributionStore interface {
+	SaveAttribution(attribution PayerAttribution) error
+	// GetAttribution returns ErrAttributionNotFound if no attribution is stored for the payment hash.
+	GetAttribution(paymentHash string) (*PayerAttribution, error)
+}
+
+// InMemoryAttributionStore is an AttributionStore which keeps attributions in memory for a fixed time. Expired and
+// deleted attributions are no longer returned, and are removed by Sweep. It is safe for concurrent use.
+type InMemoryAttributionStore struct {
+	ttl          time.Duration
+	mutex        sync.Mutex
+	attributions map[string]*attributionEntry
+}
+
+type attributionEntry struct {
+	attribution PayerAttribution
+	deleted     bool
+}
+
+// NewInMemoryAttributionStore creates an InMemoryAttributionStore keeping attributions for the given time, which
+// should be longer than the expiry of the invoices.
+func NewInMemoryAttributionStore(ttl time.Duration) *InMemoryAttributionStore {
+	return &InMemoryAttributionStore{ttl: ttl, attributions: map[string]*attributionEntry{}}
+}
+
+func (s *InMemoryAttributionStore) SaveAttribution(attribution PayerAttribution) error {
+	if attribution.PaymentHash == "" {
+		return errors.New("payer attribution must have a payment hash")
+	}
+	if attribution.RecordedAt.IsZero() {
+		attribution.RecordedAt = sdkruntime.Now().UTC()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributions[attribution.PaymentHash] = &attributionEntry{attribution: attribution}
+	return nil
+}
+
+func (s *InMemoryAttributionStore) GetAttribution(paymentHash string) (*PayerAttribution, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, ok := s.attributions[paymentHash]
+	if !ok || entry.deleted || s.isExpired(entry) {
+		return nil, ErrAttributionNotFound
+	}
+	attribution := entry.attribution
+	return &attribution, nil
+}
+
+// DeleteAttribution soft-deletes the attribution of a payment hash, e.g. once the payment was reported. It is no
+// longer returned, but is kept until the next Sweep so that it can be archived.
+func (s *InMemoryAttributionStore) DeleteAttribution(paymentHash string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if entry, ok := s.attributions[paymentHash]; ok {
+		entry.deleted = true
+	}
+}
+
+func (s *InMemoryAttributionStore) Sweep(archive ArchiveFunc) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	removed := 0
+	for paymentHash, entry := range s.attributions {
+		if !entry.deleted && !s.isExpired(entry) {
+			continue
+		}
+		if archive != nil {
+			if err := archive(entry.attribution); err != nil {
+				return removed, err
+			}
+		}
+		delete(s.attributions, paymentHash)
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *InMemoryAttributionStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.attributions)
+}
+
+func (s *InMemoryAttributionStore) isExpired(entry *attributionEntry) bool {
+	return sdkruntime.Since(entry.attribution.RecordedAt) > s.ttl
+}
+
+// CreateAttributedUmaInvoice creates an UMA invoice like CreateUmaInvoice, and records the payer data of the payreq
+// against its payment hash in the store. Call it from the payreq handler instead of CreateUmaInvoice.
+//
+// Args:
+//
+//	amountMsats: the amount of the invoice.
+//	metadata: the LNURL metadata of the invoice.
+//	attribution: the payer data of the payreq. PaymentHash and AmountMsats are set from the invoice.
+//	store: the store in which the attribution is recorded.
+func (l LightsparkClientUmaInvoiceCreator) CreateAttributedUmaInvoice(amountMsats int64, metadata string,
+	attribution PayerAttribution, store AttributionStore,
+) (*string, error) {
+	nodeId, err := l.selectNode(amountMsats, metadata)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := l.LightsparkClient.CreateUmaInvoice(nodeId, amountMsats, metadata, l.expirySecs(amountMsats, metadata))
+	if err != nil {
+		return nil, err
+	}
+	attribution.PaymentHash = invoice.Data.PaymentHash
+	attribution.AmountMsats = amountMsats
+	if err := store.SaveAttribution(attribution); err != nil {
+		return nil, err
+	}
+	return &invoice.Data.EncodedPaymentRequest, nil
+}
+
+// GetAttributionForIncomingPayment returns the payer attribution of a settled incoming payment, e.g. the entity of a
+// PAYMENT_FINISHED webhook event. It returns ErrAttributionNotFound for payments of invoices which were not created
+// with CreateAttributedUmaInvoice, and for keysend payments.
+func GetAttributionForIncomingPayment(client *services.LightsparkClient, store AttributionStore,
+	incomingPaymentId string,
+) (*PayerAttribution, error) {
+	entity, err := client.GetEntity(incomingPaymentId)
+	if err != nil {
+		return nil, err
+	}
+	incomingPayment, ok := (*entity).(objects.IncomingPayment)
+	if !ok {
+		return nil, errors.New("entity is not an incoming payment: " + incomingPaymentId)
+	}
+	if incomingPayment.PaymentRequest == nil {
+		return nil, ErrAttributionNotFound
+	}
+	entity, err = client.GetEntity(incomingPayment.PaymentRequest.Id)
+	if err != nil {
+		return nil, err
+	}
+	invoice, ok := (*entity).(objects.Invoice)
+	if !ok {
+		return nil, ErrAttributionNotFound
+	}
+	return store.GetAttribution(invoice.Data.PaymentHash)
+}
@@ -0,0 +1,311 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// MAX_CANCELLATION_AGE is how old a PayreqCancellation may be when it is verified, bounding the window in which a
+// captured cancellation can be replayed.
+const MAX_CANCELLATION_AGE = 5 * time.Minute
+
+// DEFAULT_ISSUED_PAYREQ_TTL is the default time an IssuedPayreqs store lets the sending VASP cancel a payreq.
+const DEFAULT_ISSUED_PAYREQ_TTL = time.Hour
+
+// PayreqCancellation is a message posted by a sending VASP to the payreq callback of the receiving VASP when it
+// abandons a quoted payreq, e.g. because the sender did not confirm the payment, so that the receiver can release
+// the liquidity reserved for the quote and invalidate its invoice early. It is signed with the signing key of the
+// sending VASP.
+type PayreqCancellation struct {
+	// Invoice is the encoded invoice of the abandoned payreq response.
+	Invoice string `json:"invoice"`
+	// SenderVaspDomain is the domain of the sending VASP, used to fetch its signing public key.
+	SenderVaspDomain string `json:"senderVaspDomain"`
+	// Reason optionally explains why the payreq was abandoned.
+	Reason string `json:"reason,omitempty"`
+	// Timestamp is the time at which the cancellation was signed, in seconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+	// Signature is the hex-encoded DER secp256k1 ECDSA signature of the cancellation.
+	Signature string `json:"signature"`
+}
+
+// SignPayreqCancellation produces a PayreqCancellation for the invoice of a payreq response.
+//
+// Args:
+//
+//	invoice: the encoded invoice of the abandoned payreq response.
+//	senderVaspDomain: the domain of the sending VASP.
+//	reason: an optional explanation, or an empty string.
+//	signingPrivateKey: the secp256k1 signing private key of the sending VASP.
+func SignPayreqCancellation(invoice string, senderVaspDomain string, reason string, signingPrivateKey []byte,
+) (*PayreqCancellation, error) {
+	if invoice == "" {
+		return nil, errors.New("missing invoice")
+	}
+	if senderVaspDomain == "" {
+		return nil, errors.New("missing sender vasp domain")
+	}
+	cancellation := &PayreqCancellation{
+		Invoice:          invoice,
+		SenderVaspDomain: senderVaspDomain,
+		Reason:           reason,
+		Timestamp:        sdkruntime.Now().Unix(),
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(signingPrivateKey)
+	hash := cancellation.signedHash(false)
+	cancellation.Signature = hex.EncodeToString(ecdsa.Sign(privateKey, hash[:]).Serialize())
+	return cancellation, nil
+}
+
+// VerifyPayreqCancellation checks that a PayreqCancellation is signed by the sending VASP and is not older than
+// MAX_CANCELLATION_AGE.
+func VerifyPayreqCancellation(cancellation PayreqCancellation, senderSigningPubKey []byte) error {
+	signedAt := time.Unix(cancellation.Timestamp, 0)
+	if sdkruntime.Since(signedAt) > MAX_CANCELLATION_AGE || sdkruntime.Until(signedAt) > MAX_CANCELLATION_AGE {
+		return errors.New("the cancellation timestamp is too far from the current time")
+	}
+	publicKey, err := btcec.ParsePubKey(senderSigningPubKey)
+	if err != nil {
+		return err
+	}
+	signatureBytes, err := hex.DecodeString(cancellation.Signature)
+	if err != nil {
+		return errors.New("the cancellation signature is not hex encoded")
+	}
+	signature, err := ecdsa.ParseDERSignature(signatureBytes)
+	if err != nil {
+		return err
+	}
+	hash := cancellation.signedHash(true)
+	if !signature.Verify(hash[:], publicKey) {
+		return errors.New("invalid cancellation signature")
+	}
+	return nil
+}
+
+func (c *PayreqCancellation) signedHash(verifying bool) [32]byte {
+	payload := strings.Join([]string{
+		c.Invoice,
+		strings.ToLower(c.SenderVaspDomain),
+		c.Reason,
+		strconv.FormatInt(c.Timestamp, 10),
+	}, "|")
+	requester.ObserveSignablePayload(requester.SIGNABLE_PAYREQ_CANCELLATION, c.SenderVaspDomain, []byte(payload),
+		verifying)
+	return sha256.Sum256([]byte(payload))
+}
+
+// PostPayreqCancellation posts a PayreqCancellation to the payreq callback of the receiving VASP. Use the client
+// returned by NewCounterpartyHTTPClient, since the callback URL is provided by the counterparty.
+func PostPayreqCancellation(ctx context.Context, httpClient *http.Client, callbackUrl string,
+	cancellation PayreqCancellation,
+) error {
+	body, err := json.Marshal(cancellation)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New("payreq cancellation rejected: " + response.Status)
+	}
+	return nil
+}
+
+// ParsePayreqCancellation parses the body of a request posted to the payreq callback. It returns false if the body
+// is not a PayreqCancellation, e.g. if it is a payreq, so that the callback can handle both.
+func ParsePayreqCancellation(body []byte) (*PayreqCancellation, bool) {
+	var cancellation PayreqCancellation
+	if err := json.Unmarshal(body, &cancellation); err != nil {
+		return nil, false
+	}
+	if cancellation.Invoice == "" || cancellation.SenderVaspDomain == "" || cancellation.Signature == "" {
+		return nil, false
+	}
+	return &cancellation, true
+}
+
+// IssuedPayreq is a payreq response issued by a receiving VASP, recorded in IssuedPayreqs.
+type IssuedPayreq struct {
+	Invoice          string    `json:"invoice"`
+	SenderVaspDomain string    `json:"sender_vasp_domain"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// IssuedPayreqs records the sending VASP of each payreq response issued by a receiving VASP, so that
+// PayreqCancellationMiddleware only accepts the cancellation of a payreq from the VASP it was issued to. It implements
+// SweepableStore, and is safe for concurrent use.
+type IssuedPayreqs struct {
+	// TTL is the time a payreq can be cancelled after it was issued. Defaults to DEFAULT_ISSUED_PAYREQ_TTL.
+	TTL time.Duration
+
+	mutex   sync.Mutex
+	payreqs map[string]*IssuedPayreq
+}
+
+// NewIssuedPayreqs creates an IssuedPayreqs store letting payreqs be cancelled for the given time.
+func NewIssuedPayreqs(ttl time.Duration) *IssuedPayreqs {
+	return &IssuedPayreqs{TTL: ttl}
+}
+
+// Record records the payreq response issued with an invoice to a sending VASP. It must be called by the payreq
+// handler before the response is sent.
+func (p *IssuedPayreqs) Record(invoice string, senderVaspDomain string) {
+	ttl := p.TTL
+	if ttl <= 0 {
+		ttl = DEFAULT_ISSUED_PAYREQ_TTL
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.payreqs == nil {
+		p.payreqs = map[string]*IssuedPayreq{}
+	}
+	p.payreqs[invoice] = &IssuedPayreq{
+		Invoice:          invoice,
+		SenderVaspDomain: strings.ToLower(senderVaspDomain),
+		ExpiresAt:        sdkruntime.Now().Add(ttl),
+	}
+}
+
+// take removes and returns the payreq issued with an invoice to a sending VASP. It returns nil if the invoice was not
+// issued to that VASP, or if it expired.
+func (p *IssuedPayreqs) take(invoice string, senderVaspDomain string) *IssuedPayreq {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	payreq, ok := p.payreqs[invoice]
+	if !ok || !sdkruntime.Now().Before(payreq.ExpiresAt) ||
+		payreq.SenderVaspDomain != strings.ToLower(senderVaspDomain) {
+		return nil
+	}
+	delete(p.payreqs, invoice)
+	return payreq
+}
+
+// restore records again a payreq returned by take, e.g. when its cancellation failed.
+func (p *IssuedPayreqs) restore(payreq *IssuedPayreq) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.payreqs[payreq.Invoice] = payreq
+}
+
+// Sweep removes the expired payreqs, passing each one to archive first if it is not nil.
+func (p *IssuedPayreqs) Sweep(archive ArchiveFunc) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := sdkruntime.Now()
+	removed := 0
+	for invoice, payreq := range p.payreqs {
+		if now.Before(payreq.ExpiresAt) {
+			continue
+		}
+		if archive != nil {
+			if err := archive(*payreq); err != nil {
+				return removed, err
+			}
+		}
+		delete(p.payreqs, invoice)
+		removed++
+	}
+	return removed, nil
+}
+
+// Len returns the number of recorded payreqs, including expired ones which were not swept yet.
+func (p *IssuedPayreqs) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.payreqs)
+}
+
+// PayreqCancellationOptions configures PayreqCancellationMiddleware.
+type PayreqCancellationOptions struct {
+	// IssuedPayreqs records the sending VASP of the payreqs issued by the handler. Cancellations of payreqs which
+	// were not recorded for their SenderVaspDomain are rejected, so it is required.
+	IssuedPayreqs *IssuedPayreqs
+	// SenderSigningPubKey returns the signing public key of a sending VASP, usually from its cached pubkey response.
+	SenderSigningPubKey func(ctx context.Context, senderVaspDomain string) ([]byte, error)
+	// OnCancel is called with the verified cancellations, to release the liquidity reserved for the quote and
+	// invalidate its invoice. An error is answered with 500 Internal Server Error.
+	OnCancel func(ctx context.Context, cancellation PayreqCancellation) error
+	// EventSink, if set, receives an events.UmaStepCompleted event for each cancellation handled.
+	EventSink events.Sink
+}
+
+// PayreqCancellationMiddleware wraps the payreq callback handler of a receiving VASP, answering the POST requests
+// carrying a PayreqCancellation itself and passing the other requests to the handler. Cancellations with an invalid
+// signature, or of a payreq which was not issued to their sender, are rejected with 403 Forbidden. A payreq can only
+// be cancelled once.
+func PayreqCancellationMiddleware(options PayreqCancellationOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			if request.Method != "POST" || request.Body == nil {
+				next.ServeHTTP(w, request)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(request.Body, 1<<20))
+			request.Body.Close()
+			if err != nil {
+				http.Error(w, "error reading request body", http.StatusBadRequest)
+				return
+			}
+			cancellation, ok := ParsePayreqCancellation(body)
+			if !ok {
+				request.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(w, request)
+				return
+			}
+			pubKey, err := options.SenderSigningPubKey(request.Context(), cancellation.SenderVaspDomain)
+			if err != nil {
+				http.Error(w, "error fetching the sender signing key", http.StatusBadGateway)
+				return
+			}
+			if err := VerifyPayreqCancellation(*cancellation, pubKey); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			var payreq *IssuedPayreq
+			if options.IssuedPayreqs != nil {
+				payreq = options.IssuedPayreqs.take(cancellation.Invoice, cancellation.SenderVaspDomain)
+			}
+			if payreq == nil {
+				http.Error(w, "the payreq was not issued to the sender vasp", http.StatusForbidden)
+				return
+			}
+			if err := options.OnCancel(request.Context(), *cancellation); err != nil {
+				options.IssuedPayreqs.restore(payreq)
+				http.Error(w, "error cancelling the payreq", http.StatusInternalServerError)
+				return
+			}
+			events.Emit(options.EventSink, events.Event{
+				Type:       events.UmaStepCompleted,
+				Step:       events.UMA_STEP_PAYREQ_CANCELLED,
+				Attributes: map[string]string{"counterparty_domain": cancellation.SenderVaspDomain},
+			})
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
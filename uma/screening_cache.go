@@ -0,0 +1,142 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// ScreenCounterpartyFunc screens a counterparty VASP for payments in a direction, e.g. by calling the compliance
+// service of the VASP, and returns whether the counterparty is approved.
+type ScreenCounterpartyFunc func(counterpartyDomain string, direction objects.PaymentDirection) (bool, error)
+
+// ScreeningVerdict is the approval of a counterparty VASP cached by ScreeningCache.
+type ScreeningVerdict struct {
+	CounterpartyDomain string                   `json:"counterparty_domain"`
+	Direction          objects.PaymentDirection `json:"direction"`
+	ApprovedAt         time.Time                `json:"approved_at"`
+	ExpiresAt          time.Time                `json:"expires_at"`
+}
+
+type screeningKey struct {
+	counterpartyDomain string
+	direction          objects.PaymentDirection
+}
+
+// ScreeningCache caches the approvals of counterparty VASPs per counterparty and direction, so that high-volume
+// corridors are not screened again on every payreq. Rejections and screening errors are never cached. It implements
+// SweepableStore, and is safe for concurrent use.
+type ScreeningCache struct {
+	screen     ScreenCounterpartyFunc
+	defaultTtl time.Duration
+	mutex      sync.Mutex
+	ttls       map[string]time.Duration
+	verdicts   map[screeningKey]*ScreeningVerdict
+}
+
+// NewScreeningCache creates a ScreeningCache screening counterparties with screen, and keeping approvals for
+// defaultTtl unless SetTTL sets another TTL for the counterparty.
+func NewScreeningCache(screen ScreenCounterpartyFunc, defaultTtl time.Duration) *ScreeningCache {
+	return &ScreeningCache{
+		screen:     screen,
+		defaultTtl: defaultTtl,
+		ttls:       map[string]time.Duration{},
+		verdicts:   map[screeningKey]*ScreeningVerdict{},
+	}
+}
+
+// SetTTL sets how long the approvals of a counterparty VASP are kept. A TTL of 0 disables caching for the
+// counterparty. It applies to the approvals cached from then on.
+func (c *ScreeningCache) SetTTL(counterpartyDomain string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ttls[strings.ToLower(counterpartyDomain)] = ttl
+}
+
+// IsApproved returns whether a counterparty VASP is approved for payments in a direction, screening it only if no
+// approval is cached.
+func (c *ScreeningCache) IsApproved(counterpartyDomain string, direction objects.PaymentDirection) (bool, error) {
+	key := screeningKey{counterpartyDomain: strings.ToLower(counterpartyDomain), direction: direction}
+	c.mutex.Lock()
+	verdict, ok := c.verdicts[key]
+	if ok && sdkruntime.Now().Before(verdict.ExpiresAt) {
+		c.mutex.Unlock()
+		return true, nil
+	}
+	c.mutex.Unlock()
+	return c.refresh(key)
+}
+
+// Refresh screens a counterparty VASP for payments in a direction even if an approval is cached, e.g. after its
+// compliance status changed, and caches the new verdict.
+func (c *ScreeningCache) Refresh(counterpartyDomain string, direction objects.PaymentDirection) (bool, error) {
+	return c.refresh(screeningKey{counterpartyDomain: strings.ToLower(counterpartyDomain), direction: direction})
+}
+
+// Invalidate removes the cached approvals of a counterparty VASP in both directions.
+func (c *ScreeningCache) Invalidate(counterpartyDomain string) {
+	counterpartyDomain = strings.ToLower(counterpartyDomain)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.verdicts {
+		if key.counterpartyDomain == counterpartyDomain {
+			delete(c.verdicts, key)
+		}
+	}
+}
+
+func (c *ScreeningCache) refresh(key screeningKey) (bool, error) {
+	approved, err := c.screen(key.counterpartyDomain, key.direction)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err != nil || !approved {
+		delete(c.verdicts, key)
+		return false, err
+	}
+	ttl, ok := c.ttls[key.counterpartyDomain]
+	if !ok {
+		ttl = c.defaultTtl
+	}
+	if ttl <= 0 {
+		delete(c.verdicts, key)
+		return true, nil
+	}
+	now := sdkruntime.Now()
+	c.verdicts[key] = &ScreeningVerdict{
+		CounterpartyDomain: key.counterpartyDomain,
+		Direction:          key.direction,
+		ApprovedAt:         now.UTC(),
+		ExpiresAt:          now.Add(ttl),
+	}
+	return true, nil
+}
+
+func (c *ScreeningCache) Sweep(archive ArchiveFunc) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := sdkruntime.Now()
+	removed := 0
+	for key, verdict := range c.verdicts {
+		if now.Before(verdict.ExpiresAt) {
+			continue
+		}
+		if archive != nil {
+			if err := archive(*verdict); err != nil {
+				return removed, err
+			}
+		}
+		delete(c.verdicts, key)
+		removed++
+	}
+	return removed, nil
+}
+
+func (c *ScreeningCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.verdicts)
+}
@@ -0,0 +1,133 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// ErrIdentityMismatch is returned when a signing key does not belong to the VASP domain it is used for.
+var ErrIdentityMismatch = errors.New("signing key does not match the VASP domain identity")
+
+// ErrUnknownIdentity is returned when no sending identity is registered for a name or VASP domain.
+var ErrUnknownIdentity = errors.New("unknown sending identity")
+
+// ErrDomainAlreadyRegistered is returned when registering an identity for a VASP domain used by another identity.
+var ErrDomainAlreadyRegistered = errors.New("the VASP domain is already used by another sending identity")
+
+// SendingIdentity is a VASP identity under which UMA payments are sent, e.g. one of the brands of a platform. Its
+// signing key signs the lnurlp and payreq requests, and receivers verify them against the pubkeys published at
+// https://<VaspDomain>/.well-known/lnurlpubkey.
+type SendingIdentity struct {
+	Name       string
+	VaspDomain string
+	// SigningPrivateKey is the secp256k1 private key signing the requests of this identity.
+	SigningPrivateKey []byte
+	// SigningPubKey is the signing pubkey published by VaspDomain.
+	SigningPubKey []byte
+}
+
+// IdentityRegistry holds the sending identities of a platform, so that requests are signed with the key of the
+// VASP domain they are sent from. It is safe for concurrent use.
+type IdentityRegistry struct {
+	mutex    sync.RWMutex
+	byName   map[string]*SendingIdentity
+	byDomain map[string]*SendingIdentity
+}
+
+func NewIdentityRegistry() *IdentityRegistry {
+	return &IdentityRegistry{
+		byName:   map[string]*SendingIdentity{},
+		byDomain: map[string]*SendingIdentity{},
+	}
+}
+
+// Register adds or replaces a sending identity. It returns ErrIdentityMismatch if the signing private key does not
+// match the published pubkey, and ErrDomainAlreadyRegistered if the VASP domain is already used by another identity.
+func (r *IdentityRegistry) Register(identity SendingIdentity) error {
+	if identity.Name == "" || identity.VaspDomain == "" {
+		return errors.New("sending identity must have a name and a VASP domain")
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(identity.SigningPrivateKey)
+	pubKey, err := btcec.ParsePubKey(identity.SigningPubKey)
+	if err != nil {
+		return errors.New("invalid signing pubkey for " + identity.Name + ": " + err.Error())
+	}
+	if !privateKey.PubKey().IsEqual(pubKey) {
+		return ErrIdentityMismatch
+	}
+
+	domain := normalizeDomain(identity.VaspDomain)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, ok := r.byDomain[domain]; ok && existing.Name != identity.Name {
+		return ErrDomainAlreadyRegistered
+	}
+	if existing, ok := r.byName[identity.Name]; ok {
+		delete(r.byDomain, normalizeDomain(existing.VaspDomain))
+	}
+	registered := identity.copy()
+	r.byName[identity.Name] = registered
+	r.byDomain[domain] = registered
+	return nil
+}
+
+// Get returns a copy of the sending identity registered with the given name.
+func (r *IdentityRegistry) Get(name string) (*SendingIdentity, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	identity, ok := r.byName[name]
+	if !ok {
+		return nil, ErrUnknownIdentity
+	}
+	return identity.copy(), nil
+}
+
+// ForVaspDomain returns a copy of the sending identity of a VASP domain.
+func (r *IdentityRegistry) ForVaspDomain(vaspDomain string) (*SendingIdentity, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	identity, ok := r.byDomain[normalizeDomain(vaspDomain)]
+	if !ok {
+		return nil, ErrUnknownIdentity
+	}
+	return identity.copy(), nil
+}
+
+// ForSenderAddress returns the sending identity of an UMA address of the platform, e.g. $alice@brand.example.com,
+// from the domain of the address.
+func (r *IdentityRegistry) ForSenderAddress(senderAddress string) (*SendingIdentity, error) {
+	_, domain, ok := strings.Cut(senderAddress, "@")
+	if !ok {
+		return nil, errors.New("invalid UMA address: " + senderAddress)
+	}
+	return r.ForVaspDomain(domain)
+}
+
+// Check returns ErrIdentityMismatch unless the named identity is the one registered for vaspDomain. Call it before
+// signing a request on behalf of a brand to make sure its key is not used for another domain.
+func (r *IdentityRegistry) Check(name string, vaspDomain string) error {
+	identity, err := r.ForVaspDomain(vaspDomain)
+	if err != nil {
+		return err
+	}
+	if identity.Name != name {
+		return ErrIdentityMismatch
+	}
+	return nil
+}
+
+// copy returns a copy of the identity, so that callers cannot modify the keys of the registered identities.
+func (i *SendingIdentity) copy() *SendingIdentity {
+	identityCopy := *i
+	identityCopy.SigningPrivateKey = append([]byte(nil), i.SigningPrivateKey...)
+	identityCopy.SigningPubKey = append([]byte(nil), i.SigningPubKey...)
+	return &identityCopy
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
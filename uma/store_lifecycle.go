@@ -0,0 +1,100 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ArchiveFunc receives the entries removed from a store, e.g. to export them before they are dropped.
+type ArchiveFunc func(entry interface{}) error
+
+// SweepableStore is a protocol state store whose expired and deleted entries are removed by Sweep, so that the state
+// of long-running VASPs does not grow unboundedly. InMemoryAttributionStore and AsyncUmaInvoiceCreator implement it.
+type SweepableStore interface {
+	// Sweep removes the expired and deleted entries, passing each one to archive first if it is not nil. An entry
+	// which fails to be archived is kept. It returns the number of entries removed.
+	Sweep(archive ArchiveFunc) (int, error)
+	// Len returns the number of entries in the store, including expired ones which were not swept yet.
+	Len() int
+}
+
+// StoreMetrics receives the size of stores after each sweep. metrics.PrometheusCollector implements it.
+type StoreMetrics interface {
+	ObserveStoreSize(store string, size int)
+}
+
+// NewJSONLinesArchive returns an ArchiveFunc writing each entry to w as a line of JSON. It is safe for concurrent use.
+func NewJSONLinesArchive(w io.Writer) ArchiveFunc {
+	var mutex sync.Mutex
+	return func(entry interface{}) error {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, err = w.Write(append(line, '\n'))
+		return err
+	}
+}
+
+// StoreJanitor periodically sweeps protocol state stores, archives the removed entries and reports the store sizes.
+type StoreJanitor struct {
+	// Stores are the stores to sweep, by name. The names are used as metric labels.
+	Stores map[string]SweepableStore
+	// Interval is the time between sweeps. Defaults to one minute.
+	Interval time.Duration
+	// Archive, if set, receives the removed entries.
+	Archive ArchiveFunc
+	// Metrics, if set, receives the size of each store after each sweep.
+	Metrics StoreMetrics
+	// OnError, if set, is called with the errors of the sweeps run by Run.
+	OnError func(err error)
+}
+
+// SweepOnce sweeps every store once. It sweeps all stores even if some fail, and returns the first error.
+func (j *StoreJanitor) SweepOnce() error {
+	names := make([]string, 0, len(j.Stores))
+	for name := range j.Stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var firstErr error
+	for _, name := range names {
+		store := j.Stores[name]
+		if _, err := store.Sweep(j.Archive); err != nil && firstErr == nil {
+			firstErr = errors.New("error sweeping store " + name + ": " + err.Error())
+		}
+		if j.Metrics != nil {
+			j.Metrics.ObserveStoreSize(name, store.Len())
+		}
+	}
+	return firstErr
+}
+
+// Run sweeps the stores every Interval until the context is done. Errors do not stop the janitor.
+func (j *StoreJanitor) Run(ctx context.Context) {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.SweepOnce(); err != nil && j.OnError != nil {
+				j.OnError(err)
+			}
+		}
+	}
+}
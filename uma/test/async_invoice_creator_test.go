@@ -0,0 +1,113 @@
+package uma_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingInvoiceCreator struct {
+	release chan struct{}
+	err     error
+}
+
+func (c blockingInvoiceCreator) CreateUmaInvoice(amountMsats int64, metadata string) (*string, error) {
+	<-c.release
+	if c.err != nil {
+		return nil, c.err
+	}
+	encodedInvoice := "lnbc" + metadata
+	return &encodedInvoice, nil
+}
+
+func TestAsyncUmaInvoiceCreator(t *testing.T) {
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "seed")
+	t.Cleanup(sdkruntimetest.SetDefault(runtime))
+	release := make(chan struct{})
+	creator := uma.NewAsyncUmaInvoiceCreator(blockingInvoiceCreator{release: release})
+
+	token, err := creator.StartUmaInvoice(1000, "1")
+	require.NoError(t, err)
+	require.Len(t, token, 32)
+	_, err = creator.PollUmaInvoice(token)
+	require.ErrorIs(t, err, uma.ErrInvoicePending)
+	_, err = creator.PollUmaInvoice("unknown")
+	require.ErrorIs(t, err, uma.ErrUnknownInvoiceToken)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = creator.WaitUmaInvoice(ctx, token)
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	encodedInvoice, err := creator.WaitUmaInvoice(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, "lnbc1", *encodedInvoice)
+	encodedInvoice, err = creator.PollUmaInvoice(token)
+	require.NoError(t, err)
+	require.Equal(t, "lnbc1", *encodedInvoice)
+
+	// Results are kept for ResultTtl after they are finished.
+	clock.Advance(5 * time.Minute)
+	removed, err := creator.Sweep(nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+
+	var archived []interface{}
+	clock.Advance(6 * time.Minute)
+	removed, err = creator.Sweep(func(entry interface{}) error {
+		archived = append(archived, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	require.Equal(t, 0, creator.Len())
+	require.Equal(t, []interface{}{uma.ArchivedInvoiceResult{
+		Token:          token,
+		EncodedInvoice: encodedInvoice,
+		FinishedAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}, archived)
+	_, err = creator.PollUmaInvoice(token)
+	require.ErrorIs(t, err, uma.ErrUnknownInvoiceToken)
+}
+
+func TestAsyncUmaInvoiceCreator_Error(t *testing.T) {
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "seed")
+	t.Cleanup(sdkruntimetest.SetDefault(runtime))
+	release := make(chan struct{})
+	close(release)
+	creator := uma.NewAsyncUmaInvoiceCreator(blockingInvoiceCreator{release: release, err: errors.New("node offline")})
+	creator.ResultTtl = time.Minute
+
+	token, err := creator.StartUmaInvoice(1000, "1")
+	require.NoError(t, err)
+	_, err = creator.WaitUmaInvoice(context.Background(), token)
+	require.EqualError(t, err, "node offline")
+
+	// Expired results are also removed when an invoice is started.
+	clock.Advance(2 * time.Minute)
+	secondToken, err := creator.StartUmaInvoice(1000, "2")
+	require.NoError(t, err)
+	_, err = creator.PollUmaInvoice(token)
+	require.ErrorIs(t, err, uma.ErrUnknownInvoiceToken)
+	_, err = creator.WaitUmaInvoice(context.Background(), secondToken)
+	require.Error(t, err)
+
+	clock.Advance(2 * time.Minute)
+	var archived []interface{}
+	_, err = creator.Sweep(func(entry interface{}) error {
+		archived = append(archived, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	require.Equal(t, secondToken, archived[0].(uma.ArchivedInvoiceResult).Token)
+	require.Equal(t, "node offline", archived[0].(uma.ArchivedInvoiceResult).Error)
+	require.Nil(t, archived[0].(uma.ArchivedInvoiceResult).EncodedInvoice)
+}
@@ -0,0 +1,53 @@
+package uma_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSignature(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	publicKey := privateKey.PubKey().SerializeCompressed()
+	message := []byte(`{"payerData":{"identifier":"$alice@vasp1.com"},"amount":1000}`)
+
+	auditSignature, err := uma.SignAuditRecord(message, uma.AuditDirectionInbound, "payreq", privateKey.Serialize(), "audit-key-1")
+	require.NoError(t, err)
+	require.NoError(t, uma.VerifyAuditRecord(message, *auditSignature, publicKey))
+
+	require.Error(t, uma.VerifyAuditRecord([]byte(`{"amount":2000}`), *auditSignature, publicKey))
+	tampered := *auditSignature
+	tampered.Direction = uma.AuditDirectionOutbound
+	require.Error(t, uma.VerifyAuditRecord(message, tampered, publicKey))
+}
+
+func TestNewAuditSigner_ValidatesKey(t *testing.T) {
+	_, err := uma.NewAuditSigner(make([]byte, 32), "audit-key-1")
+	require.Error(t, err)
+	_, err = uma.NewAuditSigner([]byte{1, 2, 3}, "audit-key-1")
+	require.Error(t, err)
+	overflowing := make([]byte, 32)
+	for i := range overflowing {
+		overflowing[i] = 0xff
+	}
+	_, err = uma.NewAuditSigner(overflowing, "audit-key-1")
+	require.Error(t, err)
+	_, err = uma.SignAuditRecord([]byte("{}"), uma.AuditDirectionInbound, "payreq", nil, "audit-key-1")
+	require.Error(t, err)
+
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	_, err = uma.NewAuditSigner(privateKey.Serialize(), "")
+	require.Error(t, err)
+	signer, err := uma.NewAuditSigner(privateKey.Serialize(), "audit-key-1")
+	require.NoError(t, err)
+	_, err = signer.Sign([]byte("{}"), "SIDEWAYS", "payreq")
+	require.Error(t, err)
+	auditSignature, err := signer.Sign([]byte("{}"), uma.AuditDirectionOutbound, "payreq_response")
+	require.NoError(t, err)
+	require.Equal(t, "audit-key-1", auditSignature.KeyId)
+	require.NoError(t, uma.VerifyAuditRecord([]byte("{}"), *auditSignature, privateKey.PubKey().SerializeCompressed()))
+}
@@ -0,0 +1,55 @@
+package uma_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasePathMiddleware(t *testing.T) {
+	var path, host string
+	handler := uma.BasePathMiddleware(uma.BasePathOptions{BasePath: "/payments/"})(
+		http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			path, host = request.URL.Path, request.Host
+		}))
+
+	for requestPath, expectedPath := range map[string]string{
+		"/payments/.well-known/lnurlp/alice": "/.well-known/lnurlp/alice",
+		"/payments":                          "/",
+		"/paymentsfoo/.well-known/lnurlp/a":  "/paymentsfoo/.well-known/lnurlp/a",
+		"/.well-known/lnurlpubkey":           "/.well-known/lnurlpubkey",
+	} {
+		request := httptest.NewRequest("GET", "https://vasp.com"+requestPath, nil)
+		request.Header.Set("X-Forwarded-Host", "public.vasp.com")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		require.Equal(t, expectedPath, path, requestPath)
+		require.Equal(t, "vasp.com", host)
+	}
+
+	handler = uma.BasePathMiddleware(uma.BasePathOptions{TrustForwardedHost: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			path, host = request.URL.Path, request.Host
+		}))
+	request := httptest.NewRequest("GET", "https://internal.vasp.com/.well-known/lnurlp/alice", nil)
+	request.Header.Set("X-Forwarded-Host", "public.vasp.com, proxy.vasp.com")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	require.Equal(t, "/.well-known/lnurlp/alice", path)
+	require.Equal(t, "public.vasp.com", host)
+}
+
+func TestMatchLnurlpPath(t *testing.T) {
+	for path, expectedUsername := range map[string]string{
+		"/.well-known/lnurlp/alice":           "alice",
+		"/payments/.well-known/lnurlp/alice/": "alice",
+		"/.well-known/lnurlp/":                "",
+		"/.well-known/lnurlp/alice/bob":       "",
+		"/.well-known/lnurlpubkey":            "",
+	} {
+		username, ok := uma.MatchLnurlpPath(path)
+		require.Equal(t, expectedUsername, username, path)
+		require.Equal(t, expectedUsername != "", ok, path)
+	}
+}
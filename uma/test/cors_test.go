@@ -0,0 +1,70 @@
+package uma_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorsMiddleware_Defaults(t *testing.T) {
+	var method string
+	handler := uma.CorsMiddleware(uma.CorsOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.Write([]byte(`{"callback": "https://vasp.com/payreq"}`))
+	}))
+
+	request := httptest.NewRequest("OPTIONS", "https://vasp.com/.well-known/lnurlp/alice", nil)
+	request.Header.Set("Origin", "https://wallet.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, "", method)
+	require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, POST, HEAD, OPTIONS", recorder.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type", recorder.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+	require.Empty(t, recorder.Header().Values("Vary"))
+
+	request = httptest.NewRequest("HEAD", "https://vasp.com/.well-known/lnurlp/alice", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, http.MethodGet, method)
+	require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestCorsMiddleware_AllowedOrigins(t *testing.T) {
+	handler := uma.CorsMiddleware(uma.CorsOptions{
+		AllowedOrigins: []string{"https://wallet.com"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		AllowedMethods: []string{"GET"},
+		MaxAge:         time.Hour,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := httptest.NewRequest("GET", "https://vasp.com/.well-known/lnurlpubkey", nil)
+	request.Header.Set("Origin", "https://WALLET.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "https://WALLET.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, []string{"Origin"}, recorder.Header().Values("Vary"))
+
+	request = httptest.NewRequest("OPTIONS", "https://vasp.com/.well-known/lnurlpubkey", nil)
+	request.Header.Set("Origin", "https://other.com")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET", recorder.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type, Authorization", recorder.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "3600", recorder.Header().Get("Access-Control-Max-Age"))
+
+	request = httptest.NewRequest("GET", "https://vasp.com/.well-known/lnurlpubkey", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,101 @@
+package uma_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+const testCapabilitiesLnurlpResponse = `{
+	"callback": "https://vasp.com/api/uma/payreq/alice",
+	"minSendable": 1000,
+	"maxSendable": 100000000,
+	"metadata": "[[\"text/plain\", \"Pay alice\"]]",
+	"umaVersion": "1.0",
+	"commentAllowed": 140,
+	"currencies": [
+		{"code": "USD", "name": "US Dollar", "symbol": "$", "decimals": 2, "convertible": {"min": 1, "max": 100000}}
+	],
+	"payerData": {
+		"identifier": {"mandatory": true},
+		"compliance": {"mandatory": true},
+		"name": {"mandatory": false}
+	}
+}`
+
+func TestFetchCounterpartyCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		require.Equal(t, "/.well-known/uma-configuration", request.URL.Path)
+		w.Write([]byte(`{"uma_request_endpoint": "https://vasp.com/uma/request_pay", "uma_major_versions": [0, 1]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	capabilities, err := uma.FetchCounterpartyCapabilities(context.Background(), server.Client(),
+		strings.TrimPrefix(server.URL, "http://"), []byte(testCapabilitiesLnurlpResponse))
+	require.NoError(t, err)
+	require.True(t, capabilities.IsUma())
+	require.True(t, capabilities.SupportsUmaMajorVersion(1))
+	require.True(t, capabilities.SupportsComments())
+	require.False(t, capabilities.AllowsNostr)
+	require.Equal(t, []string{"compliance", "identifier"}, capabilities.RequiredPayerData)
+	require.Equal(t, []string{"name"}, capabilities.OptionalPayerData)
+	usd, ok := capabilities.Currency("usd")
+	require.True(t, ok)
+	require.Equal(t, int64(100000), usd.MaxSendable)
+
+	capabilities, err = uma.ParseCounterpartyCapabilities(
+		[]byte(`{"minSendable": 1000, "maxSendable": 2000, "allowsNostr": true, "nostrPubkey": "abcd"}`), nil)
+	require.NoError(t, err)
+	require.False(t, capabilities.IsUma())
+	require.False(t, capabilities.SupportsComments())
+	require.True(t, capabilities.AllowsNostr)
+	require.Empty(t, capabilities.UmaMajorVersions)
+}
+
+func TestCounterpartyCapabilitiesStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/.well-known/lnurlp/alice":
+			w.Write([]byte(testCapabilitiesLnurlpResponse))
+		case "/.well-known/uma-configuration":
+			w.Write([]byte(`{"uma_major_versions": [1]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	store := uma.NewCounterpartyCapabilitiesStore()
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		CapabilitiesStore:     store,
+	})
+	domain := strings.TrimPrefix(server.URL, "http://")
+
+	_, err := store.Capabilities(context.Background(), "$alice@"+domain)
+	require.ErrorIs(t, err, uma.ErrCapabilitiesNotFound)
+
+	response, err := client.Get(server.URL + "/.well-known/lnurlp/alice")
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, testCapabilitiesLnurlpResponse, string(body))
+
+	capabilities, err := store.Capabilities(context.Background(), "$alice@"+domain)
+	require.NoError(t, err)
+	require.True(t, capabilities.IsUma())
+	require.Equal(t, []int{1}, capabilities.UmaMajorVersions)
+	require.Equal(t, 140, capabilities.CommentMaxLength)
+
+	response, err = client.Get(server.URL + "/.well-known/lnurlp/bob")
+	require.NoError(t, err)
+	response.Body.Close()
+	_, err = store.Capabilities(context.Background(), "$bob@"+domain)
+	require.ErrorIs(t, err, uma.ErrCapabilitiesNotFound)
+}
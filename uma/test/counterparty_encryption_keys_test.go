@@ -0,0 +1,77 @@
+package uma_test
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterpartyEncryptionKeys(t *testing.T) {
+	masterKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	keys := uma.CounterpartyEncryptionKeys{MasterPrivateKey: masterKey.Serialize()}
+
+	pubKey, err := keys.PubKey("Sender.example.com")
+	require.NoError(t, err)
+	samePubKey, err := keys.PubKey("sender.example.com")
+	require.NoError(t, err)
+	require.Equal(t, pubKey, samePubKey)
+	otherPubKey, err := keys.PubKey("other.example.com")
+	require.NoError(t, err)
+	require.NotEqual(t, pubKey, otherPubKey)
+	require.NotEqual(t, masterKey.PubKey().SerializeCompressed(), pubKey)
+
+	encryptedMemo, err := uma.EncryptMemo("thanks", pubKey)
+	require.NoError(t, err)
+	memo, err := keys.DecryptMemo("sender.example.com", encryptedMemo)
+	require.NoError(t, err)
+	require.Equal(t, "thanks", memo)
+	_, err = keys.DecryptMemo("other.example.com", encryptedMemo)
+	require.Error(t, err)
+
+	encrypted, err := crypto.EciesEncrypt(masterKey.PubKey().SerializeCompressed(), []byte("travel rule info"))
+	require.NoError(t, err)
+	_, err = keys.Decrypt("sender.example.com", encrypted)
+	require.Error(t, err)
+	keys.AcceptMasterKey = true
+	message, err := keys.Decrypt("sender.example.com", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "travel rule info", string(message))
+	_, err = keys.DecryptMemo("sender.example.com", hex.EncodeToString([]byte("not encrypted")))
+	require.Error(t, err)
+}
+
+func TestCounterpartyEncryptionKeys_PubKeyEndpoint(t *testing.T) {
+	masterKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	keys := uma.CounterpartyEncryptionKeys{MasterPrivateKey: masterKey.Serialize()}
+
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/lnurlpubkey?vaspDomain=sender.example.com", nil)
+	pubKey, err := keys.PubKeyForRequest(request)
+	require.NoError(t, err)
+	expected, err := keys.PubKey("sender.example.com")
+	require.NoError(t, err)
+	require.Equal(t, expected, pubKey)
+
+	request = httptest.NewRequest(http.MethodGet, "/.well-known/lnurlpubkey", nil)
+	_, err = keys.PubKeyForRequest(request)
+	require.Error(t, err)
+	keys.AcceptMasterKey = true
+	pubKey, err = keys.PubKeyForRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, masterKey.PubKey().SerializeCompressed(), pubKey)
+
+	encrypted, err := crypto.EciesEncrypt(expected, []byte(`{"name": "Alice"}`))
+	require.NoError(t, err)
+	travelRuleInfo, err := keys.DecryptTravelRuleInfo("sender.example.com", hex.EncodeToString(encrypted))
+	require.NoError(t, err)
+	require.Equal(t, `{"name": "Alice"}`, travelRuleInfo)
+	_, err = keys.DecryptTravelRuleInfo("sender.example.com", "not hex")
+	require.Error(t, err)
+}
@@ -0,0 +1,420 @@
+package uma_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestCounterpartyHTTPClient_BlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{})
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, uma.ErrPrivateAddress)
+
+	client = uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{AllowPrivateAddresses: true})
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+}
+
+type staticResolver []net.IPAddr
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r, nil
+}
+
+func TestCounterpartyHTTPClient_BlocksSpecialPurposeAddresses(t *testing.T) {
+	for _, address := range []string{
+		"0.1.2.3",
+		"10.1.2.3",
+		"100.64.0.1",
+		"127.0.0.1",
+		"169.254.169.254",
+		"172.16.0.1",
+		"192.0.0.8",
+		"192.0.2.1",
+		"192.168.1.1",
+		"198.18.0.1",
+		"203.0.113.1",
+		"224.0.0.1",
+		"255.255.255.255",
+		"::",
+		"::1",
+		"::ffff:10.0.0.1",
+		"64:ff9b::a00:1",
+		"2001::1",
+		"2001:db8::1",
+		"2002:a00:1::1",
+		"fd00::1",
+		"fe80::1",
+		"ff02::1",
+	} {
+		t.Run(address, func(t *testing.T) {
+			client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+				Resolver: staticResolver{{IP: net.ParseIP(address)}},
+			})
+			_, err := client.Get("https://vasp.example/.well-known/lnurlpubkey")
+			require.ErrorIs(t, err, uma.ErrPrivateAddress)
+		})
+	}
+}
+
+func TestCounterpartyHTTPClient_RedirectPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same-domain":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/other-domain":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{AllowPrivateAddresses: true})
+
+	response, err := client.Get(server.URL + "/same-domain")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	_, err = client.Get(server.URL + "/other-domain")
+	require.ErrorContains(t, err, "another domain")
+
+	_, err = client.Get(server.URL + "/loop")
+	require.ErrorContains(t, err, "too many redirects")
+}
+
+func TestCounterpartyHTTPClient_HandshakeSLA(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/lnurlp/alice" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var exceeded []*uma.SLAExceededError
+	sla := &uma.HandshakeSLA{
+		PubKey:     time.Second,
+		Lnurlp:     20 * time.Millisecond,
+		OnExceeded: func(err *uma.SLAExceededError) { exceeded = append(exceeded, err) },
+	}
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		HandshakeSLA:          sla,
+	})
+	response, err := client.Get(server.URL + "/.well-known/lnurlpubkey")
+	require.NoError(t, err)
+	response.Body.Close()
+	response, err = client.Get(server.URL + "/.well-known/lnurlp/alice")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Len(t, exceeded, 1)
+	require.Equal(t, uma.HandshakeStepLnurlp, exceeded[0].Step)
+
+	sla.Enforce = true
+	_, err = client.Get(server.URL + "/.well-known/lnurlp/alice")
+	require.ErrorIs(t, err, uma.ErrReceiverUnavailable)
+}
+
+func TestHandshakeStepForRequest(t *testing.T) {
+	for url, expected := range map[string]uma.HandshakeStep{
+		"https://vasp.com/.well-known/lnurlpubkey":      uma.HandshakeStepPubKey,
+		"https://vasp.com/.well-known/lnurlp/alice":     uma.HandshakeStepLnurlp,
+		"https://vasp.com/api/uma/payreq/1234":          uma.HandshakeStepPayReq,
+		"https://vasp.com/api/uma/utxoCallback?txid=12": "",
+	} {
+		request, err := http.NewRequest(http.MethodPost, url, nil)
+		require.NoError(t, err)
+		step, ok := uma.HandshakeStepForRequest(request)
+		require.Equal(t, expected, step, url)
+		require.Equal(t, expected != "", ok, url)
+	}
+
+	request, err := http.NewRequestWithContext(uma.WithHandshakeStep(context.Background(), uma.HandshakeStepPayReq),
+		http.MethodPost, "https://vasp.com/callbacks/alice", nil)
+	require.NoError(t, err)
+	step, ok := uma.HandshakeStepForRequest(request)
+	require.True(t, ok)
+	require.Equal(t, uma.HandshakeStepPayReq, step)
+}
+
+func TestCounterpartyHTTPClient_HandshakeSLABodyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"pr": `))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	var exceeded []*uma.SLAExceededError
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		HandshakeSLA: &uma.HandshakeSLA{
+			PayReq:     50 * time.Millisecond,
+			Enforce:    true,
+			OnExceeded: func(err *uma.SLAExceededError) { exceeded = append(exceeded, err) },
+		},
+	})
+	response, err := client.Post(server.URL+"/api/uma/payreq/alice", "application/json", nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	_, err = io.ReadAll(response.Body)
+	require.ErrorIs(t, err, uma.ErrReceiverUnavailable)
+	var slaErr *uma.SLAExceededError
+	require.ErrorAs(t, err, &slaErr)
+	require.Equal(t, uma.HandshakeStepPayReq, slaErr.Step)
+	require.Len(t, exceeded, 1)
+}
+
+func TestCounterpartyHTTPClient_LnurlpCache(t *testing.T) {
+	lnurlpRequests := 0
+	pubKey := "key1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/lnurlpubkey" {
+			w.Write([]byte(pubKey))
+			return
+		}
+		lnurlpRequests++
+		w.Write([]byte(`{"callback": "/api/uma/payreq/alice"}`))
+	}))
+	defer server.Close()
+	cache := uma.NewLnurlpCache(time.Minute)
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		LnurlpCache:           cache,
+	})
+	get := func(path string) string {
+		response, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	require.Equal(t, "key1", get("/.well-known/lnurlpubkey"))
+	get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com")
+	require.Equal(t, `{"callback": "/api/uma/payreq/alice"}`, get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com"))
+	require.Equal(t, 1, lnurlpRequests)
+	response, err := client.Get(server.URL + "/.well-known/lnurlp/alice?vaspDomain=brand1.example.com")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, "200 OK", response.Status)
+	require.Equal(t, 1, lnurlpRequests)
+
+	// Another sending identity gets its own response.
+	get("/.well-known/lnurlp/alice?vaspDomain=brand2.example.com")
+	require.Equal(t, 2, lnurlpRequests)
+
+	// Signed UMA requests are never served from the cache.
+	get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com&signature=abcd&nonce=1")
+	get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com&signature=abcd&nonce=1")
+	require.Equal(t, 4, lnurlpRequests)
+
+	require.NoError(t, cache.Invalidate("$alice@"+server.Listener.Addr().String()))
+	get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com")
+	require.Equal(t, 5, lnurlpRequests)
+
+	pubKey = "key2"
+	get("/.well-known/lnurlpubkey")
+	get("/.well-known/lnurlp/alice?vaspDomain=brand1.example.com")
+	require.Equal(t, 6, lnurlpRequests)
+}
+
+func TestCounterpartyHTTPClient_LnurlpCacheSkipsSignedResponses(t *testing.T) {
+	lnurlpRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lnurlpRequests++
+		w.Write([]byte(`{"callback": "/api/uma/payreq/alice", "compliance": {"signature": "3045", "signatureNonce": "1"}}`))
+	}))
+	defer server.Close()
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		LnurlpCache:           uma.NewLnurlpCache(time.Minute),
+	})
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(server.URL + "/.well-known/lnurlp/alice")
+		require.NoError(t, err)
+		response.Body.Close()
+	}
+	require.Equal(t, 2, lnurlpRequests)
+}
+
+func TestDohResolver_LookupIPAddr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, recordType := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+		switch {
+		case name == "missing.example":
+			w.Write([]byte(`{"Status": 3}`))
+		case name == "failing.example":
+			w.Write([]byte(`{"Status": 2}`))
+		case recordType == "A":
+			w.Write([]byte(`{"Status": 0, "Answer": [{"type": 1, "data": "203.0.114.1"}]}`))
+		default:
+			// AAAA lookups fail, as on resolvers dropping IPv6 queries.
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	resolver := &uma.DohResolver{Endpoint: server.URL}
+
+	addresses, err := resolver.LookupIPAddr(context.Background(), "vasp.example")
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	require.Equal(t, "203.0.114.1", addresses[0].IP.String())
+
+	_, err = resolver.LookupIPAddr(context.Background(), "missing.example")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	require.True(t, dnsErr.IsNotFound)
+
+	_, err = resolver.LookupIPAddr(context.Background(), "failing.example")
+	require.ErrorAs(t, err, &dnsErr)
+	require.False(t, dnsErr.IsNotFound)
+	require.True(t, dnsErr.IsTemporary)
+}
+
+type recordingLogger struct {
+	entries [][]interface{}
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record(msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record(msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record(msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record(msg, args) }
+
+func (l *recordingLogger) record(msg string, args []interface{}) {
+	l.entries = append(l.entries, append([]interface{}{msg}, args...))
+}
+
+func TestCounterpartyHTTPClient_LoggerRedactsReceivers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{AllowPrivateAddresses: true, Logger: logger})
+	response, err := client.Get(server.URL + "/.well-known/lnurlp/alice")
+	require.NoError(t, err)
+	response.Body.Close()
+	server.Close()
+	_, err = client.Get(server.URL + "/.well-known/lnurlp/alice?amount=1000")
+	require.Error(t, err)
+
+	require.Len(t, logger.entries, 2)
+	for _, entry := range logger.entries {
+		for _, value := range entry {
+			require.NotContains(t, fmt.Sprint(value), "alice")
+		}
+	}
+	require.Equal(t, "counterparty request", logger.entries[0][0])
+	require.Contains(t, logger.entries[0], "/.well-known/lnurlp/")
+	require.Equal(t, "counterparty request failed", logger.entries[1][0])
+}
+
+type recordingTracerProvider struct {
+	noop.TracerProvider
+	spans []*recordedSpan
+}
+
+func (p *recordingTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingTracerProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, spanName string, options ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &recordedSpan{name: spanName, attributes: map[attribute.Key]attribute.Value{}}
+	span.SetAttributes(config.Attributes()...)
+	t.provider.spans = append(t.provider.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	noop.Span
+	name       string
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	for _, kv := range attributes {
+		s.attributes[kv.Key] = kv.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+func TestCounterpartyHTTPClient_TracerProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	tracerProvider := &recordingTracerProvider{}
+	client := uma.NewCounterpartyHTTPClient(uma.CounterpartyHTTPClientConfig{
+		AllowPrivateAddresses: true,
+		TracerProvider:        tracerProvider,
+	})
+
+	response, err := client.Get(server.URL + "/.well-known/lnurlpubkey")
+	require.NoError(t, err)
+	response.Body.Close()
+	response, err = client.Get(server.URL + "/missing")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Len(t, tracerProvider.spans, 2)
+	span := tracerProvider.spans[0]
+	require.Equal(t, "uma GET", span.name)
+	require.Equal(t, "GET", span.attributes["http.request.method"].AsString())
+	require.Equal(t, "127.0.0.1", span.attributes["server.address"].AsString())
+	require.Equal(t, "/.well-known/lnurlpubkey", span.attributes["url.path"].AsString())
+	require.Equal(t, int64(http.StatusOK), span.attributes["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Unset, span.status)
+	require.True(t, span.ended)
+
+	span = tracerProvider.spans[1]
+	require.Equal(t, int64(http.StatusNotFound), span.attributes["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Error, span.status)
+	require.True(t, span.ended)
+}
@@ -0,0 +1,94 @@
+package uma_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyRateCache(t *testing.T) {
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "seed")
+	t.Cleanup(sdkruntimetest.SetDefault(runtime))
+	rates := map[string]float64{"USD": 23.5, "EUR": 25}
+	source := uma.RateSourceFunc(func(ctx context.Context, currencyCode string) (float64, error) {
+		rate, ok := rates[currencyCode]
+		if !ok {
+			return 0, errors.New("unsupported currency: " + currencyCode)
+		}
+		return rate, nil
+	})
+	cache := uma.NewCurrencyRateCache(source, "USD", "MXN", "EUR")
+
+	_, err := cache.GetRate("USD")
+	require.ErrorIs(t, err, uma.ErrRateUnavailable)
+
+	// A failing currency does not prevent the other ones from being refreshed.
+	require.EqualError(t, cache.Refresh(context.Background()), "unsupported currency: MXN")
+	rate, err := cache.GetRate("USD")
+	require.NoError(t, err)
+	require.Equal(t, 23.5, rate)
+	rate, err = cache.GetRate("EUR")
+	require.NoError(t, err)
+	require.Equal(t, 25.0, rate)
+	_, err = cache.GetRate("MXN")
+	require.ErrorIs(t, err, uma.ErrRateUnavailable)
+
+	// A failed refresh keeps serving the previous rate until it is stale.
+	delete(rates, "USD")
+	clock.Advance(4 * time.Minute)
+	require.Error(t, cache.Refresh(context.Background()))
+	rate, err = cache.GetRate("USD")
+	require.NoError(t, err)
+	require.Equal(t, 23.5, rate)
+	clock.Advance(2 * time.Minute)
+	_, err = cache.GetRate("USD")
+	require.ErrorIs(t, err, uma.ErrRateUnavailable)
+	_, err = cache.GetRate("EUR")
+	require.NoError(t, err)
+}
+
+func TestCurrencyRateCache_Run(t *testing.T) {
+	var mutex sync.Mutex
+	fetches := 0
+	source := uma.RateSourceFunc(func(ctx context.Context, currencyCode string) (float64, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		fetches++
+		if fetches <= 3 {
+			return 0, errors.New("oracle unavailable")
+		}
+		return 23.5, nil
+	})
+	cache := uma.NewCurrencyRateCache(source, "USD")
+	cache.MinRetryInterval = time.Millisecond
+	cache.RefreshInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cache.Run(ctx)
+		close(done)
+	}()
+
+	// Failed refreshes are retried with a short backoff instead of waiting for the refresh interval.
+	require.Eventually(t, func() bool {
+		_, err := cache.GetRate("USD")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, 4, fetches)
+}
@@ -0,0 +1,49 @@
+package uma_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupAll(t *testing.T) {
+	var running, maxRunning int32
+	lookup := func(ctx context.Context, address string) (string, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		if address == "$slow@vasp.com" {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		if address == "$bad@vasp.com" {
+			return "", errors.New("lookup failed")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return "ok:" + address, nil
+	}
+
+	addresses := []string{"$alice@vasp.com", "$bad@vasp.com", "$slow@vasp.com", "$bob@vasp.com", "$carol@vasp.com"}
+	results := uma.LookupAll(context.Background(), addresses, lookup,
+		uma.LookupAllOptions{MaxConcurrency: 2, PerTargetTimeout: 50 * time.Millisecond})
+
+	require.Len(t, results, len(addresses))
+	require.LessOrEqual(t, maxRunning, int32(2))
+	for i, result := range results {
+		require.Equal(t, addresses[i], result.Address)
+	}
+	require.Equal(t, "ok:$alice@vasp.com", results[0].Value)
+	require.EqualError(t, results[1].Err, "lookup failed")
+	require.ErrorIs(t, results[2].Err, context.DeadlineExceeded)
+	require.NoError(t, results[4].Err)
+}
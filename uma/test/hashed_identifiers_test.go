@@ -0,0 +1,56 @@
+package uma_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashedIdentifiers(t *testing.T) {
+	senderKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	receiverKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	nonce, err := uma.NewIdentifierSaltNonce()
+	require.NoError(t, err)
+	senderSalt, err := uma.DeriveIdentifierSalt(senderKey.Serialize(), receiverKey.PubKey().SerializeUncompressed(),
+		nonce)
+	require.NoError(t, err)
+	receiverSalt, err := uma.DeriveIdentifierSalt(receiverKey.Serialize(), senderKey.PubKey().SerializeUncompressed(),
+		nonce)
+	require.NoError(t, err)
+	require.Equal(t, senderSalt, receiverSalt)
+
+	// Each payment has its own salt, which is not the hash of the ECDH secret of the encryption keys.
+	otherNonce, err := uma.NewIdentifierSaltNonce()
+	require.NoError(t, err)
+	otherSalt, err := uma.DeriveIdentifierSalt(senderKey.Serialize(), receiverKey.PubKey().SerializeUncompressed(),
+		otherNonce)
+	require.NoError(t, err)
+	require.NotEqual(t, senderSalt, otherSalt)
+	encryptionSecret := btcec.GenerateSharedSecret(senderKey, receiverKey.PubKey())
+	mac := hmac.New(sha256.New, encryptionSecret)
+	mac.Write([]byte("uma-identifier-salt"))
+	mac.Write(nonce)
+	require.NotEqual(t, mac.Sum(nil), senderSalt)
+	_, err = uma.DeriveIdentifierSalt(senderKey.Serialize(), receiverKey.PubKey().SerializeUncompressed(), nil)
+	require.Error(t, err)
+
+	hashed, err := uma.HashIdentifier("$Alice@vasp.com", senderSalt)
+	require.NoError(t, err)
+	require.Regexp(t, `^\$[0-9a-f]{32}@vasp\.com$`, hashed)
+	require.True(t, uma.MatchHashedIdentifier(hashed, "$alice@vasp.com", receiverSalt))
+	require.False(t, uma.MatchHashedIdentifier(hashed, "$bob@vasp.com", receiverSalt))
+
+	policy := uma.TravelRulePolicy{Thresholds: []uma.TravelRuleThreshold{{CurrencyCode: "USD", MinAmount: 100_000}}}
+	identifier, err := uma.PrivateIdentifier("$alice@vasp.com", senderSalt, policy, "US", "USD", 500)
+	require.NoError(t, err)
+	require.Equal(t, hashed, identifier)
+	identifier, err = uma.PrivateIdentifier("$alice@vasp.com", senderSalt, policy, "US", "USD", 100_000)
+	require.NoError(t, err)
+	require.Equal(t, "$alice@vasp.com", identifier)
+}
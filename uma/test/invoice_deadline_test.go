@@ -0,0 +1,42 @@
+package uma_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := services.NewLightsparkClient("client_id", "client_secret", &server.URL)
+	creator := uma.LightsparkClientLnurlInvoiceCreator{
+		LightsparkClient: *client,
+		NodeId:           "node1",
+		InvoiceDeadline:  50 * time.Millisecond,
+	}
+
+	startedAt := time.Now()
+	_, err := creator.CreateLnurlInvoice(1000, "[]")
+	require.Less(t, time.Since(startedAt), 500*time.Millisecond)
+	var deadlineErr *uma.InvoiceDeadlineExceededError
+	require.True(t, errors.As(err, &deadlineErr))
+	require.True(t, deadlineErr.Retryable())
+	require.Zero(t, client.Requester.Timeout)
+
+	recorder := httptest.NewRecorder()
+	deadlineErr.WriteResponse(recorder)
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+}
@@ -0,0 +1,58 @@
+package uma_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightsparkClientLnurlInvoiceCreator(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("CreateLnurlInvoice", map[string]interface{}{"create_lnurl_invoice": map[string]interface{}{
+			"invoice": map[string]interface{}{
+				"__typename": "Invoice",
+				"invoice_id": "invoice:1",
+				"invoice_data": map[string]interface{}{
+					"__typename":                           "InvoiceData",
+					"invoice_data_encoded_payment_request": "lnbc1",
+				},
+			},
+		}})
+	client := newMockLightsparkClient(t, mock)
+	expirySecs := int32(600)
+	creator := uma.LightsparkClientLnurlInvoiceCreator{
+		LightsparkClient: *client,
+		NodeId:           "node:1",
+		ExpirySecs:       &expirySecs,
+	}
+
+	encodedInvoice, err := creator.CreateLnurlInvoice(1000, "[]")
+	require.NoError(t, err)
+	require.Equal(t, "lnbc1", *encodedInvoice)
+	variables := mock.Calls()[0].Variables
+	require.Equal(t, "node:1", variables["node_id"])
+	require.Equal(t, float64(1000), variables["amount_msats"])
+	require.Equal(t, crypto.Sha256HexString("[]"), variables["metadata_hash"])
+	require.Equal(t, float64(600), variables["expiry_secs"])
+
+	creator.NodeSelector = uma.NodeSelectorFunc(func(amountMsats int64, metadata string) (string, error) {
+		if amountMsats > 5000 {
+			return "", errors.New("no node can receive this amount")
+		}
+		return "node:2", nil
+	})
+	_, err = creator.CreateLnurlInvoice(2000, "[]")
+	require.NoError(t, err)
+	require.Equal(t, "node:2", mock.Calls()[1].Variables["node_id"])
+
+	_, err = creator.CreateLnurlInvoice(6000, "[]")
+	require.EqualError(t, err, "no node can receive this amount")
+	creator.NodeSelector = uma.StaticNodeSelector("")
+	_, err = creator.CreateLnurlInvoice(2000, "[]")
+	require.EqualError(t, err, "the node selector returned no node")
+	require.Len(t, mock.Calls(), 2)
+}
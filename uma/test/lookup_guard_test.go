@@ -0,0 +1,74 @@
+package uma_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupGuardMiddleware(t *testing.T) {
+	handler := uma.LookupGuardMiddleware(uma.LookupGuardOptions{
+		MinResponseTime: 20 * time.Millisecond,
+		MaxNotFound:     2,
+	})(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		username, _ := uma.MatchLnurlpPath(request.URL.Path)
+		if username != "alice" {
+			http.NotFound(w, request)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	serve := func(path string) (*httptest.ResponseRecorder, time.Duration) {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("GET", path, nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		startedAt := time.Now()
+		handler.ServeHTTP(recorder, request)
+		return recorder, time.Since(startedAt)
+	}
+
+	recorder, elapsed := serve("/.well-known/lnurlp/alice")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "{}", recorder.Body.String())
+	require.GreaterOrEqual(t, elapsed, 20*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		recorder, elapsed = serve("/.well-known/lnurlp/bob")
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.GreaterOrEqual(t, elapsed, 20*time.Millisecond)
+	}
+	recorder, _ = serve("/.well-known/lnurlp/alice")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	recorder, elapsed = serve("/api/uma/payreq/alice")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Less(t, elapsed, 20*time.Millisecond)
+}
+
+func TestJitterExpirySecs(t *testing.T) {
+	require.Nil(t, uma.JitterExpirySecs(nil, 0))
+	expiry := int32(600)
+	for i := 0; i < 20; i++ {
+		jittered := uma.JitterExpirySecs(&expiry, 60)
+		require.GreaterOrEqual(t, *jittered, int32(540))
+		require.LessOrEqual(t, *jittered, int32(600))
+	}
+	require.LessOrEqual(t, *uma.JitterExpirySecs(nil, 60), int32(uma.DEFAULT_INVOICE_EXPIRY_SECS))
+}
+
+func TestForwardedForClientKey(t *testing.T) {
+	clientKey := uma.ForwardedForClientKey(2)
+	request := httptest.NewRequest("GET", "/.well-known/lnurlp/alice", nil)
+	request.RemoteAddr = "10.0.0.2:1234"
+	require.Equal(t, "10.0.0.2", clientKey(request))
+
+	request.Header.Add("X-Forwarded-For", "198.51.100.7, 203.0.113.5")
+	request.Header.Add("X-Forwarded-For", "10.0.0.1")
+	require.Equal(t, "203.0.113.5", clientKey(request))
+	require.Equal(t, "10.0.0.1", uma.ForwardedForClientKey(1)(request))
+}
@@ -0,0 +1,80 @@
+package uma_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/crypto"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightsparkClientUmaInvoiceCreator_NodeSelector(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("CreateUmaInvoice", map[string]interface{}{"create_uma_invoice": map[string]interface{}{
+			"invoice": map[string]interface{}{
+				"__typename": "Invoice",
+				"invoice_id": "invoice:1",
+				"invoice_data": map[string]interface{}{
+					"__typename":                           "InvoiceData",
+					"invoice_data_encoded_payment_request": "lnbc1",
+				},
+			},
+		}})
+	var selections []string
+	creator := uma.LightsparkClientUmaInvoiceCreator{
+		LightsparkClient: *newMockLightsparkClient(t, mock),
+		NodeId:           "node:1",
+		NodeSelector: uma.NodeSelectorFunc(func(amountMsats int64, metadata string) (string, error) {
+			selections = append(selections, metadata)
+			switch {
+			case amountMsats > 5000:
+				return "", errors.New("no node can receive this amount")
+			case amountMsats > 4000:
+				return "", nil
+			}
+			return "node:2", nil
+		}),
+	}
+
+	// The selected node issues the invoice instead of NodeId.
+	encodedInvoice, err := creator.CreateUmaInvoice(1000, "[]")
+	require.NoError(t, err)
+	require.Equal(t, "lnbc1", *encodedInvoice)
+	require.Equal(t, "node:2", mock.Calls()[0].Variables["node_id"])
+	_, err = creator.CreateUmaInvoiceWithMetadataHash(1000, crypto.Sha256HexString("[]"))
+	require.NoError(t, err)
+	require.Equal(t, "node:2", mock.Calls()[1].Variables["node_id"])
+	require.Equal(t, []string{"[]", ""}, selections)
+
+	// No invoice is created if the selector fails or selects no node.
+	_, err = creator.CreateUmaInvoice(6000, "[]")
+	require.EqualError(t, err, "no node can receive this amount")
+	_, err = creator.CreateUmaInvoice(4500, "[]")
+	require.EqualError(t, err, "the node selector returned no node")
+	_, err = creator.CreateUmaInvoiceWithMetadataHash(4500, crypto.Sha256HexString("[]"))
+	require.EqualError(t, err, "the node selector returned no node")
+	require.Len(t, mock.Calls(), 2)
+}
+
+func TestNodeSelectors(t *testing.T) {
+	nodeId, err := uma.StaticNodeSelector("node:1").SelectNode(1000, "[]")
+	require.NoError(t, err)
+	require.Equal(t, "node:1", nodeId)
+
+	selector := &uma.RoundRobinNodeSelector{NodeIds: []string{"node:1", "node:2"}}
+	var nodeIds []string
+	for i := 0; i < 3; i++ {
+		nodeId, err := selector.SelectNode(1000, "[]")
+		require.NoError(t, err)
+		nodeIds = append(nodeIds, nodeId)
+	}
+	require.Equal(t, []string{"node:1", "node:2", "node:1"}, nodeIds)
+	_, err = (&uma.RoundRobinNodeSelector{}).SelectNode(1000, "[]")
+	require.EqualError(t, err, "no node to select from")
+
+	// Without nodes, even a zero amount has no node to be received on.
+	_, err = uma.InboundLiquidityNodeSelector{}.SelectNode(0, "[]")
+	require.EqualError(t, err, "no node has enough inbound liquidity for the payment")
+}
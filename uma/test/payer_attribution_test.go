@@ -0,0 +1,106 @@
+package uma_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/requester"
+	"github.com/lightsparkdev/go-sdk/requester/requestertest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockLightsparkClient(t *testing.T, mock *requestertest.Mock) *services.LightsparkClient {
+	client, err := services.NewLightsparkClientWithOptions("client_id", "client_secret",
+		services.WithBaseUrl(requestertest.MOCK_BASE_URL),
+		services.WithRequesterOptions(requester.WithHTTPClient(&http.Client{Transport: mock})))
+	require.NoError(t, err)
+	return client
+}
+
+func TestInMemoryAttributionStore_Expiry(t *testing.T) {
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "seed")
+	t.Cleanup(sdkruntimetest.SetDefault(runtime))
+	store := uma.NewInMemoryAttributionStore(time.Hour)
+
+	require.Error(t, store.SaveAttribution(uma.PayerAttribution{PayerIdentifier: "$alice@vasp1.com"}))
+	require.NoError(t, store.SaveAttribution(uma.PayerAttribution{
+		PaymentHash:     "hash",
+		PayerIdentifier: "$alice@vasp1.com",
+	}))
+	attribution, err := store.GetAttribution("hash")
+	require.NoError(t, err)
+	require.Equal(t, "$alice@vasp1.com", attribution.PayerIdentifier)
+	require.Equal(t, runtime.Now().UTC(), attribution.RecordedAt)
+	_, err = store.GetAttribution("other")
+	require.ErrorIs(t, err, uma.ErrAttributionNotFound)
+
+	clock.Advance(2 * time.Hour)
+	_, err = store.GetAttribution("hash")
+	require.ErrorIs(t, err, uma.ErrAttributionNotFound)
+	removed, err := store.Sweep(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	require.Equal(t, 0, store.Len())
+}
+
+func TestPayerAttribution_RecordedForIncomingPayment(t *testing.T) {
+	mock := requestertest.NewMock().
+		RespondData("CreateUmaInvoice", map[string]interface{}{"create_uma_invoice": map[string]interface{}{
+			"invoice": map[string]interface{}{
+				"__typename": "Invoice",
+				"invoice_id": "invoice:1",
+				"invoice_data": map[string]interface{}{
+					"__typename":                           "InvoiceData",
+					"invoice_data_encoded_payment_request": "lnbc1",
+					"invoice_data_payment_hash":            "hash",
+				},
+			},
+		}}).
+		Handle("GetEntity", func(call requestertest.Call) requestertest.Response {
+			switch call.Variables["id"] {
+			case "payment:1":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":                       "IncomingPayment",
+					"incoming_payment_id":              "payment:1",
+					"incoming_payment_payment_request": map[string]interface{}{"id": "invoice:1"},
+				}}}
+			case "payment:2":
+				return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+					"__typename":          "IncomingPayment",
+					"incoming_payment_id": "payment:2",
+				}}}
+			}
+			return requestertest.Response{Data: map[string]interface{}{"entity": map[string]interface{}{
+				"__typename": "Invoice",
+				"invoice_id": "invoice:1",
+				"invoice_data": map[string]interface{}{
+					"__typename":                "InvoiceData",
+					"invoice_data_payment_hash": "hash",
+				},
+			}}}
+		})
+	client := newMockLightsparkClient(t, mock)
+	store := uma.NewInMemoryAttributionStore(time.Hour)
+	creator := uma.LightsparkClientUmaInvoiceCreator{LightsparkClient: *client, NodeId: "node:1"}
+
+	encodedInvoice, err := creator.CreateAttributedUmaInvoice(1000, "[]",
+		uma.PayerAttribution{PayerIdentifier: "$alice@vasp1.com", SenderVaspDomain: "vasp1.com"}, store)
+	require.NoError(t, err)
+	require.Equal(t, "lnbc1", *encodedInvoice)
+
+	attribution, err := uma.GetAttributionForIncomingPayment(client, store, "payment:1")
+	require.NoError(t, err)
+	require.Equal(t, "hash", attribution.PaymentHash)
+	require.Equal(t, int64(1000), attribution.AmountMsats)
+	require.Equal(t, "$alice@vasp1.com", attribution.PayerIdentifier)
+	require.Equal(t, "vasp1.com", attribution.SenderVaspDomain)
+
+	// Keysend payments have no invoice.
+	_, err = uma.GetAttributionForIncomingPayment(client, store, "payment:2")
+	require.ErrorIs(t, err, uma.ErrAttributionNotFound)
+}
@@ -0,0 +1,64 @@
+package uma_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayreqCancellation(t *testing.T) {
+	senderKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	var cancelled []string
+	var payreqs []string
+	issuedPayreqs := uma.NewIssuedPayreqs(time.Hour)
+	issuedPayreqs.Record("lnbc1invoice", "sender.example.com")
+	issuedPayreqs.Record("lnbc1other", "other.example.com")
+	handler := uma.PayreqCancellationMiddleware(uma.PayreqCancellationOptions{
+		IssuedPayreqs: issuedPayreqs,
+		SenderSigningPubKey: func(ctx context.Context, senderVaspDomain string) ([]byte, error) {
+			return senderKey.PubKey().SerializeCompressed(), nil
+		},
+		OnCancel: func(ctx context.Context, cancellation uma.PayreqCancellation) error {
+			cancelled = append(cancelled, cancellation.Invoice)
+			return nil
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		payreqs = append(payreqs, string(body))
+	}))
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cancellation, err := uma.SignPayreqCancellation("lnbc1invoice", "sender.example.com", "timeout",
+		senderKey.Serialize())
+	require.NoError(t, err)
+	require.NoError(t, uma.PostPayreqCancellation(context.Background(), server.Client(), server.URL, *cancellation))
+	require.Equal(t, []string{"lnbc1invoice"}, cancelled)
+
+	cancellation.Reason = "tampered"
+	require.Error(t, uma.PostPayreqCancellation(context.Background(), server.Client(), server.URL, *cancellation))
+	require.Len(t, cancelled, 1)
+
+	// A payreq issued to another VASP, or already cancelled, cannot be cancelled.
+	cancellation, err = uma.SignPayreqCancellation("lnbc1other", "sender.example.com", "", senderKey.Serialize())
+	require.NoError(t, err)
+	require.Error(t, uma.PostPayreqCancellation(context.Background(), server.Client(), server.URL, *cancellation))
+	cancellation, err = uma.SignPayreqCancellation("lnbc1invoice", "sender.example.com", "", senderKey.Serialize())
+	require.NoError(t, err)
+	require.Error(t, uma.PostPayreqCancellation(context.Background(), server.Client(), server.URL, *cancellation))
+	require.Len(t, cancelled, 1)
+	require.Equal(t, 1, issuedPayreqs.Len())
+
+	response, err := server.Client().Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, []string{""}, payreqs)
+}
@@ -0,0 +1,51 @@
+package uma_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreeningCache(t *testing.T) {
+	screenings := 0
+	approved := true
+	cache := uma.NewScreeningCache(func(counterpartyDomain string, direction objects.PaymentDirection) (bool, error) {
+		screenings++
+		return approved, nil
+	}, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ok, err := cache.IsApproved("Vasp.example.com", objects.PaymentDirectionSent)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	require.Equal(t, 1, screenings)
+
+	_, err := cache.IsApproved("vasp.example.com", objects.PaymentDirectionReceived)
+	require.NoError(t, err)
+	require.Equal(t, 2, screenings)
+
+	approved = false
+	ok, err := cache.Refresh("vasp.example.com", objects.PaymentDirectionSent)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = cache.IsApproved("vasp.example.com", objects.PaymentDirectionSent)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 4, screenings)
+
+	cache.Invalidate("vasp.example.com")
+	require.Equal(t, 0, cache.Len())
+
+	approved = true
+	cache.SetTTL("uncached.example.com", 0)
+	for i := 0; i < 2; i++ {
+		ok, err = cache.IsApproved("uncached.example.com", objects.PaymentDirectionSent)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	require.Equal(t, 6, screenings)
+}
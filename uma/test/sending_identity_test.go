@@ -0,0 +1,58 @@
+package uma_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRegistry(t *testing.T) {
+	brandKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	registry := uma.NewIdentityRegistry()
+
+	require.NoError(t, registry.Register(uma.SendingIdentity{
+		Name:              "brand",
+		VaspDomain:        "brand.example.com",
+		SigningPrivateKey: brandKey.Serialize(),
+		SigningPubKey:     brandKey.PubKey().SerializeUncompressed(),
+	}))
+	identity, err := registry.ForSenderAddress("$alice@Brand.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "brand", identity.Name)
+	require.NoError(t, registry.Check("brand", "brand.example.com"))
+
+	err = registry.Register(uma.SendingIdentity{
+		Name:              "other",
+		VaspDomain:        "other.example.com",
+		SigningPrivateKey: otherKey.Serialize(),
+		SigningPubKey:     brandKey.PubKey().SerializeCompressed(),
+	})
+	require.ErrorIs(t, err, uma.ErrIdentityMismatch)
+
+	err = registry.Register(uma.SendingIdentity{
+		Name:              "other",
+		VaspDomain:        "brand.example.com",
+		SigningPrivateKey: otherKey.Serialize(),
+		SigningPubKey:     otherKey.PubKey().SerializeCompressed(),
+	})
+	require.ErrorIs(t, err, uma.ErrDomainAlreadyRegistered)
+	require.ErrorIs(t, registry.Check("other", "brand.example.com"), uma.ErrIdentityMismatch)
+
+	_, err = registry.Get("unknown")
+	require.ErrorIs(t, err, uma.ErrUnknownIdentity)
+
+	// The registered identities cannot be modified through the returned copies.
+	identity, err = registry.Get("brand")
+	require.NoError(t, err)
+	identity.VaspDomain = "other.example.com"
+	identity.SigningPrivateKey[0] ^= 0xff
+	identity, err = registry.ForVaspDomain("brand.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "brand.example.com", identity.VaspDomain)
+	require.Equal(t, brandKey.Serialize(), identity.SigningPrivateKey)
+}
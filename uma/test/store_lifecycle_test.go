@@ -0,0 +1,47 @@
+package uma_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+type storeSizes map[string]int
+
+func (s storeSizes) ObserveStoreSize(store string, size int) {
+	s[store] = size
+}
+
+func TestStoreJanitor(t *testing.T) {
+	store := uma.NewInMemoryAttributionStore(time.Hour)
+	require.NoError(t, store.SaveAttribution(uma.PayerAttribution{PaymentHash: "expired",
+		RecordedAt: time.Now().Add(-2 * time.Hour)}))
+	require.NoError(t, store.SaveAttribution(uma.PayerAttribution{PaymentHash: "deleted"}))
+	require.NoError(t, store.SaveAttribution(uma.PayerAttribution{PaymentHash: "live"}))
+	store.DeleteAttribution("deleted")
+	_, err := store.GetAttribution("deleted")
+	require.ErrorIs(t, err, uma.ErrAttributionNotFound)
+
+	var archive bytes.Buffer
+	sizes := storeSizes{}
+	janitor := uma.StoreJanitor{
+		Stores:  map[string]uma.SweepableStore{"attributions": store},
+		Archive: uma.NewJSONLinesArchive(&archive),
+		Metrics: sizes,
+	}
+	require.NoError(t, janitor.SweepOnce())
+
+	lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+	require.Len(t, lines, 2)
+	var archived uma.PayerAttribution
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &archived))
+	require.Contains(t, []string{"expired", "deleted"}, archived.PaymentHash)
+	require.Equal(t, 1, sizes["attributions"])
+	_, err = store.GetAttribution("live")
+	require.NoError(t, err)
+}
@@ -0,0 +1,54 @@
+package uma_test
+
+import (
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTravelRulePolicy_IsRequired(t *testing.T) {
+	policy := uma.TravelRulePolicy{Thresholds: []uma.TravelRuleThreshold{
+		{CurrencyCode: "USD", MinAmount: 100_000},
+		{Jurisdiction: "DE", CurrencyCode: "EUR", MinAmount: 0},
+		{CurrencyCode: "EUR", MinAmount: 100_000},
+		{Jurisdiction: "US", CurrencyCode: "USD", MinAmount: 300_000},
+	}}
+	tests := []struct {
+		name         string
+		jurisdiction string
+		currencyCode string
+		amount       int64
+		required     bool
+	}{
+		{"below the currency threshold", "FR", "USD", 99_999, false},
+		{"at the currency threshold", "FR", "USD", 100_000, true},
+		{"jurisdiction threshold takes precedence", "US", "USD", 200_000, false},
+		{"above the jurisdiction threshold", "us", "usd", 300_000, true},
+		{"zero jurisdiction threshold", "DE", "EUR", 1, true},
+		{"other jurisdiction in the same currency", "FR", "EUR", 1, false},
+		{"unknown currency", "US", "GBP", 1_000_000, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.required, policy.IsRequired(test.jurisdiction, test.currencyCode, test.amount))
+		})
+	}
+
+	policy.RequireByDefault = true
+	require.True(t, policy.IsRequired("US", "GBP", 1))
+}
+
+func TestTravelRulePolicy_ThresholdsRequireACurrency(t *testing.T) {
+	// A threshold without a currency would compare amounts in different units.
+	policy := uma.TravelRulePolicy{Thresholds: []uma.TravelRuleThreshold{{Jurisdiction: "US", MinAmount: 1_000}}}
+	require.Error(t, policy.Validate())
+	require.False(t, policy.IsRequired("US", "SAT", 1_000_000))
+	trInfo := "travel rule info"
+	require.Error(t, policy.Check("US", "SAT", 1_000_000, &trInfo))
+
+	policy.Thresholds[0].CurrencyCode = "USD"
+	require.NoError(t, policy.Validate())
+	require.NoError(t, policy.Check("US", "USD", 1_000_000, &trInfo))
+	require.ErrorIs(t, policy.Check("US", "USD", 1_000_000, nil), uma.ErrTravelRuleInfoRequired)
+}
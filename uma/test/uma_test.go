@@ -0,0 +1,51 @@
+package uma_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/services"
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightsparkClientUmaInvoiceCreator_RateLockWindow(t *testing.T) {
+	var expirySecs interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		expirySecs = payload.Variables["expiry_secs"]
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	client := services.NewLightsparkClient("client_id", "client_secret", &server.URL)
+	expiry := func(secs int32) *int32 { return &secs }
+
+	for _, test := range []struct {
+		expirySecs         *int32
+		rateLockWindowSecs *int32
+		expected           interface{}
+	}{
+		{nil, nil, float64(uma.DEFAULT_RATE_LOCK_WINDOW_SECS)},
+		{expiry(3600), nil, float64(uma.DEFAULT_RATE_LOCK_WINDOW_SECS)},
+		{expiry(60), nil, float64(60)},
+		{expiry(3600), expiry(120), float64(120)},
+		{expiry(3600), expiry(0), float64(3600)},
+		{nil, expiry(0), nil},
+	} {
+		creator := uma.LightsparkClientUmaInvoiceCreator{
+			LightsparkClient:   *client,
+			NodeId:             "node1",
+			ExpirySecs:         test.expirySecs,
+			RateLockWindowSecs: test.rateLockWindowSecs,
+		}
+		expirySecs = nil
+		_, err := creator.CreateUmaInvoice(1000, "[]")
+		require.Error(t, err)
+		require.Equal(t, test.expected, expirySecs)
+	}
+}
@@ -0,0 +1,30 @@
+package uma_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/uma"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtxo(t *testing.T) {
+	txid := strings.Repeat("ab", 32)
+	utxo, err := uma.ParseUtxo(strings.ToUpper(txid) + ":3")
+	require.NoError(t, err)
+	require.Equal(t, uma.Utxo{Txid: txid, Vout: 3}, utxo)
+	require.Equal(t, txid+":3", utxo.String())
+
+	encoded, err := json.Marshal([]uma.Utxo{utxo})
+	require.NoError(t, err)
+	require.JSONEq(t, `["`+txid+`:3"]`, string(encoded))
+	var decoded []uma.Utxo
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, []uma.Utxo{utxo}, decoded)
+
+	for _, invalid := range []string{txid, "abcd:1", txid + ":-1", txid + ":x", strings.Repeat("zz", 32) + ":0"} {
+		_, err = uma.ParseUtxo(invalid)
+		require.Error(t, err, invalid)
+	}
+}
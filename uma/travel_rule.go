@@ -0,0 +1,97 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrTravelRuleInfoRequired is returned when a payment requires travel rule information which was not provided.
+var ErrTravelRuleInfoRequired = errors.New("travel rule information is required for this payment")
+
+// TravelRuleThreshold is the amount at or above which travel rule information must be attached to a payment.
+type TravelRuleThreshold struct {
+	// Jurisdiction is the jurisdiction the threshold applies to, e.g. an ISO 3166 country code. An empty jurisdiction
+	// matches every jurisdiction.
+	Jurisdiction string
+	// CurrencyCode is the currency the threshold is expressed in. It is required: a threshold only applies to the
+	// amounts in its currency, so amounts in other currencies must be converted before they are checked.
+	CurrencyCode string
+	// MinAmount is the threshold, in the smallest unit of the currency.
+	MinAmount int64
+}
+
+// TravelRulePolicy decides whether travel rule information (the trInfo of a payreq) must be attached to an outgoing
+// UMA payment. Among the thresholds expressed in the currency of the payment, the most specific one applies: a
+// threshold for the jurisdiction of the payment takes precedence over one for every jurisdiction. When no threshold
+// matches, RequireByDefault decides.
+type TravelRulePolicy struct {
+	Thresholds []TravelRuleThreshold
+	// RequireByDefault requires travel rule information when no threshold matches, so that unknown jurisdictions
+	// fail closed.
+	RequireByDefault bool
+}
+
+// Validate returns an error if a threshold of the policy does not name its currency.
+func (p TravelRulePolicy) Validate() error {
+	for _, threshold := range p.Thresholds {
+		if threshold.CurrencyCode == "" {
+			return errors.New("travel rule thresholds must have a currency code")
+		}
+	}
+	return nil
+}
+
+// IsRequired returns whether travel rule information must be attached to a payment.
+//
+// Args:
+//
+//	jurisdiction: the jurisdiction of the payment, e.g. the receiving VASP's country code.
+//	currencyCode: the currency the amount is expressed in.
+//	amount: the amount of the payment, in the smallest unit of the currency.
+func (p TravelRulePolicy) IsRequired(jurisdiction string, currencyCode string, amount int64) bool {
+	threshold := p.matchThreshold(jurisdiction, currencyCode)
+	if threshold == nil {
+		return p.RequireByDefault
+	}
+	return amount >= threshold.MinAmount
+}
+
+// Check returns ErrTravelRuleInfoRequired if travel rule information is required for a payment but trInfo is empty,
+// or the error of Validate if the policy is invalid.
+//
+// Args:
+//
+//	jurisdiction: the jurisdiction of the payment, e.g. the receiving VASP's country code.
+//	currencyCode: the currency the amount is expressed in.
+//	amount: the amount of the payment, in the smallest unit of the currency.
+//	trInfo: the travel rule information which will be attached to the payreq, if any.
+func (p TravelRulePolicy) Check(jurisdiction string, currencyCode string, amount int64, trInfo *string) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.IsRequired(jurisdiction, currencyCode, amount) && (trInfo == nil || *trInfo == "") {
+		return ErrTravelRuleInfoRequired
+	}
+	return nil
+}
+
+func (p TravelRulePolicy) matchThreshold(jurisdiction string, currencyCode string) *TravelRuleThreshold {
+	var match *TravelRuleThreshold
+	for i := range p.Thresholds {
+		threshold := &p.Thresholds[i]
+		if threshold.CurrencyCode == "" || !strings.EqualFold(threshold.CurrencyCode, currencyCode) {
+			continue
+		}
+		if threshold.Jurisdiction != "" {
+			if !strings.EqualFold(threshold.Jurisdiction, jurisdiction) {
+				continue
+			}
+			return threshold
+		}
+		if match == nil {
+			match = threshold
+		}
+	}
+	return match
+}
@@ -2,9 +2,17 @@
 package uma
 
 import (
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/events"
+	"github.com/lightsparkdev/go-sdk/objects"
 	"github.com/lightsparkdev/go-sdk/services"
 )
 
+// DEFAULT_RATE_LOCK_WINDOW_SECS is the default number of seconds the conversion rate quoted in a payreq response is
+// valid, capping the expiry of the invoices created by LightsparkClientUmaInvoiceCreator.
+const DEFAULT_RATE_LOCK_WINDOW_SECS = 600
+
 // LightsparkClientUmaInvoiceCreator is a wrapper around the LightsparkClient that implements the UmaInvoiceCreator
 // interface.
 // See github.com/uma-universal-money-address/uma-go-sdk for the interface and its documentation.
@@ -12,14 +20,87 @@ type LightsparkClientUmaInvoiceCreator struct {
 	LightsparkClient services.LightsparkClient
 	// NodeId: the node ID of the receiver.
 	NodeId string
+	// NodeSelector: if set, chooses the node issuing each invoice instead of NodeId.
+	NodeSelector NodeSelector
 	// ExpirySecs: the number of seconds until the invoice expires.
 	ExpirySecs *int32
+	// RateLockWindowSecs: the number of seconds the conversion rate quoted in the payreq response is valid. Defaults
+	// to DEFAULT_RATE_LOCK_WINDOW_SECS, and a value of 0 or less removes the cap. Invoices expire at the earliest of
+	// ExpirySecs and the end of the rate lock window, so that they cannot be settled at a stale rate.
+	RateLockWindowSecs *int32
+	// ExpiryPolicy: if set, returns the expiry of each invoice in seconds, overriding ExpirySecs and
+	// RateLockWindowSecs. A nil result uses the API default.
+	ExpiryPolicy func(amountMsats int64, metadata string) *int32
+	// ExpiryJitterSecs: if set, each invoice expires up to this number of seconds earlier, chosen at random, so that
+	// invoice expiries do not fingerprint the receiving VASP. See JitterExpirySecs.
+	ExpiryJitterSecs int32
+	// EventSink: if set, receives an events.UmaStepCompleted event for each invoice created.
+	EventSink events.Sink
+	// InvoiceDeadline: if set, the invoice creation is cancelled after this duration, usually the timeout budget of
+	// the counterparty minus a margin, and fails with an InvoiceDeadlineExceededError.
+	InvoiceDeadline time.Duration
 }
 
 func (l LightsparkClientUmaInvoiceCreator) CreateUmaInvoice(amountMsats int64, metadata string) (*string, error) {
-	invoice, err := l.LightsparkClient.CreateUmaInvoice(l.NodeId, amountMsats, metadata, l.ExpirySecs)
+	nodeId, err := l.selectNode(amountMsats, metadata)
+	if err != nil {
+		return nil, err
+	}
+	client := clientWithDeadline(l.LightsparkClient, l.InvoiceDeadline)
+	invoice, err := client.CreateUmaInvoice(nodeId, amountMsats, metadata, l.expirySecs(amountMsats, metadata))
+	if err != nil {
+		return nil, deadlineError(err, l.InvoiceDeadline)
+	}
+	l.emitInvoiceCreated(nodeId, invoice)
+	return &invoice.Data.EncodedPaymentRequest, nil
+}
+
+// CreateUmaInvoiceWithMetadataHash creates an UMA invoice from the hex-encoded SHA256 hash of the metadata, for
+// integrations which compute the LNURL metadata and its description hash themselves.
+func (l LightsparkClientUmaInvoiceCreator) CreateUmaInvoiceWithMetadataHash(amountMsats int64, metadataHash string) (*string, error) {
+	nodeId, err := l.selectNode(amountMsats, "")
 	if err != nil {
 		return nil, err
 	}
+	client := clientWithDeadline(l.LightsparkClient, l.InvoiceDeadline)
+	invoice, err := client.CreateUmaInvoiceWithMetadataHash(nodeId, amountMsats, metadataHash,
+		l.expirySecs(amountMsats, ""))
+	if err != nil {
+		return nil, deadlineError(err, l.InvoiceDeadline)
+	}
+	l.emitInvoiceCreated(nodeId, invoice)
 	return &invoice.Data.EncodedPaymentRequest, nil
 }
+
+func (l LightsparkClientUmaInvoiceCreator) emitInvoiceCreated(nodeId string, invoice *objects.Invoice) {
+	events.Emit(l.EventSink, events.Event{
+		Type: events.UmaStepCompleted,
+		Step: events.UMA_STEP_INVOICE_CREATED,
+		Attributes: map[string]string{
+			"node_id":    nodeId,
+			"invoice_id": invoice.Id,
+		},
+	})
+}
+
+func (l LightsparkClientUmaInvoiceCreator) selectNode(amountMsats int64, metadata string) (string, error) {
+	return selectNode(l.NodeSelector, l.NodeId, amountMsats, metadata)
+}
+
+func (l LightsparkClientUmaInvoiceCreator) expirySecs(amountMsats int64, metadata string) *int32 {
+	return JitterExpirySecs(l.baseExpirySecs(amountMsats, metadata), l.ExpiryJitterSecs)
+}
+
+func (l LightsparkClientUmaInvoiceCreator) baseExpirySecs(amountMsats int64, metadata string) *int32 {
+	if l.ExpiryPolicy != nil {
+		return l.ExpiryPolicy(amountMsats, metadata)
+	}
+	rateLockWindowSecs := int32(DEFAULT_RATE_LOCK_WINDOW_SECS)
+	if l.RateLockWindowSecs != nil {
+		rateLockWindowSecs = *l.RateLockWindowSecs
+	}
+	if rateLockWindowSecs <= 0 || (l.ExpirySecs != nil && *l.ExpirySecs < rateLockWindowSecs) {
+		return l.ExpirySecs
+	}
+	return &rateLockWindowSecs
+}
@@ -0,0 +1,123 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package uma
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+)
+
+// Utxo is a transaction output, e.g. of a channel funding transaction, as exchanged in UMA compliance payloads.
+type Utxo struct {
+	// Txid is the hex-encoded transaction hash.
+	Txid string
+	// Vout is the index of the output in the transaction.
+	Vout uint32
+	// AmountMsats is the amount of the payment which went through the output, for post-transaction callbacks. It is
+	// not part of the string representation.
+	AmountMsats int64
+}
+
+// ParseUtxo parses an UTXO in the `<txid>:<vout>` representation of the UMA specification.
+func ParseUtxo(utxo string) (Utxo, error) {
+	txid, vout, ok := strings.Cut(utxo, ":")
+	if !ok {
+		return Utxo{}, errors.New("invalid utxo, expected <txid>:<vout>: " + utxo)
+	}
+	index, err := strconv.ParseUint(vout, 10, 32)
+	if err != nil {
+		return Utxo{}, errors.New("invalid utxo output index: " + utxo)
+	}
+	parsed := Utxo{Txid: strings.ToLower(txid), Vout: uint32(index)}
+	if err := parsed.Validate(); err != nil {
+		return Utxo{}, err
+	}
+	return parsed, nil
+}
+
+// ParseUtxos parses a list of UTXOs, e.g. the `utxos` of a compliance payload or GetNodeChannelUtxos.
+func ParseUtxos(utxos []string) ([]Utxo, error) {
+	parsed := make([]Utxo, 0, len(utxos))
+	for _, utxo := range utxos {
+		parsedUtxo, err := ParseUtxo(utxo)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, parsedUtxo)
+	}
+	return parsed, nil
+}
+
+// FormatUtxos formats a list of UTXOs in the representation of the UMA specification.
+func FormatUtxos(utxos []Utxo) []string {
+	formatted := make([]string, 0, len(utxos))
+	for _, utxo := range utxos {
+		formatted = append(formatted, utxo.String())
+	}
+	return formatted
+}
+
+// UtxosFromPostTransactionData returns the UTXOs and amounts of the post-transaction data of a payment, to send to
+// the UTXO callback of the counterparty.
+func UtxosFromPostTransactionData(data []objects.PostTransactionData) ([]Utxo, error) {
+	utxos := make([]Utxo, 0, len(data))
+	for _, item := range data {
+		utxo, err := ParseUtxo(item.Utxo)
+		if err != nil {
+			return nil, err
+		}
+		utxo.AmountMsats, err = utils.ValueMilliSatoshi(item.Amount)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, nil
+}
+
+// Validate checks that the transaction hash is 32 bytes of hex.
+func (u Utxo) Validate() error {
+	if len(u.Txid) != 64 {
+		return errors.New("invalid utxo txid length: " + u.Txid)
+	}
+	if _, err := hex.DecodeString(u.Txid); err != nil {
+		return errors.New("invalid utxo txid: " + u.Txid)
+	}
+	return nil
+}
+
+// String returns the `<txid>:<vout>` representation of the UTXO.
+func (u Utxo) String() string {
+	return u.Txid + ":" + strconv.FormatUint(uint64(u.Vout), 10)
+}
+
+func (u Utxo) MarshalText() ([]byte, error) {
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(u.String()), nil
+}
+
+func (u *Utxo) UnmarshalText(text []byte) error {
+	parsed, err := ParseUtxo(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// CallbackUtxo is the representation of an UTXO and its amount in the body of UTXO callbacks.
+type CallbackUtxo struct {
+	Utxo   string `json:"utxo"`
+	Amount int64  `json:"amount"`
+}
+
+// CallbackUtxo returns the representation of the UTXO in UTXO callbacks.
+func (u Utxo) CallbackUtxo() CallbackUtxo {
+	return CallbackUtxo{Utxo: u.String(), Amount: u.AmountMsats}
+}
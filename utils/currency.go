@@ -2,22 +2,88 @@ package utils
 
 import (
 	"errors"
+	"math"
+	"math/big"
+
 	"github.com/lightsparkdev/go-sdk/objects"
 )
 
 func ValueMilliSatoshi(amount objects.CurrencyAmount) (int64, error) {
-	if amount.OriginalUnit == objects.CurrencyUnitMillisatoshi {
-		return amount.OriginalValue, nil
-	} else if amount.OriginalUnit == objects.CurrencyUnitSatoshi {
-		return amount.OriginalValue * 1000, nil
-	} else if amount.OriginalUnit == objects.CurrencyUnitBitcoin {
-		return amount.OriginalValue * 100_000_000_000, nil
-	} else if amount.OriginalUnit == objects.CurrencyUnitMicrobitcoin {
-		return amount.OriginalValue * 100_000, nil
-	} else if amount.OriginalUnit == objects.CurrencyUnitMillibitcoin {
-		return amount.OriginalValue * 100_000_000, nil
-	} else if amount.OriginalUnit == objects.CurrencyUnitNanobitcoin {
-		return amount.OriginalValue * 100, nil
+	milliSatoshis, ok := milliSatoshisPerUnit(amount.OriginalUnit)
+	if !ok {
+		return -1, errors.New("invalid currency conversion")
+	}
+	return amount.OriginalValue * milliSatoshis, nil
+}
+
+// milliSatoshisPerUnit returns the number of millisatoshis in one unit of a bitcoin-denominated currency unit.
+func milliSatoshisPerUnit(unit objects.CurrencyUnit) (int64, bool) {
+	switch unit {
+	case objects.CurrencyUnitMillisatoshi:
+		return 1, true
+	case objects.CurrencyUnitSatoshi:
+		return 1000, true
+	case objects.CurrencyUnitBitcoin:
+		return 100_000_000_000, true
+	case objects.CurrencyUnitMicrobitcoin:
+		return 100_000, true
+	case objects.CurrencyUnitMillibitcoin:
+		return 100_000_000, true
+	case objects.CurrencyUnitNanobitcoin:
+		return 100, true
+	}
+	return 0, false
+}
+
+// ConvertCurrencyAmount returns the amount with its preferred currency value in targetUnit. Conversions between
+// bitcoin denominated units are computed exactly from the ratios of the units. Other conversions, e.g. to USD, use the
+// preferred currency value estimated by the API when the amount was fetched, so they are only possible when the
+// preferred currency unit of the amount, or its original unit, is targetUnit or is bitcoin denominated like
+// targetUnit.
+func ConvertCurrencyAmount(amount objects.CurrencyAmount, targetUnit objects.CurrencyUnit,
+) (objects.CurrencyAmount, error) {
+	converted := amount
+	converted.PreferredCurrencyUnit = targetUnit
+	targetMsats, targetIsBitcoin := milliSatoshisPerUnit(targetUnit)
+
+	if originalMsats, ok := milliSatoshisPerUnit(amount.OriginalUnit); ok && targetIsBitcoin {
+		msats := new(big.Int).Mul(big.NewInt(amount.OriginalValue), big.NewInt(originalMsats))
+		rounded, ok := divideRounded(msats, targetMsats)
+		if !ok {
+			return objects.CurrencyAmount{}, errors.New("converted amount overflows an int64")
+		}
+		converted.PreferredCurrencyValueRounded = rounded
+		converted.PreferredCurrencyValueApprox, _ = new(big.Rat).SetFrac(msats, big.NewInt(targetMsats)).Float64()
+		return converted, nil
+	}
+	if amount.OriginalUnit == targetUnit {
+		converted.PreferredCurrencyValueRounded = amount.OriginalValue
+		converted.PreferredCurrencyValueApprox = float64(amount.OriginalValue)
+		return converted, nil
+	}
+
+	var value float64
+	if amount.PreferredCurrencyUnit == targetUnit {
+		value = amount.PreferredCurrencyValueApprox
+	} else if preferredMsats, ok := milliSatoshisPerUnit(amount.PreferredCurrencyUnit); ok && targetIsBitcoin {
+		value = amount.PreferredCurrencyValueApprox * float64(preferredMsats) / float64(targetMsats)
+	} else {
+		return objects.CurrencyAmount{}, errors.New("no conversion from " + amount.OriginalUnit.StringValue() + " to " +
+			targetUnit.StringValue())
+	}
+	converted.PreferredCurrencyValueApprox = value
+	converted.PreferredCurrencyValueRounded = int64(math.Round(value))
+	return converted, nil
+}
+
+// divideRounded divides an amount by a unit ratio, rounding halves away from zero like math.Round.
+func divideRounded(amount *big.Int, divisor int64) (int64, bool) {
+	quotient, remainder := new(big.Int).QuoRem(amount, big.NewInt(divisor), new(big.Int))
+	if new(big.Int).Abs(remainder).Int64()*2 >= divisor {
+		quotient.Add(quotient, big.NewInt(int64(amount.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return 0, false
 	}
-	return -1, errors.New("invalid currency conversion")
+	return quotient.Int64(), true
 }
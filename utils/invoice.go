@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+)
+
+// InvoiceDataAmountMsats returns the requested amount of an invoice in millisatoshis. It returns 0 if the invoice is
+// nil, has no amount (the sender chooses the amount), or its amount is not in a bitcoin unit.
+func InvoiceDataAmountMsats(invoiceData *objects.InvoiceData) int64 {
+	if invoiceData == nil {
+		return 0
+	}
+	amountMsats, err := ValueMilliSatoshi(invoiceData.Amount)
+	if err != nil {
+		return 0
+	}
+	return amountMsats
+}
+
+// IsInvoiceDataExpired returns whether an invoice is expired at the given time. A nil invoice is considered expired.
+func IsInvoiceDataExpired(invoiceData *objects.InvoiceData, now time.Time) bool {
+	if invoiceData == nil {
+		return true
+	}
+	return !now.Before(invoiceData.ExpiresAt)
+}
+
+// InvoiceAmountMsats returns the requested amount of an invoice in millisatoshis, or 0 if the invoice is nil or has
+// no amount.
+func InvoiceAmountMsats(invoice *objects.Invoice) int64 {
+	if invoice == nil {
+		return 0
+	}
+	return InvoiceDataAmountMsats(&invoice.Data)
+}
+
+// InvoiceAmountPaidMsats returns the total amount paid to an invoice in millisatoshis, or 0 if nothing was paid.
+func InvoiceAmountPaidMsats(invoice *objects.Invoice) int64 {
+	if invoice == nil || invoice.AmountPaid == nil {
+		return 0
+	}
+	amountMsats, err := ValueMilliSatoshi(*invoice.AmountPaid)
+	if err != nil {
+		return 0
+	}
+	return amountMsats
+}
+
+// InvoiceExpiresAt returns the expiry time of an invoice, or the zero time if the invoice is nil.
+func InvoiceExpiresAt(invoice *objects.Invoice) time.Time {
+	if invoice == nil {
+		return time.Time{}
+	}
+	return invoice.Data.ExpiresAt
+}
+
+// InvoicePaymentHash returns the payment hash of an invoice, or an empty string if the invoice is nil.
+func InvoicePaymentHash(invoice *objects.Invoice) string {
+	if invoice == nil {
+		return ""
+	}
+	return invoice.Data.PaymentHash
+}
+
+// InvoiceEncodedPaymentRequest returns the BOLT11 encoded invoice, or an empty string if the invoice is nil.
+func InvoiceEncodedPaymentRequest(invoice *objects.Invoice) string {
+	if invoice == nil {
+		return ""
+	}
+	return invoice.Data.EncodedPaymentRequest
+}
+
+// IsInvoiceExpired returns whether an invoice is expired at the given time. A nil invoice is considered expired.
+func IsInvoiceExpired(invoice *objects.Invoice, now time.Time) bool {
+	if invoice == nil {
+		return true
+	}
+	return IsInvoiceDataExpired(&invoice.Data, now)
+}
@@ -0,0 +1,57 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertCurrencyAmount(t *testing.T) {
+	amount := objects.CurrencyAmount{
+		OriginalValue:                 150_000,
+		OriginalUnit:                  objects.CurrencyUnitSatoshi,
+		PreferredCurrencyUnit:         objects.CurrencyUnitUsd,
+		PreferredCurrencyValueRounded: 6_000,
+		PreferredCurrencyValueApprox:  6_000.4,
+	}
+
+	converted, err := utils.ConvertCurrencyAmount(amount, objects.CurrencyUnitMillisatoshi)
+	require.NoError(t, err)
+	require.Equal(t, int64(150_000_000), converted.PreferredCurrencyValueRounded)
+	require.Equal(t, objects.CurrencyUnitSatoshi, converted.OriginalUnit)
+
+	converted, err = utils.ConvertCurrencyAmount(amount, objects.CurrencyUnitUsd)
+	require.NoError(t, err)
+	require.Equal(t, int64(6_000), converted.PreferredCurrencyValueRounded)
+
+	usdAmount := objects.CurrencyAmount{OriginalValue: 500, OriginalUnit: objects.CurrencyUnitUsd}
+	_, err = utils.ConvertCurrencyAmount(usdAmount, objects.CurrencyUnitSatoshi)
+	require.Error(t, err)
+
+	largeAmount := objects.CurrencyAmount{OriginalValue: 9_007_199_254_740_993, OriginalUnit: objects.CurrencyUnitSatoshi}
+	converted, err = utils.ConvertCurrencyAmount(largeAmount, objects.CurrencyUnitMillisatoshi)
+	require.NoError(t, err)
+	require.Equal(t, int64(9_007_199_254_740_993_000), converted.PreferredCurrencyValueRounded)
+	converted, err = utils.ConvertCurrencyAmount(largeAmount, objects.CurrencyUnitBitcoin)
+	require.NoError(t, err)
+	require.Equal(t, int64(90_071_993), converted.PreferredCurrencyValueRounded)
+	_, err = utils.ConvertCurrencyAmount(
+		objects.CurrencyAmount{OriginalValue: 100_000_000, OriginalUnit: objects.CurrencyUnitBitcoin},
+		objects.CurrencyUnitMillisatoshi)
+	require.Error(t, err)
+
+	largeAmount.OriginalUnit = objects.CurrencyUnitMillisatoshi
+	converted, err = utils.ConvertCurrencyAmount(largeAmount, objects.CurrencyUnitMillisatoshi)
+	require.NoError(t, err)
+	require.Equal(t, int64(9_007_199_254_740_993), converted.PreferredCurrencyValueRounded)
+	converted, err = utils.ConvertCurrencyAmount(largeAmount, objects.CurrencyUnitSatoshi)
+	require.NoError(t, err)
+	require.Equal(t, int64(9_007_199_254_741), converted.PreferredCurrencyValueRounded)
+
+	negativeAmount := objects.CurrencyAmount{OriginalValue: -1_500, OriginalUnit: objects.CurrencyUnitMillisatoshi}
+	converted, err = utils.ConvertCurrencyAmount(negativeAmount, objects.CurrencyUnitSatoshi)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), converted.PreferredCurrencyValueRounded)
+}
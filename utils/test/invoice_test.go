@@ -0,0 +1,36 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceAccessors(t *testing.T) {
+	expiresAt := time.Date(2023, 11, 5, 12, 17, 57, 0, time.UTC)
+	invoice := &objects.Invoice{
+		Data: objects.InvoiceData{
+			EncodedPaymentRequest: "lnbcrt34170n1pj5vdn4pp5",
+			PaymentHash:           "d4aee7ebca6535ae546cfc63161eee7719d6338541abd56c1d454361b36fbbac",
+			Amount:                objects.CurrencyAmount{OriginalValue: 3417, OriginalUnit: objects.CurrencyUnitSatoshi},
+			ExpiresAt:             expiresAt,
+		},
+	}
+
+	require.Equal(t, int64(3_417_000), utils.InvoiceAmountMsats(invoice))
+	require.Equal(t, int64(0), utils.InvoiceAmountPaidMsats(invoice))
+	require.Equal(t, expiresAt, utils.InvoiceExpiresAt(invoice))
+	require.Equal(t, "d4aee7ebca6535ae546cfc63161eee7719d6338541abd56c1d454361b36fbbac", utils.InvoicePaymentHash(invoice))
+	require.Equal(t, "lnbcrt34170n1pj5vdn4pp5", utils.InvoiceEncodedPaymentRequest(invoice))
+	require.False(t, utils.IsInvoiceExpired(invoice, expiresAt.Add(-time.Second)))
+	require.True(t, utils.IsInvoiceExpired(invoice, expiresAt))
+
+	require.Equal(t, int64(0), utils.InvoiceAmountMsats(nil))
+	require.Equal(t, "", utils.InvoicePaymentHash(nil))
+	require.True(t, utils.InvoiceExpiresAt(nil).IsZero())
+	require.True(t, utils.IsInvoiceExpired(nil, time.Now()))
+	require.Equal(t, int64(0), utils.InvoiceDataAmountMsats(&objects.InvoiceData{}))
+}
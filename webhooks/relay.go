@@ -0,0 +1,137 @@
+// Copyright ©, 2023-present, Lightspark Group, Inc. - All Rights Reserved
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+)
+
+// DEFAULT_RELAY_MAX_BODY_BYTES is the default size limit of the webhook messages accepted by a Relay.
+const DEFAULT_RELAY_MAX_BODY_BYTES = 1 << 20
+
+// Publisher publishes a message to a message queue (Kafka, NATS, SQS...). The key identifies the message and should
+// be used for partitioning or deduplication when the queue supports it.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key string, payload []byte) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface, e.g. to wrap a Kafka writer or an SQS client.
+type PublisherFunc func(ctx context.Context, topic string, key string, payload []byte) error
+
+func (f PublisherFunc) Publish(ctx context.Context, topic string, key string, payload []byte) error {
+	return f(ctx, topic, key, payload)
+}
+
+// NatsConn is the subset of a NATS connection used by NatsPublisher. *nats.Conn implements it.
+type NatsConn interface {
+	Publish(subject string, data []byte) error
+}
+
+// NatsPublisher publishes messages to NATS subjects named after the topic.
+type NatsPublisher struct {
+	Conn NatsConn
+}
+
+func (p NatsPublisher) Publish(ctx context.Context, topic string, key string, payload []byte) error {
+	return p.Conn.Publish(topic, payload)
+}
+
+// Relay republishes verified webhook events to a message queue. The published payload is the original event body,
+// so consumers can decode it with Parse, and the key is the event id.
+type Relay struct {
+	// Publisher is the queue the events are published to.
+	Publisher Publisher
+	// WebhookSecret is the webhook secret configured at the Lightspark API configuration.
+	WebhookSecret string
+	// Topic returns the topic of an event. Defaults to "lightspark.<event type>", e.g. "lightspark.PAYMENT_FINISHED".
+	Topic func(event *WebhookEvent) string
+	// MaxEventAge, if set, rejects the events whose timestamp is older, so that a captured message cannot be replayed
+	// later, with ErrStaleEvent. The age is measured with the clock of sdkruntime.Default().
+	MaxEventAge time.Duration
+	// MaxBodyBytes is the size limit of the messages, which are rejected with ErrEventTooLarge above it. Defaults to
+	// DEFAULT_RELAY_MAX_BODY_BYTES.
+	MaxBodyBytes int64
+}
+
+// ErrStaleEvent is returned by Relay for the events older than its MaxEventAge.
+var ErrStaleEvent = errors.New("webhook event is too old")
+
+// ErrEventTooLarge is returned by Relay for the messages larger than its MaxBodyBytes.
+var ErrEventTooLarge = errors.New("webhook event is too large")
+
+// Relay verifies a webhook message and publishes it.
+//
+// Args:
+//
+//	ctx: the context of the publish call.
+//	data: the POST message body received by the webhook.
+//	hexdigest: the message signature sent in the `lightspark-signature` header.
+func (r *Relay) Relay(ctx context.Context, data []byte, hexdigest string) (*WebhookEvent, error) {
+	event, err := r.verifyAndParse(data, hexdigest)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.publish(ctx, event, data); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ServeHTTP implements http.Handler, so the relay can be mounted as the webhook endpoint. It responds with 400 if the
+// message cannot be verified, 413 if it is larger than MaxBodyBytes, and 500 if it cannot be published, so that
+// Lightspark retries the delivery.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, request.Body, r.maxBodyBytes()))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, ErrEventTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	event, err := r.verifyAndParse(data, request.Header.Get(SIGNATURE_HEADER))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.publish(request.Context(), event, data); err != nil {
+		http.Error(w, "failed to publish event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Relay) maxBodyBytes() int64 {
+	if r.MaxBodyBytes > 0 {
+		return r.MaxBodyBytes
+	}
+	return DEFAULT_RELAY_MAX_BODY_BYTES
+}
+
+func (r *Relay) verifyAndParse(data []byte, hexdigest string) (*WebhookEvent, error) {
+	if int64(len(data)) > r.maxBodyBytes() {
+		return nil, ErrEventTooLarge
+	}
+	event, err := VerifyAndParse(data, hexdigest, r.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxEventAge > 0 && sdkruntime.Since(event.Timestamp) > r.MaxEventAge {
+		return nil, ErrStaleEvent
+	}
+	return event, nil
+}
+
+func (r *Relay) publish(ctx context.Context, event *WebhookEvent, data []byte) error {
+	topic := "lightspark." + event.EventType.StringValue()
+	if r.Topic != nil {
+		topic = r.Topic(event)
+	}
+	return r.Publisher.Publish(ctx, topic, event.EventId, data)
+}
@@ -0,0 +1,89 @@
+package webhooks_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightsparkdev/go-sdk/internal/sdkruntimetest"
+	"github.com/lightsparkdev/go-sdk/sdkruntime"
+	"github.com/lightsparkdev/go-sdk/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay_ServeHTTP(t *testing.T) {
+	data := `{"event_type": "NODE_STATUS", "event_id": "1615c8be5aa44e429eba700db2ed8ca5", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "lightning_node:01882c25-157a-f96b-0000-362d42b64397"}`
+	hexdigest := "62a8829aeb48b4142533520b1f7f86cdb1ee7d718bf3ea15bc1c662d4c453b74"
+	var topic, key, payload string
+	relay := &webhooks.Relay{
+		WebhookSecret: "3gZ5oQQUASYmqQNuEk0KambNMVkOADDItIJjzUlAWjX",
+		Publisher: webhooks.PublisherFunc(func(ctx context.Context, t string, k string, p []byte) error {
+			topic, key, payload = t, k, string(p)
+			return nil
+		}),
+	}
+
+	request := httptest.NewRequest("POST", "/webhooks", strings.NewReader(data))
+	request.Header.Set(webhooks.SIGNATURE_HEADER, hexdigest)
+	recorder := httptest.NewRecorder()
+	relay.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "lightspark.NODE_STATUS", topic)
+	require.Equal(t, "1615c8be5aa44e429eba700db2ed8ca5", key)
+	require.Equal(t, data, payload)
+
+	request = httptest.NewRequest("POST", "/webhooks", strings.NewReader(data))
+	request.Header.Set(webhooks.SIGNATURE_HEADER, "00")
+	recorder = httptest.NewRecorder()
+	relay.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRelay_MaxEventAge(t *testing.T) {
+	data := `{"event_type": "NODE_STATUS", "event_id": "1615c8be5aa44e429eba700db2ed8ca5", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "lightning_node:01882c25-157a-f96b-0000-362d42b64397"}`
+	hexdigest := "62a8829aeb48b4142533520b1f7f86cdb1ee7d718bf3ea15bc1c662d4c453b74"
+	runtime, clock := sdkruntime.NewDeterministicRuntime(time.Date(2023, 5, 17, 23, 57, 0, 0, time.UTC), "seed")
+	t.Cleanup(sdkruntimetest.SetDefault(runtime))
+	relay := &webhooks.Relay{
+		WebhookSecret: "3gZ5oQQUASYmqQNuEk0KambNMVkOADDItIJjzUlAWjX",
+		Publisher: webhooks.PublisherFunc(func(ctx context.Context, t string, k string, p []byte) error {
+			return nil
+		}),
+		MaxEventAge: time.Minute,
+	}
+
+	_, err := relay.Relay(context.Background(), []byte(data), hexdigest)
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+	_, err = relay.Relay(context.Background(), []byte(data), hexdigest)
+	require.ErrorIs(t, err, webhooks.ErrStaleEvent)
+}
+
+func TestRelay_MaxBodyBytes(t *testing.T) {
+	data := `{"event_type": "NODE_STATUS", "event_id": "1615c8be5aa44e429eba700db2ed8ca5", "timestamp": "2023-05-17T23:56:47.874449+00:00", "entity_id": "lightning_node:01882c25-157a-f96b-0000-362d42b64397"}`
+	hexdigest := "62a8829aeb48b4142533520b1f7f86cdb1ee7d718bf3ea15bc1c662d4c453b74"
+	published := 0
+	relay := &webhooks.Relay{
+		WebhookSecret: "3gZ5oQQUASYmqQNuEk0KambNMVkOADDItIJjzUlAWjX",
+		Publisher: webhooks.PublisherFunc(func(ctx context.Context, t string, k string, p []byte) error {
+			published++
+			return nil
+		}),
+		MaxBodyBytes: int64(len(data)),
+	}
+
+	_, err := relay.Relay(context.Background(), []byte(data), hexdigest)
+	require.NoError(t, err)
+	_, err = relay.Relay(context.Background(), []byte(data+" "), hexdigest)
+	require.ErrorIs(t, err, webhooks.ErrEventTooLarge)
+
+	request := httptest.NewRequest("POST", "/webhooks", strings.NewReader(data+strings.Repeat(" ", 1024)))
+	request.Header.Set(webhooks.SIGNATURE_HEADER, hexdigest)
+	recorder := httptest.NewRecorder()
+	relay.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	require.Equal(t, 1, published)
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/lightsparkdev/go-sdk/objects"
+	"github.com/lightsparkdev/go-sdk/requester"
 )
 
 const SIGNATURE_HEADER = "lightspark-signature"
@@ -33,6 +34,7 @@ type WebhookEvent struct {
 //	hexdigest: the message signature sent in the `lightspark-signature` header.
 //	webhookSecret: the webhook secret configured at the Lightspark API configuration.
 func VerifyAndParse(data []byte, hexdigest string, webhookSecret string) (*WebhookEvent, error) {
+	requester.ObserveSignablePayload(requester.SIGNABLE_WEBHOOK, "", data, true)
 	hash := hmac.New(sha256.New, []byte(webhookSecret))
 	hash.Write(data)
 	result := hash.Sum(nil)